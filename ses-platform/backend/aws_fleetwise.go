@@ -3,36 +3,50 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"regexp"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise"
 	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise/types"
+
+	"ses-platform/condition"
 )
 
 // AWSFleetWiseClient wraps AWS IoT FleetWise operations
 type AWSFleetWiseClient struct {
 	client *iotfleetwise.Client
-	ctx    context.Context
 }
 
 // FleetWiseConfig holds configuration for AWS IoT FleetWise integration
 type FleetWiseConfig struct {
-	Region              string   `json:"region"`
-	SignalCatalogARN    string   `json:"signal_catalog_arn"`
-	ModelManifestARN    string   `json:"model_manifest_arn"`
-	DecoderManifestARN  string   `json:"decoder_manifest_arn"`
-	FleetID             string   `json:"fleet_id"`
-	CampaignARN         string   `json:"campaign_arn"`
-	VehicleNames        []string `json:"vehicle_names"`
-	DataDestinationS3   string   `json:"data_destination_s3"`
-	DataDestinationMQTT string   `json:"data_destination_mqtt"`
-	EnableCompression   bool     `json:"enable_compression"`
-	EnableSpooling      bool     `json:"enable_spooling"`
-	EnableDiagnostics   bool     `json:"enable_diagnostics"`
+	Region             string   `json:"region"`
+	SignalCatalogARN   string   `json:"signal_catalog_arn"`
+	ModelManifestARN   string   `json:"model_manifest_arn"`
+	DecoderManifestARN string   `json:"decoder_manifest_arn"`
+	FleetID            string   `json:"fleet_id"`
+	CampaignARN        string   `json:"campaign_arn"` // Existing campaign to reuse; it is never created or deleted for the environment
+	VehicleNames       []string `json:"vehicle_names"`
+	// Campaigns are created with the environment, run while it runs and are
+	// deleted with it
+	Campaigns []CampaignConfig `json:"campaigns,omitempty"`
+	// RequireCampaignApproval holds new campaigns until they are approved through
+	// POST /environments/:id/campaigns/approve instead of approving them when
+	// the environment starts
+	RequireCampaignApproval bool `json:"require_campaign_approval,omitempty"`
+	// Defaults for campaigns that do not set data_destinations, compression,
+	// spooling_mode or diagnostics_mode
+	DataDestinationS3 string `json:"data_destination_s3"`
+	EnableCompression bool   `json:"enable_compression"`
+	EnableSpooling    bool   `json:"enable_spooling"`
+	EnableDiagnostics bool   `json:"enable_diagnostics"`
 }
 
 // VehicleConfig represents vehicle-specific configuration
@@ -44,20 +58,22 @@ type VehicleConfig struct {
 	CreateIoTThing     bool              `json:"create_iot_thing"`
 }
 
-// CampaignConfig represents campaign configuration
+// CampaignConfig represents campaign configuration. In FleetWiseConfig.Campaigns
+// the name is prefixed with the environment's campaign name, and the signal
+// catalog and target default to the environment's.
 type CampaignConfig struct {
-	Name                  string                 `json:"name"`
-	Description           string                 `json:"description"`
-	SignalCatalogARN      string                 `json:"signal_catalog_arn"`
-	TargetARN             string                 `json:"target_arn"`
-	CollectionScheme      CollectionScheme       `json:"collection_scheme"`
-	SignalsToCollect      []SignalToCollect      `json:"signals_to_collect"`
-	DataDestinations      []DataDestination      `json:"data_destinations"`
-	Compression           string                 `json:"compression"`
-	DiagnosticsMode       string                 `json:"diagnostics_mode"`
-	SpoolingMode          string                 `json:"spooling_mode"`
-	DataExtraDimensions   []string               `json:"data_extra_dimensions"`
-	PostTriggerDurationMs int64                  `json:"post_trigger_duration_ms"`
+	Name                  string            `json:"name"`
+	Description           string            `json:"description"`
+	SignalCatalogARN      string            `json:"signal_catalog_arn"`
+	TargetARN             string            `json:"target_arn"`
+	CollectionScheme      CollectionScheme  `json:"collection_scheme"`
+	SignalsToCollect      []SignalToCollect `json:"signals_to_collect"`
+	DataDestinations      []DataDestination `json:"data_destinations"`
+	Compression           string            `json:"compression"`
+	DiagnosticsMode       string            `json:"diagnostics_mode"`
+	SpoolingMode          string            `json:"spooling_mode"`
+	DataExtraDimensions   []string          `json:"data_extra_dimensions"`
+	PostTriggerDurationMs int64             `json:"post_trigger_duration_ms"`
 }
 
 // CollectionScheme defines how data is collected
@@ -78,36 +94,48 @@ type SignalToCollect struct {
 
 // DataDestination defines where data should be sent
 type DataDestination struct {
-	Type                     string `json:"type"` // "s3", "timestream", or "mqtt"
-	S3BucketARN              string `json:"s3_bucket_arn,omitempty"`
-	S3Prefix                 string `json:"s3_prefix,omitempty"`
-	S3DataFormat             string `json:"s3_data_format,omitempty"` // "JSON" or "PARQUET"
-	S3StorageCompression     string `json:"s3_storage_compression,omitempty"` // "NONE" or "GZIP"
-	TimestreamTableARN       string `json:"timestream_table_arn,omitempty"`
-	TimestreamExecutionRole  string `json:"timestream_execution_role,omitempty"`
-	MQTTTopicARN             string `json:"mqtt_topic_arn,omitempty"`
-	MQTTExecutionRole        string `json:"mqtt_execution_role,omitempty"`
+	Type                    string `json:"type"` // "s3" or "timestream"
+	S3BucketARN             string `json:"s3_bucket_arn,omitempty"`
+	S3Prefix                string `json:"s3_prefix,omitempty"`
+	S3DataFormat            string `json:"s3_data_format,omitempty"`         // "JSON" or "PARQUET"
+	S3StorageCompression    string `json:"s3_storage_compression,omitempty"` // "NONE" or "GZIP"
+	TimestreamTableARN      string `json:"timestream_table_arn,omitempty"`
+	TimestreamExecutionRole string `json:"timestream_execution_role,omitempty"`
 }
 
+// fleetWiseEndpoint overrides the FleetWise endpoint, e.g. to point the client
+// at the emulator. It is read from FLEETWISE_ENDPOINT.
+var fleetWiseEndpoint = os.Getenv("FLEETWISE_ENDPOINT")
+
+// fleetWiseCredentials replaces the default credential chain when set. The
+// in-process emulator sets it because requests must be signed even though the
+// emulator does not verify signatures.
+var fleetWiseCredentials aws.CredentialsProvider
+
 // NewAWSFleetWiseClient creates a new FleetWise client
 func NewAWSFleetWiseClient(region string) (*AWSFleetWiseClient, error) {
 	ctx := context.Background()
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	opts := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	if fleetWiseCredentials != nil {
+		opts = append(opts, config.WithCredentialsProvider(fleetWiseCredentials))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("unable to load SDK config: %v", err)
+		return nil, fmt.Errorf("unable to load SDK config: %w", err)
 	}
 
-	client := iotfleetwise.NewFromConfig(cfg)
+	client := iotfleetwise.NewFromConfig(cfg, func(o *iotfleetwise.Options) {
+		if fleetWiseEndpoint != "" {
+			o.BaseEndpoint = aws.String(fleetWiseEndpoint)
+		}
+	})
 
-	return &AWSFleetWiseClient{
-		client: client,
-		ctx:    ctx,
-	}, nil
+	return &AWSFleetWiseClient{client: client}, nil
 }
 
 // CreateVehicle creates a new vehicle in AWS IoT FleetWise
-func (c *AWSFleetWiseClient) CreateVehicle(vehicleConfig VehicleConfig) (*types.CreateVehicleOutput, error) {
+func (c *AWSFleetWiseClient) CreateVehicle(ctx context.Context, vehicleConfig VehicleConfig) (*iotfleetwise.CreateVehicleOutput, error) {
 	log.Printf("Creating vehicle: %s", vehicleConfig.Name)
 
 	// Convert attributes map to AWS SDK format
@@ -125,25 +153,26 @@ func (c *AWSFleetWiseClient) CreateVehicle(vehicleConfig VehicleConfig) (*types.
 	}
 
 	input := &iotfleetwise.CreateVehicleInput{
-		VehicleName:          aws.String(vehicleConfig.Name),
-		ModelManifestArn:     aws.String(vehicleConfig.ModelManifestARN),
-		DecoderManifestArn:   aws.String(vehicleConfig.DecoderManifestARN),
-		Attributes:           attributes,
-		AssociationBehavior:  associationBehavior,
+		VehicleName:         aws.String(vehicleConfig.Name),
+		ModelManifestArn:    aws.String(vehicleConfig.ModelManifestARN),
+		DecoderManifestArn:  aws.String(vehicleConfig.DecoderManifestARN),
+		Attributes:          attributes,
+		AssociationBehavior: associationBehavior,
 	}
 
-	result, err := c.client.CreateVehicle(c.ctx, input)
+	result, err := c.client.CreateVehicle(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create vehicle: %v", err)
+		return nil, fmt.Errorf("failed to create vehicle: %w", err)
 	}
 
 	log.Printf("Successfully created vehicle: %s (ARN: %s)", vehicleConfig.Name, *result.Arn)
 	return result, nil
 }
 
-// BatchCreateVehicles creates multiple vehicles in a batch
-func (c *AWSFleetWiseClient) BatchCreateVehicles(vehicles []VehicleConfig) ([]string, []error) {
-	var createdARNs []string
+// BatchCreateVehicles creates multiple vehicles in a batch and returns the
+// name and ARN of every vehicle that was created
+func (c *AWSFleetWiseClient) BatchCreateVehicles(ctx context.Context, vehicles []VehicleConfig) ([]types.CreateVehicleResponseItem, []error) {
+	var created []types.CreateVehicleResponseItem
 	var errors []error
 
 	// AWS supports up to 10 vehicles per batch, so we'll batch them
@@ -171,11 +200,11 @@ func (c *AWSFleetWiseClient) BatchCreateVehicles(vehicles []VehicleConfig) ([]st
 			}
 
 			batchInput = append(batchInput, types.CreateVehicleRequestItem{
-				VehicleName:          aws.String(v.Name),
-				ModelManifestArn:     aws.String(v.ModelManifestARN),
-				DecoderManifestArn:   aws.String(v.DecoderManifestARN),
-				Attributes:           attributes,
-				AssociationBehavior:  associationBehavior,
+				VehicleName:         aws.String(v.Name),
+				ModelManifestArn:    aws.String(v.ModelManifestARN),
+				DecoderManifestArn:  aws.String(v.DecoderManifestARN),
+				Attributes:          attributes,
+				AssociationBehavior: associationBehavior,
 			})
 		}
 
@@ -183,16 +212,16 @@ func (c *AWSFleetWiseClient) BatchCreateVehicles(vehicles []VehicleConfig) ([]st
 			Vehicles: batchInput,
 		}
 
-		result, err := c.client.BatchCreateVehicle(c.ctx, input)
+		result, err := c.client.BatchCreateVehicle(ctx, input)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("batch create failed: %v", err))
+			errors = append(errors, fmt.Errorf("batch create failed: %w", err))
 			continue
 		}
 
 		// Process successful vehicles
 		for _, v := range result.Vehicles {
 			if v.Arn != nil {
-				createdARNs = append(createdARNs, *v.Arn)
+				created = append(created, v)
 				log.Printf("Created vehicle: %s (ARN: %s)", *v.VehicleName, *v.Arn)
 			}
 		}
@@ -203,25 +232,25 @@ func (c *AWSFleetWiseClient) BatchCreateVehicles(vehicles []VehicleConfig) ([]st
 		}
 	}
 
-	return createdARNs, errors
+	return created, errors
 }
 
 // GetVehicle retrieves vehicle information
-func (c *AWSFleetWiseClient) GetVehicle(vehicleName string) (*iotfleetwise.GetVehicleOutput, error) {
+func (c *AWSFleetWiseClient) GetVehicle(ctx context.Context, vehicleName string) (*iotfleetwise.GetVehicleOutput, error) {
 	input := &iotfleetwise.GetVehicleInput{
 		VehicleName: aws.String(vehicleName),
 	}
 
-	result, err := c.client.GetVehicle(c.ctx, input)
+	result, err := c.client.GetVehicle(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get vehicle: %v", err)
+		return nil, fmt.Errorf("failed to get vehicle: %w", err)
 	}
 
 	return result, nil
 }
 
 // UpdateVehicle updates vehicle configuration
-func (c *AWSFleetWiseClient) UpdateVehicle(vehicleName string, updates VehicleConfig) error {
+func (c *AWSFleetWiseClient) UpdateVehicle(ctx context.Context, vehicleName string, updates VehicleConfig) error {
 	log.Printf("Updating vehicle: %s", vehicleName)
 
 	attributes := make(map[string]string)
@@ -230,16 +259,16 @@ func (c *AWSFleetWiseClient) UpdateVehicle(vehicleName string, updates VehicleCo
 	}
 
 	input := &iotfleetwise.UpdateVehicleInput{
-		VehicleName:        aws.String(vehicleName),
-		ModelManifestArn:   aws.String(updates.ModelManifestARN),
-		DecoderManifestArn: aws.String(updates.DecoderManifestARN),
-		Attributes:         attributes,
+		VehicleName:         aws.String(vehicleName),
+		ModelManifestArn:    aws.String(updates.ModelManifestARN),
+		DecoderManifestArn:  aws.String(updates.DecoderManifestARN),
+		Attributes:          attributes,
 		AttributeUpdateMode: types.UpdateModeOverwrite,
 	}
 
-	_, err := c.client.UpdateVehicle(c.ctx, input)
+	_, err := c.client.UpdateVehicle(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to update vehicle: %v", err)
+		return fmt.Errorf("failed to update vehicle: %w", err)
 	}
 
 	log.Printf("Successfully updated vehicle: %s", vehicleName)
@@ -247,16 +276,16 @@ func (c *AWSFleetWiseClient) UpdateVehicle(vehicleName string, updates VehicleCo
 }
 
 // DeleteVehicle deletes a vehicle
-func (c *AWSFleetWiseClient) DeleteVehicle(vehicleName string) error {
+func (c *AWSFleetWiseClient) DeleteVehicle(ctx context.Context, vehicleName string) error {
 	log.Printf("Deleting vehicle: %s", vehicleName)
 
 	input := &iotfleetwise.DeleteVehicleInput{
 		VehicleName: aws.String(vehicleName),
 	}
 
-	_, err := c.client.DeleteVehicle(c.ctx, input)
+	_, err := c.client.DeleteVehicle(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to delete vehicle: %v", err)
+		return fmt.Errorf("failed to delete vehicle: %w", err)
 	}
 
 	log.Printf("Successfully deleted vehicle: %s", vehicleName)
@@ -264,7 +293,7 @@ func (c *AWSFleetWiseClient) DeleteVehicle(vehicleName string) error {
 }
 
 // CreateCampaign creates a data collection campaign
-func (c *AWSFleetWiseClient) CreateCampaign(campaignConfig CampaignConfig) (*types.CreateCampaignOutput, error) {
+func (c *AWSFleetWiseClient) CreateCampaign(ctx context.Context, campaignConfig CampaignConfig) (*iotfleetwise.CreateCampaignOutput, error) {
 	log.Printf("Creating campaign: %s", campaignConfig.Name)
 
 	// Build collection scheme
@@ -285,10 +314,10 @@ func (c *AWSFleetWiseClient) CreateCampaign(campaignConfig CampaignConfig) (*typ
 
 		collectionScheme = &types.CollectionSchemeMemberConditionBasedCollectionScheme{
 			Value: types.ConditionBasedCollectionScheme{
-				Expression:                 aws.String(campaignConfig.CollectionScheme.Expression),
-				MinimumTriggerIntervalMs:   aws.Int64(campaignConfig.CollectionScheme.MinimumTriggerIntervalMs),
-				TriggerMode:                triggerMode,
-				ConditionLanguageVersion:   aws.Int32(1),
+				Expression:               aws.String(campaignConfig.CollectionScheme.Expression),
+				MinimumTriggerIntervalMs: aws.Int64(campaignConfig.CollectionScheme.MinimumTriggerIntervalMs),
+				TriggerMode:              triggerMode,
+				ConditionLanguageVersion: aws.Int32(1),
 			},
 		}
 	}
@@ -297,9 +326,9 @@ func (c *AWSFleetWiseClient) CreateCampaign(campaignConfig CampaignConfig) (*typ
 	var signalsToCollect []types.SignalInformation
 	for _, signal := range campaignConfig.SignalsToCollect {
 		signalsToCollect = append(signalsToCollect, types.SignalInformation{
-			Name:                       aws.String(signal.Name),
-			MaxSampleCount:             aws.Int64(signal.MaxSampleCount),
-			MinimumSamplingIntervalMs:  aws.Int64(signal.MinimumSamplingIntervalMs),
+			Name:                      aws.String(signal.Name),
+			MaxSampleCount:            aws.Int64(signal.MaxSampleCount),
+			MinimumSamplingIntervalMs: aws.Int64(signal.MinimumSamplingIntervalMs),
 		})
 	}
 
@@ -324,10 +353,10 @@ func (c *AWSFleetWiseClient) CreateCampaign(campaignConfig CampaignConfig) (*typ
 
 			dataDestinationConfigs = append(dataDestinationConfigs, &types.DataDestinationConfigMemberS3Config{
 				Value: types.S3Config{
-					BucketArn:                 aws.String(dest.S3BucketARN),
-					Prefix:                    aws.String(dest.S3Prefix),
-					DataFormat:                dataFormat,
-					StorageCompressionFormat:  compression,
+					BucketArn:                aws.String(dest.S3BucketARN),
+					Prefix:                   aws.String(dest.S3Prefix),
+					DataFormat:               dataFormat,
+					StorageCompressionFormat: compression,
 				},
 			})
 
@@ -338,14 +367,6 @@ func (c *AWSFleetWiseClient) CreateCampaign(campaignConfig CampaignConfig) (*typ
 					ExecutionRoleArn:   aws.String(dest.TimestreamExecutionRole),
 				},
 			})
-
-		case "mqtt":
-			dataDestinationConfigs = append(dataDestinationConfigs, &types.DataDestinationConfigMemberMqttTopicConfig{
-				Value: types.MqttTopicConfig{
-					MqttTopicArn:     aws.String(dest.MQTTTopicARN),
-					ExecutionRoleArn: aws.String(dest.MQTTExecutionRole),
-				},
-			})
 		}
 	}
 
@@ -374,23 +395,23 @@ func (c *AWSFleetWiseClient) CreateCampaign(campaignConfig CampaignConfig) (*typ
 	}
 
 	input := &iotfleetwise.CreateCampaignInput{
-		Name:                           aws.String(campaignConfig.Name),
-		Description:                    aws.String(campaignConfig.Description),
-		SignalCatalogArn:               aws.String(campaignConfig.SignalCatalogARN),
-		TargetArn:                      aws.String(campaignConfig.TargetARN),
-		CollectionScheme:               collectionScheme,
-		SignalsToCollect:               signalsToCollect,
-		DataDestinationConfigs:         dataDestinationConfigs,
-		Compression:                    compression,
-		DiagnosticsMode:                diagnosticsMode,
-		SpoolingMode:                   spoolingMode,
-		DataExtraDimensions:            campaignConfig.DataExtraDimensions,
-		PostTriggerCollectionDuration:  aws.Int64(campaignConfig.PostTriggerDurationMs),
+		Name:                          aws.String(campaignConfig.Name),
+		Description:                   optionalString(campaignConfig.Description),
+		SignalCatalogArn:              aws.String(campaignConfig.SignalCatalogARN),
+		TargetArn:                     aws.String(campaignConfig.TargetARN),
+		CollectionScheme:              collectionScheme,
+		SignalsToCollect:              signalsToCollect,
+		DataDestinationConfigs:        dataDestinationConfigs,
+		Compression:                   compression,
+		DiagnosticsMode:               diagnosticsMode,
+		SpoolingMode:                  spoolingMode,
+		DataExtraDimensions:           campaignConfig.DataExtraDimensions,
+		PostTriggerCollectionDuration: aws.Int64(campaignConfig.PostTriggerDurationMs),
 	}
 
-	result, err := c.client.CreateCampaign(c.ctx, input)
+	result, err := c.client.CreateCampaign(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create campaign: %v", err)
+		return nil, fmt.Errorf("failed to create campaign: %w", err)
 	}
 
 	log.Printf("Successfully created campaign: %s (ARN: %s)", campaignConfig.Name, *result.Arn)
@@ -398,21 +419,21 @@ func (c *AWSFleetWiseClient) CreateCampaign(campaignConfig CampaignConfig) (*typ
 }
 
 // GetCampaign retrieves campaign information
-func (c *AWSFleetWiseClient) GetCampaign(campaignName string) (*iotfleetwise.GetCampaignOutput, error) {
+func (c *AWSFleetWiseClient) GetCampaign(ctx context.Context, campaignName string) (*iotfleetwise.GetCampaignOutput, error) {
 	input := &iotfleetwise.GetCampaignInput{
 		Name: aws.String(campaignName),
 	}
 
-	result, err := c.client.GetCampaign(c.ctx, input)
+	result, err := c.client.GetCampaign(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get campaign: %v", err)
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
 	}
 
 	return result, nil
 }
 
 // UpdateCampaign updates campaign configuration
-func (c *AWSFleetWiseClient) UpdateCampaign(campaignName string, action string) error {
+func (c *AWSFleetWiseClient) UpdateCampaign(ctx context.Context, campaignName string, action string) error {
 	log.Printf("Updating campaign %s with action: %s", campaignName, action)
 
 	var updateAction types.UpdateCampaignAction
@@ -434,9 +455,9 @@ func (c *AWSFleetWiseClient) UpdateCampaign(campaignName string, action string)
 		Action: updateAction,
 	}
 
-	_, err := c.client.UpdateCampaign(c.ctx, input)
+	_, err := c.client.UpdateCampaign(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to update campaign: %v", err)
+		return fmt.Errorf("failed to update campaign: %w", err)
 	}
 
 	log.Printf("Successfully updated campaign: %s", campaignName)
@@ -444,16 +465,16 @@ func (c *AWSFleetWiseClient) UpdateCampaign(campaignName string, action string)
 }
 
 // DeleteCampaign deletes a campaign
-func (c *AWSFleetWiseClient) DeleteCampaign(campaignName string) error {
+func (c *AWSFleetWiseClient) DeleteCampaign(ctx context.Context, campaignName string) error {
 	log.Printf("Deleting campaign: %s", campaignName)
 
 	input := &iotfleetwise.DeleteCampaignInput{
 		Name: aws.String(campaignName),
 	}
 
-	_, err := c.client.DeleteCampaign(c.ctx, input)
+	_, err := c.client.DeleteCampaign(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to delete campaign: %v", err)
+		return fmt.Errorf("failed to delete campaign: %w", err)
 	}
 
 	log.Printf("Successfully deleted campaign: %s", campaignName)
@@ -461,7 +482,7 @@ func (c *AWSFleetWiseClient) DeleteCampaign(campaignName string) error {
 }
 
 // CreateFleet creates a vehicle fleet
-func (c *AWSFleetWiseClient) CreateFleet(fleetID, description, signalCatalogARN string) (*types.CreateFleetOutput, error) {
+func (c *AWSFleetWiseClient) CreateFleet(ctx context.Context, fleetID, description, signalCatalogARN string) (*iotfleetwise.CreateFleetOutput, error) {
 	log.Printf("Creating fleet: %s", fleetID)
 
 	input := &iotfleetwise.CreateFleetInput{
@@ -470,9 +491,9 @@ func (c *AWSFleetWiseClient) CreateFleet(fleetID, description, signalCatalogARN
 		SignalCatalogArn: aws.String(signalCatalogARN),
 	}
 
-	result, err := c.client.CreateFleet(c.ctx, input)
+	result, err := c.client.CreateFleet(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create fleet: %v", err)
+		return nil, fmt.Errorf("failed to create fleet: %w", err)
 	}
 
 	log.Printf("Successfully created fleet: %s (ARN: %s)", fleetID, *result.Arn)
@@ -480,7 +501,7 @@ func (c *AWSFleetWiseClient) CreateFleet(fleetID, description, signalCatalogARN
 }
 
 // AssociateVehicleToFleet associates a vehicle with a fleet
-func (c *AWSFleetWiseClient) AssociateVehicleToFleet(vehicleName, fleetID string) error {
+func (c *AWSFleetWiseClient) AssociateVehicleToFleet(ctx context.Context, vehicleName, fleetID string) error {
 	log.Printf("Associating vehicle %s to fleet %s", vehicleName, fleetID)
 
 	input := &iotfleetwise.AssociateVehicleFleetInput{
@@ -488,76 +509,668 @@ func (c *AWSFleetWiseClient) AssociateVehicleToFleet(vehicleName, fleetID string
 		FleetId:     aws.String(fleetID),
 	}
 
-	_, err := c.client.AssociateVehicleFleet(c.ctx, input)
+	_, err := c.client.AssociateVehicleFleet(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to associate vehicle to fleet: %v", err)
+		return fmt.Errorf("failed to associate vehicle to fleet: %w", err)
 	}
 
 	log.Printf("Successfully associated vehicle %s to fleet %s", vehicleName, fleetID)
 	return nil
 }
 
-// ListVehicles lists all vehicles
-func (c *AWSFleetWiseClient) ListVehicles(modelManifestARN string, maxResults int32) ([]types.VehicleSummary, error) {
-	input := &iotfleetwise.ListVehiclesInput{
+// DisassociateVehicleFromFleet removes a vehicle from a fleet
+func (c *AWSFleetWiseClient) DisassociateVehicleFromFleet(ctx context.Context, vehicleName, fleetID string) error {
+	log.Printf("Disassociating vehicle %s from fleet %s", vehicleName, fleetID)
+
+	input := &iotfleetwise.DisassociateVehicleFleetInput{
+		VehicleName: aws.String(vehicleName),
+		FleetId:     aws.String(fleetID),
+	}
+
+	_, err := c.client.DisassociateVehicleFleet(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to disassociate vehicle from fleet: %w", err)
+	}
+
+	log.Printf("Successfully disassociated vehicle %s from fleet %s", vehicleName, fleetID)
+	return nil
+}
+
+// DeleteFleet deletes a fleet. Vehicles must be disassociated first.
+func (c *AWSFleetWiseClient) DeleteFleet(ctx context.Context, fleetID string) error {
+	log.Printf("Deleting fleet: %s", fleetID)
+
+	input := &iotfleetwise.DeleteFleetInput{
+		FleetId: aws.String(fleetID),
+	}
+
+	_, err := c.client.DeleteFleet(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to delete fleet: %w", err)
+	}
+
+	log.Printf("Successfully deleted fleet: %s", fleetID)
+	return nil
+}
+
+// AssociateVehiclesToFleet associates many vehicles with a fleet and returns
+// the names of the vehicles that were associated. FleetWise has no batch
+// association, so vehicles are associated ten at a time, concurrently.
+func (c *AWSFleetWiseClient) AssociateVehiclesToFleet(ctx context.Context, vehicleNames []string, fleetID string) ([]string, []error) {
+	var associated []string
+	var errors []error
+
+	batchSize := 10
+	for i := 0; i < len(vehicleNames); i += batchSize {
+		end := i + batchSize
+		if end > len(vehicleNames) {
+			end = len(vehicleNames)
+		}
+
+		batch := vehicleNames[i:end]
+		results := make([]error, len(batch))
+		var wg sync.WaitGroup
+		for j, name := range batch {
+			wg.Add(1)
+			go func(j int, name string) {
+				defer wg.Done()
+				results[j] = c.AssociateVehicleToFleet(ctx, name, fleetID)
+			}(j, name)
+		}
+		wg.Wait()
+
+		for j, err := range results {
+			if err != nil {
+				errors = append(errors, fmt.Errorf("vehicle %s: %w", batch[j], err))
+				continue
+			}
+			associated = append(associated, batch[j])
+		}
+	}
+
+	return associated, errors
+}
+
+// GetFleet retrieves fleet information
+func (c *AWSFleetWiseClient) GetFleet(ctx context.Context, fleetID string) (*iotfleetwise.GetFleetOutput, error) {
+	input := &iotfleetwise.GetFleetInput{
+		FleetId: aws.String(fleetID),
+	}
+
+	result, err := c.client.GetFleet(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fleet: %w", err)
+	}
+
+	return result, nil
+}
+
+// ListFleets lists every fleet
+func (c *AWSFleetWiseClient) ListFleets(ctx context.Context) ([]types.FleetSummary, error) {
+	input := &iotfleetwise.ListFleetsInput{
+		MaxResults: aws.Int32(fleetWiseMaxResults),
+	}
+
+	var fleets []types.FleetSummary
+	paginator := iotfleetwise.NewListFleetsPaginator(c.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list fleets: %w", err)
+		}
+		fleets = append(fleets, page.FleetSummaries...)
+	}
+
+	return fleets, nil
+}
+
+// ListFleetsPage lists one page of fleets and returns the token of the next
+// page ("" after the last)
+func (c *AWSFleetWiseClient) ListFleetsPage(ctx context.Context, nextToken string, maxResults int32) ([]types.FleetSummary, string, error) {
+	input := &iotfleetwise.ListFleetsInput{
+		NextToken:  optionalString(nextToken),
 		MaxResults: aws.Int32(maxResults),
 	}
 
-	if modelManifestARN != "" {
-		input.ModelManifestArn = aws.String(modelManifestARN)
+	result, err := c.client.ListFleets(ctx, input)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list fleets: %w", err)
+	}
+
+	return result.FleetSummaries, aws.ToString(result.NextToken), nil
+}
+
+// ListVehiclesInFleet lists the names of every vehicle in a fleet
+func (c *AWSFleetWiseClient) ListVehiclesInFleet(ctx context.Context, fleetID string) ([]string, error) {
+	input := &iotfleetwise.ListVehiclesInFleetInput{
+		FleetId:    aws.String(fleetID),
+		MaxResults: aws.Int32(fleetWiseMaxResults),
+	}
+
+	var vehicles []string
+	paginator := iotfleetwise.NewListVehiclesInFleetPaginator(c.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list vehicles in fleet: %w", err)
+		}
+		vehicles = append(vehicles, page.Vehicles...)
+	}
+
+	return vehicles, nil
+}
+
+// ListFleetsForVehicle lists the IDs of every fleet a vehicle belongs to
+func (c *AWSFleetWiseClient) ListFleetsForVehicle(ctx context.Context, vehicleName string) ([]string, error) {
+	input := &iotfleetwise.ListFleetsForVehicleInput{
+		VehicleName: aws.String(vehicleName),
+		MaxResults:  aws.Int32(fleetWiseMaxResults),
+	}
+
+	var fleets []string
+	paginator := iotfleetwise.NewListFleetsForVehiclePaginator(c.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list fleets for vehicle: %w", err)
+		}
+		fleets = append(fleets, page.Fleets...)
+	}
+
+	return fleets, nil
+}
+
+// ListVehicles lists every vehicle, optionally only those of a model manifest
+func (c *AWSFleetWiseClient) ListVehicles(ctx context.Context, modelManifestARN string) ([]types.VehicleSummary, error) {
+	input := &iotfleetwise.ListVehiclesInput{
+		ModelManifestArn: optionalString(modelManifestARN),
+		MaxResults:       aws.Int32(fleetWiseMaxResults),
+	}
+
+	var vehicles []types.VehicleSummary
+	paginator := iotfleetwise.NewListVehiclesPaginator(c.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list vehicles: %w", err)
+		}
+		vehicles = append(vehicles, page.VehicleSummaries...)
+	}
+
+	return vehicles, nil
+}
+
+// ListVehiclesPage lists one page of vehicles, optionally only those of a
+// model manifest, and returns the token of the next page ("" after the last)
+func (c *AWSFleetWiseClient) ListVehiclesPage(ctx context.Context, modelManifestARN, nextToken string, maxResults int32) ([]types.VehicleSummary, string, error) {
+	input := &iotfleetwise.ListVehiclesInput{
+		ModelManifestArn: optionalString(modelManifestARN),
+		NextToken:        optionalString(nextToken),
+		MaxResults:       aws.Int32(maxResults),
 	}
 
-	result, err := c.client.ListVehicles(c.ctx, input)
+	result, err := c.client.ListVehicles(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list vehicles: %v", err)
+		return nil, "", fmt.Errorf("failed to list vehicles: %w", err)
+	}
+
+	return result.VehicleSummaries, aws.ToString(result.NextToken), nil
+}
+
+// ListCampaigns lists every campaign, optionally only those with a status
+func (c *AWSFleetWiseClient) ListCampaigns(ctx context.Context, status string) ([]types.CampaignSummary, error) {
+	input := &iotfleetwise.ListCampaignsInput{
+		Status:     optionalString(status),
+		MaxResults: aws.Int32(fleetWiseMaxResults),
+	}
+
+	var campaigns []types.CampaignSummary
+	paginator := iotfleetwise.NewListCampaignsPaginator(c.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list campaigns: %w", err)
+		}
+		campaigns = append(campaigns, page.CampaignSummaries...)
 	}
 
-	return result.VehicleSummaries, nil
+	return campaigns, nil
 }
 
-// ListCampaigns lists all campaigns
-func (c *AWSFleetWiseClient) ListCampaigns(maxResults int32) ([]types.CampaignSummary, error) {
+// ListCampaignsPage lists one page of campaigns, optionally only those with a
+// status, and returns the token of the next page ("" after the last)
+func (c *AWSFleetWiseClient) ListCampaignsPage(ctx context.Context, status, nextToken string, maxResults int32) ([]types.CampaignSummary, string, error) {
 	input := &iotfleetwise.ListCampaignsInput{
+		Status:     optionalString(status),
+		NextToken:  optionalString(nextToken),
 		MaxResults: aws.Int32(maxResults),
 	}
 
-	result, err := c.client.ListCampaigns(c.ctx, input)
+	result, err := c.client.ListCampaigns(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list campaigns: %v", err)
+		return nil, "", fmt.Errorf("failed to list campaigns: %w", err)
 	}
 
-	return result.CampaignSummaries, nil
+	return result.CampaignSummaries, aws.ToString(result.NextToken), nil
 }
 
 // GetVehicleStatus gets the status of a vehicle
-func (c *AWSFleetWiseClient) GetVehicleStatus(vehicleName string) (*iotfleetwise.GetVehicleStatusOutput, error) {
+func (c *AWSFleetWiseClient) GetVehicleStatus(ctx context.Context, vehicleName string) (*iotfleetwise.GetVehicleStatusOutput, error) {
 	input := &iotfleetwise.GetVehicleStatusInput{
 		VehicleName: aws.String(vehicleName),
 	}
 
-	result, err := c.client.GetVehicleStatus(c.ctx, input)
+	result, err := c.client.GetVehicleStatus(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get vehicle status: %v", err)
+		return nil, fmt.Errorf("failed to get vehicle status: %w", err)
 	}
 
 	return result, nil
 }
 
-// ProvisionFleetWiseEnvironment provisions a complete FleetWise environment
-func (c *AWSFleetWiseClient) ProvisionFleetWiseEnvironment(envID string, config FleetWiseConfig) error {
-	log.Printf("Provisioning FleetWise environment: %s", envID)
+// CreateSignalCatalog creates a signal catalog with the given nodes
+func (c *AWSFleetWiseClient) CreateSignalCatalog(ctx context.Context, name, description string, nodes []SignalNode) (*iotfleetwise.CreateSignalCatalogOutput, error) {
+	log.Printf("Creating signal catalog: %s (%d nodes)", name, len(nodes))
 
-	// Step 1: Create fleet if specified
-	if config.FleetID != "" {
-		_, err := c.CreateFleet(config.FleetID, fmt.Sprintf("Fleet for environment %s", envID), config.SignalCatalogARN)
+	converted, err := toFleetWiseNodes(nodes)
+	if err != nil {
+		return nil, err
+	}
+	input := &iotfleetwise.CreateSignalCatalogInput{
+		Name:        aws.String(name),
+		Description: optionalString(description),
+		Nodes:       converted,
+	}
+
+	result, err := c.client.CreateSignalCatalog(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signal catalog: %w", err)
+	}
+
+	log.Printf("Successfully created signal catalog: %s (ARN: %s)", name, *result.Arn)
+	return result, nil
+}
+
+// GetSignalCatalog retrieves a signal catalog and its node counts
+func (c *AWSFleetWiseClient) GetSignalCatalog(ctx context.Context, name string) (*iotfleetwise.GetSignalCatalogOutput, error) {
+	input := &iotfleetwise.GetSignalCatalogInput{
+		Name: aws.String(name),
+	}
+
+	result, err := c.client.GetSignalCatalog(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signal catalog: %w", err)
+	}
+
+	return result, nil
+}
+
+// ListSignalCatalogNodes returns every node of a signal catalog. Node types
+// SignalNode cannot represent, such as custom structs, are skipped.
+func (c *AWSFleetWiseClient) ListSignalCatalogNodes(ctx context.Context, name string) ([]SignalNode, error) {
+	input := &iotfleetwise.ListSignalCatalogNodesInput{
+		Name: aws.String(name),
+	}
+
+	var nodes []SignalNode
+	paginator := iotfleetwise.NewListSignalCatalogNodesPaginator(c.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to create fleet: %v", err)
+			return nil, fmt.Errorf("failed to list signal catalog nodes: %w", err)
+		}
+		for _, node := range page.Nodes {
+			if converted, ok := fromFleetWiseNode(node); ok {
+				nodes = append(nodes, converted)
+			}
 		}
 	}
 
-	// Step 2: Create vehicles
+	return nodes, nil
+}
+
+// UpdateSignalCatalog applies node changes to a signal catalog. Nodes to
+// replace are removed in a separate call first and then added with the others.
+func (c *AWSFleetWiseClient) UpdateSignalCatalog(ctx context.Context, name, description string, changes SignalCatalogChanges) error {
+	log.Printf("Updating signal catalog %s: %d to add, %d to update, %d to remove, %d to replace",
+		name, len(changes.NodesToAdd), len(changes.NodesToUpdate), len(changes.NodesToRemove), len(changes.NodesToReplace))
+
+	toAdd, err := toFleetWiseNodes(slices.Concat(changes.NodesToReplace, changes.NodesToAdd))
+	if err != nil {
+		return err
+	}
+	toUpdate, err := toFleetWiseNodes(changes.NodesToUpdate)
+	if err != nil {
+		return err
+	}
+
+	var replaced []string
+	for _, node := range changes.NodesToReplace {
+		replaced = append(replaced, node.FullyQualifiedName)
+	}
+	if len(replaced) > 0 {
+		_, err := c.client.UpdateSignalCatalog(ctx, &iotfleetwise.UpdateSignalCatalogInput{
+			Name:          aws.String(name),
+			NodesToRemove: replaced,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove replaced signal catalog nodes: %w", err)
+		}
+	}
+
+	input := &iotfleetwise.UpdateSignalCatalogInput{
+		Name:          aws.String(name),
+		Description:   optionalString(description),
+		NodesToAdd:    toAdd,
+		NodesToUpdate: toUpdate,
+		NodesToRemove: changes.NodesToRemove,
+	}
+
+	_, err = c.client.UpdateSignalCatalog(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to update signal catalog: %w", err)
+	}
+
+	log.Printf("Successfully updated signal catalog: %s", name)
+	return nil
+}
+
+// DeleteSignalCatalog deletes a signal catalog
+func (c *AWSFleetWiseClient) DeleteSignalCatalog(ctx context.Context, name string) error {
+	log.Printf("Deleting signal catalog: %s", name)
+
+	input := &iotfleetwise.DeleteSignalCatalogInput{
+		Name: aws.String(name),
+	}
+
+	_, err := c.client.DeleteSignalCatalog(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to delete signal catalog: %w", err)
+	}
+
+	log.Printf("Successfully deleted signal catalog: %s", name)
+	return nil
+}
+
+// ListSignalCatalogs lists signal catalogs
+func (c *AWSFleetWiseClient) ListSignalCatalogs(ctx context.Context, maxResults int32) ([]types.SignalCatalogSummary, error) {
+	input := &iotfleetwise.ListSignalCatalogsInput{
+		MaxResults: aws.Int32(maxResults),
+	}
+
+	result, err := c.client.ListSignalCatalogs(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signal catalogs: %w", err)
+	}
+
+	return result.Summaries, nil
+}
+
+// CreateModelManifest creates a DRAFT model manifest from signal catalog nodes
+func (c *AWSFleetWiseClient) CreateModelManifest(ctx context.Context, name, description, signalCatalogARN string, nodes []string) (*iotfleetwise.CreateModelManifestOutput, error) {
+	log.Printf("Creating model manifest: %s (%d nodes)", name, len(nodes))
+
+	input := &iotfleetwise.CreateModelManifestInput{
+		Name:             aws.String(name),
+		Description:      optionalString(description),
+		SignalCatalogArn: aws.String(signalCatalogARN),
+		Nodes:            nodes,
+	}
+
+	result, err := c.client.CreateModelManifest(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create model manifest: %w", err)
+	}
+
+	log.Printf("Successfully created model manifest: %s (ARN: %s)", name, *result.Arn)
+	return result, nil
+}
+
+// GetModelManifest retrieves a model manifest and its status
+func (c *AWSFleetWiseClient) GetModelManifest(ctx context.Context, name string) (*iotfleetwise.GetModelManifestOutput, error) {
+	input := &iotfleetwise.GetModelManifestInput{
+		Name: aws.String(name),
+	}
+
+	result, err := c.client.GetModelManifest(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model manifest: %w", err)
+	}
+
+	return result, nil
+}
+
+// ListModelManifestNodes returns every node of a model manifest
+func (c *AWSFleetWiseClient) ListModelManifestNodes(ctx context.Context, name string) ([]SignalNode, error) {
+	input := &iotfleetwise.ListModelManifestNodesInput{
+		Name: aws.String(name),
+	}
+
+	var nodes []SignalNode
+	paginator := iotfleetwise.NewListModelManifestNodesPaginator(c.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list model manifest nodes: %w", err)
+		}
+		for _, node := range page.Nodes {
+			if converted, ok := fromFleetWiseNode(node); ok {
+				nodes = append(nodes, converted)
+			}
+		}
+	}
+
+	return nodes, nil
+}
+
+// UpdateModelManifest adds and removes nodes of a DRAFT model manifest. A
+// non-empty status, e.g. ACTIVE, is applied too.
+func (c *AWSFleetWiseClient) UpdateModelManifest(ctx context.Context, name, description string, nodesToAdd, nodesToRemove []string, status types.ManifestStatus) error {
+	log.Printf("Updating model manifest %s: %d to add, %d to remove, status %q", name, len(nodesToAdd), len(nodesToRemove), status)
+
+	input := &iotfleetwise.UpdateModelManifestInput{
+		Name:          aws.String(name),
+		Description:   optionalString(description),
+		NodesToAdd:    nodesToAdd,
+		NodesToRemove: nodesToRemove,
+		Status:        status,
+	}
+
+	_, err := c.client.UpdateModelManifest(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to update model manifest: %w", err)
+	}
+
+	log.Printf("Successfully updated model manifest: %s", name)
+	return nil
+}
+
+// DeleteModelManifest deletes a model manifest
+func (c *AWSFleetWiseClient) DeleteModelManifest(ctx context.Context, name string) error {
+	log.Printf("Deleting model manifest: %s", name)
+
+	input := &iotfleetwise.DeleteModelManifestInput{
+		Name: aws.String(name),
+	}
+
+	_, err := c.client.DeleteModelManifest(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to delete model manifest: %w", err)
+	}
+
+	log.Printf("Successfully deleted model manifest: %s", name)
+	return nil
+}
+
+// ListModelManifests lists model manifests, optionally only those of a signal catalog
+func (c *AWSFleetWiseClient) ListModelManifests(ctx context.Context, signalCatalogARN string, maxResults int32) ([]types.ModelManifestSummary, error) {
+	input := &iotfleetwise.ListModelManifestsInput{
+		SignalCatalogArn: optionalString(signalCatalogARN),
+		MaxResults:       aws.Int32(maxResults),
+	}
+
+	result, err := c.client.ListModelManifests(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list model manifests: %w", err)
+	}
+
+	return result.Summaries, nil
+}
+
+// CreateDecoderManifest creates a DRAFT decoder manifest for a model manifest
+func (c *AWSFleetWiseClient) CreateDecoderManifest(ctx context.Context, name, description, modelManifestARN string, interfaces []NetworkInterfaceConfig, decoders []SignalDecoderConfig) (*iotfleetwise.CreateDecoderManifestOutput, error) {
+	log.Printf("Creating decoder manifest: %s (%d interfaces, %d signal decoders)", name, len(interfaces), len(decoders))
+
+	input := &iotfleetwise.CreateDecoderManifestInput{
+		Name:              aws.String(name),
+		Description:       optionalString(description),
+		ModelManifestArn:  aws.String(modelManifestARN),
+		NetworkInterfaces: toFleetWiseNetworkInterfaces(interfaces),
+		SignalDecoders:    toFleetWiseSignalDecoders(decoders),
+	}
+
+	result, err := c.client.CreateDecoderManifest(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create decoder manifest: %w", err)
+	}
+
+	log.Printf("Successfully created decoder manifest: %s (ARN: %s)", name, *result.Arn)
+	return result, nil
+}
+
+// GetDecoderManifest retrieves a decoder manifest and its status
+func (c *AWSFleetWiseClient) GetDecoderManifest(ctx context.Context, name string) (*iotfleetwise.GetDecoderManifestOutput, error) {
+	input := &iotfleetwise.GetDecoderManifestInput{
+		Name: aws.String(name),
+	}
+
+	result, err := c.client.GetDecoderManifest(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get decoder manifest: %w", err)
+	}
+
+	return result, nil
+}
+
+// ListDecoderManifestNetworkInterfaces returns every network interface of a decoder manifest
+func (c *AWSFleetWiseClient) ListDecoderManifestNetworkInterfaces(ctx context.Context, name string) ([]NetworkInterfaceConfig, error) {
+	input := &iotfleetwise.ListDecoderManifestNetworkInterfacesInput{
+		Name: aws.String(name),
+	}
+
+	var interfaces []NetworkInterfaceConfig
+	paginator := iotfleetwise.NewListDecoderManifestNetworkInterfacesPaginator(c.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list decoder manifest network interfaces: %w", err)
+		}
+		for _, ni := range page.NetworkInterfaces {
+			interfaces = append(interfaces, fromFleetWiseNetworkInterface(ni))
+		}
+	}
+
+	return interfaces, nil
+}
+
+// ListDecoderManifestSignalDecoders returns every signal decoder of a decoder manifest
+func (c *AWSFleetWiseClient) ListDecoderManifestSignalDecoders(ctx context.Context, name string) ([]SignalDecoderConfig, error) {
+	input := &iotfleetwise.ListDecoderManifestSignalsInput{
+		Name: aws.String(name),
+	}
+
+	var decoders []SignalDecoderConfig
+	paginator := iotfleetwise.NewListDecoderManifestSignalsPaginator(c.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list decoder manifest signals: %w", err)
+		}
+		for _, d := range page.SignalDecoders {
+			decoders = append(decoders, fromFleetWiseSignalDecoder(d))
+		}
+	}
+
+	return decoders, nil
+}
+
+// UpdateDecoderManifest edits the interfaces and decoders of a DRAFT decoder
+// manifest. A non-empty status, e.g. ACTIVE, is applied too.
+func (c *AWSFleetWiseClient) UpdateDecoderManifest(ctx context.Context, name, description string, changes DecoderManifestChanges, status types.ManifestStatus) error {
+	log.Printf("Updating decoder manifest %s: %d/%d/%d interfaces and %d/%d/%d signal decoders to add/update/remove, status %q", name,
+		len(changes.NetworkInterfacesToAdd), len(changes.NetworkInterfacesToUpdate), len(changes.NetworkInterfacesToRemove),
+		len(changes.SignalDecodersToAdd), len(changes.SignalDecodersToUpdate), len(changes.SignalDecodersToRemove), status)
+
+	input := &iotfleetwise.UpdateDecoderManifestInput{
+		Name:                      aws.String(name),
+		Description:               optionalString(description),
+		NetworkInterfacesToAdd:    toFleetWiseNetworkInterfaces(changes.NetworkInterfacesToAdd),
+		NetworkInterfacesToUpdate: toFleetWiseNetworkInterfaces(changes.NetworkInterfacesToUpdate),
+		NetworkInterfacesToRemove: changes.NetworkInterfacesToRemove,
+		SignalDecodersToAdd:       toFleetWiseSignalDecoders(changes.SignalDecodersToAdd),
+		SignalDecodersToUpdate:    toFleetWiseSignalDecoders(changes.SignalDecodersToUpdate),
+		SignalDecodersToRemove:    changes.SignalDecodersToRemove,
+		Status:                    status,
+	}
+
+	_, err := c.client.UpdateDecoderManifest(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to update decoder manifest: %w", err)
+	}
+
+	log.Printf("Successfully updated decoder manifest: %s", name)
+	return nil
+}
+
+// DeleteDecoderManifest deletes a decoder manifest
+func (c *AWSFleetWiseClient) DeleteDecoderManifest(ctx context.Context, name string) error {
+	log.Printf("Deleting decoder manifest: %s", name)
+
+	input := &iotfleetwise.DeleteDecoderManifestInput{
+		Name: aws.String(name),
+	}
+
+	_, err := c.client.DeleteDecoderManifest(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to delete decoder manifest: %w", err)
+	}
+
+	log.Printf("Successfully deleted decoder manifest: %s", name)
+	return nil
+}
+
+// ListDecoderManifests lists decoder manifests, optionally only those of a model manifest
+func (c *AWSFleetWiseClient) ListDecoderManifests(ctx context.Context, modelManifestARN string, maxResults int32) ([]types.DecoderManifestSummary, error) {
+	input := &iotfleetwise.ListDecoderManifestsInput{
+		ModelManifestArn: optionalString(modelManifestARN),
+		MaxResults:       aws.Int32(maxResults),
+	}
+
+	result, err := c.client.ListDecoderManifests(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list decoder manifests: %w", err)
+	}
+
+	return result.Summaries, nil
+}
+
+// CreateEnvironmentFleet creates the environment's fleet, if one is configured,
+// and returns its ARN
+func CreateEnvironmentFleet(ctx context.Context, api FleetWiseAPI, envID string, config FleetWiseConfig) (string, error) {
+	if config.FleetID == "" {
+		return "", nil
+	}
+	result, err := api.CreateFleet(ctx, config.FleetID, fmt.Sprintf("Fleet for environment %s", envID), config.SignalCatalogARN)
+	if err != nil {
+		return "", fmt.Errorf("failed to create fleet: %w", err)
+	}
+	return *result.Arn, nil
+}
+
+// CreateEnvironmentVehicles creates the named vehicles tagged with the environment's ID
+func CreateEnvironmentVehicles(ctx context.Context, api FleetWiseAPI, envID string, config FleetWiseConfig, names []string) ([]types.CreateVehicleResponseItem, []error) {
 	var vehicles []VehicleConfig
-	for _, name := range config.VehicleNames {
+	for _, name := range names {
 		vehicles = append(vehicles, VehicleConfig{
 			Name:               name,
 			ModelManifestARN:   config.ModelManifestARN,
@@ -569,32 +1182,58 @@ func (c *AWSFleetWiseClient) ProvisionFleetWiseEnvironment(envID string, config
 			CreateIoTThing: true,
 		})
 	}
+	return api.BatchCreateVehicles(ctx, vehicles)
+}
 
-	createdARNs, errors := c.BatchCreateVehicles(vehicles)
-	if len(errors) > 0 {
-		log.Printf("Errors creating vehicles: %v", errors)
+// environmentCampaignName is the name of an environment's campaign. Campaigns
+// of FleetWiseConfig.Campaigns are suffixed with their configured name;
+// environments provisioned before those existed had one unsuffixed campaign.
+func environmentCampaignName(envID, name string) string {
+	if name == "" {
+		return fmt.Sprintf("campaign-%s", envID)
 	}
-	log.Printf("Created %d vehicles", len(createdARNs))
+	return fmt.Sprintf("campaign-%s-%s", envID, name)
+}
 
-	// Step 3: Associate vehicles to fleet
-	if config.FleetID != "" {
-		for _, name := range config.VehicleNames {
-			err := c.AssociateVehicleToFleet(name, config.FleetID)
-			if err != nil {
-				log.Printf("Warning: failed to associate vehicle %s to fleet: %v", name, err)
-			}
-		}
+// environmentCampaignPattern matches the names environmentCampaignName gives.
+// Environment IDs are env-<unix time>, optionally followed by _<hex>, so they
+// end at the next dash.
+var environmentCampaignPattern = regexp.MustCompile(`^campaign-(env-[^-]+)(?:-|$)`)
+
+// campaignEnvironmentID returns the ID of the environment a campaign was created for
+func campaignEnvironmentID(campaignName string) (string, bool) {
+	match := environmentCampaignPattern.FindStringSubmatch(campaignName)
+	if match == nil {
+		return "", false
 	}
+	return match[1], true
+}
 
-	// Step 4: Create campaign if configured
-	if config.CampaignARN != "" {
-		// Extract campaign name from ARN or use a generated name
-		campaignName := fmt.Sprintf("campaign-%s", envID)
+// campaignNameFromARN returns the name in a campaign ARN,
+// arn:aws:iotfleetwise:<region>:<account>:campaign/<name>
+func campaignNameFromARN(arn string) string {
+	_, name, _ := cutLast(arn, "/")
+	return name
+}
+
+// EnvironmentCampaign completes a campaign of the environment's configuration:
+// it names the campaign after the environment, and fills in the signal
+// catalog, target and the data destination and mode defaults
+func EnvironmentCampaign(envID string, config FleetWiseConfig, campaign CampaignConfig, targetARN string) CampaignConfig {
+	campaign.Name = environmentCampaignName(envID, campaign.Name)
+	if campaign.Description == "" {
+		campaign.Description = fmt.Sprintf("Data collection campaign for environment %s", envID)
+	}
+	if campaign.SignalCatalogARN == "" {
+		campaign.SignalCatalogARN = config.SignalCatalogARN
+	}
+	if campaign.TargetARN == "" {
+		campaign.TargetARN = targetARN
+	}
 
-		// Build data destinations
-		var destinations []DataDestination
+	if len(campaign.DataDestinations) == 0 {
 		if config.DataDestinationS3 != "" {
-			destinations = append(destinations, DataDestination{
+			campaign.DataDestinations = append(campaign.DataDestinations, DataDestination{
 				Type:                 "s3",
 				S3BucketARN:          config.DataDestinationS3,
 				S3Prefix:             fmt.Sprintf("fleetwise/%s/", envID),
@@ -602,91 +1241,229 @@ func (c *AWSFleetWiseClient) ProvisionFleetWiseEnvironment(envID string, config
 				S3StorageCompression: "GZIP",
 			})
 		}
-		if config.DataDestinationMQTT != "" {
-			destinations = append(destinations, DataDestination{
-				Type:              "mqtt",
-				MQTTTopicARN:      config.DataDestinationMQTT,
-				MQTTExecutionRole: "", // Should be provided in config
-			})
-		}
+	}
 
-		compression := "OFF"
+	if campaign.Compression == "" {
+		campaign.Compression = "OFF"
 		if config.EnableCompression {
-			compression = "SNAPPY"
+			campaign.Compression = "SNAPPY"
 		}
-
-		diagnosticsMode := "OFF"
+	}
+	if campaign.DiagnosticsMode == "" {
+		campaign.DiagnosticsMode = "OFF"
 		if config.EnableDiagnostics {
-			diagnosticsMode = "SEND_ACTIVE_DTCS"
+			campaign.DiagnosticsMode = "SEND_ACTIVE_DTCS"
 		}
-
-		spoolingMode := "OFF"
+	}
+	if campaign.SpoolingMode == "" {
+		campaign.SpoolingMode = "OFF"
 		if config.EnableSpooling {
-			spoolingMode = "TO_DISK"
+			campaign.SpoolingMode = "TO_DISK"
 		}
+	}
+	return campaign
+}
+
+// campaignNamePattern leaves room for the environment prefix within the
+// 100 characters FleetWise allows
+var campaignNamePattern = regexp.MustCompile(`^[a-zA-Z\d\-_:]{1,60}$`)
+
+// validateCampaignConfig checks what FleetWise needs to create a campaign of
+// the environment's configuration
+func validateCampaignConfig(campaign CampaignConfig) []string {
+	var errs []string
+	prefix := fmt.Sprintf("campaign %s", campaign.Name)
+	if !campaignNamePattern.MatchString(campaign.Name) {
+		errs = append(errs, fmt.Sprintf("campaign name %q must be 1-60 letters, digits, -, _ or :", campaign.Name))
+	}
 
-		targetARN := config.FleetID
-		if targetARN == "" && len(createdARNs) > 0 {
-			targetARN = createdARNs[0] // Use first vehicle if no fleet
+	scheme := campaign.CollectionScheme
+	switch scheme.Type {
+	case "time-based":
+		if scheme.PeriodMs < 10000 {
+			errs = append(errs, prefix+": time-based collection needs period_ms of at least 10000")
+		}
+	case "condition-based":
+		if scheme.Expression == "" {
+			errs = append(errs, prefix+": condition-based collection needs an expression")
+		} else if expression, err := condition.Parse(scheme.Expression); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", prefix, err))
+		} else if err := expression.Check(nil); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", prefix, err))
+		}
+		if scheme.MinimumTriggerIntervalMs < 0 || scheme.MinimumTriggerIntervalMs > condition.MaxTriggerIntervalMs {
+			errs = append(errs, fmt.Sprintf("%s: minimum_trigger_interval_ms must be between 0 and %d", prefix, int64(condition.MaxTriggerIntervalMs)))
+		}
+		if scheme.TriggerMode != "" && scheme.TriggerMode != "ALWAYS" && scheme.TriggerMode != "RISING_EDGE" {
+			errs = append(errs, fmt.Sprintf("%s: unsupported trigger_mode %q", prefix, scheme.TriggerMode))
 		}
+	default:
+		errs = append(errs, fmt.Sprintf("%s: collection_scheme type must be time-based or condition-based, got %q", prefix, scheme.Type))
+	}
 
-		campaignConfig := CampaignConfig{
-			Name:             campaignName,
-			Description:      fmt.Sprintf("Data collection campaign for environment %s", envID),
-			SignalCatalogARN: config.SignalCatalogARN,
-			TargetARN:        targetARN,
-			CollectionScheme: CollectionScheme{
-				Type:     "time-based",
-				PeriodMs: 10000, // 10 seconds
-			},
-			SignalsToCollect: []SignalToCollect{
-				{
-					Name:                      "Vehicle.Speed",
-					MaxSampleCount:            1000,
-					MinimumSamplingIntervalMs: 100,
-				},
-			},
-			DataDestinations:      destinations,
-			Compression:           compression,
-			DiagnosticsMode:       diagnosticsMode,
-			SpoolingMode:          spoolingMode,
-			PostTriggerDurationMs: 0,
+	if len(campaign.SignalsToCollect) == 0 {
+		errs = append(errs, prefix+": signals_to_collect is required")
+	}
+	for _, signal := range campaign.SignalsToCollect {
+		if signal.Name == "" {
+			errs = append(errs, prefix+": signal to collect without a name")
 		}
+	}
 
-		_, err := c.CreateCampaign(campaignConfig)
-		if err != nil {
-			log.Printf("Warning: failed to create campaign: %v", err)
+	for _, dest := range campaign.DataDestinations {
+		switch {
+		case dest.Type == "s3" && dest.S3BucketARN == "":
+			errs = append(errs, prefix+": s3 destinations need s3_bucket_arn")
+		case dest.Type == "timestream" && (dest.TimestreamTableARN == "" || dest.TimestreamExecutionRole == ""):
+			errs = append(errs, prefix+": timestream destinations need timestream_table_arn and timestream_execution_role")
+		case dest.Type == "mqtt":
+			// MQTT topic destinations are newer than the pinned iotfleetwise SDK
+			errs = append(errs, prefix+": mqtt data destinations are not supported; use s3 or timestream")
+		case dest.Type != "s3" && dest.Type != "timestream":
+			errs = append(errs, fmt.Sprintf("%s: unsupported data destination type %q", prefix, dest.Type))
 		}
 	}
+	return errs
+}
 
-	log.Printf("Successfully provisioned FleetWise environment: %s", envID)
-	return nil
+// validateFleetWiseConfig checks the region and campaigns of a FleetWise configuration
+func validateFleetWiseConfig(config FleetWiseConfig) []string {
+	var errs []string
+	if config.Region != "" {
+		if err := checkFleetWiseRegion(config.Region); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	seen := map[string]bool{}
+	for _, campaign := range config.Campaigns {
+		if seen[campaign.Name] {
+			errs = append(errs, fmt.Sprintf("campaign %s is configured more than once", campaign.Name))
+		}
+		seen[campaign.Name] = true
+		errs = append(errs, validateCampaignConfig(campaign)...)
+	}
+	if config.CampaignARN != "" && campaignNameFromARN(config.CampaignARN) == "" {
+		errs = append(errs, fmt.Sprintf("campaign_arn %q is not a campaign ARN", config.CampaignARN))
+	}
+	return errs
+}
+
+// signalCatalogNameFromARN returns the name in a signal catalog ARN,
+// arn:aws:iotfleetwise:<region>:<account>:signal-catalog/<name>
+func signalCatalogNameFromARN(arn string) string {
+	_, name, _ := cutLast(arn, "/")
+	return name
+}
+
+// ConditionSignals returns the nodes of a signal catalog in the form
+// condition expressions are checked against
+func ConditionSignals(ctx context.Context, api FleetWiseAPI, signalCatalogARN string) (map[string]condition.Signal, error) {
+	nodes, err := api.ListSignalCatalogNodes(ctx, signalCatalogNameFromARN(signalCatalogARN))
+	if err != nil {
+		return nil, err
+	}
+	signals := make(map[string]condition.Signal, len(nodes))
+	for _, node := range nodes {
+		signals[node.FullyQualifiedName] = condition.Signal{NodeType: node.Type, DataType: node.DataType}
+	}
+	return signals, nil
+}
+
+// CheckCampaignExpression checks the condition of a condition-based campaign
+// against its signal catalog. Problems with the expression are returned as
+// *condition.SyntaxError or *condition.ValidationError.
+func CheckCampaignExpression(ctx context.Context, api FleetWiseAPI, campaign CampaignConfig) error {
+	if campaign.CollectionScheme.Type != "condition-based" {
+		return nil
+	}
+	expression, err := condition.Parse(campaign.CollectionScheme.Expression)
+	if err != nil {
+		return err
+	}
+	signals, err := ConditionSignals(ctx, api, campaign.SignalCatalogARN)
+	if err != nil {
+		return err
+	}
+	return expression.Check(signals)
+}
+
+// isExpressionError reports whether err is a problem with a condition expression
+func isExpressionError(err error) bool {
+	var syntaxErr *condition.SyntaxError
+	var validationErr *condition.ValidationError
+	return errors.As(err, &syntaxErr) || errors.As(err, &validationErr)
+}
+
+const (
+	// campaignCreationTimeout bounds how long AwaitCampaign waits for a new
+	// campaign to finish CREATING
+	campaignCreationTimeout = 2 * time.Minute
+	campaignPollInterval    = 2 * time.Second
+)
+
+// AwaitCampaign waits until FleetWise has finished creating a campaign and
+// returns its status
+func AwaitCampaign(ctx context.Context, api FleetWiseAPI, name string) (types.CampaignStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, campaignCreationTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(campaignPollInterval)
+	defer ticker.Stop()
+	for {
+		campaign, err := api.GetCampaign(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		if campaign.Status != types.CampaignStatusCreating {
+			return campaign.Status, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return "", fmt.Errorf("campaign %s is still %s: %w", name, campaign.Status, ctx.Err())
+			}
+			return "", ctx.Err()
+		}
+	}
 }
 
-// DeProvisionFleetWiseEnvironment cleans up FleetWise resources
-func (c *AWSFleetWiseClient) DeProvisionFleetWiseEnvironment(envID string, config FleetWiseConfig) error {
+// DeProvisionFleetWiseEnvironment deletes the environment's campaign and
+// vehicles by name. Resources that no longer exist are skipped.
+func DeProvisionFleetWiseEnvironment(ctx context.Context, api FleetWiseAPI, envID string, config FleetWiseConfig) error {
 	log.Printf("De-provisioning FleetWise environment: %s", envID)
 
-	// Delete campaign
+	var errs []error
+
+	// Delete campaigns. Environments with a campaign_arn were given an
+	// unsuffixed campaign before campaign_arn meant reusing a campaign.
+	var campaigns []string
 	if config.CampaignARN != "" {
-		campaignName := fmt.Sprintf("campaign-%s", envID)
-		err := c.DeleteCampaign(campaignName)
-		if err != nil {
-			log.Printf("Warning: failed to delete campaign: %v", err)
+		campaigns = append(campaigns, environmentCampaignName(envID, ""))
+	}
+	for _, campaign := range config.Campaigns {
+		campaigns = append(campaigns, environmentCampaignName(envID, campaign.Name))
+	}
+	for _, name := range campaigns {
+		if err := api.DeleteCampaign(ctx, name); err != nil && !isAWSNotFound(err) {
+			errs = append(errs, err)
 		}
 	}
 
 	// Delete vehicles
 	for _, name := range config.VehicleNames {
-		err := c.DeleteVehicle(name)
-		if err != nil {
-			log.Printf("Warning: failed to delete vehicle %s: %v", name, err)
+		if err := api.DeleteVehicle(ctx, name); err != nil && !isAWSNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete vehicle %s: %w", name, err))
 		}
 	}
 
 	// Note: Fleets, signal catalogs, model manifests, and decoder manifests
 	// are typically not deleted as they may be reused across environments
 
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
 	log.Printf("Successfully de-provisioned FleetWise environment: %s", envID)
 	return nil
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ses-platform/condition"
+)
+
+// FleetWise Campaign Expression API Handlers
+
+// campaignExpressionRequest is an expression to check, against the signal
+// catalog when one is given
+type campaignExpressionRequest struct {
+	Expression       string `json:"expression" binding:"required"`
+	SignalCatalogARN string `json:"signal_catalog_arn"`
+	Region           string `json:"region"`
+}
+
+// parse parses and checks the expression. The error is nil when the
+// expression is valid, and otherwise reports whether the expression or the
+// request to FleetWise failed.
+func (req campaignExpressionRequest) parse(c *gin.Context) (*condition.Expression, error) {
+	expression, err := condition.Parse(req.Expression)
+	if err != nil {
+		return nil, err
+	}
+	var signals map[string]condition.Signal
+	if req.SignalCatalogARN != "" {
+		client, err := fleetWiseClients.Client(req.Region)
+		if err != nil {
+			return nil, err
+		}
+		if signals, err = ConditionSignals(c.Request.Context(), client, req.SignalCatalogARN); err != nil {
+			return nil, err
+		}
+	}
+	return expression, expression.Check(signals)
+}
+
+// validateCampaignExpression reports whether an expression is valid and which
+// signals it references
+func validateCampaignExpression(c *gin.Context) {
+	var req campaignExpressionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expression, err := req.parse(c)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"valid": true, "signals": expression.Signals()})
+	case isExpressionError(err):
+		response := gin.H{"valid": false, "errors": expressionErrors(err)}
+		if expression != nil {
+			response["signals"] = expression.Signals()
+		}
+		c.JSON(http.StatusOK, response)
+	default:
+		respondFleetWiseError(c, err)
+	}
+}
+
+// dryRunCampaignExpression evaluates an expression over a recorded or
+// synthetic signal trace and returns the timestamps at which a campaign with
+// the given trigger would collect data
+func dryRunCampaignExpression(c *gin.Context) {
+	var req struct {
+		campaignExpressionRequest
+		TriggerMode              string             `json:"trigger_mode"`
+		MinimumTriggerIntervalMs int64              `json:"minimum_trigger_interval_ms"`
+		Trace                    []condition.Sample `json:"trace" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expression, err := req.parse(c)
+	if isExpressionError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid expression", "errors": expressionErrors(err)})
+		return
+	} else if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	result, err := expression.Evaluate(req.Trace, condition.Trigger{
+		Mode:              req.TriggerMode,
+		MinimumIntervalMs: req.MinimumTriggerIntervalMs,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"signals":      expression.Signals(),
+		"evaluations":  result.Evaluations,
+		"firings":      result.Firings,
+		"firing_count": len(result.Firings),
+	})
+}
+
+// expressionErrors lists the problems an expression error reports
+func expressionErrors(err error) []string {
+	var validationErr *condition.ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr.Errors
+	}
+	return []string{err.Error()}
+}
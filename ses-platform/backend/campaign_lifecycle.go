@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise/types"
+	"gorm.io/gorm"
+)
+
+// JobTypeCampaignLifecycle keeps an environment's FleetWise campaigns in step
+// with the environment: they run while it is running and are suspended while
+// it is stopped. Campaigns are deleted by the environment's teardown.
+const JobTypeCampaignLifecycle = "campaign_lifecycle"
+
+// campaignStatusInterval is how often the status of campaigns that need no
+// action is polled
+var campaignStatusInterval = time.Minute
+
+func init() {
+	jobHandlers[JobTypeCampaignLifecycle] = runCampaignLifecycleJob
+}
+
+// campaignTargetStatus is the campaign status each environment state wants.
+// In any other state campaigns are left as they are.
+var campaignTargetStatus = map[string]types.CampaignStatus{
+	StateRunning:   types.CampaignStatusRunning,
+	StateReady:     types.CampaignStatusSuspended,
+	StateCompleted: types.CampaignStatusSuspended,
+}
+
+// campaignActions are the UpdateCampaign actions the lifecycle takes, with the
+// status each leads to and the audit log action recorded for it
+var campaignActions = map[string]struct {
+	status types.CampaignStatus
+	audit  string
+}{
+	"APPROVE": {types.CampaignStatusRunning, "campaign_approved"},
+	"RESUME":  {types.CampaignStatusRunning, "campaign_resumed"},
+	"SUSPEND": {types.CampaignStatusSuspended, "campaign_suspended"},
+}
+
+// enqueueCampaignLifecycle returns a write that has the environment's
+// campaigns follow a transition. A lifecycle job that is waiting for its next
+// poll is brought forward.
+func enqueueCampaignLifecycle(envID string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		var campaigns int64
+		if err := tx.Model(&ResourceAllocation{}).
+			Where("environment_id = ? AND resource_type = ? AND status = ?", envID, "campaign", AllocationStatusActive).
+			Count(&campaigns).Error; err != nil {
+			return err
+		}
+		if campaigns == 0 {
+			return nil
+		}
+		if err := enqueueUniqueJob(tx, JobTypeCampaignLifecycle, envID, nil); err != nil {
+			return err
+		}
+		return tx.Model(&Job{}).
+			Where("environment_id = ? AND type = ? AND status = ?", envID, JobTypeCampaignLifecycle, JobStatusQueued).
+			Update("run_at", time.Now()).Error
+	}
+}
+
+// runCampaignLifecycleJob moves the environment's campaigns towards the status
+// its state wants and records their status. It polls quickly while campaigns
+// are changing and once a minute otherwise, until the environment is no longer
+// running, stopped or completed.
+func runCampaignLifecycleJob(ctx context.Context, job *Job) error {
+	var env Environment
+	if err := db.First(&env, "id = ?", job.EnvironmentID).Error; err != nil {
+		return &permanentJobError{fmt.Errorf("environment not found: %v", err)}
+	}
+	want, ok := campaignTargetStatus[env.Status]
+	if !ok || env.FleetWiseConfig == nil {
+		return nil
+	}
+	campaigns, err := campaignAllocations(env.ID)
+	if err != nil {
+		return err
+	}
+	if len(campaigns) == 0 {
+		return nil
+	}
+	client, err := fleetWiseClients.Client(env.FleetWiseConfig.Region)
+	if err != nil {
+		return err
+	}
+
+	settled := true
+	for i := range campaigns {
+		done, err := syncCampaign(ctx, client, env, &campaigns[i], want)
+		if err != nil {
+			log.Printf("Error syncing campaign %s of environment %s: %v", campaigns[i].ResourceID, env.ID, err)
+		}
+		settled = settled && done && err == nil
+	}
+
+	// A start or stop during this run is acted on right away
+	var current Environment
+	if err := db.Select("id", "status").First(&current, "id = ?", env.ID).Error; err == nil && current.Status != env.Status {
+		return &rescheduleJob{}
+	}
+	if !settled {
+		return &rescheduleJob{after: campaignPollInterval}
+	}
+	return &rescheduleJob{after: campaignStatusInterval}
+}
+
+// syncCampaign polls a campaign, takes the action that moves it towards want
+// and records its status on the allocation. It reports whether the campaign
+// needs no further action for now. Reused campaigns are only polled.
+func syncCampaign(ctx context.Context, api FleetWiseAPI, env Environment, allocation *ResourceAllocation, want types.CampaignStatus) (bool, error) {
+	name := fmt.Sprint(allocation.Config["name"])
+	campaign, err := api.GetCampaign(ctx, name)
+	if isAWSNotFound(err) {
+		// Reported by drift reconciliation
+		return true, recordCampaignStatus(allocation, "NOT_FOUND", false)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	status := campaign.Status
+	settled, awaitingApproval := true, false
+	var action string
+	switch {
+	case allocation.Config["reused"] == true:
+	case status == types.CampaignStatusCreating:
+		settled = want != types.CampaignStatusRunning
+	case want == types.CampaignStatusRunning && status == types.CampaignStatusWaitingForApproval:
+		if env.FleetWiseConfig.RequireCampaignApproval && !campaignsApproved(env.ID, allocation.AllocatedAt) {
+			awaitingApproval = true
+		} else {
+			action = "APPROVE"
+		}
+	case want == types.CampaignStatusRunning && status == types.CampaignStatusSuspended:
+		action = "RESUME"
+	case want == types.CampaignStatusSuspended && status == types.CampaignStatusRunning:
+		action = "SUSPEND"
+	}
+
+	if action != "" {
+		if err := api.UpdateCampaign(ctx, name, action); err != nil {
+			return false, err
+		}
+		status = campaignActions[action].status
+		auditLog := AuditLog{
+			EnvironmentID: env.ID,
+			Action:        campaignActions[action].audit,
+			UserID:        "system",
+			Details: map[string]interface{}{
+				"message":  fmt.Sprintf("Campaign %s is %s as the environment is %s", name, status, env.Status),
+				"campaign": name,
+			},
+			CreatedAt: time.Now(),
+		}
+		db.Create(&auditLog)
+	}
+	return settled, recordCampaignStatus(allocation, status, awaitingApproval)
+}
+
+// recordCampaignStatus stores the polled status of a campaign on its allocation
+func recordCampaignStatus(allocation *ResourceAllocation, status types.CampaignStatus, awaitingApproval bool) error {
+	config := map[string]interface{}{}
+	for k, v := range allocation.Config {
+		config[k] = v
+	}
+	config["status"] = string(status)
+	config["status_checked_at"] = time.Now().Format(time.RFC3339)
+	config["awaiting_approval"] = awaitingApproval
+	allocation.Config = config
+	return db.Model(&ResourceAllocation{ID: allocation.ID}).Select("config").Updates(&ResourceAllocation{Config: config}).Error
+}
+
+// campaignAllocations returns the active campaigns of an environment
+func campaignAllocations(envID string) ([]ResourceAllocation, error) {
+	var allocations []ResourceAllocation
+	err := db.Where("environment_id = ? AND resource_type = ? AND status = ?", envID, "campaign", AllocationStatusActive).
+		Order("allocated_at, id").
+		Find(&allocations).Error
+	return allocations, err
+}
+
+// campaignsApproved reports whether someone approved the environment's
+// campaigns since the given time
+func campaignsApproved(envID string, since time.Time) bool {
+	var approvals int64
+	db.Model(&AuditLog{}).
+		Where("environment_id = ? AND action = ? AND created_at >= ?", envID, "campaigns_approved", since).
+		Count(&approvals)
+	return approvals > 0
+}
+
+// campaignStatuses describes the environment's campaigns as last polled
+func campaignStatuses(envID string) ([]map[string]interface{}, error) {
+	allocations, err := campaignAllocations(envID)
+	if err != nil {
+		return nil, err
+	}
+	campaigns := make([]map[string]interface{}, 0, len(allocations))
+	for _, allocation := range allocations {
+		campaigns = append(campaigns, map[string]interface{}{
+			"name":              allocation.Config["name"],
+			"arn":               allocation.ResourceID,
+			"status":            allocation.Config["status"],
+			"status_checked_at": allocation.Config["status_checked_at"],
+			"awaiting_approval": allocation.Config["awaiting_approval"] == true,
+			"reused":            allocation.Config["reused"] == true,
+		})
+	}
+	return campaigns, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ApproveCampaignsRequest struct {
+	ApprovedBy string `json:"approved_by" binding:"required"`
+}
+
+// approveEnvironmentCampaigns is the human approval gate of environments with
+// require_campaign_approval. The approval is recorded in the audit log and the
+// campaigns are approved once the environment is running.
+func approveEnvironmentCampaigns(c *gin.Context) {
+	id := c.Param("id")
+	var req ApproveCampaignsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var env Environment
+	if err := db.First(&env, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Environment not found"})
+		return
+	}
+	if env.FleetWiseConfig == nil || !env.FleetWiseConfig.RequireCampaignApproval {
+		c.JSON(http.StatusConflict, gin.H{"error": "Campaigns of this environment are approved when it starts"})
+		return
+	}
+	allocations, err := campaignAllocations(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var campaigns []string
+	for _, allocation := range allocations {
+		if allocation.Config["reused"] != true {
+			campaigns = append(campaigns, fmt.Sprint(allocation.Config["name"]))
+		}
+	}
+	if len(campaigns) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Environment has no campaigns to approve"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		auditLog := AuditLog{
+			EnvironmentID: id,
+			Action:        "campaigns_approved",
+			UserID:        req.ApprovedBy,
+			Details: map[string]interface{}{
+				"message":   fmt.Sprintf("%d campaign(s) approved", len(campaigns)),
+				"campaigns": campaigns,
+			},
+			CreatedAt: time.Now(),
+		}
+		if err := tx.Create(&auditLog).Error; err != nil {
+			return err
+		}
+		return enqueueCampaignLifecycle(id)(tx)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Campaigns approved",
+		"campaigns":          campaigns,
+		"environment_status": env.Status,
+	})
+}
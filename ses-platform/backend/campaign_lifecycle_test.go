@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise"
+	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise/types"
+	"github.com/aws/smithy-go"
+)
+
+// campaignAPI serves one campaign's status and records the actions taken
+type campaignAPI struct {
+	FleetWiseAPI
+	status    types.CampaignStatus
+	getErr    error
+	updateErr error
+	actions   []string
+}
+
+func (a *campaignAPI) GetCampaign(ctx context.Context, name string) (*iotfleetwise.GetCampaignOutput, error) {
+	if a.getErr != nil {
+		return nil, a.getErr
+	}
+	return &iotfleetwise.GetCampaignOutput{Status: a.status}, nil
+}
+
+func (a *campaignAPI) UpdateCampaign(ctx context.Context, name, action string) error {
+	a.actions = append(a.actions, action)
+	return a.updateErr
+}
+
+func TestSyncCampaign(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           types.CampaignStatus
+		want             types.CampaignStatus
+		requireApproval  bool
+		reused           bool
+		getErr           error
+		updateErr        error
+		action           string
+		audit            string
+		recorded         types.CampaignStatus
+		awaitingApproval bool
+		settled          bool
+		err              bool
+	}{
+		{
+			name:     "approved when running",
+			status:   types.CampaignStatusWaitingForApproval,
+			want:     types.CampaignStatusRunning,
+			action:   "APPROVE",
+			audit:    "campaign_approved",
+			recorded: types.CampaignStatusRunning,
+			settled:  true,
+		},
+		{
+			name:             "awaits a reviewer's approval",
+			status:           types.CampaignStatusWaitingForApproval,
+			want:             types.CampaignStatusRunning,
+			requireApproval:  true,
+			recorded:         types.CampaignStatusWaitingForApproval,
+			awaitingApproval: true,
+			settled:          true,
+		},
+		{
+			name:     "resumed when running",
+			status:   types.CampaignStatusSuspended,
+			want:     types.CampaignStatusRunning,
+			action:   "RESUME",
+			audit:    "campaign_resumed",
+			recorded: types.CampaignStatusRunning,
+			settled:  true,
+		},
+		{
+			name:     "suspended when stopped",
+			status:   types.CampaignStatusRunning,
+			want:     types.CampaignStatusSuspended,
+			action:   "SUSPEND",
+			audit:    "campaign_suspended",
+			recorded: types.CampaignStatusSuspended,
+			settled:  true,
+		},
+		{
+			name:     "already suspended",
+			status:   types.CampaignStatusSuspended,
+			want:     types.CampaignStatusSuspended,
+			recorded: types.CampaignStatusSuspended,
+			settled:  true,
+		},
+		{
+			name:     "still being created for a running environment",
+			status:   types.CampaignStatusCreating,
+			want:     types.CampaignStatusRunning,
+			recorded: types.CampaignStatusCreating,
+		},
+		{
+			name:     "still being created for a stopped environment",
+			status:   types.CampaignStatusCreating,
+			want:     types.CampaignStatusSuspended,
+			recorded: types.CampaignStatusCreating,
+			settled:  true,
+		},
+		{
+			name:     "reused campaigns are only polled",
+			status:   types.CampaignStatusSuspended,
+			want:     types.CampaignStatusRunning,
+			reused:   true,
+			recorded: types.CampaignStatusSuspended,
+			settled:  true,
+		},
+		{
+			name:     "deleted outside the platform",
+			getErr:   &smithy.GenericAPIError{Code: "ResourceNotFoundException"},
+			want:     types.CampaignStatusRunning,
+			recorded: "NOT_FOUND",
+			settled:  true,
+		},
+		{
+			name:   "polling fails",
+			getErr: errors.New("connection reset"),
+			want:   types.CampaignStatusRunning,
+			err:    true,
+		},
+		{
+			name:      "action fails",
+			status:    types.CampaignStatusRunning,
+			want:      types.CampaignStatusSuspended,
+			updateErr: &smithy.GenericAPIError{Code: "ConflictException"},
+			action:    "SUSPEND",
+			err:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements := useDryRunDB(t)
+			api := &campaignAPI{status: tt.status, getErr: tt.getErr, updateErr: tt.updateErr}
+			env := Environment{ID: "env-1", Status: StateRunning, FleetWiseConfig: &FleetWiseConfig{RequireCampaignApproval: tt.requireApproval}}
+			allocation := &ResourceAllocation{ID: 3, Config: map[string]interface{}{"name": "speed"}, AllocatedAt: time.Now()}
+			if tt.reused {
+				allocation.Config["reused"] = true
+			}
+
+			settled, err := syncCampaign(context.Background(), api, env, allocation, tt.want)
+			if (err != nil) != tt.err {
+				t.Fatalf("syncCampaign error = %v, want error %t", err, tt.err)
+			}
+			if settled != tt.settled {
+				t.Errorf("settled = %t, want %t", settled, tt.settled)
+			}
+			var action string
+			if len(api.actions) > 0 {
+				action = api.actions[0]
+			}
+			if len(api.actions) > 1 || action != tt.action {
+				t.Errorf("actions = %q, want %q", api.actions, tt.action)
+			}
+			if tt.err {
+				return
+			}
+
+			if status := allocation.Config["status"]; status != string(tt.recorded) {
+				t.Errorf("recorded status = %v, want %s", status, tt.recorded)
+			}
+			if awaiting := allocation.Config["awaiting_approval"]; awaiting != tt.awaitingApproval {
+				t.Errorf("recorded awaiting_approval = %v, want %t", awaiting, tt.awaitingApproval)
+			}
+			audited := false
+			for _, statement := range *statements {
+				audited = audited || containsAll(statement, `INSERT INTO "audit_logs"`, "'"+tt.audit+"'")
+			}
+			if audited != (tt.audit != "") {
+				t.Errorf("statements = %q, want audit log %q", *statements, tt.audit)
+			}
+			if last := (*statements)[len(*statements)-1]; !containsAll(last, `UPDATE "resource_allocations" SET "config"=`, `"id" = 3`) {
+				t.Errorf("last statement = %s, want the allocation's config updated", last)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+// Command fleetwise-emulator serves an in-memory AWS IoT FleetWise API.
+// Point the backend at it with FLEETWISE_ENDPOINT=http://<addr>; the SDK
+// still needs credentials to sign requests, so set AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY to any value.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"ses-platform/emulator"
+)
+
+func main() {
+	addr := flag.String("addr", ":4567", "address to listen on")
+	campaignDelay := flag.Duration("campaign-delay", 0, "how long new campaigns stay CREATING (default 2s)")
+	flag.Parse()
+
+	fleetWise := emulator.NewFleetWise()
+	if *campaignDelay > 0 {
+		fleetWise.CampaignCreationDelay = *campaignDelay
+	}
+
+	log.Printf("FleetWise emulator listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, fleetWise))
+}
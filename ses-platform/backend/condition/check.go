@@ -0,0 +1,193 @@
+package condition
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Signal describes a signal catalog node an expression may reference
+type Signal struct {
+	NodeType string // branch, sensor, attribute or actuator
+	DataType string // FleetWise data type, e.g. DOUBLE; not set for branches
+}
+
+// ValidationError collects every problem found by Check
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("expression is invalid: %s", strings.Join(e.Errors, "; "))
+}
+
+type valueType int
+
+const (
+	typeUnknown valueType = iota // a signal whose data type is not known
+	typeNumber
+	typeBool
+)
+
+func (t valueType) String() string {
+	switch t {
+	case typeNumber:
+		return "number"
+	case typeBool:
+		return "boolean"
+	default:
+		return "unknown"
+	}
+}
+
+// numericDataTypes are the FleetWise data types conditions treat as numbers
+var numericDataTypes = map[string]bool{
+	"INT8": true, "UINT8": true, "INT16": true, "UINT16": true, "INT32": true, "UINT32": true,
+	"INT64": true, "UINT64": true, "FLOAT": true, "DOUBLE": true, "UNIX_TIMESTAMP": true,
+}
+
+// Check type-checks the expression, which must be boolean. With a catalog,
+// keyed by fully qualified name, every referenced signal must be a scalar
+// numeric or boolean signal of it; without one, signals may have any type.
+func (e *Expression) Check(catalog map[string]Signal) error {
+	c := &checker{catalog: catalog}
+	if t := c.typeOf(e.root); t == typeNumber {
+		c.errorf(e.root, "the expression is a number, not a condition")
+	}
+	if len(c.errs) > 0 {
+		return &ValidationError{Errors: c.errs}
+	}
+	return nil
+}
+
+type checker struct {
+	catalog map[string]Signal
+	errs    []string
+}
+
+func (c *checker) errorf(n node, format string, args ...interface{}) {
+	c.errs = append(c.errs, fmt.Sprintf("position %d: %s", n.position(), fmt.Sprintf(format, args...)))
+}
+
+// want reports an error unless n, of type got, has the wanted type
+func (c *checker) want(n node, got, want valueType, context string) {
+	if got != typeUnknown && got != want {
+		c.errorf(n, "%s needs a %s, got a %s", context, want, got)
+	}
+}
+
+func (c *checker) typeOf(n node) valueType {
+	switch n := n.(type) {
+	case *numberNode:
+		return typeNumber
+	case *boolNode:
+		return typeBool
+	case *signalNode:
+		return c.signalType(n)
+	case *unaryNode:
+		if n.op == "!" {
+			c.want(n.x, c.typeOf(n.x), typeBool, "!")
+			return typeBool
+		}
+		c.want(n.x, c.typeOf(n.x), typeNumber, "unary -")
+		return typeNumber
+	case *binaryNode:
+		x, y := c.typeOf(n.x), c.typeOf(n.y)
+		switch n.op {
+		case "&&", "||":
+			c.want(n.x, x, typeBool, n.op)
+			c.want(n.y, y, typeBool, n.op)
+			return typeBool
+		case "==", "!=":
+			if x != typeUnknown && y != typeUnknown && x != y {
+				c.errorf(n, "%s compares a %s with a %s", n.op, x, y)
+			}
+			return typeBool
+		case ">", "<", ">=", "<=":
+			c.want(n.x, x, typeNumber, n.op)
+			c.want(n.y, y, typeNumber, n.op)
+			return typeBool
+		default:
+			c.want(n.x, x, typeNumber, n.op)
+			c.want(n.y, y, typeNumber, n.op)
+			return typeNumber
+		}
+	case *callNode:
+		t := c.signalType(n.signal)
+		switch n.function {
+		case "timeout":
+			return typeBool
+		case "last_change":
+			return t
+		default:
+			c.want(n.signal, t, typeNumber, n.function)
+			return typeNumber
+		}
+	}
+	return typeUnknown
+}
+
+func (c *checker) signalType(n *signalNode) valueType {
+	if c.catalog == nil {
+		return typeUnknown
+	}
+	signal, ok := c.catalog[n.name]
+	switch {
+	case !ok:
+		if suggestion := c.closest(n.name); suggestion != "" {
+			c.errorf(n, "unknown signal %s, did you mean %s?", n.name, suggestion)
+		} else {
+			c.errorf(n, "unknown signal %s", n.name)
+		}
+	case signal.NodeType == "branch":
+		c.errorf(n, "%s is a branch, not a signal", n.name)
+	case signal.DataType == "BOOLEAN":
+		return typeBool
+	case numericDataTypes[signal.DataType]:
+		return typeNumber
+	default:
+		c.errorf(n, "%s has data type %s; conditions can only use numeric and boolean signals", n.name, signal.DataType)
+	}
+	return typeUnknown
+}
+
+// closest returns the catalog signal nearest to name by edit distance, if it
+// is close enough to be a likely typo
+func (c *checker) closest(name string) string {
+	names := make([]string, 0, len(c.catalog))
+	for candidate, signal := range c.catalog {
+		if signal.NodeType != "branch" {
+			names = append(names, candidate)
+		}
+	}
+	sort.Strings(names)
+
+	best, bestDistance := "", len(name)/3+1
+	for _, candidate := range names {
+		if d := editDistance(name, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,107 @@
+package condition
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testCatalog = map[string]Signal{
+	"Vehicle":       {NodeType: "branch"},
+	"Vehicle.Speed": {NodeType: "sensor", DataType: "DOUBLE"},
+	"Vehicle.Brake": {NodeType: "sensor", DataType: "BOOLEAN"},
+	"Vehicle.VIN":   {NodeType: "attribute", DataType: "STRING"},
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		source string
+		errs   []string // Substrings of the expected errors, in order
+	}{
+		{"$variable.`Vehicle.Speed` > 100", nil},
+		{"$variable.`Vehicle.Brake`", nil},
+		{"!$variable.`Vehicle.Brake` && avg($variable.`Vehicle.Speed`, 1000) > 50", nil},
+		{"last_change($variable.`Vehicle.Brake`, 100) == true", nil},
+		{"timeout($variable.`Vehicle.Speed`, 1000)", nil},
+		{"$variable.`Vehicle.Sped` > 100", []string{"position 1: unknown signal Vehicle.Sped, did you mean Vehicle.Speed?"}},
+		{"$variable.`Engine.Temperature` > 100", []string{"unknown signal Engine.Temperature"}},
+		{"$variable.`Vehicle` > 1", []string{"Vehicle is a branch, not a signal"}},
+		{"$variable.`Vehicle.VIN` == 1", []string{"Vehicle.VIN has data type STRING"}},
+		{"$variable.`Vehicle.Speed` + 1", []string{"the expression is a number, not a condition"}},
+		{"$variable.`Vehicle.Brake` > 1", []string{"position 1: > needs a number, got a boolean"}},
+		{"true && $variable.`Vehicle.Speed`", []string{"position 9: && needs a boolean, got a number"}},
+		{"$variable.`Vehicle.Speed` == true", []string{"== compares a number with a boolean"}},
+		{"-$variable.`Vehicle.Brake` < 0", []string{"unary - needs a number, got a boolean"}},
+		{"avg($variable.`Vehicle.Brake`, 100) > 0", []string{"avg needs a number, got a boolean"}},
+		{"$variable.`A` > 1 && $variable.`B` > 1", []string{"unknown signal A", "unknown signal B"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expression, err := Parse(tt.source)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			err = expression.Check(testCatalog)
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Fatalf("Check: %v", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Check error = %v, want a ValidationError", err)
+			}
+			if len(validationErr.Errors) != len(tt.errs) {
+				t.Fatalf("Check errors = %q, want %d", validationErr.Errors, len(tt.errs))
+			}
+			for i, want := range tt.errs {
+				if !strings.Contains(validationErr.Errors[i], want) {
+					t.Errorf("error %d = %q, want it to contain %q", i, validationErr.Errors[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckWithoutCatalog(t *testing.T) {
+	tests := []struct {
+		source string
+		valid  bool
+	}{
+		{"$variable.`Anything` > 1", true},
+		{"$variable.`Anything`", true},
+		{"$variable.`Anything` + 1 > 0 && $variable.`Other`", true},
+		{"1 + 2", false},
+		{"true > 1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expression, err := Parse(tt.source)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if err := expression.Check(nil); (err == nil) != tt.valid {
+				t.Errorf("Check(nil) = %v, want valid %t", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"Vehicle.Speed", "Vehicle.Speed", 0},
+		{"Vehicle.Sped", "Vehicle.Speed", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package condition
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	// TriggerAlways fires every time the condition is evaluated true
+	TriggerAlways = "ALWAYS"
+	// TriggerRisingEdge fires only when the condition turns from false to true
+	TriggerRisingEdge = "RISING_EDGE"
+
+	// MaxTriggerIntervalMs is the largest MinimumTriggerIntervalMs FleetWise accepts
+	MaxTriggerIntervalMs = 4294967295
+)
+
+// Sample is one received value of a signal. Boolean values are given as
+// true or false and evaluated as 1 or 0.
+type Sample struct {
+	TimestampMs int64   `json:"timestamp_ms"`
+	Signal      string  `json:"signal"`
+	Value       float64 `json:"value"`
+}
+
+// UnmarshalJSON accepts boolean values as well as numbers
+func (s *Sample) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		TimestampMs int64           `json:"timestamp_ms"`
+		Signal      string          `json:"signal"`
+		Value       json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	s.TimestampMs, s.Signal = raw.TimestampMs, raw.Signal
+	switch string(raw.Value) {
+	case "true":
+		s.Value = 1
+	case "false":
+		s.Value = 0
+	default:
+		if err := json.Unmarshal(raw.Value, &s.Value); err != nil || string(raw.Value) == "null" {
+			return fmt.Errorf("sample of %s at %d: value must be a number or a boolean", raw.Signal, raw.TimestampMs)
+		}
+	}
+	return nil
+}
+
+// Trigger is how a campaign turns condition results into collections
+type Trigger struct {
+	Mode              string // ALWAYS, the default, or RISING_EDGE
+	MinimumIntervalMs int64
+}
+
+// DryRun is the result of evaluating an expression over a trace
+type DryRun struct {
+	Evaluations int     `json:"evaluations"`
+	Firings     []int64 `json:"firings"` // Timestamps at which the trigger fires
+}
+
+// Evaluate replays a trace of samples, in any order, and returns when the
+// trigger would fire. Like the Edge Agent, the condition is evaluated each
+// time one of its signals receives a sample, once per timestamp, and when a
+// timeout elapses before the next sample; samples of other signals are
+// ignored and the replay ends with the last sample. A condition that
+// references a signal without a value yet, or divides by zero, is not met.
+func (e *Expression) Evaluate(trace []Sample, trigger Trigger) (*DryRun, error) {
+	if trigger.Mode == "" {
+		trigger.Mode = TriggerAlways
+	}
+	if trigger.Mode != TriggerAlways && trigger.Mode != TriggerRisingEdge {
+		return nil, fmt.Errorf("unsupported trigger mode %q", trigger.Mode)
+	}
+	if trigger.MinimumIntervalMs < 0 || trigger.MinimumIntervalMs > MaxTriggerIntervalMs {
+		return nil, fmt.Errorf("minimum trigger interval must be between 0 and %d ms", int64(MaxTriggerIntervalMs))
+	}
+
+	referenced := map[string]bool{}
+	for _, name := range e.signals {
+		referenced[name] = true
+	}
+	var samples []Sample
+	for _, sample := range trace {
+		if referenced[sample.Signal] {
+			samples = append(samples, sample)
+		}
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].TimestampMs < samples[j].TimestampMs })
+
+	result := &DryRun{Firings: []int64{}}
+	if len(samples) == 0 {
+		return result, nil
+	}
+	s := &state{now: samples[0].TimestampMs, start: samples[0].TimestampMs, history: map[string][]Sample{}}
+	timeouts := timeoutCalls(e.root, nil)
+	var previous, fired bool
+	var lastFiring int64
+	evaluate := func() {
+		result.Evaluations++
+		value, ok := s.eval(e.root)
+		met := ok && value != 0
+		fire := met && (trigger.Mode == TriggerAlways || !previous)
+		if fire && fired && s.now-lastFiring < trigger.MinimumIntervalMs {
+			fire = false
+		}
+		previous = met
+		if fire {
+			fired, lastFiring = true, s.now
+			result.Firings = append(result.Firings, s.now)
+		}
+	}
+
+	for i := 0; i < len(samples); {
+		for {
+			elapsed, ok := s.nextTimeout(timeouts, samples[i].TimestampMs)
+			if !ok {
+				break
+			}
+			s.now = elapsed
+			evaluate()
+		}
+
+		s.now = samples[i].TimestampMs
+		for ; i < len(samples) && samples[i].TimestampMs == s.now; i++ {
+			s.history[samples[i].Signal] = append(s.history[samples[i].Signal], samples[i])
+		}
+		evaluate()
+	}
+	return result, nil
+}
+
+// timeoutCalls appends the timeout calls of the expression to calls
+func timeoutCalls(n node, calls []*callNode) []*callNode {
+	switch n := n.(type) {
+	case *unaryNode:
+		return timeoutCalls(n.x, calls)
+	case *binaryNode:
+		return timeoutCalls(n.y, timeoutCalls(n.x, calls))
+	case *callNode:
+		if n.function == "timeout" {
+			return append(calls, n)
+		}
+	}
+	return calls
+}
+
+// nextTimeout returns the earliest time after now and before the given time
+// at which one of the timeouts elapses
+func (s *state) nextTimeout(timeouts []*callNode, before int64) (int64, bool) {
+	next, ok := before, false
+	for _, call := range timeouts {
+		elapsed := s.lastReceived(call.signal.name) + call.windowMs
+		if elapsed > s.now && elapsed < next {
+			next, ok = elapsed, true
+		}
+	}
+	return next, ok
+}
+
+// lastReceived returns when the signal was last received, or the start of
+// the trace if it has not been received yet
+func (s *state) lastReceived(signal string) int64 {
+	samples := s.history[signal]
+	if len(samples) == 0 {
+		return s.start
+	}
+	return samples[len(samples)-1].TimestampMs
+}
+
+// state is the samples received up to now
+type state struct {
+	now     int64
+	start   int64
+	history map[string][]Sample
+}
+
+// eval returns the value of n, with booleans as 1 or 0, and whether it is defined
+func (s *state) eval(n node) (float64, bool) {
+	switch n := n.(type) {
+	case *numberNode:
+		return n.value, true
+	case *boolNode:
+		return truth(n.value), true
+	case *signalNode:
+		samples := s.history[n.name]
+		if len(samples) == 0 {
+			return 0, false
+		}
+		return samples[len(samples)-1].Value, true
+	case *unaryNode:
+		x, ok := s.eval(n.x)
+		if n.op == "!" {
+			return truth(x == 0), ok
+		}
+		return -x, ok
+	case *binaryNode:
+		return s.evalBinary(n)
+	case *callNode:
+		return s.evalCall(n)
+	}
+	return 0, false
+}
+
+func (s *state) evalBinary(n *binaryNode) (float64, bool) {
+	x, xok := s.eval(n.x)
+	y, yok := s.eval(n.y)
+	// A defined operand decides && and || on its own
+	switch n.op {
+	case "&&":
+		if (xok && x == 0) || (yok && y == 0) {
+			return 0, true
+		}
+		return truth(x != 0 && y != 0), xok && yok
+	case "||":
+		if (xok && x != 0) || (yok && y != 0) {
+			return 1, true
+		}
+		return 0, xok && yok
+	}
+	if !xok || !yok {
+		return 0, false
+	}
+	switch n.op {
+	case "==":
+		return truth(x == y), true
+	case "!=":
+		return truth(x != y), true
+	case ">":
+		return truth(x > y), true
+	case "<":
+		return truth(x < y), true
+	case ">=":
+		return truth(x >= y), true
+	case "<=":
+		return truth(x <= y), true
+	case "+":
+		return x + y, true
+	case "-":
+		return x - y, true
+	case "*":
+		return x * y, true
+	case "/":
+		if y == 0 {
+			return 0, false
+		}
+		return x / y, true
+	}
+	return 0, false
+}
+
+func (s *state) evalCall(n *callNode) (float64, bool) {
+	samples := s.history[n.signal.name]
+	since := s.now - n.windowMs
+	switch n.function {
+	case "timeout":
+		return truth(s.now-s.lastReceived(n.signal.name) >= n.windowMs), true
+	case "last_change":
+		for i := len(samples) - 1; i > 0 && samples[i].TimestampMs > since; i-- {
+			if samples[i].Value != samples[i-1].Value {
+				return samples[i-1].Value, true
+			}
+		}
+		return 0, false
+	}
+
+	var count int
+	sum, low, high := 0.0, math.Inf(1), math.Inf(-1)
+	for i := len(samples) - 1; i >= 0 && samples[i].TimestampMs > since; i-- {
+		count++
+		sum += samples[i].Value
+		low = math.Min(low, samples[i].Value)
+		high = math.Max(high, samples[i].Value)
+	}
+	if count == 0 {
+		return 0, false
+	}
+	switch n.function {
+	case "avg":
+		return sum / float64(count), true
+	case "min":
+		return low, true
+	default:
+		return high, true
+	}
+}
+
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
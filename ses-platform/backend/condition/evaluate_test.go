@@ -0,0 +1,200 @@
+package condition
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// trace builds the samples of one signal from timestamp/value pairs
+func trace(signal string, pairs ...float64) []Sample {
+	samples := make([]Sample, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		samples = append(samples, Sample{TimestampMs: int64(pairs[i]), Signal: signal, Value: pairs[i+1]})
+	}
+	return samples
+}
+
+func TestEvaluate(t *testing.T) {
+	speed := trace("S", 0, 50, 1000, 150, 2000, 200, 3000, 90, 4000, 120)
+	window := trace("S", 0, 50, 1000, 150, 2000, 200, 3000, 0)
+
+	tests := []struct {
+		name        string
+		source      string
+		trace       []Sample
+		trigger     Trigger
+		firings     []int64
+		evaluations int
+	}{
+		{
+			name:        "always",
+			source:      "$variable.S > 100",
+			trace:       speed,
+			firings:     []int64{1000, 2000, 4000},
+			evaluations: 5,
+		},
+		{
+			name:        "rising edge",
+			source:      "$variable.S > 100",
+			trace:       speed,
+			trigger:     Trigger{Mode: TriggerRisingEdge},
+			firings:     []int64{1000, 4000},
+			evaluations: 5,
+		},
+		{
+			name:        "minimum interval",
+			source:      "$variable.S > 100",
+			trace:       speed,
+			trigger:     Trigger{Mode: TriggerAlways, MinimumIntervalMs: 3500},
+			firings:     []int64{1000},
+			evaluations: 5,
+		},
+		{
+			name:        "unordered trace with unreferenced signals",
+			source:      "$variable.S > 100",
+			trace:       append(trace("T", 500, 1000, 1500, 1000), speed[4], speed[1], speed[0]),
+			firings:     []int64{1000, 4000},
+			evaluations: 3,
+		},
+		{
+			name:        "samples at the same time are evaluated once",
+			source:      "$variable.A > 0 && $variable.B > 0",
+			trace:       append(trace("A", 0, 1, 1000, 1), trace("B", 1000, 1)...),
+			firings:     []int64{1000},
+			evaluations: 2,
+		},
+		{
+			name:        "avg",
+			source:      "avg($variable.S, 2000) > 100",
+			trace:       window,
+			firings:     []int64{2000},
+			evaluations: 4,
+		},
+		{
+			name:        "min and max",
+			source:      "max($variable.S, 1500) - min($variable.S, 1500) > 100",
+			trace:       window,
+			firings:     []int64{3000},
+			evaluations: 4,
+		},
+		{
+			name:        "last_change",
+			source:      "last_change($variable.S, 1000) == 0 && $variable.S == 1",
+			trace:       trace("S", 0, 0, 1000, 0, 2000, 1, 5000, 1),
+			firings:     []int64{2000},
+			evaluations: 4,
+		},
+		{
+			name:        "division by zero is not met",
+			source:      "10 / $variable.S > 1",
+			trace:       trace("S", 0, 0, 1000, 5, 2000, 20),
+			firings:     []int64{1000},
+			evaluations: 3,
+		},
+		{
+			name:        "signal without a value is not met",
+			source:      "$variable.A > 1 && $variable.B > 1",
+			trace:       trace("A", 0, 5, 1000, 5),
+			firings:     []int64{},
+			evaluations: 2,
+		},
+		{
+			name:        "or is decided by a defined operand",
+			source:      "$variable.A > 1 || $variable.B > 1",
+			trace:       trace("A", 0, 5, 1000, 0),
+			firings:     []int64{0},
+			evaluations: 2,
+		},
+		{
+			name:        "timeout fires when it elapses",
+			source:      "timeout($variable.A, 1000)",
+			trace:       trace("A", 0, 1, 5000, 1, 9000, 1),
+			firings:     []int64{1000, 6000},
+			evaluations: 5,
+		},
+		{
+			name:        "timeout with another signal",
+			source:      "timeout($variable.A, 1000) && $variable.B > 0",
+			trace:       append(trace("A", 0, 1), trace("B", 0, 1, 500, 1, 3000, 1)...),
+			firings:     []int64{1000, 3000},
+			evaluations: 4,
+		},
+		{
+			name:        "timeout rising edge",
+			source:      "timeout($variable.A, 1000)",
+			trace:       append(trace("A", 0, 1, 5000, 1), trace("B", 2000, 1)...),
+			trigger:     Trigger{Mode: TriggerRisingEdge},
+			firings:     []int64{1000},
+			evaluations: 3,
+		},
+		{
+			name:        "empty trace",
+			source:      "$variable.S > 100",
+			trace:       nil,
+			firings:     []int64{},
+			evaluations: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := Parse(tt.source)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			result, err := expression.Evaluate(tt.trace, tt.trigger)
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+			if !reflect.DeepEqual(result.Firings, tt.firings) {
+				t.Errorf("Firings = %v, want %v", result.Firings, tt.firings)
+			}
+			if result.Evaluations != tt.evaluations {
+				t.Errorf("Evaluations = %d, want %d", result.Evaluations, tt.evaluations)
+			}
+		})
+	}
+}
+
+func TestEvaluateRejectsInvalidTriggers(t *testing.T) {
+	expression, err := Parse("$variable.S > 1")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	for _, trigger := range []Trigger{
+		{Mode: "SOMETIMES"},
+		{MinimumIntervalMs: -1},
+		{MinimumIntervalMs: MaxTriggerIntervalMs + 1},
+	} {
+		if _, err := expression.Evaluate(nil, trigger); err == nil {
+			t.Errorf("Evaluate with %+v succeeded, want an error", trigger)
+		}
+	}
+}
+
+func TestSampleUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data  string
+		value float64
+		valid bool
+	}{
+		{`{"timestamp_ms": 1, "signal": "S", "value": 102.5}`, 102.5, true},
+		{`{"timestamp_ms": 1, "signal": "S", "value": true}`, 1, true},
+		{`{"timestamp_ms": 1, "signal": "S", "value": false}`, 0, true},
+		{`{"timestamp_ms": 1, "signal": "S", "value": null}`, 0, false},
+		{`{"timestamp_ms": 1, "signal": "S", "value": "fast"}`, 0, false},
+		{`{"timestamp_ms": 1, "signal": "S"}`, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			var sample Sample
+			err := json.Unmarshal([]byte(tt.data), &sample)
+			if (err == nil) != tt.valid {
+				t.Fatalf("Unmarshal error = %v, want valid %t", err, tt.valid)
+			}
+			if tt.valid && (sample.Value != tt.value || sample.Signal != "S" || sample.TimestampMs != 1) {
+				t.Errorf("Unmarshal = %+v, want value %v", sample, tt.value)
+			}
+		})
+	}
+}
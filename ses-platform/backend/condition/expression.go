@@ -0,0 +1,411 @@
+// Package condition parses, checks and evaluates IoT FleetWise condition
+// language (version 1) expressions, the triggers of condition-based campaigns,
+// so that mistakes surface before CreateCampaign is called.
+package condition
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError is returned when an expression cannot be parsed. Pos is the
+// 1-based character position of the offending token.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// Expression is a parsed condition
+type Expression struct {
+	source  string
+	root    node
+	signals []string
+}
+
+// String returns the expression as it was given
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Signals returns the fully qualified names of the referenced signals in
+// order of first reference
+func (e *Expression) Signals() []string {
+	return append([]string(nil), e.signals...)
+}
+
+// Parse parses an expression. Signals are referenced as
+// $variable.`Vehicle.Speed`; the backticks may be left out when the name
+// contains only letters, digits, underscores and dots.
+func Parse(source string) (*Expression, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, seen: map[string]bool{}}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %s", tok)}
+	}
+	return &Expression{source: source, root: root, signals: p.signals}, nil
+}
+
+// Abstract syntax tree
+
+type node interface {
+	position() int
+}
+
+type numberNode struct {
+	pos   int
+	value float64
+}
+
+type boolNode struct {
+	pos   int
+	value bool
+}
+
+type signalNode struct {
+	pos  int
+	name string
+}
+
+type unaryNode struct {
+	pos int
+	op  string
+	x   node
+}
+
+type binaryNode struct {
+	pos  int
+	op   string
+	x, y node
+}
+
+// callNode is a function over the recent samples of one signal
+type callNode struct {
+	pos      int
+	function string
+	signal   *signalNode
+	windowMs int64
+}
+
+func (n *numberNode) position() int { return n.pos }
+func (n *boolNode) position() int   { return n.pos }
+func (n *signalNode) position() int { return n.pos }
+func (n *unaryNode) position() int  { return n.pos }
+func (n *binaryNode) position() int { return n.pos }
+func (n *callNode) position() int   { return n.pos }
+
+// functions are the supported functions. Each takes a signal and a window in
+// milliseconds:
+//
+//	last_change(s, ms)  the value s had before its latest change, if that change is at most ms old
+//	timeout(s, ms)      true when s has not been received for ms
+//	avg(s, ms)          the average of the samples of s received in the last ms
+//	min(s, ms)          their minimum
+//	max(s, ms)          their maximum
+var functions = map[string]bool{
+	"last_change": true,
+	"timeout":     true,
+	"avg":         true,
+	"min":         true,
+	"max":         true,
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenSignal
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+const signalPrefix = "$variable."
+
+// operators are the operator tokens, two-character ones first
+var operators = []string{"&&", "||", ">=", "<=", "==", "!=", ">", "<", "!", "+", "-", "*", "/"}
+
+func lex(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", pos})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokenComma, ",", pos})
+			i++
+		case r == '$':
+			if !strings.HasPrefix(string(runes[i:]), signalPrefix) {
+				return nil, &SyntaxError{Pos: pos, Msg: "signals are referenced as $variable.`Signal.Path`"}
+			}
+			i += len(signalPrefix)
+			var name string
+			if i < len(runes) && runes[i] == '`' {
+				end := i + 1
+				for end < len(runes) && runes[end] != '`' {
+					end++
+				}
+				if end == len(runes) {
+					return nil, &SyntaxError{Pos: pos, Msg: "unterminated signal name"}
+				}
+				name = string(runes[i+1 : end])
+				i = end + 1
+			} else {
+				start := i
+				for i < len(runes) && (isIdentRune(runes[i]) || runes[i] == '.') {
+					i++
+				}
+				name = string(runes[start:i])
+			}
+			if name == "" {
+				return nil, &SyntaxError{Pos: pos, Msg: "missing signal name after $variable."}
+			}
+			tokens = append(tokens, token{tokenSignal, name, pos})
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				i++
+				if i < len(runes) && (runes[i] == '+' || runes[i] == '-') {
+					i++
+				}
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[start:i]), pos})
+		case isIdentRune(r):
+			start := i
+			for i < len(runes) && isIdentRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, string(runes[start:i]), pos})
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("unexpected character %q", r)}
+			}
+			tokens = append(tokens, token{tokenOperator, op, pos})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Parser
+
+type parser struct {
+	tokens  []token
+	next    int
+	signals []string
+	seen    map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the given operators
+func (p *parser) accept(ops ...string) (token, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOperator {
+		return tok, false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			return p.advance(), true
+		}
+	}
+	return tok, false
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	tok := p.advance()
+	if tok.kind != kind {
+		return tok, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("expected %s, got %s", what, tok)}
+	}
+	return tok, nil
+}
+
+// binary parses a left-associative chain of the given operators over operands
+// parsed by next
+func (p *parser) binary(next func() (node, error), ops ...string) (node, error) {
+	x, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.accept(ops...)
+		if !ok {
+			return x, nil
+		}
+		y, err := next()
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryNode{pos: tok.pos, op: tok.text, x: x, y: y}
+	}
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.binary(p.parseAnd, "||")
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.binary(p.parseEquality, "&&")
+}
+
+func (p *parser) parseEquality() (node, error) {
+	return p.binary(p.parseComparison, "==", "!=")
+}
+
+func (p *parser) parseComparison() (node, error) {
+	return p.binary(p.parseSum, ">", "<", ">=", "<=")
+}
+
+func (p *parser) parseSum() (node, error) {
+	return p.binary(p.parseProduct, "+", "-")
+}
+
+func (p *parser) parseProduct() (node, error) {
+	return p.binary(p.parseUnary, "*", "/")
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if tok, ok := p.accept("!", "-"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{pos: tok.pos, op: tok.text, x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.advance()
+	switch tok.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("invalid number %s", tok)}
+		}
+		return &numberNode{pos: tok.pos, value: value}, nil
+	case tokenSignal:
+		return p.signal(tok), nil
+	case tokenLParen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen, `")"`); err != nil {
+			return nil, err
+		}
+		return x, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true", "false":
+			return &boolNode{pos: tok.pos, value: tok.text == "true"}, nil
+		}
+		if !functions[tok.text] {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unknown function %s", tok)}
+		}
+		return p.parseCall(tok)
+	default:
+		return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %s", tok)}
+	}
+}
+
+// parseCall parses the arguments of a function: a signal and a window in
+// milliseconds
+func (p *parser) parseCall(name token) (node, error) {
+	if _, err := p.expect(tokenLParen, `"(" after `+name.text); err != nil {
+		return nil, err
+	}
+	signal, err := p.expect(tokenSignal, name.text+" signal argument")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokenComma, `","`); err != nil {
+		return nil, err
+	}
+	window, err := p.expect(tokenNumber, name.text+" window in milliseconds")
+	if err != nil {
+		return nil, err
+	}
+	windowMs, err := strconv.ParseInt(window.text, 10, 64)
+	if err != nil || windowMs <= 0 {
+		return nil, &SyntaxError{Pos: window.pos, Msg: fmt.Sprintf("%s window must be a positive whole number of milliseconds", name.text)}
+	}
+	if _, err := p.expect(tokenRParen, `")"`); err != nil {
+		return nil, err
+	}
+	return &callNode{pos: name.pos, function: name.text, signal: p.signal(signal), windowMs: windowMs}, nil
+}
+
+func (p *parser) signal(tok token) *signalNode {
+	if !p.seen[tok.text] {
+		p.seen[tok.text] = true
+		p.signals = append(p.signals, tok.text)
+	}
+	return &signalNode{pos: tok.pos, name: tok.text}
+}
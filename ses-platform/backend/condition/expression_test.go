@@ -0,0 +1,75 @@
+package condition
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		source  string
+		signals []string
+	}{
+		{"$variable.`Vehicle.Speed` > 100", []string{"Vehicle.Speed"}},
+		{"$variable.Vehicle.Speed > 100", []string{"Vehicle.Speed"}},
+		{"$variable.`Vehicle.Speed` > 100 && $variable.Vehicle.Speed < 200 || !$variable.`Vehicle.Brake`", []string{"Vehicle.Speed", "Vehicle.Brake"}},
+		{"avg($variable.`Vehicle.Speed`, 1000) >= 50", []string{"Vehicle.Speed"}},
+		{"timeout($variable.A, 500) && last_change($variable.B, 100) == 1", []string{"A", "B"}},
+		{"-(1 + 2) * 3 == -9", nil},
+		{"1.5e3 > 1000", nil},
+		{"  true  ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expression, err := Parse(tt.source)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := expression.Signals(); !reflect.DeepEqual(got, tt.signals) {
+				t.Errorf("Signals() = %q, want %q", got, tt.signals)
+			}
+			if got := expression.String(); got != tt.source {
+				t.Errorf("String() = %q, want %q", got, tt.source)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		pos    int
+		msg    string
+	}{
+		{"", 1, "unexpected end of expression"},
+		{"$var.x > 1", 1, "signals are referenced as"},
+		{"$variable.`A > 1", 1, "unterminated signal name"},
+		{"$variable. > 1", 1, "missing signal name"},
+		{"1 > 2 )", 7, `unexpected ")"`},
+		{"(1 > 2", 7, `expected ")", got end of expression`},
+		{"1 # 2", 3, "unexpected character '#'"},
+		{"foo($variable.A, 10)", 1, `unknown function "foo"`},
+		{"avg(1, 10)", 5, "expected avg signal argument"},
+		{"avg($variable.A 10)", 17, `expected ","`},
+		{"avg($variable.A, 0)", 18, "window must be a positive whole number"},
+		{"avg($variable.A, 1.5)", 18, "window must be a positive whole number"},
+		{"1 >", 4, "unexpected end of expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := Parse(tt.source)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Parse error = %v, want a SyntaxError", err)
+			}
+			if syntaxErr.Pos != tt.pos {
+				t.Errorf("Pos = %d, want %d (%v)", syntaxErr.Pos, tt.pos, err)
+			}
+			if !strings.Contains(syntaxErr.Msg, tt.msg) {
+				t.Errorf("Msg = %q, want it to contain %q", syntaxErr.Msg, tt.msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// dockerAPIVersion is the Docker Engine API version requests are made against
+const dockerAPIVersion = "v1.41"
+
+// dockerClient is a minimal Docker Engine API client over the local socket
+type dockerClient struct {
+	http *http.Client
+}
+
+// newDockerClient connects to the socket in DOCKER_HOST (unix://...), or to
+// /var/run/docker.sock
+func newDockerClient() (*dockerClient, error) {
+	socket := "/var/run/docker.sock"
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		path, ok := strings.CutPrefix(host, "unix://")
+		if !ok {
+			return nil, fmt.Errorf("unsupported DOCKER_HOST %q: only unix sockets are supported", host)
+		}
+		socket = path
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+	return &dockerClient{http: &http.Client{Transport: transport}}, nil
+}
+
+// dockerError is a non-2xx response from the Engine API
+type dockerError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *dockerError) Error() string {
+	return fmt.Sprintf("docker: %s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// classifyDockerError treats server errors as recoverable and client errors,
+// such as a missing image or a name conflict, as fatal
+func classifyDockerError(err *dockerError) *ClassifiedError {
+	classified := &ClassifiedError{Severity: SeverityFatal, Type: ErrorTypeProvisioning, Code: strconv.Itoa(err.StatusCode), Err: err}
+	switch {
+	case err.StatusCode >= 500:
+		classified.Severity = SeverityRecoverable
+	case err.StatusCode == http.StatusNotFound, err.StatusCode == http.StatusBadRequest, err.StatusCode == http.StatusConflict:
+		classified.Type = ErrorTypeValidation
+	}
+	return classified
+}
+
+// do sends a request and decodes a JSON response into out, if set
+func (d *dockerClient) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := d.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (d *dockerClient) send(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	target := "http://docker/" + dockerAPIVersion + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var msg struct {
+			Message string `json:"message"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&msg) != nil || msg.Message == "" {
+			msg.Message = http.StatusText(resp.StatusCode)
+		}
+		return nil, classifyDockerError(&dockerError{StatusCode: resp.StatusCode, Message: msg.Message})
+	}
+	return resp, nil
+}
+
+// isDockerNotFound reports whether err is a 404 from the Engine API
+func isDockerNotFound(err error) bool {
+	var dockerErr *dockerError
+	return errors.As(err, &dockerErr) && dockerErr.StatusCode == http.StatusNotFound
+}
+
+func (d *dockerClient) Ping(ctx context.Context) error {
+	return d.do(ctx, http.MethodGet, "/_ping", nil, nil, nil)
+}
+
+type dockerInfo struct {
+	NCPU     int   `json:"NCPU"`
+	MemTotal int64 `json:"MemTotal"`
+}
+
+func (d *dockerClient) Info(ctx context.Context) (*dockerInfo, error) {
+	var info dockerInfo
+	if err := d.do(ctx, http.MethodGet, "/info", nil, nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// PullImage pulls an image unless it is already present
+func (d *dockerClient) PullImage(ctx context.Context, image string) error {
+	if err := d.do(ctx, http.MethodGet, "/images/"+image+"/json", nil, nil, nil); err == nil {
+		return nil
+	}
+
+	resp, err := d.send(ctx, http.MethodPost, "/images/create", url.Values{"fromImage": {image}}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Progress is streamed as JSON lines; failures are reported in-band
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var progress struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &progress) == nil && progress.Error != "" {
+			return &ClassifiedError{Severity: SeverityFatal, Type: ErrorTypeValidation, Err: fmt.Errorf("pulling %s: %s", image, progress.Error)}
+		}
+	}
+	return scanner.Err()
+}
+
+type dockerIPAMConfig struct {
+	Subnet string `json:"Subnet,omitempty"`
+}
+
+type dockerNetworkCreate struct {
+	Name   string            `json:"Name"`
+	Driver string            `json:"Driver"`
+	Labels map[string]string `json:"Labels,omitempty"`
+	IPAM   struct {
+		Config []dockerIPAMConfig `json:"Config,omitempty"`
+	} `json:"IPAM"`
+}
+
+func (d *dockerClient) CreateNetwork(ctx context.Context, req dockerNetworkCreate) (string, error) {
+	var resp struct {
+		ID string `json:"Id"`
+	}
+	if err := d.do(ctx, http.MethodPost, "/networks/create", nil, req, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+func (d *dockerClient) ConnectNetwork(ctx context.Context, networkID, containerID string) error {
+	return d.do(ctx, http.MethodPost, "/networks/"+networkID+"/connect", nil, map[string]string{"Container": containerID}, nil)
+}
+
+func (d *dockerClient) RemoveNetwork(ctx context.Context, id string) error {
+	return d.do(ctx, http.MethodDelete, "/networks/"+id, nil, nil, nil)
+}
+
+func (d *dockerClient) CreateVolume(ctx context.Context, name string, labels map[string]string) (string, error) {
+	var resp struct {
+		Name string `json:"Name"`
+	}
+	body := map[string]interface{}{"Name": name, "Labels": labels}
+	if err := d.do(ctx, http.MethodPost, "/volumes/create", nil, body, &resp); err != nil {
+		return "", err
+	}
+	return resp.Name, nil
+}
+
+func (d *dockerClient) RemoveVolume(ctx context.Context, name string) error {
+	return d.do(ctx, http.MethodDelete, "/volumes/"+name, nil, nil, nil)
+}
+
+// dockerHealthConfig durations are in nanoseconds
+type dockerHealthConfig struct {
+	Test     []string `json:"Test"`
+	Interval int64    `json:"Interval,omitempty"`
+	Timeout  int64    `json:"Timeout,omitempty"`
+	Retries  int      `json:"Retries,omitempty"`
+}
+
+type dockerContainerCreate struct {
+	Image       string              `json:"Image"`
+	Env         []string            `json:"Env,omitempty"`
+	Labels      map[string]string   `json:"Labels,omitempty"`
+	Healthcheck *dockerHealthConfig `json:"Healthcheck,omitempty"`
+	HostConfig  struct {
+		NetworkMode string   `json:"NetworkMode,omitempty"`
+		Binds       []string `json:"Binds,omitempty"`
+	} `json:"HostConfig"`
+}
+
+func (d *dockerClient) CreateContainer(ctx context.Context, name string, req dockerContainerCreate) (string, error) {
+	var resp struct {
+		ID string `json:"Id"`
+	}
+	if err := d.do(ctx, http.MethodPost, "/containers/create", url.Values{"name": {name}}, req, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+func (d *dockerClient) StartContainer(ctx context.Context, id string) error {
+	return d.do(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil, nil)
+}
+
+// RemoveContainer stops and removes a container; its volumes are kept
+func (d *dockerClient) RemoveContainer(ctx context.Context, id string) error {
+	return d.do(ctx, http.MethodDelete, "/containers/"+id, url.Values{"force": {"true"}}, nil, nil)
+}
+
+type dockerContainerState struct {
+	Running  bool   `json:"Running"`
+	Status   string `json:"Status"`
+	ExitCode int    `json:"ExitCode"`
+	Health   *struct {
+		Status string `json:"Status"` // starting, healthy, unhealthy
+	} `json:"Health"`
+}
+
+func (d *dockerClient) InspectContainer(ctx context.Context, id string) (*dockerContainerState, error) {
+	var resp struct {
+		State dockerContainerState `json:"State"`
+	}
+	if err := d.do(ctx, http.MethodGet, "/containers/"+id+"/json", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.State, nil
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DriftRecord is a difference between the recorded resource allocations and
+// what a provider actually has. Orphaned resources exist at the provider
+// without a matching environment; unallocated resources belong to a live
+// environment that has no allocation for them, such as environments
+// provisioned before resources were recorded; missing resources are recorded
+// as active but no longer exist at the provider. Only orphans are collected.
+type DriftRecord struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Kind          string     `gorm:"index" json:"kind"`   // orphaned, unallocated, missing
+	Status        string     `gorm:"index" json:"status"` // open, resolved, collected
+	Provider      string     `json:"provider"`
+	Region        string     `json:"region,omitempty"`
+	ResourceType  string     `json:"resource_type"` // vehicle, campaign
+	ResourceID    string     `gorm:"index" json:"resource_id"`
+	ResourceName  string     `json:"resource_name"`
+	EnvironmentID string     `gorm:"index" json:"environment_id,omitempty"` // Environment the resource is tagged with or recorded for
+	AllocationID  *uint      `json:"allocation_id,omitempty"`
+	Reason        string     `json:"reason"`
+	FirstSeenAt   time.Time  `json:"first_seen_at"`
+	LastSeenAt    time.Time  `json:"last_seen_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+}
+
+const (
+	DriftKindOrphaned    = "orphaned"
+	DriftKindUnallocated = "unallocated"
+	DriftKindMissing     = "missing"
+
+	DriftStatusOpen      = "open"
+	DriftStatusResolved  = "resolved"
+	DriftStatusCollected = "collected"
+
+	JobTypeReconcileDrift = "reconcile_drift"
+)
+
+var (
+	driftReconcileInterval = 15 * time.Minute
+	// Orphans are only garbage-collected once they have been seen for this long
+	driftGracePeriod = 24 * time.Hour
+)
+
+func init() {
+	jobHandlers[JobTypeReconcileDrift] = runReconcileDriftJob
+}
+
+// enqueueDriftReconciliation schedules the recurring reconciler. It runs in
+// dry-run mode unless DRIFT_GC is "true".
+func enqueueDriftReconciliation() error {
+	return enqueueUniqueJob(db, JobTypeReconcileDrift, "", map[string]interface{}{
+		"dry_run":   os.Getenv("DRIFT_GC") != "true",
+		"recurring": true,
+	})
+}
+
+// runReconcileDriftJob runs one reconciliation. The recurring job is
+// rescheduled even when a run fails so that reconciliation never stops.
+func runReconcileDriftJob(ctx context.Context, job *Job) error {
+	dryRun, ok := job.Payload["dry_run"].(bool)
+	if !ok {
+		dryRun = true
+	}
+	_, err := reconcileDrift(ctx, dryRun)
+	if recurring, _ := job.Payload["recurring"].(bool); !recurring {
+		return err
+	}
+	if err != nil {
+		log.Printf("Drift reconciliation failed: %v", err)
+	}
+	return &rescheduleJob{after: driftReconcileInterval}
+}
+
+// DriftReport summarizes one reconciliation run
+type DriftReport struct {
+	DryRun      bool     `json:"dry_run"`
+	Regions     []string `json:"regions"`
+	Orphaned    int      `json:"orphaned"`
+	Unallocated int      `json:"unallocated"`
+	Missing     int      `json:"missing"`
+	Resolved    int      `json:"resolved"`
+	Collected   int      `json:"collected"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// reconcileDrift lists the FleetWise vehicles and campaigns of every region in
+// use, diffs them against resource_allocations and updates the drift records.
+// Unless dryRun is set, orphans older than driftGracePeriod are deleted.
+func reconcileDrift(ctx context.Context, dryRun bool) (*DriftReport, error) {
+	regions, err := driftRegions()
+	if err != nil {
+		return nil, err
+	}
+	report := &DriftReport{DryRun: dryRun, Regions: regions}
+	observed := map[uint]bool{}
+
+	for _, region := range regions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		client, err := fleetWiseClients.Client(region)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", region, err))
+			continue
+		}
+		if err := reconcileRegion(ctx, client, region, report, observed); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", region, err))
+			continue
+		}
+		if !dryRun {
+			collectOrphans(ctx, client, region, report)
+		}
+	}
+
+	// Drift that was not seen again has been fixed, unless its region could not be listed
+	if len(report.Errors) == 0 {
+		var open []DriftRecord
+		if err := db.Where("status = ?", DriftStatusOpen).Find(&open).Error; err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		for _, record := range open {
+			if observed[record.ID] {
+				continue
+			}
+			if err := db.Model(&record).Updates(map[string]interface{}{"status": DriftStatusResolved, "resolved_at": now}).Error; err != nil {
+				return nil, err
+			}
+			report.Resolved++
+		}
+	}
+
+	log.Printf("Drift reconciliation (dry_run=%t): %d orphaned, %d unallocated, %d missing, %d resolved, %d collected",
+		dryRun, report.Orphaned, report.Unallocated, report.Missing, report.Resolved, report.Collected)
+	return report, nil
+}
+
+// driftRegions returns the regions of every FleetWise environment and recorded AWS resource
+func driftRegions() ([]string, error) {
+	seen := map[string]bool{}
+	var regions []string
+	add := func(region string) {
+		if region != "" && !seen[region] {
+			seen[region] = true
+			regions = append(regions, region)
+		}
+	}
+
+	var envs []Environment
+	if err := db.Find(&envs).Error; err != nil {
+		return nil, err
+	}
+	for _, env := range envs {
+		if env.FleetWiseConfig != nil {
+			add(env.FleetWiseConfig.Region)
+		}
+	}
+
+	var allocated []string
+	if err := db.Model(&ResourceAllocation{}).Where("provider = ?", "aws").Distinct().Pluck("region", &allocated).Error; err != nil {
+		return nil, err
+	}
+	for _, region := range allocated {
+		add(region)
+	}
+	return regions, nil
+}
+
+func reconcileRegion(ctx context.Context, client FleetWiseAPI, region string, report *DriftReport, observed map[uint]bool) error {
+	vehicles, err := client.ListVehicles(ctx, "")
+	if err != nil {
+		return err
+	}
+	for _, vehicle := range vehicles {
+		envID := vehicle.Attributes["EnvironmentID"]
+		if envID == "" || vehicle.Arn == nil || vehicle.VehicleName == nil {
+			continue // Not created by this platform
+		}
+		if err := checkOrphan(region, "vehicle", *vehicle.Arn, *vehicle.VehicleName, envID, report, observed); err != nil {
+			return err
+		}
+	}
+
+	campaigns, err := client.ListCampaigns(ctx, "")
+	if err != nil {
+		return err
+	}
+	for _, campaign := range campaigns {
+		if campaign.Name == nil || campaign.Arn == nil {
+			continue
+		}
+		envID, ok := campaignEnvironmentID(*campaign.Name)
+		if !ok {
+			continue
+		}
+		if err := checkOrphan(region, "campaign", *campaign.Arn, *campaign.Name, envID, report, observed); err != nil {
+			return err
+		}
+	}
+
+	// Recorded resources that the provider no longer knows about
+	var allocations []ResourceAllocation
+	if err := db.Where("provider = ? AND region = ? AND status = ? AND resource_type IN ?",
+		"aws", region, AllocationStatusActive, []string{"vehicle", "campaign"}).Find(&allocations).Error; err != nil {
+		return err
+	}
+	for _, allocation := range allocations {
+		name := fmt.Sprint(allocation.Config["name"])
+		if allocation.ResourceType == "vehicle" {
+			_, err = client.GetVehicle(ctx, name)
+		} else {
+			_, err = client.GetCampaign(ctx, name)
+		}
+		if !isAWSNotFound(err) {
+			continue
+		}
+		record := DriftRecord{
+			Kind:          DriftKindMissing,
+			Provider:      "aws",
+			Region:        region,
+			ResourceType:  allocation.ResourceType,
+			ResourceID:    allocation.ResourceID,
+			ResourceName:  name,
+			EnvironmentID: allocation.EnvironmentID,
+			AllocationID:  &allocation.ID,
+			Reason:        "recorded as active but no longer exists at the provider",
+		}
+		if err := flagDrift(&record); err != nil {
+			return err
+		}
+		observed[record.ID] = true
+		report.Missing++
+	}
+	return nil
+}
+
+// checkOrphan flags a provider resource tagged with an environment that does
+// not exist or was cleaned as orphaned, and one of a live environment without
+// an allocation for it as unallocated
+func checkOrphan(region, resourceType, arn, name, envID string, report *DriftReport, observed map[uint]bool) error {
+	var env Environment
+	kind, reason := DriftKindOrphaned, ""
+	err := db.Unscoped().Select("id", "status").First(&env, "id = ?", envID).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		reason = "environment does not exist"
+	case err != nil:
+		return err
+	case env.Status == StateCleaned:
+		reason = "environment was cleaned"
+	case env.Status == StateProvisioning:
+		return nil // Resources may be created before they are recorded
+	default:
+		var allocated int64
+		if err := db.Model(&ResourceAllocation{}).
+			Where("environment_id = ? AND resource_id = ? AND status <> ?", envID, arn, AllocationStatusReleased).
+			Count(&allocated).Error; err != nil {
+			return err
+		}
+		if allocated > 0 {
+			return nil
+		}
+		// Released by the environment's teardown, see ReleaseUnrecorded
+		kind = DriftKindUnallocated
+		reason = fmt.Sprintf("environment is %s but has no allocation for this resource", env.Status)
+	}
+
+	record := DriftRecord{
+		Kind:          kind,
+		Provider:      "aws",
+		Region:        region,
+		ResourceType:  resourceType,
+		ResourceID:    arn,
+		ResourceName:  name,
+		EnvironmentID: envID,
+		Reason:        reason,
+	}
+	if err := flagDrift(&record); err != nil {
+		return err
+	}
+	observed[record.ID] = true
+	if kind == DriftKindOrphaned {
+		report.Orphaned++
+	} else {
+		report.Unallocated++
+	}
+	return nil
+}
+
+// flagDrift creates an open drift record, or refreshes the existing one so
+// that FirstSeenAt keeps measuring how long the drift has lasted
+func flagDrift(record *DriftRecord) error {
+	now := time.Now()
+	var existing DriftRecord
+	err := db.Where("kind = ? AND resource_id = ? AND status = ?", record.Kind, record.ResourceID, DriftStatusOpen).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		record.Status = DriftStatusOpen
+		record.FirstSeenAt = now
+		record.LastSeenAt = now
+		return db.Create(record).Error
+	}
+	if err != nil {
+		return err
+	}
+	*record = existing
+	return db.Model(record).Updates(map[string]interface{}{"last_seen_at": now}).Error
+}
+
+// collectOrphans deletes the open orphans of a region that are older than the grace period
+func collectOrphans(ctx context.Context, client FleetWiseAPI, region string, report *DriftReport) {
+	var orphans []DriftRecord
+	if err := db.Where("kind = ? AND status = ? AND provider = ? AND region = ? AND first_seen_at <= ?",
+		DriftKindOrphaned, DriftStatusOpen, "aws", region, time.Now().Add(-driftGracePeriod)).Find(&orphans).Error; err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", region, err))
+		return
+	}
+	for _, orphan := range orphans {
+		var err error
+		switch orphan.ResourceType {
+		case "vehicle":
+			err = client.DeleteVehicle(ctx, orphan.ResourceName)
+		case "campaign":
+			err = client.DeleteCampaign(ctx, orphan.ResourceName)
+		}
+		if err != nil && !isAWSNotFound(err) {
+			report.Errors = append(report.Errors, fmt.Sprintf("collecting %s %s: %v", orphan.ResourceType, orphan.ResourceName, err))
+			continue
+		}
+		now := time.Now()
+		db.Model(&orphan).Updates(map[string]interface{}{"status": DriftStatusCollected, "resolved_at": now})
+		report.Collected++
+	}
+}
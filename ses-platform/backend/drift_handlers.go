@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Drift API Handlers
+
+// getDriftReport returns open drift records with a count per kind. Filter with
+// kind, provider and environment_id; status selects resolved or collected records.
+func getDriftReport(c *gin.Context) {
+	query := db.Order("first_seen_at, id").Where("status = ?", c.DefaultQuery("status", DriftStatusOpen))
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if provider := c.Query("provider"); provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+	if envID := c.Query("environment_id"); envID != "" {
+		query = query.Where("environment_id = ?", envID)
+	}
+
+	var records []DriftRecord
+	if err := query.Find(&records).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	counts := map[string]int{DriftKindOrphaned: 0, DriftKindUnallocated: 0, DriftKindMissing: 0}
+	for _, record := range records {
+		counts[record.Kind]++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"orphaned":    counts[DriftKindOrphaned],
+		"unallocated": counts[DriftKindUnallocated],
+		"missing":     counts[DriftKindMissing],
+		"records":     records,
+	})
+}
+
+// reconcileDriftNow queues a one-off reconciliation. Orphans past the grace
+// period are only deleted with dry_run=false.
+func reconcileDriftNow(c *gin.Context) {
+	job, err := enqueueJob(db, JobTypeReconcileDrift, "", map[string]interface{}{
+		"dry_run": c.Query("dry_run") != "false",
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// schemaCheckValues returns the values a CHECK (column IN (...)) constraint
+// in schema.sql allows
+func schemaCheckValues(t *testing.T, constraint string) []string {
+	t.Helper()
+	schema, err := os.ReadFile("../database/schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := regexp.MustCompile(`CONSTRAINT ` + constraint + ` CHECK \(\w+ IN \(([^)]*)\)\)`).FindSubmatch(schema)
+	if match == nil {
+		t.Fatalf("schema.sql has no %s constraint", constraint)
+	}
+	var values []string
+	for _, value := range strings.Split(string(match[1]), ",") {
+		values = append(values, strings.Trim(strings.TrimSpace(value), "'"))
+	}
+	return values
+}
+
+func TestDriftRecordsSatisfySchema(t *testing.T) {
+	kinds := schemaCheckValues(t, "chk_drift_kind")
+	statuses := schemaCheckValues(t, "chk_drift_status")
+	tests := []DriftRecord{
+		{Kind: DriftKindOrphaned, Status: DriftStatusOpen},
+		{Kind: DriftKindUnallocated, Status: DriftStatusOpen},
+		{Kind: DriftKindMissing, Status: DriftStatusResolved},
+		{Kind: DriftKindOrphaned, Status: DriftStatusCollected},
+	}
+	for _, record := range tests {
+		if !slices.Contains(kinds, record.Kind) {
+			t.Errorf("chk_drift_kind %v rejects kind %q", kinds, record.Kind)
+		}
+		if !slices.Contains(statuses, record.Status) {
+			t.Errorf("chk_drift_status %v rejects status %q", statuses, record.Status)
+		}
+	}
+}
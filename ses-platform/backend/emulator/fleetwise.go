@@ -0,0 +1,2105 @@
+// Package emulator provides in-memory stand-ins for provider APIs so that the
+// provider code paths can run in CI without cloud credentials.
+package emulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ses-platform/condition"
+)
+
+const (
+	// fleetWiseTargetPrefix prefixes the operation name in the X-Amz-Target header
+	fleetWiseTargetPrefix = "IoTAutobahnControlPlane."
+	fleetWiseAccount      = "123456789012"
+	defaultRegion         = "us-east-1"
+	maxBatchVehicles      = 10
+	maxListResults        = 100
+)
+
+const (
+	CampaignStatusCreating           = "CREATING"
+	CampaignStatusWaitingForApproval = "WAITING_FOR_APPROVAL"
+	CampaignStatusRunning            = "RUNNING"
+	CampaignStatusSuspended          = "SUSPENDED"
+)
+
+const (
+	ManifestStatusDraft  = "DRAFT"
+	ManifestStatusActive = "ACTIVE"
+)
+
+// namePattern is the pattern FleetWise enforces on vehicle, fleet and campaign names
+var namePattern = regexp.MustCompile(`^[a-zA-Z\d\-_:]{1,100}$`)
+
+// FleetWise emulates the AWS IoT FleetWise control plane. It speaks the
+// awsJson1_0 protocol the SDK uses: every call is a POST to / whose
+// X-Amz-Target header names the operation. State is kept in memory per
+// region, taken from the request signature.
+type FleetWise struct {
+	// CampaignCreationDelay is how long a new campaign stays CREATING before
+	// it is WAITING_FOR_APPROVAL
+	CampaignCreationDelay time.Duration
+
+	mu      sync.Mutex
+	regions map[string]*fleetWiseRegion
+	faults  []*Fault
+}
+
+type fleetWiseRegion struct {
+	name      string
+	vehicles  map[string]*emulatedVehicle
+	fleets    map[string]*emulatedFleet
+	campaigns map[string]*emulatedCampaign
+	catalogs  map[string]*emulatedSignalCatalog
+	models    map[string]*emulatedModelManifest
+	decoders  map[string]*emulatedDecoderManifest
+}
+
+type emulatedVehicle struct {
+	name               string
+	arn                string
+	modelManifestArn   string
+	decoderManifestArn string
+	attributes         map[string]string
+	fleets             map[string]bool
+	created, modified  time.Time
+}
+
+type emulatedFleet struct {
+	id                string
+	arn               string
+	description       string
+	signalCatalogArn  string
+	created, modified time.Time
+}
+
+type emulatedCampaign struct {
+	name              string
+	arn               string
+	description       string
+	signalCatalogArn  string
+	targetArn         string
+	status            string
+	dataExtraDims     []string
+	spec              map[string]json.RawMessage // Other CreateCampaign members, returned as given by GetCampaign
+	created, modified time.Time
+}
+
+// Fault makes calls to an operation fail with an AWS error, e.g. to exercise
+// throttling retries
+type Fault struct {
+	Operation string `json:"operation"`
+	Code      string `json:"code"` // ThrottlingException, InternalServerException, ...
+	Message   string `json:"message,omitempty"`
+	Count     int    `json:"count,omitempty"` // Number of calls to fail; 0 fails every call until Reset
+}
+
+// NewFleetWise creates an emulator with no resources
+func NewFleetWise() *FleetWise {
+	return &FleetWise{
+		CampaignCreationDelay: 2 * time.Second,
+		regions:               make(map[string]*fleetWiseRegion),
+	}
+}
+
+// InjectFault makes the next calls to fault.Operation fail
+func (f *FleetWise) InjectFault(fault Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = append(f.faults, &fault)
+}
+
+// Reset removes every resource and fault
+func (f *FleetWise) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.regions = make(map[string]*fleetWiseRegion)
+	f.faults = nil
+}
+
+// apiError is an AWS-shaped error response
+type apiError struct {
+	Code         string
+	Message      string
+	ResourceID   string
+	ResourceType string
+}
+
+func (e *apiError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// statusCode is the HTTP status FleetWise returns with each error code
+func (e *apiError) statusCode() int {
+	switch e.Code {
+	case "AccessDeniedException":
+		return http.StatusForbidden
+	case "ResourceNotFoundException":
+		return http.StatusNotFound
+	case "ConflictException":
+		return http.StatusConflict
+	case "LimitExceededException":
+		return http.StatusPaymentRequired
+	case "ThrottlingException":
+		return http.StatusTooManyRequests
+	case "InternalServerException":
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func validationError(format string, args ...interface{}) *apiError {
+	return &apiError{Code: "ValidationException", Message: fmt.Sprintf(format, args...)}
+}
+
+func notFound(resourceType, id string) *apiError {
+	return &apiError{
+		Code:         "ResourceNotFoundException",
+		Message:      fmt.Sprintf("%s %s not found", resourceType, id),
+		ResourceID:   id,
+		ResourceType: resourceType,
+	}
+}
+
+func conflict(format string, args ...interface{}) *apiError {
+	return &apiError{Code: "ConflictException", Message: fmt.Sprintf(format, args...)}
+}
+
+type fleetWiseOperation func(f *FleetWise, r *fleetWiseRegion, body []byte) (interface{}, error)
+
+var fleetWiseOperations = map[string]fleetWiseOperation{
+	"CreateVehicle":            (*FleetWise).createVehicle,
+	"BatchCreateVehicle":       (*FleetWise).batchCreateVehicle,
+	"GetVehicle":               (*FleetWise).getVehicle,
+	"UpdateVehicle":            (*FleetWise).updateVehicle,
+	"DeleteVehicle":            (*FleetWise).deleteVehicle,
+	"ListVehicles":             (*FleetWise).listVehicles,
+	"GetVehicleStatus":         (*FleetWise).getVehicleStatus,
+	"CreateFleet":              (*FleetWise).createFleet,
+	"GetFleet":                 (*FleetWise).getFleet,
+	"DeleteFleet":              (*FleetWise).deleteFleet,
+	"ListFleets":               (*FleetWise).listFleets,
+	"AssociateVehicleFleet":    (*FleetWise).associateVehicleFleet,
+	"DisassociateVehicleFleet": (*FleetWise).disassociateVehicleFleet,
+	"ListVehiclesInFleet":      (*FleetWise).listVehiclesInFleet,
+	"ListFleetsForVehicle":     (*FleetWise).listFleetsForVehicle,
+	"CreateCampaign":           (*FleetWise).createCampaign,
+	"GetCampaign":              (*FleetWise).getCampaign,
+	"UpdateCampaign":           (*FleetWise).updateCampaign,
+	"DeleteCampaign":           (*FleetWise).deleteCampaign,
+	"ListCampaigns":            (*FleetWise).listCampaigns,
+	"CreateSignalCatalog":      (*FleetWise).createSignalCatalog,
+	"GetSignalCatalog":         (*FleetWise).getSignalCatalog,
+	"UpdateSignalCatalog":      (*FleetWise).updateSignalCatalog,
+	"DeleteSignalCatalog":      (*FleetWise).deleteSignalCatalog,
+	"ListSignalCatalogs":       (*FleetWise).listSignalCatalogs,
+	"ListSignalCatalogNodes":   (*FleetWise).listSignalCatalogNodes,
+
+	"CreateModelManifest":                  (*FleetWise).createModelManifest,
+	"GetModelManifest":                     (*FleetWise).getModelManifest,
+	"UpdateModelManifest":                  (*FleetWise).updateModelManifest,
+	"DeleteModelManifest":                  (*FleetWise).deleteModelManifest,
+	"ListModelManifests":                   (*FleetWise).listModelManifests,
+	"ListModelManifestNodes":               (*FleetWise).listModelManifestNodes,
+	"CreateDecoderManifest":                (*FleetWise).createDecoderManifest,
+	"GetDecoderManifest":                   (*FleetWise).getDecoderManifest,
+	"UpdateDecoderManifest":                (*FleetWise).updateDecoderManifest,
+	"DeleteDecoderManifest":                (*FleetWise).deleteDecoderManifest,
+	"ListDecoderManifests":                 (*FleetWise).listDecoderManifests,
+	"ListDecoderManifestNetworkInterfaces": (*FleetWise).listDecoderManifestNetworkInterfaces,
+	"ListDecoderManifestSignals":           (*FleetWise).listDecoderManifestSignals,
+}
+
+// ServeHTTP serves the FleetWise API on / and the emulator controls on
+// /_emulator/reset and /_emulator/faults
+func (f *FleetWise) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.URL.Path {
+	case "/_emulator/reset":
+		f.Reset()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case "/_emulator/faults":
+		var fault Fault
+		if err := json.NewDecoder(req.Body).Decode(&fault); err != nil || fault.Operation == "" || fault.Code == "" {
+			http.Error(w, "operation and code are required", http.StatusBadRequest)
+			return
+		}
+		f.InjectFault(fault)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if req.Method != http.MethodPost {
+		writeError(w, &apiError{Code: "UnknownOperationException", Message: "FleetWise operations must be POSTed"})
+		return
+	}
+	name, _ := strings.CutPrefix(req.Header.Get("X-Amz-Target"), fleetWiseTargetPrefix)
+	operation, ok := fleetWiseOperations[name]
+	if !ok {
+		writeError(w, &apiError{Code: "UnknownOperationException", Message: fmt.Sprintf("operation %q is not emulated", name)})
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		writeError(w, &apiError{Code: "SerializationException", Message: err.Error()})
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if fault := f.takeFault(name); fault != nil {
+		writeError(w, fault)
+		return
+	}
+	out, err := operation(f, f.region(signingRegion(req)), body)
+	if err != nil {
+		apiErr, ok := err.(*apiError)
+		if !ok {
+			apiErr = &apiError{Code: "SerializationException", Message: err.Error()}
+		}
+		writeError(w, apiErr)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	json.NewEncoder(w).Encode(out)
+}
+
+func writeError(w http.ResponseWriter, err *apiError) {
+	body := map[string]string{"__type": err.Code, "message": err.Message}
+	if err.ResourceID != "" {
+		body["resourceId"] = err.ResourceID
+		body["resourceType"] = err.ResourceType
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.Header().Set("X-Amzn-ErrorType", err.Code)
+	w.WriteHeader(err.statusCode())
+	json.NewEncoder(w).Encode(body)
+}
+
+// takeFault returns the error of the next fault injected for an operation
+func (f *FleetWise) takeFault(operation string) *apiError {
+	for i, fault := range f.faults {
+		if fault.Operation != operation {
+			continue
+		}
+		if fault.Count > 0 {
+			fault.Count--
+			if fault.Count == 0 {
+				f.faults = append(f.faults[:i], f.faults[i+1:]...)
+			}
+		}
+		message := fault.Message
+		if message == "" {
+			message = "injected fault"
+		}
+		return &apiError{Code: fault.Code, Message: message}
+	}
+	return nil
+}
+
+// signingRegion reads the region from the SigV4 credential scope,
+// Credential=<key>/<date>/<region>/<service>/aws4_request
+func signingRegion(req *http.Request) string {
+	_, scope, ok := strings.Cut(req.Header.Get("Authorization"), "Credential=")
+	if !ok {
+		return defaultRegion
+	}
+	parts := strings.Split(strings.SplitN(scope, ",", 2)[0], "/")
+	if len(parts) < 3 || parts[2] == "" {
+		return defaultRegion
+	}
+	return parts[2]
+}
+
+func (f *FleetWise) region(name string) *fleetWiseRegion {
+	r, ok := f.regions[name]
+	if !ok {
+		r = &fleetWiseRegion{
+			name:      name,
+			vehicles:  make(map[string]*emulatedVehicle),
+			fleets:    make(map[string]*emulatedFleet),
+			campaigns: make(map[string]*emulatedCampaign),
+			catalogs:  make(map[string]*emulatedSignalCatalog),
+			models:    make(map[string]*emulatedModelManifest),
+			decoders:  make(map[string]*emulatedDecoderManifest),
+		}
+		f.regions[name] = r
+	}
+	return r
+}
+
+func (r *fleetWiseRegion) arn(resourceType, name string) string {
+	return fmt.Sprintf("arn:aws:iotfleetwise:%s:%s:%s/%s", r.name, fleetWiseAccount, resourceType, name)
+}
+
+// campaignStatus moves a campaign out of CREATING once its creation delay has passed
+func (f *FleetWise) campaignStatus(c *emulatedCampaign) string {
+	if c.status == CampaignStatusCreating && time.Since(c.created) >= f.CampaignCreationDelay {
+		c.status = CampaignStatusWaitingForApproval
+		c.modified = time.Now()
+	}
+	return c.status
+}
+
+func decode(body []byte, in interface{}) error {
+	if len(body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(body, in)
+}
+
+// epochSeconds formats a timestamp the way awsJson1_0 does
+func epochSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+func requireName(field string, value *string) *apiError {
+	if value == nil || *value == "" {
+		return validationError("%s is required", field)
+	}
+	if !namePattern.MatchString(*value) {
+		return validationError("%s %q must match %s", field, *value, namePattern)
+	}
+	return nil
+}
+
+func requireARN(field string, value *string) *apiError {
+	if value == nil || !strings.HasPrefix(*value, "arn:") {
+		return validationError("%s must be an ARN", field)
+	}
+	return nil
+}
+
+// page selects the items of one page of a sorted listing. Tokens are the
+// offset of the page's first item.
+func page(total int, nextToken *string, maxResults *int32) (start, end int, next *string, err error) {
+	limit := maxListResults
+	if maxResults != nil {
+		if *maxResults < 1 || *maxResults > maxListResults {
+			return 0, 0, nil, validationError("maxResults must be between 1 and %d", maxListResults)
+		}
+		limit = int(*maxResults)
+	}
+	if nextToken != nil && *nextToken != "" {
+		offset, convErr := strconv.Atoi(*nextToken)
+		if convErr != nil || offset < 0 {
+			return 0, 0, nil, validationError("invalid nextToken")
+		}
+		start = offset
+	}
+	if start > total {
+		start = total
+	}
+	end = start + limit
+	if end > total {
+		end = total
+	}
+	if end < total {
+		token := strconv.Itoa(end)
+		next = &token
+	}
+	return start, end, next, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Vehicles
+
+type vehicleInput struct {
+	VehicleName         *string           `json:"vehicleName"`
+	ModelManifestArn    *string           `json:"modelManifestArn"`
+	DecoderManifestArn  *string           `json:"decoderManifestArn"`
+	Attributes          map[string]string `json:"attributes"`
+	AssociationBehavior string            `json:"associationBehavior"`
+}
+
+type vehicleOutput struct {
+	VehicleName          string            `json:"vehicleName"`
+	Arn                  string            `json:"arn"`
+	ModelManifestArn     string            `json:"modelManifestArn"`
+	DecoderManifestArn   string            `json:"decoderManifestArn"`
+	Attributes           map[string]string `json:"attributes,omitempty"`
+	CreationTime         float64           `json:"creationTime"`
+	LastModificationTime float64           `json:"lastModificationTime"`
+}
+
+func (v *emulatedVehicle) output() vehicleOutput {
+	return vehicleOutput{
+		VehicleName:          v.name,
+		Arn:                  v.arn,
+		ModelManifestArn:     v.modelManifestArn,
+		DecoderManifestArn:   v.decoderManifestArn,
+		Attributes:           v.attributes,
+		CreationTime:         epochSeconds(v.created),
+		LastModificationTime: epochSeconds(v.modified),
+	}
+}
+
+type createVehicleOutput struct {
+	VehicleName string `json:"vehicleName"`
+	Arn         string `json:"arn"`
+	ThingArn    string `json:"thingArn,omitempty"`
+}
+
+func (r *fleetWiseRegion) addVehicle(in vehicleInput) (*createVehicleOutput, *apiError) {
+	if err := requireName("vehicleName", in.VehicleName); err != nil {
+		return nil, err
+	}
+	if err := requireARN("modelManifestArn", in.ModelManifestArn); err != nil {
+		return nil, err
+	}
+	if err := requireARN("decoderManifestArn", in.DecoderManifestArn); err != nil {
+		return nil, err
+	}
+	name := *in.VehicleName
+	if _, exists := r.vehicles[name]; exists {
+		return nil, conflict("vehicle %s already exists", name)
+	}
+
+	now := time.Now()
+	v := &emulatedVehicle{
+		name:               name,
+		arn:                r.arn("vehicle", name),
+		modelManifestArn:   *in.ModelManifestArn,
+		decoderManifestArn: *in.DecoderManifestArn,
+		attributes:         in.Attributes,
+		fleets:             make(map[string]bool),
+		created:            now,
+		modified:           now,
+	}
+	r.vehicles[name] = v
+
+	out := &createVehicleOutput{VehicleName: name, Arn: v.arn}
+	if in.AssociationBehavior == "CreateIotThing" {
+		out.ThingArn = fmt.Sprintf("arn:aws:iot:%s:%s:thing/%s", r.name, fleetWiseAccount, name)
+	}
+	return out, nil
+}
+
+func (f *FleetWise) createVehicle(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in vehicleInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	out, err := r.addVehicle(in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// batchCreateVehicle reports per-vehicle failures in the response rather than
+// failing the call, as FleetWise does
+func (f *FleetWise) batchCreateVehicle(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in struct {
+		Vehicles []vehicleInput `json:"vehicles"`
+	}
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if len(in.Vehicles) == 0 || len(in.Vehicles) > maxBatchVehicles {
+		return nil, validationError("vehicles must contain between 1 and %d items", maxBatchVehicles)
+	}
+
+	type itemError struct {
+		VehicleName string `json:"vehicleName"`
+		Code        string `json:"code"`
+		Message     string `json:"message"`
+	}
+	out := struct {
+		Vehicles []*createVehicleOutput `json:"vehicles"`
+		Errors   []itemError            `json:"errors"`
+	}{Vehicles: []*createVehicleOutput{}, Errors: []itemError{}}
+	for _, item := range in.Vehicles {
+		created, err := r.addVehicle(item)
+		if err != nil {
+			name := ""
+			if item.VehicleName != nil {
+				name = *item.VehicleName
+			}
+			out.Errors = append(out.Errors, itemError{VehicleName: name, Code: strconv.Itoa(err.statusCode()), Message: err.Message})
+			continue
+		}
+		out.Vehicles = append(out.Vehicles, created)
+	}
+	return out, nil
+}
+
+func (r *fleetWiseRegion) vehicle(name *string) (*emulatedVehicle, *apiError) {
+	if err := requireName("vehicleName", name); err != nil {
+		return nil, err
+	}
+	v, ok := r.vehicles[*name]
+	if !ok {
+		return nil, notFound("vehicle", *name)
+	}
+	return v, nil
+}
+
+func (f *FleetWise) getVehicle(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in vehicleInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	v, err := r.vehicle(in.VehicleName)
+	if err != nil {
+		return nil, err
+	}
+	return v.output(), nil
+}
+
+func (f *FleetWise) updateVehicle(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in struct {
+		vehicleInput
+		AttributeUpdateMode string `json:"attributeUpdateMode"`
+	}
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	v, err := r.vehicle(in.VehicleName)
+	if err != nil {
+		return nil, err
+	}
+	if in.ModelManifestArn != nil {
+		if err := requireARN("modelManifestArn", in.ModelManifestArn); err != nil {
+			return nil, err
+		}
+	}
+	if in.DecoderManifestArn != nil {
+		if err := requireARN("decoderManifestArn", in.DecoderManifestArn); err != nil {
+			return nil, err
+		}
+	}
+	if len(in.Attributes) > 0 && in.AttributeUpdateMode != "Overwrite" && in.AttributeUpdateMode != "Merge" {
+		return nil, validationError("attributeUpdateMode must be Overwrite or Merge when attributes are given")
+	}
+
+	if in.ModelManifestArn != nil {
+		v.modelManifestArn = *in.ModelManifestArn
+	}
+	if in.DecoderManifestArn != nil {
+		v.decoderManifestArn = *in.DecoderManifestArn
+	}
+	switch in.AttributeUpdateMode {
+	case "Overwrite":
+		v.attributes = in.Attributes
+	case "Merge":
+		// An empty value removes the attribute
+		if v.attributes == nil {
+			v.attributes = make(map[string]string)
+		}
+		for key, value := range in.Attributes {
+			if value == "" {
+				delete(v.attributes, key)
+			} else {
+				v.attributes[key] = value
+			}
+		}
+	}
+	v.modified = time.Now()
+	return map[string]string{"vehicleName": v.name, "arn": v.arn}, nil
+}
+
+// deleteVehicle succeeds for vehicles that do not exist, as FleetWise does
+func (f *FleetWise) deleteVehicle(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in vehicleInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if err := requireName("vehicleName", in.VehicleName); err != nil {
+		return nil, err
+	}
+	delete(r.vehicles, *in.VehicleName)
+	return map[string]string{"vehicleName": *in.VehicleName, "arn": r.arn("vehicle", *in.VehicleName)}, nil
+}
+
+func (f *FleetWise) listVehicles(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in struct {
+		ModelManifestArn *string `json:"modelManifestArn"`
+		NextToken        *string `json:"nextToken"`
+		MaxResults       *int32  `json:"maxResults"`
+	}
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	var matching []*emulatedVehicle
+	for _, name := range sortedKeys(r.vehicles) {
+		v := r.vehicles[name]
+		if in.ModelManifestArn != nil && *in.ModelManifestArn != v.modelManifestArn {
+			continue
+		}
+		matching = append(matching, v)
+	}
+	start, end, next, err := page(len(matching), in.NextToken, in.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	summaries := []vehicleOutput{}
+	for _, v := range matching[start:end] {
+		summaries = append(summaries, v.output())
+	}
+	return map[string]interface{}{"vehicleSummaries": summaries, "nextToken": next}, nil
+}
+
+// getVehicleStatus reports every campaign that targets the vehicle, directly
+// or through one of its fleets
+func (f *FleetWise) getVehicleStatus(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in struct {
+		VehicleName *string `json:"vehicleName"`
+		NextToken   *string `json:"nextToken"`
+		MaxResults  *int32  `json:"maxResults"`
+	}
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	v, err := r.vehicle(in.VehicleName)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := map[string]bool{v.arn: true}
+	for id := range v.fleets {
+		if fleet, ok := r.fleets[id]; ok {
+			targets[fleet.arn] = true
+		}
+	}
+	type vehicleStatus struct {
+		CampaignName string `json:"campaignName"`
+		VehicleName  string `json:"vehicleName"`
+		Status       string `json:"status"`
+	}
+	var statuses []vehicleStatus
+	for _, name := range sortedKeys(r.campaigns) {
+		c := r.campaigns[name]
+		if !targets[c.targetArn] {
+			continue
+		}
+		// The vehicle receives the campaign once it has been approved
+		status := "CREATED"
+		switch f.campaignStatus(c) {
+		case CampaignStatusRunning:
+			status = "HEALTHY"
+		case CampaignStatusSuspended:
+			status = "SUSPENDED"
+		}
+		statuses = append(statuses, vehicleStatus{CampaignName: c.name, VehicleName: v.name, Status: status})
+	}
+	start, end, next, pageErr := page(len(statuses), in.NextToken, in.MaxResults)
+	if pageErr != nil {
+		return nil, pageErr
+	}
+	return map[string]interface{}{"campaigns": append([]vehicleStatus{}, statuses[start:end]...), "nextToken": next}, nil
+}
+
+// Fleets
+
+type fleetInput struct {
+	FleetID          *string `json:"fleetId"`
+	Description      *string `json:"description"`
+	SignalCatalogArn *string `json:"signalCatalogArn"`
+	VehicleName      *string `json:"vehicleName"`
+	NextToken        *string `json:"nextToken"`
+	MaxResults       *int32  `json:"maxResults"`
+}
+
+func (fl *emulatedFleet) output() map[string]interface{} {
+	return map[string]interface{}{
+		"id":                   fl.id,
+		"arn":                  fl.arn,
+		"description":          fl.description,
+		"signalCatalogArn":     fl.signalCatalogArn,
+		"creationTime":         epochSeconds(fl.created),
+		"lastModificationTime": epochSeconds(fl.modified),
+	}
+}
+
+func (r *fleetWiseRegion) fleet(id *string) (*emulatedFleet, *apiError) {
+	if err := requireName("fleetId", id); err != nil {
+		return nil, err
+	}
+	fl, ok := r.fleets[*id]
+	if !ok {
+		return nil, notFound("fleet", *id)
+	}
+	return fl, nil
+}
+
+func (f *FleetWise) createFleet(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in fleetInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if err := requireName("fleetId", in.FleetID); err != nil {
+		return nil, err
+	}
+	if err := requireARN("signalCatalogArn", in.SignalCatalogArn); err != nil {
+		return nil, err
+	}
+	if _, exists := r.fleets[*in.FleetID]; exists {
+		return nil, conflict("fleet %s already exists", *in.FleetID)
+	}
+
+	now := time.Now()
+	fl := &emulatedFleet{
+		id:               *in.FleetID,
+		arn:              r.arn("fleet", *in.FleetID),
+		signalCatalogArn: *in.SignalCatalogArn,
+		created:          now,
+		modified:         now,
+	}
+	if in.Description != nil {
+		fl.description = *in.Description
+	}
+	r.fleets[fl.id] = fl
+	return map[string]string{"id": fl.id, "arn": fl.arn}, nil
+}
+
+func (f *FleetWise) getFleet(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in fleetInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	fl, err := r.fleet(in.FleetID)
+	if err != nil {
+		return nil, err
+	}
+	return fl.output(), nil
+}
+
+// deleteFleet succeeds for fleets that do not exist, but refuses to delete a
+// fleet that still has vehicles
+func (f *FleetWise) deleteFleet(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in fleetInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if err := requireName("fleetId", in.FleetID); err != nil {
+		return nil, err
+	}
+	id := *in.FleetID
+	for _, v := range r.vehicles {
+		if v.fleets[id] {
+			return nil, validationError("fleet %s still has vehicles; disassociate them first", id)
+		}
+	}
+	delete(r.fleets, id)
+	return map[string]string{"id": id, "arn": r.arn("fleet", id)}, nil
+}
+
+func (f *FleetWise) listFleets(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in fleetInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	ids := sortedKeys(r.fleets)
+	start, end, next, err := page(len(ids), in.NextToken, in.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	summaries := []map[string]interface{}{}
+	for _, id := range ids[start:end] {
+		summaries = append(summaries, r.fleets[id].output())
+	}
+	return map[string]interface{}{"fleetSummaries": summaries, "nextToken": next}, nil
+}
+
+func (f *FleetWise) associateVehicleFleet(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in fleetInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	v, err := r.vehicle(in.VehicleName)
+	if err != nil {
+		return nil, err
+	}
+	fl, err := r.fleet(in.FleetID)
+	if err != nil {
+		return nil, err
+	}
+	v.fleets[fl.id] = true
+	return struct{}{}, nil
+}
+
+func (f *FleetWise) disassociateVehicleFleet(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in fleetInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	v, err := r.vehicle(in.VehicleName)
+	if err != nil {
+		return nil, err
+	}
+	fl, err := r.fleet(in.FleetID)
+	if err != nil {
+		return nil, err
+	}
+	delete(v.fleets, fl.id)
+	return struct{}{}, nil
+}
+
+func (f *FleetWise) listVehiclesInFleet(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in fleetInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	fl, err := r.fleet(in.FleetID)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range sortedKeys(r.vehicles) {
+		if r.vehicles[name].fleets[fl.id] {
+			names = append(names, name)
+		}
+	}
+	start, end, next, pageErr := page(len(names), in.NextToken, in.MaxResults)
+	if pageErr != nil {
+		return nil, pageErr
+	}
+	return map[string]interface{}{"vehicles": append([]string{}, names[start:end]...), "nextToken": next}, nil
+}
+
+func (f *FleetWise) listFleetsForVehicle(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in fleetInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	v, err := r.vehicle(in.VehicleName)
+	if err != nil {
+		return nil, err
+	}
+	ids := sortedKeys(v.fleets)
+	start, end, next, pageErr := page(len(ids), in.NextToken, in.MaxResults)
+	if pageErr != nil {
+		return nil, pageErr
+	}
+	return map[string]interface{}{"fleets": append([]string{}, ids[start:end]...), "nextToken": next}, nil
+}
+
+// Campaigns
+
+// campaignSpecMembers are the CreateCampaign members stored as given
+var campaignSpecMembers = []string{
+	"collectionScheme", "compression", "dataDestinationConfigs", "diagnosticsMode", "expiryTime",
+	"postTriggerCollectionDuration", "priority", "signalsToCollect", "spoolingMode", "startTime",
+}
+
+type campaignInput struct {
+	Name                *string  `json:"name"`
+	Description         *string  `json:"description"`
+	SignalCatalogArn    *string  `json:"signalCatalogArn"`
+	TargetArn           *string  `json:"targetArn"`
+	DataExtraDimensions []string `json:"dataExtraDimensions"`
+	Action              string   `json:"action"`
+	Status              *string  `json:"status"`
+	NextToken           *string  `json:"nextToken"`
+	MaxResults          *int32   `json:"maxResults"`
+}
+
+func (r *fleetWiseRegion) campaign(name *string) (*emulatedCampaign, *apiError) {
+	if err := requireName("name", name); err != nil {
+		return nil, err
+	}
+	c, ok := r.campaigns[*name]
+	if !ok {
+		return nil, notFound("campaign", *name)
+	}
+	return c, nil
+}
+
+func (f *FleetWise) campaignSummary(c *emulatedCampaign) map[string]interface{} {
+	return map[string]interface{}{
+		"name":                 c.name,
+		"arn":                  c.arn,
+		"description":          c.description,
+		"signalCatalogArn":     c.signalCatalogArn,
+		"targetArn":            c.targetArn,
+		"status":               f.campaignStatus(c),
+		"creationTime":         epochSeconds(c.created),
+		"lastModificationTime": epochSeconds(c.modified),
+	}
+}
+
+// createCampaign requires the target vehicle or fleet to exist. The campaign
+// starts CREATING and waits for approval after CampaignCreationDelay.
+func (f *FleetWise) createCampaign(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in campaignInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	var members map[string]json.RawMessage
+	if err := decode(body, &members); err != nil {
+		return nil, err
+	}
+	if err := requireName("name", in.Name); err != nil {
+		return nil, err
+	}
+	if err := requireARN("signalCatalogArn", in.SignalCatalogArn); err != nil {
+		return nil, err
+	}
+	if err := requireARN("targetArn", in.TargetArn); err != nil {
+		return nil, err
+	}
+	if _, ok := members["collectionScheme"]; !ok {
+		return nil, validationError("collectionScheme is required")
+	}
+	if _, exists := r.campaigns[*in.Name]; exists {
+		return nil, conflict("campaign %s already exists", *in.Name)
+	}
+	if !r.targetExists(*in.TargetArn) {
+		return nil, notFound("target", *in.TargetArn)
+	}
+	if err := r.checkCondition(members["collectionScheme"], *in.SignalCatalogArn); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	c := &emulatedCampaign{
+		name:             *in.Name,
+		arn:              r.arn("campaign", *in.Name),
+		signalCatalogArn: *in.SignalCatalogArn,
+		targetArn:        *in.TargetArn,
+		status:           CampaignStatusCreating,
+		dataExtraDims:    in.DataExtraDimensions,
+		spec:             make(map[string]json.RawMessage),
+		created:          now,
+		modified:         now,
+	}
+	if in.Description != nil {
+		c.description = *in.Description
+	}
+	for _, member := range campaignSpecMembers {
+		if value, ok := members[member]; ok {
+			c.spec[member] = value
+		}
+	}
+	r.campaigns[c.name] = c
+	return map[string]string{"name": c.name, "arn": c.arn}, nil
+}
+
+// checkCondition parses the expression of a condition-based collection scheme
+// and, when the signal catalog is emulated, checks it against the catalog
+func (r *fleetWiseRegion) checkCondition(scheme json.RawMessage, signalCatalogArn string) *apiError {
+	var union struct {
+		ConditionBased *struct {
+			Expression string `json:"expression"`
+		} `json:"conditionBasedCollectionScheme"`
+	}
+	if err := json.Unmarshal(scheme, &union); err != nil {
+		return validationError("collectionScheme is invalid: %v", err)
+	}
+	if union.ConditionBased == nil {
+		return nil
+	}
+	expression, err := condition.Parse(union.ConditionBased.Expression)
+	if err != nil {
+		return validationError("expression: %v", err)
+	}
+	sc, apiErr := r.signalCatalogByARN(signalCatalogArn)
+	if apiErr != nil {
+		return nil
+	}
+	signals := make(map[string]condition.Signal, len(sc.nodes))
+	for name, n := range sc.nodes {
+		signals[name] = condition.Signal{NodeType: n.kind, DataType: n.dataType}
+	}
+	if err := expression.Check(signals); err != nil {
+		return validationError("expression: %v", err)
+	}
+	return nil
+}
+
+func (r *fleetWiseRegion) targetExists(arn string) bool {
+	for _, v := range r.vehicles {
+		if v.arn == arn {
+			return true
+		}
+	}
+	for _, fl := range r.fleets {
+		if fl.arn == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *FleetWise) getCampaign(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in campaignInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	c, err := r.campaign(in.Name)
+	if err != nil {
+		return nil, err
+	}
+	out := f.campaignSummary(c)
+	for member, value := range c.spec {
+		out[member] = value
+	}
+	if len(c.dataExtraDims) > 0 {
+		out["dataExtraDimensions"] = c.dataExtraDims
+	}
+	return out, nil
+}
+
+// campaignActions are the states from which each UpdateCampaign action is
+// allowed and the state it moves the campaign to. UPDATE keeps the state.
+var campaignActions = map[string]struct {
+	from []string
+	to   string
+}{
+	"APPROVE": {from: []string{CampaignStatusWaitingForApproval}, to: CampaignStatusRunning},
+	"SUSPEND": {from: []string{CampaignStatusRunning}, to: CampaignStatusSuspended},
+	"RESUME":  {from: []string{CampaignStatusSuspended}, to: CampaignStatusRunning},
+	"UPDATE":  {from: []string{CampaignStatusWaitingForApproval, CampaignStatusRunning, CampaignStatusSuspended}},
+}
+
+func (f *FleetWise) updateCampaign(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in campaignInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	c, err := r.campaign(in.Name)
+	if err != nil {
+		return nil, err
+	}
+	action, ok := campaignActions[in.Action]
+	if !ok {
+		return nil, validationError("action must be one of APPROVE, SUSPEND, RESUME, UPDATE")
+	}
+	status := f.campaignStatus(c)
+	allowed := false
+	for _, from := range action.from {
+		allowed = allowed || from == status
+	}
+	if !allowed {
+		return nil, conflict("campaign %s is %s and cannot be updated with action %s", c.name, status, in.Action)
+	}
+
+	if action.to != "" {
+		c.status = action.to
+	}
+	if in.Description != nil {
+		c.description = *in.Description
+	}
+	if in.DataExtraDimensions != nil {
+		c.dataExtraDims = in.DataExtraDimensions
+	}
+	c.modified = time.Now()
+	return map[string]string{"name": c.name, "arn": c.arn, "status": c.status}, nil
+}
+
+func (f *FleetWise) deleteCampaign(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in campaignInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	c, err := r.campaign(in.Name)
+	if err != nil {
+		return nil, err
+	}
+	delete(r.campaigns, c.name)
+	return map[string]string{"name": c.name, "arn": c.arn}, nil
+}
+
+func (f *FleetWise) listCampaigns(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in campaignInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	var matching []*emulatedCampaign
+	for _, name := range sortedKeys(r.campaigns) {
+		c := r.campaigns[name]
+		if in.Status != nil && *in.Status != f.campaignStatus(c) {
+			continue
+		}
+		matching = append(matching, c)
+	}
+	start, end, next, err := page(len(matching), in.NextToken, in.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	summaries := []map[string]interface{}{}
+	for _, c := range matching[start:end] {
+		summaries = append(summaries, f.campaignSummary(c))
+	}
+	return map[string]interface{}{"campaignSummaries": summaries, "nextToken": next}, nil
+}
+
+// Signal catalogs
+
+type emulatedSignalCatalog struct {
+	name              string
+	arn               string
+	description       string
+	nodes             map[string]emulatedNode
+	created, modified time.Time
+}
+
+// emulatedNode is a catalog node, kept as the union member the SDK sent
+type emulatedNode struct {
+	kind     string // branch, sensor, attribute, actuator, property or struct
+	dataType string
+	raw      json.RawMessage
+}
+
+// nodeCountKeys names the GetSignalCatalog node count of each node kind
+var nodeCountKeys = map[string]string{
+	"actuator":  "totalActuators",
+	"attribute": "totalAttributes",
+	"branch":    "totalBranches",
+	"property":  "totalProperties",
+	"sensor":    "totalSensors",
+	"struct":    "totalStructs",
+}
+
+type signalCatalogInput struct {
+	Name          *string           `json:"name"`
+	Description   *string           `json:"description"`
+	Nodes         []json.RawMessage `json:"nodes"`
+	NodesToAdd    []json.RawMessage `json:"nodesToAdd"`
+	NodesToUpdate []json.RawMessage `json:"nodesToUpdate"`
+	NodesToRemove []string          `json:"nodesToRemove"`
+	NextToken     *string           `json:"nextToken"`
+	MaxResults    *int32            `json:"maxResults"`
+}
+
+func invalidNode(format string, args ...interface{}) *apiError {
+	return &apiError{Code: "InvalidNodeException", Message: fmt.Sprintf(format, args...)}
+}
+
+// parseNode reads the fully qualified name of a node union, {"sensor": {"fullyQualifiedName": ...}}
+func parseNode(raw json.RawMessage) (string, emulatedNode, *apiError) {
+	var union map[string]struct {
+		FullyQualifiedName string `json:"fullyQualifiedName"`
+		DataType           string `json:"dataType"`
+	}
+	if err := json.Unmarshal(raw, &union); err != nil || len(union) != 1 {
+		return "", emulatedNode{}, invalidNode("a node must have exactly one of branch, sensor, attribute, actuator, property or struct")
+	}
+	var kind, name, dataType string
+	for k, member := range union {
+		kind, name, dataType = k, member.FullyQualifiedName, member.DataType
+	}
+	if _, ok := nodeCountKeys[kind]; !ok {
+		return "", emulatedNode{}, invalidNode("unknown node type %s", kind)
+	}
+	if name == "" {
+		return "", emulatedNode{}, invalidNode("%s node has no fullyQualifiedName", kind)
+	}
+	return name, emulatedNode{kind: kind, dataType: dataType, raw: raw}, nil
+}
+
+// checkParents requires the parent of every node to be a branch of the catalog
+func checkParents(nodes map[string]emulatedNode) *apiError {
+	for _, name := range sortedKeys(nodes) {
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			continue
+		}
+		if parent, ok := nodes[name[:i]]; !ok || parent.kind != "branch" {
+			return invalidNode("the parent of %s must be a branch of the catalog", name)
+		}
+	}
+	return nil
+}
+
+func (r *fleetWiseRegion) signalCatalog(name *string) (*emulatedSignalCatalog, *apiError) {
+	if err := requireName("name", name); err != nil {
+		return nil, err
+	}
+	sc, ok := r.catalogs[*name]
+	if !ok {
+		return nil, notFound("signal-catalog", *name)
+	}
+	return sc, nil
+}
+
+func (f *FleetWise) createSignalCatalog(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in signalCatalogInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if err := requireName("name", in.Name); err != nil {
+		return nil, err
+	}
+	if _, exists := r.catalogs[*in.Name]; exists {
+		return nil, conflict("signal catalog %s already exists", *in.Name)
+	}
+	nodes := make(map[string]emulatedNode)
+	for _, raw := range in.Nodes {
+		name, node, err := parseNode(raw)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := nodes[name]; exists {
+			return nil, invalidNode("node %s is given more than once", name)
+		}
+		nodes[name] = node
+	}
+	if err := checkParents(nodes); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sc := &emulatedSignalCatalog{
+		name:     *in.Name,
+		arn:      r.arn("signal-catalog", *in.Name),
+		nodes:    nodes,
+		created:  now,
+		modified: now,
+	}
+	if in.Description != nil {
+		sc.description = *in.Description
+	}
+	r.catalogs[sc.name] = sc
+	return map[string]string{"name": sc.name, "arn": sc.arn}, nil
+}
+
+func (f *FleetWise) getSignalCatalog(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in signalCatalogInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	sc, err := r.signalCatalog(in.Name)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{"totalNodes": len(sc.nodes)}
+	for _, key := range nodeCountKeys {
+		counts[key] = 0
+	}
+	for _, node := range sc.nodes {
+		counts[nodeCountKeys[node.kind]]++
+	}
+	return map[string]interface{}{
+		"name":                 sc.name,
+		"arn":                  sc.arn,
+		"description":          sc.description,
+		"nodeCounts":           counts,
+		"creationTime":         epochSeconds(sc.created),
+		"lastModificationTime": epochSeconds(sc.modified),
+	}, nil
+}
+
+// updateSignalCatalog applies removals, then updates, then additions, and
+// changes nothing if any of them is invalid
+func (f *FleetWise) updateSignalCatalog(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in signalCatalogInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	sc, err := r.signalCatalog(in.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]emulatedNode, len(sc.nodes))
+	for name, node := range sc.nodes {
+		nodes[name] = node
+	}
+	for _, name := range in.NodesToRemove {
+		if _, ok := nodes[name]; !ok {
+			return nil, invalidNode("node %s to remove is not in the catalog", name)
+		}
+		delete(nodes, name)
+	}
+	for _, raw := range in.NodesToUpdate {
+		name, node, err := parseNode(raw)
+		if err != nil {
+			return nil, err
+		}
+		if current, ok := nodes[name]; !ok {
+			return nil, invalidNode("node %s to update is not in the catalog", name)
+		} else if current.kind != node.kind {
+			return nil, invalidNode("node %s cannot change from %s to %s", name, current.kind, node.kind)
+		}
+		nodes[name] = node
+	}
+	for _, raw := range in.NodesToAdd {
+		name, node, err := parseNode(raw)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := nodes[name]; exists {
+			return nil, invalidNode("node %s to add is already in the catalog", name)
+		}
+		nodes[name] = node
+	}
+	if err := checkParents(nodes); err != nil {
+		return nil, err
+	}
+
+	sc.nodes = nodes
+	if in.Description != nil {
+		sc.description = *in.Description
+	}
+	sc.modified = time.Now()
+	return map[string]string{"name": sc.name, "arn": sc.arn}, nil
+}
+
+// deleteSignalCatalog succeeds for catalogs that do not exist, but refuses to
+// delete a catalog that fleets, campaigns or model manifests still use
+func (f *FleetWise) deleteSignalCatalog(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in signalCatalogInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if err := requireName("name", in.Name); err != nil {
+		return nil, err
+	}
+	name := *in.Name
+	arn := r.arn("signal-catalog", name)
+	for _, fl := range r.fleets {
+		if fl.signalCatalogArn == arn {
+			return nil, conflict("signal catalog %s is used by fleet %s", name, fl.id)
+		}
+	}
+	for _, c := range r.campaigns {
+		if c.signalCatalogArn == arn {
+			return nil, conflict("signal catalog %s is used by campaign %s", name, c.name)
+		}
+	}
+	for _, m := range r.models {
+		if m.signalCatalogArn == arn {
+			return nil, conflict("signal catalog %s is used by model manifest %s", name, m.name)
+		}
+	}
+	delete(r.catalogs, name)
+	return map[string]string{"name": name, "arn": arn}, nil
+}
+
+func (f *FleetWise) listSignalCatalogs(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in signalCatalogInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	names := sortedKeys(r.catalogs)
+	start, end, next, err := page(len(names), in.NextToken, in.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	summaries := []map[string]interface{}{}
+	for _, name := range names[start:end] {
+		sc := r.catalogs[name]
+		summaries = append(summaries, map[string]interface{}{
+			"name":                 sc.name,
+			"arn":                  sc.arn,
+			"creationTime":         epochSeconds(sc.created),
+			"lastModificationTime": epochSeconds(sc.modified),
+		})
+	}
+	return map[string]interface{}{"summaries": summaries, "nextToken": next}, nil
+}
+
+func (f *FleetWise) listSignalCatalogNodes(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in signalCatalogInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	sc, err := r.signalCatalog(in.Name)
+	if err != nil {
+		return nil, err
+	}
+	names := sortedKeys(sc.nodes)
+	start, end, next, pageErr := page(len(names), in.NextToken, in.MaxResults)
+	if pageErr != nil {
+		return nil, pageErr
+	}
+	nodes := []json.RawMessage{}
+	for _, name := range names[start:end] {
+		nodes = append(nodes, sc.nodes[name].raw)
+	}
+	return map[string]interface{}{"nodes": nodes, "nextToken": next}, nil
+}
+
+// Model manifests
+
+type emulatedModelManifest struct {
+	name              string
+	arn               string
+	description       string
+	signalCatalogArn  string
+	status            string
+	nodes             map[string]bool
+	created, modified time.Time
+}
+
+type modelManifestInput struct {
+	Name             *string  `json:"name"`
+	Description      *string  `json:"description"`
+	SignalCatalogArn *string  `json:"signalCatalogArn"`
+	Nodes            []string `json:"nodes"`
+	NodesToAdd       []string `json:"nodesToAdd"`
+	NodesToRemove    []string `json:"nodesToRemove"`
+	Status           *string  `json:"status"`
+	NextToken        *string  `json:"nextToken"`
+	MaxResults       *int32   `json:"maxResults"`
+}
+
+func (m *emulatedModelManifest) output() map[string]interface{} {
+	return map[string]interface{}{
+		"name":                 m.name,
+		"arn":                  m.arn,
+		"description":          m.description,
+		"signalCatalogArn":     m.signalCatalogArn,
+		"status":               m.status,
+		"creationTime":         epochSeconds(m.created),
+		"lastModificationTime": epochSeconds(m.modified),
+	}
+}
+
+func (r *fleetWiseRegion) modelManifest(name *string) (*emulatedModelManifest, *apiError) {
+	if err := requireName("name", name); err != nil {
+		return nil, err
+	}
+	m, ok := r.models[*name]
+	if !ok {
+		return nil, notFound("model-manifest", *name)
+	}
+	return m, nil
+}
+
+func (r *fleetWiseRegion) signalCatalogByARN(arn string) (*emulatedSignalCatalog, *apiError) {
+	for _, sc := range r.catalogs {
+		if sc.arn == arn {
+			return sc, nil
+		}
+	}
+	return nil, notFound("signal-catalog", arn)
+}
+
+func (r *fleetWiseRegion) modelManifestByARN(arn string) (*emulatedModelManifest, *apiError) {
+	for _, m := range r.models {
+		if m.arn == arn {
+			return m, nil
+		}
+	}
+	return nil, notFound("model-manifest", arn)
+}
+
+func invalidSignals(format string, args ...interface{}) *apiError {
+	return &apiError{Code: "InvalidSignalsException", Message: fmt.Sprintf(format, args...)}
+}
+
+// setManifestStatus applies a requested manifest status. Manifests can only
+// go from DRAFT to ACTIVE.
+func setManifestStatus(kind, name string, status *string, current *string) *apiError {
+	if status == nil || *status == *current {
+		return nil
+	}
+	if *current != ManifestStatusDraft || *status != ManifestStatusActive {
+		return conflict("%s %s cannot change from %s to %s", kind, name, *current, *status)
+	}
+	*current = *status
+	return nil
+}
+
+func (f *FleetWise) createModelManifest(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in modelManifestInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if err := requireName("name", in.Name); err != nil {
+		return nil, err
+	}
+	if err := requireARN("signalCatalogArn", in.SignalCatalogArn); err != nil {
+		return nil, err
+	}
+	if len(in.Nodes) == 0 {
+		return nil, validationError("nodes is required")
+	}
+	if _, exists := r.models[*in.Name]; exists {
+		return nil, conflict("model manifest %s already exists", *in.Name)
+	}
+	sc, err := r.signalCatalogByARN(*in.SignalCatalogArn)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]bool, len(in.Nodes))
+	for _, node := range in.Nodes {
+		if _, ok := sc.nodes[node]; !ok {
+			return nil, invalidSignals("node %s is not in signal catalog %s", node, sc.name)
+		}
+		nodes[node] = true
+	}
+
+	now := time.Now()
+	m := &emulatedModelManifest{
+		name:             *in.Name,
+		arn:              r.arn("model-manifest", *in.Name),
+		signalCatalogArn: sc.arn,
+		status:           ManifestStatusDraft,
+		nodes:            nodes,
+		created:          now,
+		modified:         now,
+	}
+	if in.Description != nil {
+		m.description = *in.Description
+	}
+	r.models[m.name] = m
+	return map[string]string{"name": m.name, "arn": m.arn}, nil
+}
+
+func (f *FleetWise) getModelManifest(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in modelManifestInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	m, err := r.modelManifest(in.Name)
+	if err != nil {
+		return nil, err
+	}
+	return m.output(), nil
+}
+
+// updateModelManifest edits DRAFT model manifests only, and changes nothing
+// if any edit is invalid
+func (f *FleetWise) updateModelManifest(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in modelManifestInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	m, err := r.modelManifest(in.Name)
+	if err != nil {
+		return nil, err
+	}
+	edited := in.Description != nil || len(in.NodesToAdd) > 0 || len(in.NodesToRemove) > 0
+	if edited && m.status != ManifestStatusDraft {
+		return nil, conflict("model manifest %s is %s and cannot be edited", m.name, m.status)
+	}
+
+	nodes := make(map[string]bool, len(m.nodes))
+	for node := range m.nodes {
+		nodes[node] = true
+	}
+	for _, node := range in.NodesToRemove {
+		if !nodes[node] {
+			return nil, invalidSignals("node %s to remove is not in the model manifest", node)
+		}
+		delete(nodes, node)
+	}
+	if len(in.NodesToAdd) > 0 {
+		sc, err := r.signalCatalogByARN(m.signalCatalogArn)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range in.NodesToAdd {
+			if _, ok := sc.nodes[node]; !ok {
+				return nil, invalidSignals("node %s is not in signal catalog %s", node, sc.name)
+			}
+			nodes[node] = true
+		}
+	}
+	status := m.status
+	if err := setManifestStatus("model manifest", m.name, in.Status, &status); err != nil {
+		return nil, err
+	}
+
+	m.nodes = nodes
+	m.status = status
+	if in.Description != nil {
+		m.description = *in.Description
+	}
+	m.modified = time.Now()
+	return map[string]string{"name": m.name, "arn": m.arn}, nil
+}
+
+// deleteModelManifest succeeds for manifests that do not exist, but refuses
+// to delete a manifest that decoder manifests or vehicles still use
+func (f *FleetWise) deleteModelManifest(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in modelManifestInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if err := requireName("name", in.Name); err != nil {
+		return nil, err
+	}
+	name := *in.Name
+	arn := r.arn("model-manifest", name)
+	for _, d := range r.decoders {
+		if d.modelManifestArn == arn {
+			return nil, conflict("model manifest %s is used by decoder manifest %s", name, d.name)
+		}
+	}
+	for _, v := range r.vehicles {
+		if v.modelManifestArn == arn {
+			return nil, conflict("model manifest %s is used by vehicle %s", name, v.name)
+		}
+	}
+	delete(r.models, name)
+	return map[string]string{"name": name, "arn": arn}, nil
+}
+
+func (f *FleetWise) listModelManifests(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in modelManifestInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	var matching []*emulatedModelManifest
+	for _, name := range sortedKeys(r.models) {
+		m := r.models[name]
+		if in.SignalCatalogArn != nil && *in.SignalCatalogArn != m.signalCatalogArn {
+			continue
+		}
+		matching = append(matching, m)
+	}
+	start, end, next, err := page(len(matching), in.NextToken, in.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	summaries := []map[string]interface{}{}
+	for _, m := range matching[start:end] {
+		summaries = append(summaries, m.output())
+	}
+	return map[string]interface{}{"summaries": summaries, "nextToken": next}, nil
+}
+
+// listModelManifestNodes returns the nodes as defined in the signal catalog
+func (f *FleetWise) listModelManifestNodes(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in modelManifestInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	m, err := r.modelManifest(in.Name)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := r.signalCatalogByARN(m.signalCatalogArn)
+	if err != nil {
+		return nil, err
+	}
+	names := sortedKeys(m.nodes)
+	start, end, next, pageErr := page(len(names), in.NextToken, in.MaxResults)
+	if pageErr != nil {
+		return nil, pageErr
+	}
+	nodes := []json.RawMessage{}
+	for _, name := range names[start:end] {
+		if node, ok := sc.nodes[name]; ok {
+			nodes = append(nodes, node.raw)
+		}
+	}
+	return map[string]interface{}{"nodes": nodes, "nextToken": next}, nil
+}
+
+// Decoder manifests
+
+type emulatedDecoderManifest struct {
+	name              string
+	arn               string
+	description       string
+	modelManifestArn  string
+	status            string
+	interfaces        map[string]emulatedNetworkInterface // By interface ID
+	signals           map[string]emulatedSignalDecoder    // By fully qualified name
+	created, modified time.Time
+}
+
+// emulatedNetworkInterface and emulatedSignalDecoder keep what the SDK sent
+type emulatedNetworkInterface struct {
+	kind string
+	raw  json.RawMessage
+}
+
+type emulatedSignalDecoder struct {
+	kind        string
+	interfaceID string
+	raw         json.RawMessage
+}
+
+type decoderManifestInput struct {
+	Name                      *string           `json:"name"`
+	Description               *string           `json:"description"`
+	ModelManifestArn          *string           `json:"modelManifestArn"`
+	NetworkInterfaces         []json.RawMessage `json:"networkInterfaces"`
+	SignalDecoders            []json.RawMessage `json:"signalDecoders"`
+	NetworkInterfacesToAdd    []json.RawMessage `json:"networkInterfacesToAdd"`
+	NetworkInterfacesToUpdate []json.RawMessage `json:"networkInterfacesToUpdate"`
+	NetworkInterfacesToRemove []string          `json:"networkInterfacesToRemove"`
+	SignalDecodersToAdd       []json.RawMessage `json:"signalDecodersToAdd"`
+	SignalDecodersToUpdate    []json.RawMessage `json:"signalDecodersToUpdate"`
+	SignalDecodersToRemove    []string          `json:"signalDecodersToRemove"`
+	Status                    *string           `json:"status"`
+	NextToken                 *string           `json:"nextToken"`
+	MaxResults                *int32            `json:"maxResults"`
+}
+
+// interfaceMembers and decoderMembers name the member holding the settings of
+// each network interface and signal decoder type. decoderInterfaceTypes is the
+// interface type each signal decoder type reads from.
+var (
+	interfaceMembers = map[string]string{
+		"CAN_INTERFACE":      "canInterface",
+		"OBD_INTERFACE":      "obdInterface",
+		"VEHICLE_MIDDLEWARE": "vehicleMiddleware",
+	}
+	decoderMembers = map[string]string{
+		"CAN_SIGNAL":     "canSignal",
+		"OBD_SIGNAL":     "obdSignal",
+		"MESSAGE_SIGNAL": "messageSignal",
+	}
+	decoderInterfaceTypes = map[string]string{
+		"CAN_SIGNAL":     "CAN_INTERFACE",
+		"OBD_SIGNAL":     "OBD_INTERFACE",
+		"MESSAGE_SIGNAL": "VEHICLE_MIDDLEWARE",
+	}
+)
+
+func decoderManifestInvalid(format string, args ...interface{}) *apiError {
+	return &apiError{Code: "DecoderManifestValidationException", Message: fmt.Sprintf(format, args...)}
+}
+
+func parseNetworkInterface(raw json.RawMessage) (string, emulatedNetworkInterface, *apiError) {
+	var ni map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &ni); err != nil {
+		return "", emulatedNetworkInterface{}, decoderManifestInvalid("invalid network interface: %v", err)
+	}
+	var id, kind string
+	json.Unmarshal(ni["interfaceId"], &id)
+	json.Unmarshal(ni["type"], &kind)
+	if id == "" {
+		return "", emulatedNetworkInterface{}, decoderManifestInvalid("network interface has no interfaceId")
+	}
+	member, ok := interfaceMembers[kind]
+	if !ok {
+		return "", emulatedNetworkInterface{}, decoderManifestInvalid("network interface %s has unknown type %q", id, kind)
+	}
+	if _, ok := ni[member]; !ok {
+		return "", emulatedNetworkInterface{}, decoderManifestInvalid("%s network interface %s has no %s", kind, id, member)
+	}
+	return id, emulatedNetworkInterface{kind: kind, raw: raw}, nil
+}
+
+func parseSignalDecoder(raw json.RawMessage) (string, emulatedSignalDecoder, *apiError) {
+	var d map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return "", emulatedSignalDecoder{}, decoderManifestInvalid("invalid signal decoder: %v", err)
+	}
+	var name, kind, interfaceID string
+	json.Unmarshal(d["fullyQualifiedName"], &name)
+	json.Unmarshal(d["type"], &kind)
+	json.Unmarshal(d["interfaceId"], &interfaceID)
+	if name == "" {
+		return "", emulatedSignalDecoder{}, decoderManifestInvalid("signal decoder has no fullyQualifiedName")
+	}
+	member, ok := decoderMembers[kind]
+	if !ok {
+		return "", emulatedSignalDecoder{}, decoderManifestInvalid("signal decoder %s has unknown type %q", name, kind)
+	}
+	if _, ok := d[member]; !ok {
+		return "", emulatedSignalDecoder{}, decoderManifestInvalid("%s signal decoder %s has no %s", kind, name, member)
+	}
+	return name, emulatedSignalDecoder{kind: kind, interfaceID: interfaceID, raw: raw}, nil
+}
+
+// checkSignalDecoders requires every decoder to decode a signal of the model
+// manifest from an interface of a matching type
+func checkSignalDecoders(m *emulatedModelManifest, interfaces map[string]emulatedNetworkInterface, signals map[string]emulatedSignalDecoder) *apiError {
+	for _, name := range sortedKeys(signals) {
+		d := signals[name]
+		if !m.nodes[name] {
+			return decoderManifestInvalid("signal %s is not in model manifest %s", name, m.name)
+		}
+		ni, ok := interfaces[d.interfaceID]
+		if !ok {
+			return decoderManifestInvalid("signal decoder %s reads from undefined interface %q", name, d.interfaceID)
+		}
+		if want := decoderInterfaceTypes[d.kind]; ni.kind != want {
+			return decoderManifestInvalid("%s signal decoder %s needs a %s, interface %s is %s", d.kind, name, want, d.interfaceID, ni.kind)
+		}
+	}
+	return nil
+}
+
+func (d *emulatedDecoderManifest) output() map[string]interface{} {
+	return map[string]interface{}{
+		"name":                 d.name,
+		"arn":                  d.arn,
+		"description":          d.description,
+		"modelManifestArn":     d.modelManifestArn,
+		"status":               d.status,
+		"creationTime":         epochSeconds(d.created),
+		"lastModificationTime": epochSeconds(d.modified),
+	}
+}
+
+func (r *fleetWiseRegion) decoderManifest(name *string) (*emulatedDecoderManifest, *apiError) {
+	if err := requireName("name", name); err != nil {
+		return nil, err
+	}
+	d, ok := r.decoders[*name]
+	if !ok {
+		return nil, notFound("decoder-manifest", *name)
+	}
+	return d, nil
+}
+
+func (f *FleetWise) createDecoderManifest(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in decoderManifestInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if err := requireName("name", in.Name); err != nil {
+		return nil, err
+	}
+	if err := requireARN("modelManifestArn", in.ModelManifestArn); err != nil {
+		return nil, err
+	}
+	if _, exists := r.decoders[*in.Name]; exists {
+		return nil, conflict("decoder manifest %s already exists", *in.Name)
+	}
+	m, err := r.modelManifestByARN(*in.ModelManifestArn)
+	if err != nil {
+		return nil, err
+	}
+
+	interfaces := make(map[string]emulatedNetworkInterface)
+	for _, raw := range in.NetworkInterfaces {
+		id, ni, err := parseNetworkInterface(raw)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := interfaces[id]; exists {
+			return nil, decoderManifestInvalid("network interface %s is given more than once", id)
+		}
+		interfaces[id] = ni
+	}
+	signals := make(map[string]emulatedSignalDecoder)
+	for _, raw := range in.SignalDecoders {
+		name, d, err := parseSignalDecoder(raw)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := signals[name]; exists {
+			return nil, decoderManifestInvalid("signal decoder %s is given more than once", name)
+		}
+		signals[name] = d
+	}
+	if err := checkSignalDecoders(m, interfaces, signals); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	d := &emulatedDecoderManifest{
+		name:             *in.Name,
+		arn:              r.arn("decoder-manifest", *in.Name),
+		modelManifestArn: m.arn,
+		status:           ManifestStatusDraft,
+		interfaces:       interfaces,
+		signals:          signals,
+		created:          now,
+		modified:         now,
+	}
+	if in.Description != nil {
+		d.description = *in.Description
+	}
+	r.decoders[d.name] = d
+	return map[string]string{"name": d.name, "arn": d.arn}, nil
+}
+
+func (f *FleetWise) getDecoderManifest(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in decoderManifestInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	d, err := r.decoderManifest(in.Name)
+	if err != nil {
+		return nil, err
+	}
+	return d.output(), nil
+}
+
+// updateDecoderManifest edits DRAFT decoder manifests only, and changes
+// nothing if any edit is invalid. Activation needs an ACTIVE model manifest.
+func (f *FleetWise) updateDecoderManifest(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in decoderManifestInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	d, err := r.decoderManifest(in.Name)
+	if err != nil {
+		return nil, err
+	}
+	edited := in.Description != nil || len(in.NetworkInterfacesToAdd) > 0 || len(in.NetworkInterfacesToUpdate) > 0 ||
+		len(in.NetworkInterfacesToRemove) > 0 || len(in.SignalDecodersToAdd) > 0 || len(in.SignalDecodersToUpdate) > 0 ||
+		len(in.SignalDecodersToRemove) > 0
+	if edited && d.status != ManifestStatusDraft {
+		return nil, conflict("decoder manifest %s is %s and cannot be edited", d.name, d.status)
+	}
+	m, err := r.modelManifestByARN(d.modelManifestArn)
+	if err != nil {
+		return nil, err
+	}
+
+	interfaces := make(map[string]emulatedNetworkInterface, len(d.interfaces))
+	for id, ni := range d.interfaces {
+		interfaces[id] = ni
+	}
+	signals := make(map[string]emulatedSignalDecoder, len(d.signals))
+	for name, s := range d.signals {
+		signals[name] = s
+	}
+	for _, id := range in.NetworkInterfacesToRemove {
+		if _, ok := interfaces[id]; !ok {
+			return nil, decoderManifestInvalid("network interface %s to remove is not in the decoder manifest", id)
+		}
+		delete(interfaces, id)
+	}
+	for _, name := range in.SignalDecodersToRemove {
+		if _, ok := signals[name]; !ok {
+			return nil, decoderManifestInvalid("signal decoder %s to remove is not in the decoder manifest", name)
+		}
+		delete(signals, name)
+	}
+	for i, raw := range append(in.NetworkInterfacesToUpdate, in.NetworkInterfacesToAdd...) {
+		id, ni, err := parseNetworkInterface(raw)
+		if err != nil {
+			return nil, err
+		}
+		_, exists := interfaces[id]
+		if adding := i >= len(in.NetworkInterfacesToUpdate); adding && exists {
+			return nil, decoderManifestInvalid("network interface %s to add is already in the decoder manifest", id)
+		} else if !adding && !exists {
+			return nil, decoderManifestInvalid("network interface %s to update is not in the decoder manifest", id)
+		}
+		interfaces[id] = ni
+	}
+	for i, raw := range append(in.SignalDecodersToUpdate, in.SignalDecodersToAdd...) {
+		name, s, err := parseSignalDecoder(raw)
+		if err != nil {
+			return nil, err
+		}
+		_, exists := signals[name]
+		if adding := i >= len(in.SignalDecodersToUpdate); adding && exists {
+			return nil, decoderManifestInvalid("signal decoder %s to add is already in the decoder manifest", name)
+		} else if !adding && !exists {
+			return nil, decoderManifestInvalid("signal decoder %s to update is not in the decoder manifest", name)
+		}
+		signals[name] = s
+	}
+	if err := checkSignalDecoders(m, interfaces, signals); err != nil {
+		return nil, err
+	}
+	status := d.status
+	if err := setManifestStatus("decoder manifest", d.name, in.Status, &status); err != nil {
+		return nil, err
+	}
+	if status == ManifestStatusActive && m.status != ManifestStatusActive {
+		return nil, conflict("model manifest %s must be ACTIVE before decoder manifest %s is activated", m.name, d.name)
+	}
+
+	d.interfaces = interfaces
+	d.signals = signals
+	d.status = status
+	if in.Description != nil {
+		d.description = *in.Description
+	}
+	d.modified = time.Now()
+	return map[string]string{"name": d.name, "arn": d.arn}, nil
+}
+
+// deleteDecoderManifest succeeds for manifests that do not exist, but
+// refuses to delete a manifest that vehicles still use
+func (f *FleetWise) deleteDecoderManifest(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in decoderManifestInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	if err := requireName("name", in.Name); err != nil {
+		return nil, err
+	}
+	name := *in.Name
+	arn := r.arn("decoder-manifest", name)
+	for _, v := range r.vehicles {
+		if v.decoderManifestArn == arn {
+			return nil, conflict("decoder manifest %s is used by vehicle %s", name, v.name)
+		}
+	}
+	delete(r.decoders, name)
+	return map[string]string{"name": name, "arn": arn}, nil
+}
+
+func (f *FleetWise) listDecoderManifests(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in decoderManifestInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	var matching []*emulatedDecoderManifest
+	for _, name := range sortedKeys(r.decoders) {
+		d := r.decoders[name]
+		if in.ModelManifestArn != nil && *in.ModelManifestArn != d.modelManifestArn {
+			continue
+		}
+		matching = append(matching, d)
+	}
+	start, end, next, err := page(len(matching), in.NextToken, in.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	summaries := []map[string]interface{}{}
+	for _, d := range matching[start:end] {
+		summaries = append(summaries, d.output())
+	}
+	return map[string]interface{}{"summaries": summaries, "nextToken": next}, nil
+}
+
+func (f *FleetWise) listDecoderManifestNetworkInterfaces(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in decoderManifestInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	d, err := r.decoderManifest(in.Name)
+	if err != nil {
+		return nil, err
+	}
+	ids := sortedKeys(d.interfaces)
+	start, end, next, pageErr := page(len(ids), in.NextToken, in.MaxResults)
+	if pageErr != nil {
+		return nil, pageErr
+	}
+	interfaces := []json.RawMessage{}
+	for _, id := range ids[start:end] {
+		interfaces = append(interfaces, d.interfaces[id].raw)
+	}
+	return map[string]interface{}{"networkInterfaces": interfaces, "nextToken": next}, nil
+}
+
+func (f *FleetWise) listDecoderManifestSignals(r *fleetWiseRegion, body []byte) (interface{}, error) {
+	var in decoderManifestInput
+	if err := decode(body, &in); err != nil {
+		return nil, err
+	}
+	d, err := r.decoderManifest(in.Name)
+	if err != nil {
+		return nil, err
+	}
+	names := sortedKeys(d.signals)
+	start, end, next, pageErr := page(len(names), in.NextToken, in.MaxResults)
+	if pageErr != nil {
+		return nil, pageErr
+	}
+	signals := []json.RawMessage{}
+	for _, name := range names[start:end] {
+		signals = append(signals, d.signals[name].raw)
+	}
+	return map[string]interface{}{"signalDecoders": signals, "nextToken": next}, nil
+}
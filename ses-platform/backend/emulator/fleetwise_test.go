@@ -0,0 +1,274 @@
+package emulator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise"
+	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	testModelManifest   = "arn:aws:iotfleetwise:us-east-1:123456789012:model-manifest/model"
+	testDecoderManifest = "arn:aws:iotfleetwise:us-east-1:123456789012:decoder-manifest/decoder"
+	testSignalCatalog   = "arn:aws:iotfleetwise:us-east-1:123456789012:signal-catalog/catalog"
+)
+
+// serve starts the emulator and returns its URL
+func serve(t *testing.T, f *FleetWise) string {
+	t.Helper()
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// newClient returns an SDK client of the region that talks to the emulator at url
+func newClient(url, region string) *iotfleetwise.Client {
+	return iotfleetwise.New(iotfleetwise.Options{
+		Region:       region,
+		BaseEndpoint: aws.String(url),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test"}, nil
+		}),
+		RetryMaxAttempts: 1,
+	})
+}
+
+// errorCode returns the AWS error code of err, or "" if err is not an AWS error
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
+func newVehicleInput(name string) *iotfleetwise.CreateVehicleInput {
+	return &iotfleetwise.CreateVehicleInput{
+		VehicleName:        aws.String(name),
+		ModelManifestArn:   aws.String(testModelManifest),
+		DecoderManifestArn: aws.String(testDecoderManifest),
+	}
+}
+
+func TestVehicles(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(serve(t, NewFleetWise()), "us-east-1")
+
+	created, err := client.CreateVehicle(ctx, newVehicleInput("car-1"))
+	if err != nil {
+		t.Fatalf("CreateVehicle: %v", err)
+	}
+	if want := "arn:aws:iotfleetwise:us-east-1:123456789012:vehicle/car-1"; aws.ToString(created.Arn) != want {
+		t.Errorf("Arn = %s, want %s", aws.ToString(created.Arn), want)
+	}
+	vehicle, err := client.GetVehicle(ctx, &iotfleetwise.GetVehicleInput{VehicleName: aws.String("car-1")})
+	if err != nil {
+		t.Fatalf("GetVehicle: %v", err)
+	}
+	if aws.ToString(vehicle.ModelManifestArn) != testModelManifest {
+		t.Errorf("ModelManifestArn = %s, want %s", aws.ToString(vehicle.ModelManifestArn), testModelManifest)
+	}
+
+	tests := []struct {
+		name  string
+		input *iotfleetwise.CreateVehicleInput
+		code  string
+	}{
+		{"duplicate", newVehicleInput("car-1"), "ConflictException"},
+		{"invalid name", newVehicleInput("car 1"), "ValidationException"},
+		{"manifest that is not an ARN", &iotfleetwise.CreateVehicleInput{
+			VehicleName:        aws.String("car-2"),
+			ModelManifestArn:   aws.String("model"),
+			DecoderManifestArn: aws.String(testDecoderManifest),
+		}, "ValidationException"},
+	}
+	for _, tt := range tests {
+		if _, err := client.CreateVehicle(ctx, tt.input); errorCode(err) != tt.code {
+			t.Errorf("CreateVehicle %s: error = %v, want %s", tt.name, err, tt.code)
+		}
+	}
+
+	_, err = client.GetVehicle(ctx, &iotfleetwise.GetVehicleInput{VehicleName: aws.String("car-9")})
+	var notFound *types.ResourceNotFoundException
+	if !errors.As(err, &notFound) {
+		t.Errorf("GetVehicle of a missing vehicle: error = %v, want a ResourceNotFoundException", err)
+	}
+}
+
+func TestBatchCreateVehicle(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(serve(t, NewFleetWise()), "us-east-1")
+	if _, err := client.CreateVehicle(ctx, newVehicleInput("car-1")); err != nil {
+		t.Fatalf("CreateVehicle: %v", err)
+	}
+
+	var items []types.CreateVehicleRequestItem
+	for _, name := range []string{"car-1", "car-2", "car-3"} {
+		in := newVehicleInput(name)
+		items = append(items, types.CreateVehicleRequestItem{
+			VehicleName:        in.VehicleName,
+			ModelManifestArn:   in.ModelManifestArn,
+			DecoderManifestArn: in.DecoderManifestArn,
+		})
+	}
+	out, err := client.BatchCreateVehicle(ctx, &iotfleetwise.BatchCreateVehicleInput{Vehicles: items})
+	if err != nil {
+		t.Fatalf("BatchCreateVehicle: %v", err)
+	}
+	if len(out.Vehicles) != 2 || aws.ToString(out.Vehicles[0].VehicleName) != "car-2" || aws.ToString(out.Vehicles[1].VehicleName) != "car-3" {
+		t.Errorf("created %+v, want car-2 and car-3", out.Vehicles)
+	}
+	if len(out.Errors) != 1 || aws.ToString(out.Errors[0].VehicleName) != "car-1" || aws.ToString(out.Errors[0].Code) != "409" {
+		t.Errorf("errors %+v, want a 409 for car-1", out.Errors)
+	}
+
+	items = append(items, items...)
+	items = append(items, items...)
+	if _, err := client.BatchCreateVehicle(ctx, &iotfleetwise.BatchCreateVehicleInput{Vehicles: items}); errorCode(err) != "ValidationException" {
+		t.Errorf("BatchCreateVehicle of %d vehicles: error = %v, want a ValidationException", len(items), err)
+	}
+}
+
+func TestCampaignLifecycle(t *testing.T) {
+	ctx := context.Background()
+	f := NewFleetWise()
+	f.CampaignCreationDelay = 50 * time.Millisecond
+	client := newClient(serve(t, f), "us-east-1")
+
+	vehicle, err := client.CreateVehicle(ctx, newVehicleInput("car-1"))
+	if err != nil {
+		t.Fatalf("CreateVehicle: %v", err)
+	}
+	campaign := &iotfleetwise.CreateCampaignInput{
+		Name:             aws.String("campaign-1"),
+		SignalCatalogArn: aws.String(testSignalCatalog),
+		TargetArn:        vehicle.Arn,
+		CollectionScheme: &types.CollectionSchemeMemberTimeBasedCollectionScheme{
+			Value: types.TimeBasedCollectionScheme{PeriodMs: aws.Int64(10000)},
+		},
+	}
+	if _, err := client.CreateCampaign(ctx, campaign); err != nil {
+		t.Fatalf("CreateCampaign: %v", err)
+	}
+	if _, err := client.CreateCampaign(ctx, campaign); errorCode(err) != "ConflictException" {
+		t.Errorf("CreateCampaign of an existing campaign: error = %v, want a ConflictException", err)
+	}
+	missingTarget := *campaign
+	missingTarget.Name = aws.String("campaign-2")
+	missingTarget.TargetArn = aws.String("arn:aws:iotfleetwise:us-east-1:123456789012:vehicle/car-9")
+	if _, err := client.CreateCampaign(ctx, &missingTarget); errorCode(err) != "ResourceNotFoundException" {
+		t.Errorf("CreateCampaign for a missing target: error = %v, want a ResourceNotFoundException", err)
+	}
+
+	status := func() types.CampaignStatus {
+		out, err := client.GetCampaign(ctx, &iotfleetwise.GetCampaignInput{Name: campaign.Name})
+		if err != nil {
+			t.Fatalf("GetCampaign: %v", err)
+		}
+		return out.Status
+	}
+	update := func(action types.UpdateCampaignAction) error {
+		_, err := client.UpdateCampaign(ctx, &iotfleetwise.UpdateCampaignInput{Name: campaign.Name, Action: action})
+		return err
+	}
+
+	if got := status(); got != types.CampaignStatusCreating {
+		t.Fatalf("status = %s, want CREATING", got)
+	}
+	if err := update(types.UpdateCampaignActionApprove); errorCode(err) != "ConflictException" {
+		t.Errorf("APPROVE while CREATING: error = %v, want a ConflictException", err)
+	}
+	time.Sleep(f.CampaignCreationDelay)
+	if got := status(); got != types.CampaignStatusWaitingForApproval {
+		t.Fatalf("status = %s, want WAITING_FOR_APPROVAL", got)
+	}
+
+	steps := []struct {
+		action types.UpdateCampaignAction
+		status types.CampaignStatus
+		code   string
+	}{
+		{types.UpdateCampaignActionResume, types.CampaignStatusWaitingForApproval, "ConflictException"},
+		{types.UpdateCampaignActionApprove, types.CampaignStatusRunning, ""},
+		{types.UpdateCampaignActionApprove, types.CampaignStatusRunning, "ConflictException"},
+		{types.UpdateCampaignActionSuspend, types.CampaignStatusSuspended, ""},
+		{types.UpdateCampaignActionSuspend, types.CampaignStatusSuspended, "ConflictException"},
+		{types.UpdateCampaignActionResume, types.CampaignStatusRunning, ""},
+		{types.UpdateCampaignActionUpdate, types.CampaignStatusRunning, ""},
+	}
+	for _, step := range steps {
+		if err := update(step.action); errorCode(err) != step.code {
+			t.Errorf("%s: error = %v, want %q", step.action, err, step.code)
+		}
+		if got := status(); got != step.status {
+			t.Errorf("after %s status = %s, want %s", step.action, got, step.status)
+		}
+	}
+
+	if _, err := client.DeleteCampaign(ctx, &iotfleetwise.DeleteCampaignInput{Name: campaign.Name}); err != nil {
+		t.Fatalf("DeleteCampaign: %v", err)
+	}
+	if err := update(types.UpdateCampaignActionSuspend); errorCode(err) != "ResourceNotFoundException" {
+		t.Errorf("SUSPEND of a deleted campaign: error = %v, want a ResourceNotFoundException", err)
+	}
+}
+
+func TestRegionsAreSeparate(t *testing.T) {
+	ctx := context.Background()
+	url := serve(t, NewFleetWise())
+	east, central := newClient(url, "us-east-1"), newClient(url, "eu-central-1")
+
+	created, err := central.CreateVehicle(ctx, newVehicleInput("car-1"))
+	if err != nil {
+		t.Fatalf("CreateVehicle: %v", err)
+	}
+	if want := "arn:aws:iotfleetwise:eu-central-1:123456789012:vehicle/car-1"; aws.ToString(created.Arn) != want {
+		t.Errorf("Arn = %s, want %s", aws.ToString(created.Arn), want)
+	}
+	if _, err := east.GetVehicle(ctx, &iotfleetwise.GetVehicleInput{VehicleName: aws.String("car-1")}); errorCode(err) != "ResourceNotFoundException" {
+		t.Errorf("GetVehicle in another region: error = %v, want a ResourceNotFoundException", err)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	ctx := context.Background()
+	f := NewFleetWise()
+	url := serve(t, f)
+	client := newClient(url, "us-east-1")
+
+	f.InjectFault(Fault{Operation: "CreateVehicle", Code: "ThrottlingException", Count: 1})
+	if _, err := client.CreateVehicle(ctx, newVehicleInput("car-1")); errorCode(err) != "ThrottlingException" {
+		t.Errorf("first CreateVehicle: error = %v, want a ThrottlingException", err)
+	}
+	if _, err := client.CreateVehicle(ctx, newVehicleInput("car-1")); err != nil {
+		t.Errorf("second CreateVehicle: %v", err)
+	}
+
+	// The same through the HTTP controls
+	resp, err := http.Post(url+"/_emulator/faults", "application/json",
+		bytes.NewBufferString(`{"operation": "GetVehicle", "code": "AccessDeniedException"}`))
+	if err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("POST /_emulator/faults: %v %v", resp, err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetVehicle(ctx, &iotfleetwise.GetVehicleInput{VehicleName: aws.String("car-1")}); errorCode(err) != "AccessDeniedException" {
+			t.Errorf("GetVehicle %d: error = %v, want an AccessDeniedException", i, err)
+		}
+	}
+
+	resp, err = http.Post(url+"/_emulator/reset", "application/json", nil)
+	if err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("POST /_emulator/reset: %v %v", resp, err)
+	}
+	if _, err := client.GetVehicle(ctx, &iotfleetwise.GetVehicleInput{VehicleName: aws.String("car-1")}); errorCode(err) != "ResourceNotFoundException" {
+		t.Errorf("GetVehicle after reset: error = %v, want a ResourceNotFoundException", err)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error API Handlers
+
+// listErrors returns unresolved error records, most recent first. Filter with
+// environment_id, severity and error_type; resolved=true includes resolved errors.
+func listErrors(c *gin.Context) {
+	var records []ErrorRecord
+	query := db.Order("created_at desc, id desc").Limit(100)
+	if c.Query("resolved") != "true" {
+		query = query.Where("resolved_at IS NULL")
+	}
+	if envID := c.Query("environment_id"); envID != "" {
+		query = query.Where("environment_id = ?", envID)
+	}
+	if severity := c.Query("severity"); severity != "" {
+		query = query.Where("severity = ?", severity)
+	}
+	if errorType := c.Query("error_type"); errorType != "" {
+		query = query.Where("error_type = ?", errorType)
+	}
+	if err := query.Find(&records).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, records)
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"ses-platform/spec"
+)
+
+// Error severities (spec §4.4)
+const (
+	SeverityFatal       = "fatal"       // Retrying cannot help; fail immediately
+	SeverityRecoverable = "recoverable" // Transient; retry with backoff
+	SeverityWarning     = "warning"     // Recorded, but does not fail the operation
+)
+
+// Error types recorded in error_records
+const (
+	ErrorTypeValidation        = "validation"
+	ErrorTypeProvisioning      = "provisioning"
+	ErrorTypeExecution         = "execution"
+	ErrorTypeNetwork           = "network"
+	ErrorTypeTimeout           = "timeout"
+	ErrorTypeResourceExhausted = "resource_exhausted"
+)
+
+// ErrorRecord is a persisted failure and its retry history
+type ErrorRecord struct {
+	ID               uint                   `gorm:"primaryKey" json:"id"`
+	EnvironmentID    *string                `gorm:"index" json:"environment_id,omitempty"`
+	ErrorType        string                 `json:"error_type"`
+	Severity         string                 `gorm:"index" json:"severity"`
+	ErrorCode        string                 `json:"error_code,omitempty"`
+	Message          string                 `json:"message"`
+	Context          map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"context"`
+	RetryCount       int                    `json:"retry_count"`
+	MaxRetries       int                    `json:"max_retries"`
+	RetryStrategy    string                 `json:"retry_strategy,omitempty"` // exponential_backoff, linear, fixed
+	LastRetryAt      *time.Time             `json:"last_retry_at,omitempty"`
+	ResolvedAt       *time.Time             `json:"resolved_at,omitempty"`
+	ResolutionAction string                 `json:"resolution_action,omitempty"`
+	CreatedAt        time.Time              `json:"created_at"`
+}
+
+// ClassifiedError attaches a severity, type and code to an error
+type ClassifiedError struct {
+	Severity string
+	Type     string
+	Code     string
+	Err      error
+}
+
+func (e *ClassifiedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ClassifiedError) Unwrap() error {
+	return e.Err
+}
+
+// Recoverable reports whether retrying the operation may succeed
+func (e *ClassifiedError) Recoverable() bool {
+	return e.Severity == SeverityRecoverable
+}
+
+// AWS error codes that are worth retrying, and the type they are recorded as
+var recoverableAWSCodes = map[string]string{
+	"ThrottlingException":             ErrorTypeResourceExhausted,
+	"Throttling":                      ErrorTypeResourceExhausted,
+	"TooManyRequestsException":        ErrorTypeResourceExhausted,
+	"RequestLimitExceeded":            ErrorTypeResourceExhausted,
+	"ProvisionedThroughputExceeded":   ErrorTypeResourceExhausted,
+	"InternalServerException":         ErrorTypeProvisioning,
+	"InternalFailure":                 ErrorTypeProvisioning,
+	"ServiceUnavailable":              ErrorTypeProvisioning,
+	"ServiceUnavailableException":     ErrorTypeProvisioning,
+	"RequestTimeout":                  ErrorTypeTimeout,
+	"RequestTimeoutException":         ErrorTypeTimeout,
+	"PriorRequestNotComplete":         ErrorTypeProvisioning,
+	"ConcurrentModificationException": ErrorTypeProvisioning,
+}
+
+// ClassifyError determines how an error should be handled. Errors that
+// cannot be classified are treated as fatal so they are not retried blindly.
+func ClassifyError(err error) *ClassifiedError {
+	if err == nil {
+		return nil
+	}
+
+	var classified *ClassifiedError
+	if errors.As(err, &classified) {
+		return classified
+	}
+
+	fatal := func(errType, code string) *ClassifiedError {
+		return &ClassifiedError{Severity: SeverityFatal, Type: errType, Code: code, Err: err}
+	}
+	recoverable := func(errType, code string) *ClassifiedError {
+		return &ClassifiedError{Severity: SeverityRecoverable, Type: errType, Code: code, Err: err}
+	}
+
+	var apiErr smithy.APIError
+	var pgErr *pgconn.PgError
+	var netErr net.Error
+	var validationErr *spec.ValidationError
+	var transitionErr *TransitionError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return recoverable(ErrorTypeTimeout, "")
+	case errors.Is(err, context.Canceled):
+		return fatal(ErrorTypeExecution, "")
+	case errors.As(err, &apiErr):
+		return classifyAWSError(err, apiErr)
+	case errors.As(err, &pgErr):
+		return classifyPostgresError(err, pgErr)
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return recoverable(ErrorTypeTimeout, "")
+		}
+		return recoverable(ErrorTypeNetwork, "")
+	case errors.Is(err, ErrConcurrentTransition):
+		return recoverable(ErrorTypeExecution, "")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fatal(ErrorTypeValidation, "not_found")
+	case errors.As(err, &validationErr), errors.As(err, &transitionErr):
+		return fatal(ErrorTypeValidation, "")
+	default:
+		return fatal(ErrorTypeExecution, "")
+	}
+}
+
+func classifyAWSError(err error, apiErr smithy.APIError) *ClassifiedError {
+	code := apiErr.ErrorCode()
+	if errType, ok := recoverableAWSCodes[code]; ok {
+		return &ClassifiedError{Severity: SeverityRecoverable, Type: errType, Code: code, Err: err}
+	}
+
+	switch {
+	case code == "ValidationException", strings.HasPrefix(code, "InvalidParameter"), code == "ResourceNotFoundException", code == "ConflictException":
+		return &ClassifiedError{Severity: SeverityFatal, Type: ErrorTypeValidation, Code: code, Err: err}
+	case code == "LimitExceededException", code == "ServiceQuotaExceededException":
+		// Quotas do not recover by waiting
+		return &ClassifiedError{Severity: SeverityFatal, Type: ErrorTypeResourceExhausted, Code: code, Err: err}
+	case apiErr.ErrorFault() == smithy.FaultServer:
+		return &ClassifiedError{Severity: SeverityRecoverable, Type: ErrorTypeProvisioning, Code: code, Err: err}
+	default:
+		// AccessDeniedException, UnrecognizedClientException, ExpiredTokenException, ...
+		return &ClassifiedError{Severity: SeverityFatal, Type: ErrorTypeProvisioning, Code: code, Err: err}
+	}
+}
+
+func classifyPostgresError(err error, pgErr *pgconn.PgError) *ClassifiedError {
+	code := pgErr.Code
+	switch {
+	case code == "40001", code == "40P01": // serialization_failure, deadlock_detected
+		return &ClassifiedError{Severity: SeverityRecoverable, Type: ErrorTypeExecution, Code: code, Err: err}
+	case strings.HasPrefix(code, "08"), code == "57P01", code == "57P03": // connection exceptions, shutdown
+		return &ClassifiedError{Severity: SeverityRecoverable, Type: ErrorTypeNetwork, Code: code, Err: err}
+	case strings.HasPrefix(code, "53"): // insufficient resources
+		return &ClassifiedError{Severity: SeverityRecoverable, Type: ErrorTypeResourceExhausted, Code: code, Err: err}
+	case code == "57014": // query_canceled, e.g. statement timeout
+		return &ClassifiedError{Severity: SeverityRecoverable, Type: ErrorTypeTimeout, Code: code, Err: err}
+	default: // integrity violations, data exceptions, syntax and access errors
+		return &ClassifiedError{Severity: SeverityFatal, Type: ErrorTypeValidation, Code: code, Err: err}
+	}
+}
+
+// RetryPolicy bounds retries of recoverable errors
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var defaultRetryPolicy = RetryPolicy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+
+// Backoff returns the delay before the given retry (starting at 1): exponential
+// growth capped at MaxDelay, with jitter so concurrent retries spread out
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	delay := p.BaseDelay << (retry - 1)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// ErrorScope identifies where an error happened when it is recorded
+type ErrorScope struct {
+	EnvironmentID string
+	Operation     string
+	Context       map[string]interface{}
+}
+
+// withRetry runs fn, retrying recoverable errors with backoff. Every failed
+// attempt is recorded in a single error record, which is resolved if a later
+// attempt succeeds. The returned error is classified.
+func withRetry(ctx context.Context, scope ErrorScope, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	var record *ErrorRecord
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			if record != nil {
+				resolveError(record, fmt.Sprintf("succeeded after %d retries", attempt))
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err // Cancelled by the caller, not a failure of the operation
+		}
+
+		classified := ClassifyError(err)
+		if record == nil {
+			record = recordError(scope, classified, policy.MaxRetries)
+		} else {
+			recordRetry(record, classified, attempt)
+		}
+
+		if !classified.Recoverable() || attempt >= policy.MaxRetries {
+			return classified
+		}
+
+		delay := policy.Backoff(attempt + 1)
+		log.Printf("%s failed with %s %s error, retrying in %s: %v", scope.Operation, classified.Severity, classified.Type, delay.Round(time.Millisecond), err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return classified
+		}
+	}
+}
+
+// recordError persists the first failure of an operation
+func recordError(scope ErrorScope, classified *ClassifiedError, maxRetries int) *ErrorRecord {
+	errorContext := map[string]interface{}{"operation": scope.Operation}
+	for k, v := range scope.Context {
+		errorContext[k] = v
+	}
+	record := ErrorRecord{
+		ErrorType:  classified.Type,
+		Severity:   classified.Severity,
+		ErrorCode:  classified.Code,
+		Message:    classified.Error(),
+		Context:    errorContext,
+		MaxRetries: maxRetries,
+		CreatedAt:  time.Now(),
+	}
+	if scope.EnvironmentID != "" {
+		record.EnvironmentID = &scope.EnvironmentID
+	}
+	if classified.Recoverable() {
+		record.RetryStrategy = "exponential_backoff"
+	}
+	if err := db.Create(&record).Error; err != nil {
+		log.Printf("Error recording %s error: %v", classified.Type, err)
+	}
+	return &record
+}
+
+// recordRetry updates a record after a retry failed again
+func recordRetry(record *ErrorRecord, classified *ClassifiedError, retryCount int) {
+	now := time.Now()
+	record.RetryCount = retryCount
+	record.LastRetryAt = &now
+	record.Severity = classified.Severity
+	record.ErrorType = classified.Type
+	record.ErrorCode = classified.Code
+	record.Message = classified.Error()
+	db.Model(&ErrorRecord{}).Where("id = ?", record.ID).Updates(map[string]interface{}{
+		"retry_count":   retryCount,
+		"last_retry_at": now,
+		"severity":      record.Severity,
+		"error_type":    record.ErrorType,
+		"error_code":    record.ErrorCode,
+		"message":       record.Message,
+	})
+}
+
+func resolveError(record *ErrorRecord, action string) {
+	now := time.Now()
+	record.ResolvedAt = &now
+	record.ResolutionAction = action
+	db.Model(&ErrorRecord{}).Where("id = ?", record.ID).Updates(map[string]interface{}{
+		"resolved_at":       now,
+		"resolution_action": action,
+	})
+}
+
+// retryingRunner retries recoverable step errors and records every failure
+type retryingRunner struct {
+	StepRunner
+	envID  string
+	policy RetryPolicy
+}
+
+func (r *retryingRunner) scope(operation string, step PlanStep) ErrorScope {
+	return ErrorScope{
+		EnvironmentID: r.envID,
+		Operation:     fmt.Sprintf("%s %s", operation, step.ID),
+		Context:       map[string]interface{}{"step": step.ID, "action": step.Action, "provider": step.Provider},
+	}
+}
+
+func (r *retryingRunner) RunStep(ctx context.Context, step PlanStep) error {
+	return withRetry(ctx, r.scope("run", step), r.policy, func(ctx context.Context) error {
+		return r.StepRunner.RunStep(ctx, step)
+	})
+}
+
+func (r *retryingRunner) CheckHealth(ctx context.Context, step PlanStep) error {
+	return withRetry(ctx, r.scope("health check", step), r.policy, func(ctx context.Context) error {
+		return r.StepRunner.CheckHealth(ctx, step)
+	})
+}
+
+func (r *retryingRunner) RollbackStep(ctx context.Context, step PlanStep) error {
+	return withRetry(ctx, r.scope("rollback", step), r.policy, func(ctx context.Context) error {
+		return r.StepRunner.RollbackStep(ctx, step)
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"ses-platform/spec"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		severity string
+		errType  string
+		code     string
+	}{
+		{name: "deadline", err: fmt.Errorf("deploy: %w", context.DeadlineExceeded), severity: SeverityRecoverable, errType: ErrorTypeTimeout},
+		{name: "cancelled", err: context.Canceled, severity: SeverityFatal, errType: ErrorTypeExecution},
+		{name: "AWS throttling", err: &smithy.GenericAPIError{Code: "ThrottlingException"}, severity: SeverityRecoverable, errType: ErrorTypeResourceExhausted, code: "ThrottlingException"},
+		{name: "AWS invalid parameter", err: &smithy.GenericAPIError{Code: "InvalidParameterValue"}, severity: SeverityFatal, errType: ErrorTypeValidation, code: "InvalidParameterValue"},
+		{name: "AWS quota", err: &smithy.GenericAPIError{Code: "LimitExceededException"}, severity: SeverityFatal, errType: ErrorTypeResourceExhausted, code: "LimitExceededException"},
+		{name: "AWS server fault", err: &smithy.GenericAPIError{Code: "SomethingBroke", Fault: smithy.FaultServer}, severity: SeverityRecoverable, errType: ErrorTypeProvisioning, code: "SomethingBroke"},
+		{name: "AWS access denied", err: &smithy.GenericAPIError{Code: "AccessDeniedException", Fault: smithy.FaultClient}, severity: SeverityFatal, errType: ErrorTypeProvisioning, code: "AccessDeniedException"},
+		{name: "Postgres deadlock", err: &pgconn.PgError{Code: "40P01"}, severity: SeverityRecoverable, errType: ErrorTypeExecution, code: "40P01"},
+		{name: "Postgres connection", err: &pgconn.PgError{Code: "08006"}, severity: SeverityRecoverable, errType: ErrorTypeNetwork, code: "08006"},
+		{name: "Postgres disk full", err: &pgconn.PgError{Code: "53100"}, severity: SeverityRecoverable, errType: ErrorTypeResourceExhausted, code: "53100"},
+		{name: "Postgres statement timeout", err: &pgconn.PgError{Code: "57014"}, severity: SeverityRecoverable, errType: ErrorTypeTimeout, code: "57014"},
+		{name: "Postgres unique violation", err: &pgconn.PgError{Code: "23505"}, severity: SeverityFatal, errType: ErrorTypeValidation, code: "23505"},
+		{name: "network timeout", err: &net.DNSError{IsTimeout: true}, severity: SeverityRecoverable, errType: ErrorTypeTimeout},
+		{name: "network", err: &net.DNSError{}, severity: SeverityRecoverable, errType: ErrorTypeNetwork},
+		{name: "concurrent transition", err: ErrConcurrentTransition, severity: SeverityRecoverable, errType: ErrorTypeExecution},
+		{name: "not found", err: gorm.ErrRecordNotFound, severity: SeverityFatal, errType: ErrorTypeValidation, code: "not_found"},
+		{name: "invalid spec", err: &spec.ValidationError{Errors: []string{"bad"}}, severity: SeverityFatal, errType: ErrorTypeValidation},
+		{name: "invalid transition", err: &TransitionError{From: StateCleaned, To: StateRunning}, severity: SeverityFatal, errType: ErrorTypeValidation},
+		{name: "unknown", err: errors.New("boom"), severity: SeverityFatal, errType: ErrorTypeExecution},
+		{name: "already classified", err: fmt.Errorf("step: %w", &ClassifiedError{Severity: SeverityWarning, Type: ErrorTypeNetwork, Err: errors.New("slow")}), severity: SeverityWarning, errType: ErrorTypeNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classified := ClassifyError(tt.err)
+			if classified.Severity != tt.severity || classified.Type != tt.errType || classified.Code != tt.code {
+				t.Errorf("ClassifyError = %s %s %q, want %s %s %q", classified.Severity, classified.Type, classified.Code, tt.severity, tt.errType, tt.code)
+			}
+			if !errors.Is(classified, tt.err) && !errors.Is(tt.err, classified) {
+				t.Errorf("ClassifyError does not wrap %v", tt.err)
+			}
+		})
+	}
+
+	if classified := ClassifyError(nil); classified != nil {
+		t.Errorf("ClassifyError(nil) = %v, want nil", classified)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 10, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+	tests := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{retry: 1, max: time.Second},
+		{retry: 2, max: 2 * time.Second},
+		{retry: 3, max: 4 * time.Second},
+		{retry: 6, max: 30 * time.Second},
+		{retry: 80, max: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.retry), func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				if delay := policy.Backoff(tt.retry); delay < tt.max/2 || delay > tt.max {
+					t.Fatalf("Backoff(%d) = %s, want between %s and %s", tt.retry, delay, tt.max/2, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException"}
+	tests := []struct {
+		name       string
+		errs       []error // Errors of successive attempts; later attempts succeed
+		calls      int
+		err        string
+		statements []string // Substrings of the statements run, in order
+	}{
+		{
+			name:  "succeeds",
+			calls: 1,
+		},
+		{
+			name:       "recovers after retries",
+			errs:       []error{throttled, throttled},
+			calls:      3,
+			statements: []string{`INSERT INTO "error_records"`, `"retry_count"=1`, `"resolution_action"='succeeded after 2 retries'`},
+		},
+		{
+			name:       "fatal errors are not retried",
+			errs:       []error{errors.New("boom")},
+			calls:      1,
+			err:        "boom",
+			statements: []string{`INSERT INTO "error_records"`},
+		},
+		{
+			name:       "gives up after the last retry",
+			errs:       []error{throttled, throttled, throttled},
+			calls:      3,
+			err:        "ThrottlingException",
+			statements: []string{`INSERT INTO "error_records"`, `"retry_count"=1`, `"retry_count"=2`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements := useDryRunDB(t)
+			policy := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+			calls := 0
+			err := withRetry(context.Background(), ErrorScope{EnvironmentID: "env-1", Operation: "run network"}, policy, func(ctx context.Context) error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if calls != tt.calls {
+				t.Errorf("calls = %d, want %d", calls, tt.calls)
+			}
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || !containsAll(err.Error(), tt.err)) {
+				t.Errorf("withRetry = %v, want %q", err, tt.err)
+			}
+			if err != nil {
+				var classified *ClassifiedError
+				if !errors.As(err, &classified) {
+					t.Errorf("withRetry = %T, want a classified error", err)
+				}
+			}
+			if len(*statements) != len(tt.statements) {
+				t.Fatalf("withRetry ran %q, want %q", *statements, tt.statements)
+			}
+			for i, want := range tt.statements {
+				if !containsAll((*statements)[i], want) {
+					t.Errorf("statement %d = %s, want %s", i, (*statements)[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// defaultMaxWorkers bounds the number of steps running at once
+const defaultMaxWorkers = 8
+
+// StepRunner performs plan steps, usually through a ProviderAdapter
+type StepRunner interface {
+	RunStep(ctx context.Context, step PlanStep) error
+	CheckHealth(ctx context.Context, step PlanStep) error
+	// RollbackStep performs the step's RollbackAction to undo it
+	RollbackStep(ctx context.Context, step PlanStep) error
+}
+
+// StepError records the step that failed during execution
+type StepError struct {
+	StepID string
+	Err    error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %s failed: %v", e.StepID, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// Executor runs the steps of an execution plan with a provisioning strategy (§5.3)
+type Executor struct {
+	Strategy   string
+	MaxWorkers int
+	Runner     StepRunner
+	// OnStep is called once per finished step; calls are serialized
+	OnStep func(step PlanStep, completed, total int, err error)
+
+	mu        sync.Mutex
+	completed int
+}
+
+// Execute runs every step of the plan and returns the aggregated step errors
+func (e *Executor) Execute(ctx context.Context, plan *ExecutionPlan) error {
+	workers := e.MaxWorkers
+	if workers <= 0 {
+		workers = defaultMaxWorkers
+	}
+
+	switch e.Strategy {
+	case "sequential":
+		return e.runSequential(ctx, plan.Steps)
+	case "parallel":
+		return e.runParallel(ctx, plan.Steps, workers)
+	case "", "optimized":
+		return e.runGraph(ctx, plan.Steps, workers)
+	default:
+		return fmt.Errorf("unknown provisioning strategy %q", e.Strategy)
+	}
+}
+
+func (e *Executor) report(step PlanStep, total int, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err == nil {
+		e.completed++
+	}
+	if e.OnStep != nil {
+		e.OnStep(step, e.completed, total, err)
+	}
+}
+
+// runSequential provisions steps one at a time in dependency order, waits for
+// each step to pass its health check before moving on, and stops at the first failure
+func (e *Executor) runSequential(ctx context.Context, steps []PlanStep) error {
+	for _, step := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := e.runStep(ctx, step)
+		if err == nil && step.Action != "health_check" {
+			err = e.Runner.CheckHealth(ctx, step)
+		}
+		e.report(step, len(steps), err)
+		if err != nil {
+			return &StepError{StepID: step.ID, Err: err}
+		}
+	}
+	return nil
+}
+
+func (e *Executor) runStep(ctx context.Context, step PlanStep) error {
+	if step.Action == "health_check" {
+		return e.Runner.CheckHealth(ctx, step)
+	}
+	return e.Runner.RunStep(ctx, step)
+}
+
+// runParallel provisions the infrastructure, then every component at once,
+// then the health checks, each phase on a bounded worker pool, and aggregates
+// the errors. Components do not wait for each other (spec validation rejects
+// parallel provisioning of dependent components), but they need the networks
+// and storage they use, so steps are skipped when a step they depend on failed.
+func (e *Executor) runParallel(ctx context.Context, steps []PlanStep, workers int) error {
+	var infrastructure, components, checks []PlanStep
+	for _, step := range steps {
+		switch step.Action {
+		case "health_check":
+			checks = append(checks, step)
+		case "deploy_component":
+			components = append(components, step)
+		default:
+			infrastructure = append(infrastructure, step)
+		}
+	}
+
+	failed := map[string]error{}
+	skipped := map[string]bool{}
+	for _, phase := range [][]PlanStep{infrastructure, components, checks} {
+		var ready []PlanStep
+		for _, step := range phase {
+			if slices.ContainsFunc(step.DependsOn, func(id string) bool { return failed[id] != nil || skipped[id] }) {
+				skipped[step.ID] = true
+			} else {
+				ready = append(ready, step)
+			}
+		}
+		maps.Copy(failed, e.runPool(ctx, ready, workers, len(steps)))
+	}
+
+	var errs []error
+	for _, step := range steps {
+		if err := failed[step.ID]; err != nil {
+			errs = append(errs, &StepError{StepID: step.ID, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runPool runs steps on at most workers goroutines in no particular order and
+// returns the errors of the steps that failed by step ID
+func (e *Executor) runPool(ctx context.Context, steps []PlanStep, workers, total int) map[string]error {
+	failed := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, workers)
+	for _, step := range steps {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			err := e.runStep(ctx, step)
+			e.report(step, total, err)
+			if err != nil {
+				mu.Lock()
+				failed[step.ID] = err
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return failed
+}
+
+// runGraph runs every step whose dependencies have completed on a bounded
+// worker pool. Ready steps on the longest remaining path are started first.
+// The first failure cancels running steps and nothing new is started.
+func (e *Executor) runGraph(ctx context.Context, steps []PlanStep, workers int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	byID := make(map[string]PlanStep, len(steps))
+	pending := make(map[string]int, len(steps))
+	dependents := make(map[string][]string)
+	var ready []string
+	for _, step := range steps {
+		byID[step.ID] = step
+		pending[step.ID] = len(step.DependsOn)
+		for _, dep := range step.DependsOn {
+			dependents[dep] = append(dependents[dep], step.ID)
+		}
+		if len(step.DependsOn) == 0 {
+			ready = append(ready, step.ID)
+		}
+	}
+	priority := remainingPath(steps, dependents)
+
+	type result struct {
+		step PlanStep
+		err  error
+	}
+	results := make(chan result)
+	running := 0
+	stopped := false
+	var errs []error
+
+	for {
+		sort.SliceStable(ready, func(i, j int) bool { return priority[ready[i]] > priority[ready[j]] })
+		for !stopped && running < workers && len(ready) > 0 {
+			step := byID[ready[0]]
+			ready = ready[1:]
+			running++
+			go func() {
+				results <- result{step: step, err: e.runStep(ctx, step)}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+
+		r := <-results
+		running--
+		e.report(r.step, len(steps), r.err)
+		if r.err != nil {
+			errs = append(errs, &StepError{StepID: r.step.ID, Err: r.err})
+			if !stopped {
+				stopped = true
+				cancel()
+			}
+			continue
+		}
+		for _, id := range dependents[r.step.ID] {
+			pending[id]--
+			if pending[id] == 0 {
+				ready = append(ready, id)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// remainingPath returns, for every step, the estimated duration from the start
+// of the step to the end of the plan along its longest chain of dependents
+func remainingPath(steps []PlanStep, dependents map[string][]string) map[string]int {
+	durations := make(map[string]int, len(steps))
+	for _, step := range steps {
+		durations[step.ID] = step.EstimatedDurationSeconds
+	}
+	remaining := make(map[string]int, len(steps))
+	// Plan steps are stored in dependency order, so walk them backwards
+	for i := len(steps) - 1; i >= 0; i-- {
+		id := steps[i].ID
+		longest := 0
+		for _, dep := range dependents[id] {
+			if remaining[dep] > longest {
+				longest = remaining[dep]
+			}
+		}
+		remaining[id] = durations[id] + longest
+	}
+	return remaining
+}
+
+// withoutCompletedSteps returns a copy of the plan without the completed steps.
+// Dependencies on completed steps are dropped so their dependents are ready.
+func withoutCompletedSteps(plan *ExecutionPlan, completed []string) *ExecutionPlan {
+	if len(completed) == 0 {
+		return plan
+	}
+	done := make(map[string]bool, len(completed))
+	for _, id := range completed {
+		done[id] = true
+	}
+
+	remaining := *plan
+	remaining.Steps = nil
+	for _, step := range plan.Steps {
+		if done[step.ID] {
+			continue
+		}
+		var deps []string
+		for _, dep := range step.DependsOn {
+			if !done[dep] {
+				deps = append(deps, dep)
+			}
+		}
+		step.DependsOn = deps
+		remaining.Steps = append(remaining.Steps, step)
+	}
+	return &remaining
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRunner records the steps it runs and fails the steps in fail
+type fakeRunner struct {
+	fail  map[string]bool
+	delay time.Duration
+
+	mu         sync.Mutex
+	events     []string // start:<id> and end:<id> in the order they happened
+	checked    []string
+	running    int
+	maxRunning int
+}
+
+func (r *fakeRunner) RunStep(ctx context.Context, step PlanStep) error {
+	r.mu.Lock()
+	r.events = append(r.events, "start:"+step.ID)
+	r.running++
+	r.maxRunning = max(r.maxRunning, r.running)
+	r.mu.Unlock()
+
+	time.Sleep(r.delay)
+
+	r.mu.Lock()
+	r.events = append(r.events, "end:"+step.ID)
+	r.running--
+	r.mu.Unlock()
+	if r.fail[step.ID] {
+		return fmt.Errorf("%s failed", step.ID)
+	}
+	return nil
+}
+
+func (r *fakeRunner) CheckHealth(ctx context.Context, step PlanStep) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checked = append(r.checked, step.ID)
+	if r.fail[step.ID] {
+		return fmt.Errorf("%s is unhealthy", step.ID)
+	}
+	return nil
+}
+
+func (r *fakeRunner) RollbackStep(ctx context.Context, step PlanStep) error {
+	return nil
+}
+
+// ran returns the sorted IDs of the steps that were started
+func (r *fakeRunner) ran() []string {
+	var ids []string
+	for _, event := range r.events {
+		if id, ok := strings.CutPrefix(event, "start:"); ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// ordered reports whether every step started after its dependencies ended
+func (r *fakeRunner) ordered(steps []PlanStep) bool {
+	for _, step := range steps {
+		start := slices.Index(r.events, "start:"+step.ID)
+		for _, dep := range step.DependsOn {
+			if start >= 0 && slices.Index(r.events, "end:"+dep) > start {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// stepErrorIDs returns the IDs of the failed steps in err
+func stepErrorIDs(err error) []string {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	var ids []string
+	for _, err := range errs {
+		var stepErr *StepError
+		if errors.As(err, &stepErr) {
+			ids = append(ids, stepErr.StepID)
+		}
+	}
+	return ids
+}
+
+func TestExecutorStrategies(t *testing.T) {
+	// net <- a <- c, net <- b, and a health check of a
+	plan := &ExecutionPlan{Steps: []PlanStep{
+		{ID: "net", Action: "create_network"},
+		{ID: "a", Action: "deploy_component", DependsOn: []string{"net"}},
+		{ID: "health:a", Action: "health_check", DependsOn: []string{"a"}},
+		{ID: "b", Action: "deploy_component", DependsOn: []string{"net"}},
+		{ID: "c", Action: "deploy_component", DependsOn: []string{"a"}},
+	}}
+
+	tests := []struct {
+		name       string
+		strategy   string
+		workers    int
+		fail       []string
+		ran        []string
+		checked    []string
+		errs       []string
+		ordered    bool
+		concurrent int
+		completed  int
+	}{
+		{
+			name:       "sequential",
+			strategy:   "sequential",
+			ran:        []string{"a", "b", "c", "net"},
+			checked:    []string{"net", "a", "health:a", "b", "c"},
+			ordered:    true,
+			concurrent: 1,
+			completed:  5,
+		},
+		{
+			name:       "sequential stops at the first failure",
+			strategy:   "sequential",
+			fail:       []string{"a"},
+			ran:        []string{"a", "net"},
+			checked:    []string{"net"},
+			errs:       []string{"a"},
+			ordered:    true,
+			concurrent: 1,
+			completed:  1,
+		},
+		{
+			name:       "optimized",
+			strategy:   "optimized",
+			ran:        []string{"a", "b", "c", "net"},
+			checked:    []string{"health:a"},
+			ordered:    true,
+			concurrent: 2,
+			completed:  5,
+		},
+		{
+			name:       "optimized is the default",
+			ran:        []string{"a", "b", "c", "net"},
+			checked:    []string{"health:a"},
+			ordered:    true,
+			concurrent: 2,
+			completed:  5,
+		},
+		{
+			name:       "optimized skips dependents of a failure",
+			strategy:   "optimized",
+			fail:       []string{"net"},
+			ran:        []string{"net"},
+			errs:       []string{"net"},
+			ordered:    true,
+			concurrent: 1,
+			completed:  0,
+		},
+		{
+			name:       "parallel",
+			strategy:   "parallel",
+			ran:        []string{"a", "b", "c", "net"},
+			checked:    []string{"health:a"},
+			concurrent: 3,
+			completed:  5,
+		},
+		{
+			name:       "parallel aggregates failures",
+			strategy:   "parallel",
+			fail:       []string{"a", "b"},
+			ran:        []string{"a", "b", "c", "net"},
+			errs:       []string{"a", "b"},
+			concurrent: 3,
+			completed:  2,
+		},
+		{
+			name:       "parallel skips components of failed infrastructure",
+			strategy:   "parallel",
+			fail:       []string{"net"},
+			ran:        []string{"net"},
+			errs:       []string{"net"},
+			ordered:    true,
+			concurrent: 1,
+			completed:  0,
+		},
+		{
+			name:       "parallel is bounded by the workers",
+			strategy:   "parallel",
+			workers:    1,
+			ran:        []string{"a", "b", "c", "net"},
+			checked:    []string{"health:a"},
+			ordered:    true,
+			concurrent: 1,
+			completed:  5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &fakeRunner{fail: make(map[string]bool), delay: 20 * time.Millisecond}
+			for _, id := range tt.fail {
+				runner.fail[id] = true
+			}
+			completed := 0
+			executor := &Executor{
+				Strategy:   tt.strategy,
+				MaxWorkers: tt.workers,
+				Runner:     runner,
+				OnStep: func(step PlanStep, done, total int, err error) {
+					completed = done
+					if total != len(plan.Steps) {
+						t.Errorf("OnStep total = %d, want %d", total, len(plan.Steps))
+					}
+				},
+			}
+
+			err := executor.Execute(context.Background(), plan)
+			if got := stepErrorIDs(err); !reflect.DeepEqual(got, tt.errs) {
+				t.Errorf("failed steps = %v, want %v (%v)", got, tt.errs, err)
+			}
+			if got := runner.ran(); !reflect.DeepEqual(got, tt.ran) {
+				t.Errorf("ran %v, want %v", got, tt.ran)
+			}
+			if !reflect.DeepEqual(runner.checked, tt.checked) {
+				t.Errorf("checked %v, want %v", runner.checked, tt.checked)
+			}
+			if got := runner.ordered(plan.Steps); got != tt.ordered {
+				t.Errorf("dependency order kept = %t, want %t (%v)", got, tt.ordered, runner.events)
+			}
+			if runner.maxRunning != tt.concurrent {
+				t.Errorf("%d steps ran at once, want %d", runner.maxRunning, tt.concurrent)
+			}
+			if completed != tt.completed {
+				t.Errorf("completed = %d, want %d", completed, tt.completed)
+			}
+		})
+	}
+}
+
+func TestExecutorUnknownStrategy(t *testing.T) {
+	executor := &Executor{Strategy: "eventually", Runner: &fakeRunner{}}
+	if err := executor.Execute(context.Background(), &ExecutionPlan{}); err == nil {
+		t.Error("Execute with an unknown strategy succeeded, want an error")
+	}
+}
+
+func TestWithoutCompletedSteps(t *testing.T) {
+	plan := &ExecutionPlan{Steps: []PlanStep{
+		{ID: "net"},
+		{ID: "a", DependsOn: []string{"net"}},
+		{ID: "c", DependsOn: []string{"a", "net"}},
+	}}
+	remaining := withoutCompletedSteps(plan, []string{"net"})
+	want := []PlanStep{
+		{ID: "a"},
+		{ID: "c", DependsOn: []string{"a"}},
+	}
+	if !reflect.DeepEqual(remaining.Steps, want) {
+		t.Errorf("withoutCompletedSteps = %+v, want %+v", remaining.Steps, want)
+	}
+	if len(plan.Steps) != 3 {
+		t.Errorf("withoutCompletedSteps modified the plan")
+	}
+}
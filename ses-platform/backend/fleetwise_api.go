@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise"
+	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise/types"
+)
+
+// FleetWiseAPI is the set of IoT FleetWise operations the platform uses.
+// AWSFleetWiseClient implements it against AWS; tests can substitute a fake.
+type FleetWiseAPI interface {
+	CreateVehicle(ctx context.Context, vehicleConfig VehicleConfig) (*iotfleetwise.CreateVehicleOutput, error)
+	BatchCreateVehicles(ctx context.Context, vehicles []VehicleConfig) ([]types.CreateVehicleResponseItem, []error)
+	GetVehicle(ctx context.Context, vehicleName string) (*iotfleetwise.GetVehicleOutput, error)
+	UpdateVehicle(ctx context.Context, vehicleName string, updates VehicleConfig) error
+	DeleteVehicle(ctx context.Context, vehicleName string) error
+	ListVehicles(ctx context.Context, modelManifestARN string) ([]types.VehicleSummary, error)
+	ListVehiclesPage(ctx context.Context, modelManifestARN, nextToken string, maxResults int32) ([]types.VehicleSummary, string, error)
+	GetVehicleStatus(ctx context.Context, vehicleName string) (*iotfleetwise.GetVehicleStatusOutput, error)
+
+	CreateCampaign(ctx context.Context, campaignConfig CampaignConfig) (*iotfleetwise.CreateCampaignOutput, error)
+	GetCampaign(ctx context.Context, campaignName string) (*iotfleetwise.GetCampaignOutput, error)
+	UpdateCampaign(ctx context.Context, campaignName string, action string) error
+	DeleteCampaign(ctx context.Context, campaignName string) error
+	ListCampaigns(ctx context.Context, status string) ([]types.CampaignSummary, error)
+	ListCampaignsPage(ctx context.Context, status, nextToken string, maxResults int32) ([]types.CampaignSummary, string, error)
+
+	CreateFleet(ctx context.Context, fleetID, description, signalCatalogARN string) (*iotfleetwise.CreateFleetOutput, error)
+	DeleteFleet(ctx context.Context, fleetID string) error
+	AssociateVehicleToFleet(ctx context.Context, vehicleName, fleetID string) error
+	DisassociateVehicleFromFleet(ctx context.Context, vehicleName, fleetID string) error
+	AssociateVehiclesToFleet(ctx context.Context, vehicleNames []string, fleetID string) ([]string, []error)
+	GetFleet(ctx context.Context, fleetID string) (*iotfleetwise.GetFleetOutput, error)
+	ListFleets(ctx context.Context) ([]types.FleetSummary, error)
+	ListFleetsPage(ctx context.Context, nextToken string, maxResults int32) ([]types.FleetSummary, string, error)
+	ListVehiclesInFleet(ctx context.Context, fleetID string) ([]string, error)
+	ListFleetsForVehicle(ctx context.Context, vehicleName string) ([]string, error)
+
+	CreateSignalCatalog(ctx context.Context, name, description string, nodes []SignalNode) (*iotfleetwise.CreateSignalCatalogOutput, error)
+	GetSignalCatalog(ctx context.Context, name string) (*iotfleetwise.GetSignalCatalogOutput, error)
+	ListSignalCatalogNodes(ctx context.Context, name string) ([]SignalNode, error)
+	UpdateSignalCatalog(ctx context.Context, name, description string, changes SignalCatalogChanges) error
+	DeleteSignalCatalog(ctx context.Context, name string) error
+	ListSignalCatalogs(ctx context.Context, maxResults int32) ([]types.SignalCatalogSummary, error)
+
+	CreateModelManifest(ctx context.Context, name, description, signalCatalogARN string, nodes []string) (*iotfleetwise.CreateModelManifestOutput, error)
+	GetModelManifest(ctx context.Context, name string) (*iotfleetwise.GetModelManifestOutput, error)
+	ListModelManifestNodes(ctx context.Context, name string) ([]SignalNode, error)
+	UpdateModelManifest(ctx context.Context, name, description string, nodesToAdd, nodesToRemove []string, status types.ManifestStatus) error
+	DeleteModelManifest(ctx context.Context, name string) error
+	ListModelManifests(ctx context.Context, signalCatalogARN string, maxResults int32) ([]types.ModelManifestSummary, error)
+
+	CreateDecoderManifest(ctx context.Context, name, description, modelManifestARN string, interfaces []NetworkInterfaceConfig, decoders []SignalDecoderConfig) (*iotfleetwise.CreateDecoderManifestOutput, error)
+	GetDecoderManifest(ctx context.Context, name string) (*iotfleetwise.GetDecoderManifestOutput, error)
+	ListDecoderManifestNetworkInterfaces(ctx context.Context, name string) ([]NetworkInterfaceConfig, error)
+	ListDecoderManifestSignalDecoders(ctx context.Context, name string) ([]SignalDecoderConfig, error)
+	UpdateDecoderManifest(ctx context.Context, name, description string, changes DecoderManifestChanges, status types.ManifestStatus) error
+	DeleteDecoderManifest(ctx context.Context, name string) error
+	ListDecoderManifests(ctx context.Context, modelManifestARN string, maxResults int32) ([]types.DecoderManifestSummary, error)
+}
+
+var _ FleetWiseAPI = (*AWSFleetWiseClient)(nil)
+
+// defaultFleetWiseRegion is used when a request does not name a region
+const defaultFleetWiseRegion = "us-east-1"
+
+// fleetWiseRegions are the regions AWS IoT FleetWise is available in
+var fleetWiseRegions = []string{"us-east-1", "eu-central-1", "ap-south-1"}
+
+// ErrUnsupportedRegion is returned for regions FleetWise is not available in
+var ErrUnsupportedRegion = errors.New("FleetWise is not available in region")
+
+// checkFleetWiseRegion returns ErrUnsupportedRegion unless region is one of fleetWiseRegions
+func checkFleetWiseRegion(region string) error {
+	if !slices.Contains(fleetWiseRegions, region) {
+		return fmt.Errorf("%w %q; supported regions are %s", ErrUnsupportedRegion, region, strings.Join(fleetWiseRegions, ", "))
+	}
+	return nil
+}
+
+// fleetWiseMaxResults is the largest page FleetWise list operations return
+const fleetWiseMaxResults int32 = 100
+
+// FleetWiseClients caches one FleetWise client per region so that the SDK
+// configuration is loaded once per region rather than on every request
+type FleetWiseClients struct {
+	mu        sync.Mutex
+	clients   map[string]FleetWiseAPI
+	newClient func(region string) (FleetWiseAPI, error)
+}
+
+// NewFleetWiseClients creates an empty cache. newClient creates the client of
+// a region on first use.
+func NewFleetWiseClients(newClient func(region string) (FleetWiseAPI, error)) *FleetWiseClients {
+	return &FleetWiseClients{
+		clients:   make(map[string]FleetWiseAPI),
+		newClient: newClient,
+	}
+}
+
+// fleetWiseClients is the process-wide client cache, created in main. Tests
+// can replace it with a cache whose newClient returns a fake.
+var fleetWiseClients *FleetWiseClients
+
+// newAWSFleetWiseAPI creates the AWS client of a region
+func newAWSFleetWiseAPI(region string) (FleetWiseAPI, error) {
+	return NewAWSFleetWiseClient(region)
+}
+
+// Client returns the client of a region, or of the default region if region
+// is empty. Regions FleetWise is not available in are rejected, so the cache
+// only ever holds the known regions.
+func (f *FleetWiseClients) Client(region string) (FleetWiseAPI, error) {
+	if region == "" {
+		region = defaultFleetWiseRegion
+	}
+	if err := checkFleetWiseRegion(region); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if client, ok := f.clients[region]; ok {
+		return client, nil
+	}
+	client, err := f.newClient(region)
+	if err != nil {
+		return nil, err
+	}
+	f.clients[region] = client
+	return client, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFleetWiseClientsRegions(t *testing.T) {
+	var created []string
+	clients := NewFleetWiseClients(func(region string) (FleetWiseAPI, error) {
+		created = append(created, region)
+		return &AWSFleetWiseClient{}, nil
+	})
+
+	tests := []struct {
+		region  string
+		created []string // Regions whose client was created so far
+		err     error
+	}{
+		{"", []string{defaultFleetWiseRegion}, nil},
+		{"us-east-1", []string{defaultFleetWiseRegion}, nil},
+		{"eu-central-1", []string{defaultFleetWiseRegion, "eu-central-1"}, nil},
+		{"eu-central-1", []string{defaultFleetWiseRegion, "eu-central-1"}, nil},
+		{"us-west-2", []string{defaultFleetWiseRegion, "eu-central-1"}, ErrUnsupportedRegion},
+		{"../../etc", []string{defaultFleetWiseRegion, "eu-central-1"}, ErrUnsupportedRegion},
+	}
+	for _, tt := range tests {
+		client, err := clients.Client(tt.region)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Client(%q) error = %v, want %v", tt.region, err, tt.err)
+		}
+		if (client != nil) != (tt.err == nil) {
+			t.Errorf("Client(%q) = %v, want a client %t", tt.region, client, tt.err == nil)
+		}
+		if !reflect.DeepEqual(created, tt.created) {
+			t.Errorf("after Client(%q) created %v, want %v", tt.region, created, tt.created)
+		}
+	}
+	if len(clients.clients) != 2 {
+		t.Errorf("cached %d clients, want 2", len(clients.clients))
+	}
+}
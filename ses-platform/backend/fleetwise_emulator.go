@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"ses-platform/emulator"
+)
+
+// startFleetWiseEmulator serves the FleetWise emulator on a local port and
+// points every FleetWise client at it. It is enabled with FLEETWISE_EMULATOR=true.
+func startFleetWiseEmulator() error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to start FleetWise emulator: %w", err)
+	}
+	go func() {
+		if err := http.Serve(listener, emulator.NewFleetWise()); err != nil {
+			log.Printf("FleetWise emulator stopped: %v", err)
+		}
+	}()
+
+	fleetWiseEndpoint = "http://" + listener.Addr().String()
+	fleetWiseCredentials = aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "emulator", SecretAccessKey: "emulator", Source: "FleetWiseEmulator"}, nil
+	})
+	log.Printf("Using FleetWise emulator at %s", fleetWiseEndpoint)
+	return nil
+}
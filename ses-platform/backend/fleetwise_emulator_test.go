@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise/types"
+	"github.com/aws/smithy-go"
+	"github.com/gin-gonic/gin"
+
+	"ses-platform/emulator"
+)
+
+const (
+	testModelManifest   = "arn:aws:iotfleetwise:us-east-1:123456789012:model-manifest/model"
+	testDecoderManifest = "arn:aws:iotfleetwise:us-east-1:123456789012:decoder-manifest/decoder"
+	testSignalCatalog   = "arn:aws:iotfleetwise:us-east-1:123456789012:signal-catalog/catalog"
+)
+
+// useFleetWiseEmulator serves a FleetWise emulator for the test and points
+// every FleetWise client, including fleetWiseClients, at it
+func useFleetWiseEmulator(t *testing.T) *emulator.FleetWise {
+	t.Helper()
+	fleetWise := emulator.NewFleetWise()
+	fleetWise.CampaignCreationDelay = 50 * time.Millisecond
+	server := httptest.NewServer(fleetWise)
+
+	endpoint, credentials, clients := fleetWiseEndpoint, fleetWiseCredentials, fleetWiseClients
+	fleetWiseEndpoint = server.URL
+	fleetWiseCredentials = aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test"}, nil
+	})
+	fleetWiseClients = NewFleetWiseClients(newAWSFleetWiseAPI)
+	t.Cleanup(func() {
+		server.Close()
+		fleetWiseEndpoint, fleetWiseCredentials, fleetWiseClients = endpoint, credentials, clients
+	})
+	return fleetWise
+}
+
+func newTestFleetWiseClient(t *testing.T) *AWSFleetWiseClient {
+	t.Helper()
+	client, err := NewAWSFleetWiseClient(defaultFleetWiseRegion)
+	if err != nil {
+		t.Fatalf("NewAWSFleetWiseClient: %v", err)
+	}
+	return client
+}
+
+func testVehicle(name string) VehicleConfig {
+	return VehicleConfig{
+		Name:               name,
+		ModelManifestARN:   testModelManifest,
+		DecoderManifestARN: testDecoderManifest,
+		Attributes:         map[string]string{"EnvironmentID": "env-1"},
+		CreateIoTThing:     true,
+	}
+}
+
+func TestAWSFleetWiseClientVehicles(t *testing.T) {
+	useFleetWiseEmulator(t)
+	client := newTestFleetWiseClient(t)
+	ctx := context.Background()
+
+	created, err := client.CreateVehicle(ctx, testVehicle("car-0"))
+	if err != nil {
+		t.Fatalf("CreateVehicle: %v", err)
+	}
+	if want := "arn:aws:iotfleetwise:us-east-1:123456789012:vehicle/car-0"; aws.ToString(created.Arn) != want {
+		t.Errorf("Arn = %s, want %s", aws.ToString(created.Arn), want)
+	}
+	vehicle, err := client.GetVehicle(ctx, "car-0")
+	if err != nil {
+		t.Fatalf("GetVehicle: %v", err)
+	}
+	if vehicle.Attributes["EnvironmentID"] != "env-1" {
+		t.Errorf("Attributes = %v, want EnvironmentID env-1", vehicle.Attributes)
+	}
+
+	// Two batches, the first with a vehicle that already exists
+	var vehicles []VehicleConfig
+	for i := 0; i < 12; i++ {
+		vehicles = append(vehicles, testVehicle(fmt.Sprintf("car-%d", i)))
+	}
+	batch, errs := client.BatchCreateVehicles(ctx, vehicles)
+	if len(batch) != 11 {
+		t.Errorf("BatchCreateVehicles created %d vehicles, want 11", len(batch))
+	}
+	if len(errs) != 1 {
+		t.Errorf("BatchCreateVehicles errors = %v, want one for car-0", errs)
+	}
+	summaries, err := client.ListVehicles(ctx, testModelManifest)
+	if err != nil {
+		t.Fatalf("ListVehicles: %v", err)
+	}
+	if len(summaries) != 12 {
+		t.Errorf("ListVehicles returned %d vehicles, want 12", len(summaries))
+	}
+
+	if _, err := client.CreateVehicle(ctx, testVehicle("car-0")); !isConflict(err) {
+		t.Errorf("CreateVehicle of an existing vehicle: error = %v, want a ConflictException", err)
+	}
+	if err := client.DeleteVehicle(ctx, "car-0"); err != nil {
+		t.Fatalf("DeleteVehicle: %v", err)
+	}
+	if _, err := client.GetVehicle(ctx, "car-0"); !isAWSNotFound(err) {
+		t.Errorf("GetVehicle of a deleted vehicle: error = %v, want a ResourceNotFoundException", err)
+	}
+	if isAWSNotFound(nil) || isAWSNotFound(errors.New("ResourceNotFoundException")) {
+		t.Error("isAWSNotFound matched an error that is not an AWS error")
+	}
+}
+
+func TestAWSFleetWiseClientCampaignLifecycle(t *testing.T) {
+	useFleetWiseEmulator(t)
+	client := newTestFleetWiseClient(t)
+	ctx := context.Background()
+
+	vehicle, err := client.CreateVehicle(ctx, testVehicle("car-1"))
+	if err != nil {
+		t.Fatalf("CreateVehicle: %v", err)
+	}
+	campaign := EnvironmentCampaign("env-1", FleetWiseConfig{SignalCatalogARN: testSignalCatalog}, CampaignConfig{
+		CollectionScheme: CollectionScheme{Type: "time-based", PeriodMs: 10000},
+		SignalsToCollect: []SignalToCollect{{Name: "Vehicle.Speed", MaxSampleCount: 100}},
+	}, aws.ToString(vehicle.Arn))
+	if _, err := client.CreateCampaign(ctx, campaign); err != nil {
+		t.Fatalf("CreateCampaign: %v", err)
+	}
+	if _, err := client.CreateCampaign(ctx, campaign); !isConflict(err) {
+		t.Errorf("CreateCampaign of an existing campaign: error = %v, want a ConflictException", err)
+	}
+
+	status := func() types.CampaignStatus {
+		out, err := client.GetCampaign(ctx, campaign.Name)
+		if err != nil {
+			t.Fatalf("GetCampaign: %v", err)
+		}
+		return out.Status
+	}
+	if got := status(); got != types.CampaignStatusCreating {
+		t.Fatalf("status = %s, want CREATING", got)
+	}
+	if err := client.UpdateCampaign(ctx, campaign.Name, "APPROVE"); !isConflict(err) {
+		t.Errorf("APPROVE while CREATING: error = %v, want a ConflictException", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	awaited, err := AwaitCampaign(ctx, client, campaign.Name)
+	if err != nil {
+		t.Fatalf("AwaitCampaign: %v", err)
+	}
+	if awaited != types.CampaignStatusWaitingForApproval {
+		t.Fatalf("AwaitCampaign = %s, want WAITING_FOR_APPROVAL", awaited)
+	}
+
+	steps := []struct {
+		action   string
+		status   types.CampaignStatus
+		conflict bool
+	}{
+		{"APPROVE", types.CampaignStatusRunning, false},
+		{"SUSPEND", types.CampaignStatusSuspended, false},
+		{"SUSPEND", types.CampaignStatusSuspended, true},
+		{"RESUME", types.CampaignStatusRunning, false},
+		{"APPROVE", types.CampaignStatusRunning, true},
+	}
+	for _, step := range steps {
+		err := client.UpdateCampaign(ctx, campaign.Name, step.action)
+		if step.conflict && !isConflict(err) || !step.conflict && err != nil {
+			t.Errorf("%s: error = %v, want conflict %t", step.action, err, step.conflict)
+		}
+		if got := status(); got != step.status {
+			t.Errorf("after %s status = %s, want %s", step.action, got, step.status)
+		}
+	}
+
+	if err := client.DeleteCampaign(ctx, campaign.Name); err != nil {
+		t.Fatalf("DeleteCampaign: %v", err)
+	}
+	if _, err := client.GetCampaign(ctx, campaign.Name); !isAWSNotFound(err) {
+		t.Errorf("GetCampaign of a deleted campaign: error = %v, want a ResourceNotFoundException", err)
+	}
+}
+
+func isConflict(err error) bool {
+	var conflict *types.ConflictException
+	return errors.As(err, &conflict)
+}
+
+func TestRespondFleetWiseError(t *testing.T) {
+	fleetWise := useFleetWiseEmulator(t)
+	client := newTestFleetWiseClient(t)
+	ctx := context.Background()
+	if _, err := client.CreateVehicle(ctx, testVehicle("car-1")); err != nil {
+		t.Fatalf("CreateVehicle: %v", err)
+	}
+	fleetWise.InjectFault(emulator.Fault{Operation: "GetFleet", Code: "AccessDeniedException"})
+
+	_, notFound := client.GetVehicle(ctx, "car-9")
+	_, conflict := client.CreateVehicle(ctx, testVehicle("car-1"))
+	_, invalid := client.CreateVehicle(ctx, testVehicle("car 2"))
+	_, denied := client.GetFleet(ctx, "fleet-1")
+	_, region := fleetWiseClients.Client("mars-north-1")
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"not found", notFound, http.StatusNotFound},
+		{"conflict", conflict, http.StatusConflict},
+		{"validation", invalid, http.StatusBadRequest},
+		{"access denied", denied, http.StatusForbidden},
+		// The SDK retries throttling, so it is not worth waiting for the emulator
+		{"throttling", &smithy.GenericAPIError{Code: "ThrottlingException"}, http.StatusTooManyRequests},
+		{"unsupported region", region, http.StatusBadRequest},
+		{"other", errors.New("connection reset"), http.StatusInternalServerError},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err == nil {
+				t.Fatal("the call succeeded, want an error")
+			}
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			respondFleetWiseError(c, tt.err)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d (%v)", w.Code, tt.status, tt.err)
+			}
+		})
+	}
+}
+
+func TestFleetWiseVehicleEndpoint(t *testing.T) {
+	useFleetWiseEmulator(t)
+	client := newTestFleetWiseClient(t)
+	if _, err := client.CreateVehicle(context.Background(), testVehicle("car-1")); err != nil {
+		t.Fatalf("CreateVehicle: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/fleetwise/vehicles/:name", getFleetWiseVehicle)
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/fleetwise/vehicles/car-1", http.StatusOK},
+		{"/fleetwise/vehicles/car-1?region=us-east-1", http.StatusOK},
+		{"/fleetwise/vehicles/car-1?region=eu-central-1", http.StatusNotFound},
+		{"/fleetwise/vehicles/car-9", http.StatusNotFound},
+		{"/fleetwise/vehicles/car-1?region=us-east-1x", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("GET %s = %d, want %d: %s", tt.path, w.Code, tt.status, w.Body)
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 
+	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise/types"
+	"github.com/aws/smithy-go"
 	"github.com/gin-gonic/gin"
 )
 
@@ -20,13 +26,13 @@ func createFleetWiseVehicle(c *gin.Context) {
 		region = "us-east-1" // Default region
 	}
 
-	client, err := NewAWSFleetWiseClient(region)
+	client, err := fleetWiseClients.Client(region)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	result, err := client.CreateVehicle(vehicleConfig)
+	result, err := client.CreateVehicle(c.Request.Context(), vehicleConfig)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -54,13 +60,17 @@ func batchCreateFleetWiseVehicles(c *gin.Context) {
 		req.Region = "us-east-1"
 	}
 
-	client, err := NewAWSFleetWiseClient(req.Region)
+	client, err := fleetWiseClients.Client(req.Region)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	createdARNs, errors := client.BatchCreateVehicles(req.Vehicles)
+	created, errors := client.BatchCreateVehicles(c.Request.Context(), req.Vehicles)
+	createdARNs := make([]string, len(created))
+	for i, v := range created {
+		createdARNs[i] = *v.Arn
+	}
 
 	response := gin.H{
 		"created_count": len(createdARNs),
@@ -87,13 +97,13 @@ func getFleetWiseVehicle(c *gin.Context) {
 		region = "us-east-1"
 	}
 
-	client, err := NewAWSFleetWiseClient(region)
+	client, err := fleetWiseClients.Client(region)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	result, err := client.GetVehicle(name)
+	result, err := client.GetVehicle(c.Request.Context(), name)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -115,13 +125,13 @@ func updateFleetWiseVehicle(c *gin.Context) {
 		return
 	}
 
-	client, err := NewAWSFleetWiseClient(region)
+	client, err := fleetWiseClients.Client(region)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	err = client.UpdateVehicle(name, updates)
+	err = client.UpdateVehicle(c.Request.Context(), name, updates)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -137,13 +147,13 @@ func deleteFleetWiseVehicle(c *gin.Context) {
 		region = "us-east-1"
 	}
 
-	client, err := NewAWSFleetWiseClient(region)
+	client, err := fleetWiseClients.Client(region)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	err = client.DeleteVehicle(name)
+	err = client.DeleteVehicle(c.Request.Context(), name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -159,13 +169,13 @@ func getFleetWiseVehicleStatus(c *gin.Context) {
 		region = "us-east-1"
 	}
 
-	client, err := NewAWSFleetWiseClient(region)
+	client, err := fleetWiseClients.Client(region)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	result, err := client.GetVehicleStatus(name)
+	result, err := client.GetVehicleStatus(c.Request.Context(), name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -174,31 +184,50 @@ func getFleetWiseVehicleStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// listFleetWiseVehicles lists a page of vehicles. model_manifest_arn and
+// attribute[<name>]=<value>, for example attribute[EnvironmentID]=env-1700000000,
+// filter the vehicles; page_token continues from an earlier page.
 func listFleetWiseVehicles(c *gin.Context) {
 	region := c.Query("region")
 	if region == "" {
 		region = "us-east-1"
 	}
 
+	cursor, limit, err := pageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	modelManifestARN := c.Query("model_manifest_arn")
-	maxResults := int32(50) // Default
+	attributes := c.QueryMap("attribute")
 
-	client, err := NewAWSFleetWiseClient(region)
+	client, err := fleetWiseClients.Client(region)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	vehicles, err := client.ListVehicles(modelManifestARN, maxResults)
+	list := func(ctx context.Context, nextToken string, maxResults int32) ([]types.VehicleSummary, string, error) {
+		return client.ListVehiclesPage(ctx, modelManifestARN, nextToken, maxResults)
+	}
+	vehicles, next, err := listPage(c.Request.Context(), cursor, limit, list, func(vehicle types.VehicleSummary) bool {
+		for name, value := range attributes {
+			if vehicle.Attributes[name] != value {
+				return false
+			}
+		}
+		return true
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"vehicles": vehicles,
-		"count":    len(vehicles),
-	})
+	response := gin.H{"vehicles": vehicles, "count": len(vehicles)}
+	if next != "" {
+		response["next_page_token"] = next
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // FleetWise Campaign API Handlers
@@ -215,13 +244,23 @@ func createFleetWiseCampaign(c *gin.Context) {
 		region = "us-east-1"
 	}
 
-	client, err := NewAWSFleetWiseClient(region)
+	client, err := fleetWiseClients.Client(region)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	result, err := client.CreateCampaign(campaignConfig)
+	if campaignConfig.SignalCatalogARN != "" {
+		if err := CheckCampaignExpression(c.Request.Context(), client, campaignConfig); isExpressionError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		} else if err != nil {
+			respondFleetWiseError(c, err)
+			return
+		}
+	}
+
+	result, err := client.CreateCampaign(c.Request.Context(), campaignConfig)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -241,13 +280,13 @@ func getFleetWiseCampaign(c *gin.Context) {
 		region = "us-east-1"
 	}
 
-	client, err := NewAWSFleetWiseClient(region)
+	client, err := fleetWiseClients.Client(region)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	result, err := client.GetCampaign(name)
+	result, err := client.GetCampaign(c.Request.Context(), name)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -272,13 +311,13 @@ func updateFleetWiseCampaign(c *gin.Context) {
 		return
 	}
 
-	client, err := NewAWSFleetWiseClient(region)
+	client, err := fleetWiseClients.Client(region)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	err = client.UpdateCampaign(name, req.Action)
+	err = client.UpdateCampaign(c.Request.Context(), name, req.Action)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -294,13 +333,13 @@ func deleteFleetWiseCampaign(c *gin.Context) {
 		region = "us-east-1"
 	}
 
-	client, err := NewAWSFleetWiseClient(region)
+	client, err := fleetWiseClients.Client(region)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	err = client.DeleteCampaign(name)
+	err = client.DeleteCampaign(c.Request.Context(), name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -309,30 +348,45 @@ func deleteFleetWiseCampaign(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Campaign deleted successfully"})
 }
 
+// listFleetWiseCampaigns lists a page of campaigns, optionally only those
+// with a status; page_token continues from an earlier page
 func listFleetWiseCampaigns(c *gin.Context) {
 	region := c.Query("region")
 	if region == "" {
 		region = "us-east-1"
 	}
 
-	maxResults := int32(50) // Default
+	cursor, limit, err := pageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	status := c.Query("status")
+	if status != "" && !slices.Contains(types.CampaignStatus("").Values(), types.CampaignStatus(status)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("status must be one of %v, got %q", types.CampaignStatus("").Values(), status)})
+		return
+	}
 
-	client, err := NewAWSFleetWiseClient(region)
+	client, err := fleetWiseClients.Client(region)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	campaigns, err := client.ListCampaigns(maxResults)
+	list := func(ctx context.Context, nextToken string, maxResults int32) ([]types.CampaignSummary, string, error) {
+		return client.ListCampaignsPage(ctx, status, nextToken, maxResults)
+	}
+	campaigns, next, err := listPage(c.Request.Context(), cursor, limit, list, func(types.CampaignSummary) bool { return true })
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"campaigns": campaigns,
-		"count":     len(campaigns),
-	})
+	response := gin.H{"campaigns": campaigns, "count": len(campaigns)}
+	if next != "" {
+		response["next_page_token"] = next
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // FleetWise Fleet API Handlers
@@ -354,13 +408,13 @@ func createFleetWiseFleet(c *gin.Context) {
 		req.Region = "us-east-1"
 	}
 
-	client, err := NewAWSFleetWiseClient(req.Region)
+	client, err := fleetWiseClients.Client(req.Region)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	result, err := client.CreateFleet(req.FleetID, req.Description, req.SignalCatalogARN)
+	result, err := client.CreateFleet(c.Request.Context(), req.FleetID, req.Description, req.SignalCatalogARN)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -389,13 +443,13 @@ func associateVehicleToFleet(c *gin.Context) {
 		return
 	}
 
-	client, err := NewAWSFleetWiseClient(region)
+	client, err := fleetWiseClients.Client(region)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFleetWiseError(c, err)
 		return
 	}
 
-	err = client.AssociateVehicleToFleet(req.VehicleName, fleetID)
+	err = client.AssociateVehicleToFleet(c.Request.Context(), req.VehicleName, fleetID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -407,3 +461,256 @@ func associateVehicleToFleet(c *gin.Context) {
 		"fleet":   fleetID,
 	})
 }
+
+// batchAssociateVehiclesToFleet associates many vehicles with a fleet
+func batchAssociateVehiclesToFleet(c *gin.Context) {
+	fleetID := c.Param("id")
+	region := c.Query("region")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	var req struct {
+		VehicleNames []string `json:"vehicle_names" binding:"required,min=1"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := fleetWiseClients.Client(region)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	associated, errors := client.AssociateVehiclesToFleet(c.Request.Context(), req.VehicleNames, fleetID)
+	if associated == nil {
+		associated = []string{}
+	}
+
+	response := gin.H{
+		"fleet":            fleetID,
+		"associated_count": len(associated),
+		"associated":       associated,
+	}
+
+	if len(errors) > 0 {
+		errorMessages := make([]string, len(errors))
+		for i, e := range errors {
+			errorMessages[i] = e.Error()
+		}
+		response["errors"] = errorMessages
+		c.JSON(http.StatusPartialContent, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func disassociateVehicleFromFleet(c *gin.Context) {
+	fleetID := c.Param("id")
+	vehicleName := c.Param("name")
+	region := c.Query("region")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	client, err := fleetWiseClients.Client(region)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	if err := client.DisassociateVehicleFromFleet(c.Request.Context(), vehicleName, fleetID); err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Vehicle disassociated from fleet successfully",
+		"vehicle": vehicleName,
+		"fleet":   fleetID,
+	})
+}
+
+func getFleetWiseFleet(c *gin.Context) {
+	fleetID := c.Param("id")
+	region := c.Query("region")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	client, err := fleetWiseClients.Client(region)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	result, err := client.GetFleet(c.Request.Context(), fleetID)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// listFleetWiseFleets lists a page of fleets; page_token continues from an
+// earlier page
+func listFleetWiseFleets(c *gin.Context) {
+	region := c.Query("region")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	cursor, limit, err := pageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := fleetWiseClients.Client(region)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	fleets, next, err := listPage(c.Request.Context(), cursor, limit, client.ListFleetsPage, func(types.FleetSummary) bool { return true })
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	response := gin.H{"fleets": fleets, "count": len(fleets)}
+	if next != "" {
+		response["next_page_token"] = next
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// deleteFleetWiseFleet deletes a fleet. FleetWise refuses to delete a fleet
+// with vehicles; disassociate_vehicles=true disassociates them first.
+func deleteFleetWiseFleet(c *gin.Context) {
+	fleetID := c.Param("id")
+	region := c.Query("region")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	client, err := fleetWiseClients.Client(region)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if c.Query("disassociate_vehicles") == "true" {
+		vehicles, err := client.ListVehiclesInFleet(ctx, fleetID)
+		if err != nil {
+			respondFleetWiseError(c, err)
+			return
+		}
+		for _, vehicle := range vehicles {
+			if err := client.DisassociateVehicleFromFleet(ctx, vehicle, fleetID); err != nil {
+				respondFleetWiseError(c, err)
+				return
+			}
+		}
+	}
+
+	if err := client.DeleteFleet(ctx, fleetID); err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Fleet deleted successfully",
+		"fleet":   fleetID,
+	})
+}
+
+// listFleetVehicles lists the names of the vehicles in a fleet
+func listFleetVehicles(c *gin.Context) {
+	fleetID := c.Param("id")
+	region := c.Query("region")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	client, err := fleetWiseClients.Client(region)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	vehicles, err := client.ListVehiclesInFleet(c.Request.Context(), fleetID)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+	if vehicles == nil {
+		vehicles = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"fleet":    fleetID,
+		"vehicles": vehicles,
+		"count":    len(vehicles),
+	})
+}
+
+// listVehicleFleets lists the IDs of the fleets a vehicle belongs to
+func listVehicleFleets(c *gin.Context) {
+	name := c.Param("name")
+	region := c.Query("region")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	client, err := fleetWiseClients.Client(region)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	fleets, err := client.ListFleetsForVehicle(c.Request.Context(), name)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+	if fleets == nil {
+		fleets = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"vehicle": name,
+		"fleets":  fleets,
+		"count":   len(fleets),
+	})
+}
+
+// respondFleetWiseError maps FleetWise error codes to HTTP statuses. An
+// unsupported region is a bad request.
+func respondFleetWiseError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	var apiErr smithy.APIError
+	if errors.Is(err, ErrUnsupportedRegion) {
+		status = http.StatusBadRequest
+	} else if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "ResourceNotFoundException":
+			status = http.StatusNotFound
+		case "ConflictException":
+			status = http.StatusConflict
+		case "ValidationException", "InvalidNodeException", "InvalidSignalsException", "DecoderManifestValidationException":
+			status = http.StatusBadRequest
+		case "ThrottlingException", "LimitExceededException":
+			status = http.StatusTooManyRequests
+		case "AccessDeniedException":
+			status = http.StatusForbidden
+		}
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
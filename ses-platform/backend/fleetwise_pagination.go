@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+// pageCursor is the position a page_token points at: a FleetWise page, which
+// is always listed fleetWiseMaxResults at a time, and how many of its items
+// earlier pages already went through. Tokens are opaque to clients.
+type pageCursor struct {
+	Token string `json:"t,omitempty"`
+	Skip  int    `json:"s,omitempty"`
+}
+
+func (p pageCursor) encode() string {
+	data, _ := json.Marshal(p)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// pageRequest reads the page_token and limit query parameters
+func pageRequest(c *gin.Context) (pageCursor, int, error) {
+	limit := defaultPageLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPageLimit {
+			return pageCursor{}, 0, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		limit = parsed
+	}
+
+	var cursor pageCursor
+	if token := c.Query("page_token"); token != "" {
+		data, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil || json.Unmarshal(data, &cursor) != nil || cursor.Skip < 0 {
+			return pageCursor{}, 0, errors.New("invalid page_token")
+		}
+	}
+	return cursor, limit, nil
+}
+
+// listPage collects up to limit items that match, starting at the cursor and
+// listing FleetWise pages as needed. It returns the page_token of the next
+// matching item, or "" when there is none.
+func listPage[T any](ctx context.Context, cursor pageCursor, limit int,
+	list func(ctx context.Context, nextToken string, maxResults int32) ([]T, string, error),
+	match func(T) bool) ([]T, string, error) {
+	items := []T{}
+	for {
+		page, next, err := list(ctx, cursor.Token, fleetWiseMaxResults)
+		if err != nil {
+			return nil, "", err
+		}
+		for i := cursor.Skip; i < len(page); i++ {
+			if !match(page[i]) {
+				continue
+			}
+			if len(items) == limit {
+				return items, pageCursor{Token: cursor.Token, Skip: i}.encode(), nil
+			}
+			items = append(items, page[i])
+		}
+		if next == "" {
+			return items, "", nil
+		}
+		cursor = pageCursor{Token: next}
+	}
+}
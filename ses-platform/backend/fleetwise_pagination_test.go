@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListPage(t *testing.T) {
+	// FleetWise pages by their token, and the token of the page after each
+	pages := map[string][]int{"": {1, 2, 3, 4}, "b": {5, 6, 7}, "c": {}, "d": {8}}
+	next := map[string]string{"": "b", "b": "c", "c": "d"}
+	list := func(ctx context.Context, token string, maxResults int32) ([]int, string, error) {
+		if maxResults != fleetWiseMaxResults {
+			t.Errorf("maxResults = %d, want %d", maxResults, fleetWiseMaxResults)
+		}
+		return pages[token], next[token], nil
+	}
+	all := func(int) bool { return true }
+	even := func(i int) bool { return i%2 == 0 }
+
+	tests := []struct {
+		name  string
+		limit int
+		match func(int) bool
+		pages [][]int
+	}{
+		{name: "one page", limit: 10, match: all, pages: [][]int{{1, 2, 3, 4, 5, 6, 7, 8}}},
+		{name: "exactly one page", limit: 8, match: all, pages: [][]int{{1, 2, 3, 4, 5, 6, 7, 8}}},
+		{name: "within a FleetWise page", limit: 3, match: all, pages: [][]int{{1, 2, 3}, {4, 5, 6}, {7, 8}}},
+		{name: "across FleetWise pages", limit: 5, match: all, pages: [][]int{{1, 2, 3, 4, 5}, {6, 7, 8}}},
+		{name: "filtered", limit: 2, match: even, pages: [][]int{{2, 4}, {6, 8}}},
+		{name: "one at a time", limit: 1, match: even, pages: [][]int{{2}, {4}, {6}, {8}}},
+		{name: "nothing matches", limit: 5, match: func(int) bool { return false }, pages: [][]int{{}}},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]int
+			cursor := pageCursor{}
+			for {
+				items, token, err := listPage(context.Background(), cursor, tt.limit, list, tt.match)
+				if err != nil {
+					t.Fatalf("listPage: %v", err)
+				}
+				got = append(got, items)
+				if token == "" || len(got) > 10 {
+					break
+				}
+				if cursor, err = decodeToken(token); err != nil {
+					t.Fatalf("page_token %s: %v", token, err)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.pages) {
+				t.Errorf("pages = %v, want %v", got, tt.pages)
+			}
+		})
+	}
+
+	failing := func(ctx context.Context, token string, maxResults int32) ([]int, string, error) {
+		return nil, "", errors.New("throttled")
+	}
+	if _, _, err := listPage(context.Background(), pageCursor{}, 5, failing, all); err == nil {
+		t.Error("listPage of a failing list succeeded, want an error")
+	}
+}
+
+// decodeToken reads a page_token the way a request passes it
+func decodeToken(token string) (pageCursor, error) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/?page_token="+token, nil)
+	cursor, _, err := pageRequest(c)
+	return cursor, err
+}
+
+func TestPageRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		cursor pageCursor
+		limit  int
+		err    string
+	}{
+		{name: "defaults", limit: defaultPageLimit},
+		{name: "limit", query: "limit=10", limit: 10},
+		{name: "page token", query: "page_token=" + pageCursor{Token: "abc", Skip: 3}.encode(), cursor: pageCursor{Token: "abc", Skip: 3}, limit: defaultPageLimit},
+		{name: "limit too large", query: "limit=101", err: "limit must be between 1 and 100"},
+		{name: "limit zero", query: "limit=0", err: "limit must be between 1 and 100"},
+		{name: "limit not a number", query: "limit=ten", err: "limit must be between 1 and 100"},
+		{name: "token not base64", query: "page_token=!!", err: "invalid page_token"},
+		{name: "token not JSON", query: "page_token=" + "bm90IGpzb24", err: "invalid page_token"},
+		{name: "negative skip", query: "page_token=" + pageCursor{Skip: -1}.encode(), err: "invalid page_token"},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/?"+tt.query, nil)
+			cursor, limit, err := pageRequest(c)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("pageRequest error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pageRequest: %v", err)
+			}
+			if cursor != tt.cursor || limit != tt.limit {
+				t.Errorf("pageRequest = %+v, %d, want %+v, %d", cursor, limit, tt.cursor, tt.limit)
+			}
+		})
+	}
+}
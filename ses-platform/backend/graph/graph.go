@@ -0,0 +1,240 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Graph is a directed dependency graph. An edge from A to B means A depends on B.
+// Nodes and edges keep insertion order so every traversal is deterministic.
+type Graph struct {
+	nodes []string
+	edges map[string][]string
+	known map[string]bool
+}
+
+// CycleError is returned when an operation requires an acyclic graph
+type CycleError struct {
+	Cycle []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("dependency cycle detected: %s", FormatPath(e.Cycle))
+}
+
+// New creates an empty graph
+func New() *Graph {
+	return &Graph{
+		edges: make(map[string][]string),
+		known: make(map[string]bool),
+	}
+}
+
+// AddNode adds a node if it is not already present
+func (g *Graph) AddNode(id string) {
+	if g.known[id] {
+		return
+	}
+	g.known[id] = true
+	g.nodes = append(g.nodes, id)
+}
+
+// AddEdge records that from depends on to, adding both nodes if needed
+func (g *Graph) AddEdge(from, to string) {
+	g.AddNode(from)
+	g.AddNode(to)
+	for _, existing := range g.edges[from] {
+		if existing == to {
+			return
+		}
+	}
+	g.edges[from] = append(g.edges[from], to)
+}
+
+// HasNode reports whether id is part of the graph
+func (g *Graph) HasNode(id string) bool {
+	return g.known[id]
+}
+
+// Nodes returns all nodes in insertion order
+func (g *Graph) Nodes() []string {
+	return append([]string(nil), g.nodes...)
+}
+
+// Dependencies returns the direct dependencies of id
+func (g *Graph) Dependencies(id string) []string {
+	return append([]string(nil), g.edges[id]...)
+}
+
+// Dependents returns the nodes that directly depend on id
+func (g *Graph) Dependents(id string) []string {
+	var dependents []string
+	for _, n := range g.nodes {
+		for _, dep := range g.edges[n] {
+			if dep == id {
+				dependents = append(dependents, n)
+				break
+			}
+		}
+	}
+	return dependents
+}
+
+// Cycles returns one path per cycle found by depth-first search. Each path
+// starts and ends with the same node, e.g. [A B C A].
+func (g *Graph) Cycles() [][]string {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int)
+	seen := make(map[string]bool)
+	var stack []string
+	var cycles [][]string
+
+	var visit func(n string)
+	visit = func(n string) {
+		state[n] = inProgress
+		stack = append(stack, n)
+		for _, dep := range g.edges[n] {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case inProgress:
+				start := 0
+				for i, s := range stack {
+					if s == dep {
+						start = i
+						break
+					}
+				}
+				cycle := append(append([]string(nil), stack[start:]...), dep)
+				if key := cycleKey(cycle); !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[n] = done
+	}
+
+	for _, n := range g.nodes {
+		if state[n] == unvisited {
+			visit(n)
+		}
+	}
+	return cycles
+}
+
+// cycleKey identifies a cycle independently of the node it was entered from
+func cycleKey(cycle []string) string {
+	ring := cycle[:len(cycle)-1]
+	min := 0
+	for i := range ring {
+		if ring[i] < ring[min] {
+			min = i
+		}
+	}
+	rotated := append(append([]string(nil), ring[min:]...), ring[:min]...)
+	return strings.Join(rotated, "\x00")
+}
+
+// TopologicalSort orders nodes so that every node comes after its dependencies
+func (g *Graph) TopologicalSort() ([]string, error) {
+	levels, err := g.Levels()
+	if err != nil {
+		return nil, err
+	}
+	var order []string
+	for _, level := range levels {
+		order = append(order, level...)
+	}
+	return order, nil
+}
+
+// Levels groups nodes into batches: every node's dependencies are in an
+// earlier batch, so all nodes within a batch can be processed concurrently.
+func (g *Graph) Levels() ([][]string, error) {
+	if cycles := g.Cycles(); len(cycles) > 0 {
+		return nil, &CycleError{Cycle: cycles[0]}
+	}
+
+	remaining := make(map[string]int, len(g.nodes))
+	for _, n := range g.nodes {
+		remaining[n] = len(g.edges[n])
+	}
+
+	var levels [][]string
+	placed := 0
+	for placed < len(g.nodes) {
+		var level []string
+		for _, n := range g.nodes {
+			if remaining[n] == 0 {
+				level = append(level, n)
+			}
+		}
+		for _, n := range level {
+			remaining[n] = -1
+			for _, dependent := range g.Dependents(n) {
+				remaining[dependent]--
+			}
+		}
+		levels = append(levels, level)
+		placed += len(level)
+	}
+	return levels, nil
+}
+
+// Closure returns every node reachable from roots, roots included, in
+// breadth-first order
+func (g *Graph) Closure(roots ...string) []string {
+	visited := make(map[string]bool)
+	var order []string
+	queue := append([]string(nil), roots...)
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if visited[n] {
+			continue
+		}
+		visited[n] = true
+		order = append(order, n)
+		queue = append(queue, g.edges[n]...)
+	}
+	return order
+}
+
+// Path returns the shortest dependency path from one node to another, or nil
+// if to is not reachable from from
+func (g *Graph) Path(from, to string) []string {
+	parent := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if n == to {
+			var path []string
+			for cur := to; cur != ""; cur = parent[cur] {
+				path = append([]string{cur}, path...)
+				if cur == from {
+					break
+				}
+			}
+			return path
+		}
+		for _, dep := range g.edges[n] {
+			if _, ok := parent[dep]; !ok {
+				parent[dep] = n
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// FormatPath renders a path as "A -> B -> C"
+func FormatPath(path []string) string {
+	return strings.Join(path, " -> ")
+}
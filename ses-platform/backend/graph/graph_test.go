@@ -0,0 +1,133 @@
+package graph
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// build creates a graph from "from:to" edges and bare node names
+func build(items ...string) *Graph {
+	g := New()
+	for _, item := range items {
+		from, to, ok := strings.Cut(item, ":")
+		if !ok {
+			g.AddNode(item)
+			continue
+		}
+		g.AddEdge(from, to)
+	}
+	return g
+}
+
+func TestCycles(t *testing.T) {
+	tests := []struct {
+		name   string
+		graph  *Graph
+		cycles [][]string
+	}{
+		{"empty", build(), nil},
+		{"chain", build("A:B", "B:C"), nil},
+		{"diamond", build("A:B", "A:C", "B:D", "C:D"), nil},
+		{"self loop", build("A:A"), [][]string{{"A", "A"}}},
+		{"two nodes", build("A:B", "B:A"), [][]string{{"A", "B", "A"}}},
+		{"three nodes", build("A:B", "B:C", "C:A"), [][]string{{"A", "B", "C", "A"}}},
+		{"cycle below an acyclic node", build("X:A", "A:B", "B:A"), [][]string{{"A", "B", "A"}}},
+		{"two separate cycles", build("A:B", "B:A", "C:D", "D:C"), [][]string{{"A", "B", "A"}, {"C", "D", "C"}}},
+		{"cycles sharing a node", build("A:B", "B:A", "B:C", "C:B"), [][]string{{"A", "B", "A"}, {"B", "C", "B"}}},
+		{"duplicate edges", build("A:B", "A:B", "B:A"), [][]string{{"A", "B", "A"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.graph.Cycles(); !reflect.DeepEqual(got, tt.cycles) {
+				t.Errorf("Cycles() = %v, want %v", got, tt.cycles)
+			}
+		})
+	}
+}
+
+func TestLevels(t *testing.T) {
+	tests := []struct {
+		name   string
+		graph  *Graph
+		levels [][]string
+		cycle  []string
+	}{
+		{"empty", build(), nil, nil},
+		{"independent nodes", build("A", "B"), [][]string{{"A", "B"}}, nil},
+		{"chain", build("A:B", "B:C"), [][]string{{"C"}, {"B"}, {"A"}}, nil},
+		{"diamond", build("A:B", "A:C", "B:D", "C:D"), [][]string{{"D"}, {"B", "C"}, {"A"}}, nil},
+		{"uneven branches", build("A:B", "A:D", "B:C"), [][]string{{"D", "C"}, {"B"}, {"A"}}, nil},
+		{"cycle", build("A:B", "B:C", "C:B"), nil, []string{"B", "C", "B"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels, err := tt.graph.Levels()
+			var cycleErr *CycleError
+			if tt.cycle != nil {
+				if !errors.As(err, &cycleErr) || !reflect.DeepEqual(cycleErr.Cycle, tt.cycle) {
+					t.Fatalf("Levels() error = %v, want cycle %v", err, tt.cycle)
+				}
+				if _, err := tt.graph.TopologicalSort(); !errors.As(err, &cycleErr) {
+					t.Errorf("TopologicalSort() error = %v, want a CycleError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Levels: %v", err)
+			}
+			if !reflect.DeepEqual(levels, tt.levels) {
+				t.Errorf("Levels() = %v, want %v", levels, tt.levels)
+			}
+
+			order, err := tt.graph.TopologicalSort()
+			if err != nil {
+				t.Fatalf("TopologicalSort: %v", err)
+			}
+			position := make(map[string]int, len(order))
+			for i, n := range order {
+				position[n] = i
+			}
+			for _, n := range tt.graph.Nodes() {
+				for _, dep := range tt.graph.Dependencies(n) {
+					if position[dep] > position[n] {
+						t.Errorf("TopologicalSort() = %v puts %s after its dependent %s", order, dep, n)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCycleErrorMessage(t *testing.T) {
+	err := &CycleError{Cycle: []string{"A", "B", "A"}}
+	if want := "dependency cycle detected: A -> B -> A"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestClosureAndPath(t *testing.T) {
+	g := build("A:B", "A:C", "B:D", "C:D", "D:E", "F:A")
+	tests := []struct {
+		from, to string
+		closure  []string
+		path     []string
+	}{
+		{"A", "E", []string{"A", "B", "C", "D", "E"}, []string{"A", "B", "D", "E"}},
+		{"C", "E", []string{"C", "D", "E"}, []string{"C", "D", "E"}},
+		{"E", "A", []string{"E"}, nil},
+		{"F", "F", []string{"F", "A", "B", "C", "D", "E"}, []string{"F"}},
+	}
+	for _, tt := range tests {
+		if got := g.Closure(tt.from); !reflect.DeepEqual(got, tt.closure) {
+			t.Errorf("Closure(%s) = %v, want %v", tt.from, got, tt.closure)
+		}
+		if got := g.Path(tt.from, tt.to); !reflect.DeepEqual(got, tt.path) {
+			t.Errorf("Path(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.path)
+		}
+	}
+	if got, want := g.Dependents("D"), []string{"B", "C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependents(D) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Background Job API Handlers
+
+// listJobs returns the most recent jobs, optionally filtered by status, type
+// and environment_id
+func listJobs(c *gin.Context) {
+	var jobs []Job
+	query := db.Order("created_at desc, id desc").Limit(100)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if jobType := c.Query("type"); jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+	if envID := c.Query("environment_id"); envID != "" {
+		query = query.Where("environment_id = ?", envID)
+	}
+	if err := query.Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+func getJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job id"})
+		return
+	}
+	var job Job
+	if err := db.First(&job, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// cancelJob cancels a queued job, or requests cancellation of a running one.
+// A cancelled provision job fails its environment and rolls it back.
+func cancelJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job id"})
+		return
+	}
+
+	job, err := requestJobCancellation(uint(id))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	case errors.Is(err, ErrJobFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A job cancelled before it ran leaves its environment or rollback behind
+	if job.Status == JobStatusCancelled {
+		switch job.Type {
+		case JobTypeProvision:
+			planID, _ := job.Payload["plan_id"].(float64)
+			plan := &ExecutionPlan{ID: uint(planID), EnvironmentID: job.EnvironmentID}
+			failEnvironment(job.EnvironmentID, "Provisioning cancelled",
+				map[string]interface{}{"plan_id": plan.ID, "job_id": job.ID}, failAndRollBack(plan, nil, RollbackTriggerManual))
+		case JobTypeRollback:
+			rollbackID, _ := job.Payload["rollback_id"].(float64)
+			finishRollback(&RollbackOperation{ID: uint(rollbackID)}, errJobCancelled)
+		}
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
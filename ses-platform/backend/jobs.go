@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Job is a unit of background work persisted in Postgres so it survives
+// restarts. Workers claim jobs with SELECT ... FOR UPDATE SKIP LOCKED and keep
+// them alive with heartbeats; a running job whose heartbeat goes stale is
+// reclaimed by another worker and resumed from its checkpoint.
+type Job struct {
+	ID              uint                   `gorm:"primaryKey" json:"id"`
+	Type            string                 `gorm:"index" json:"type"`                        // provision, track_uptime, rollback, reconcile_drift, teardown, campaign_lifecycle
+	EnvironmentID   string                 `gorm:"index;default:null" json:"environment_id"` // Empty for jobs not tied to an environment
+	Payload         map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"payload"`
+	Checkpoint      []string               `gorm:"type:jsonb;serializer:json" json:"checkpoint"` // Completed step IDs
+	Status          string                 `gorm:"index" json:"status"`                          // queued, running, succeeded, failed, cancelled
+	Attempts        int                    `json:"attempts"`
+	MaxAttempts     int                    `json:"max_attempts"`
+	LastError       string                 `json:"last_error,omitempty"`
+	CancelRequested bool                   `json:"cancel_requested"`
+	RunAt           time.Time              `gorm:"index" json:"run_at"`
+	LockedBy        string                 `json:"locked_by,omitempty"`
+	HeartbeatAt     *time.Time             `json:"heartbeat_at,omitempty"`
+	CompletedAt     *time.Time             `json:"completed_at,omitempty"`
+	CreatedAt       time.Time              `json:"created_at"`
+	UpdatedAt       time.Time              `json:"updated_at"`
+}
+
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+
+	JobTypeProvision   = "provision"
+	JobTypeTrackUptime = "track_uptime"
+)
+
+var (
+	jobWorkers           = 4
+	jobPollInterval      = 1 * time.Second
+	jobHeartbeatInterval = 10 * time.Second
+	// A running job without a heartbeat for this long is considered abandoned
+	jobStaleAfter      = 3 * jobHeartbeatInterval
+	jobDefaultAttempts = 3
+)
+
+// JobHandler performs a job. Returning an error retries the job until it runs
+// out of attempts, unless the error is wrapped with permanentJobError.
+type JobHandler func(ctx context.Context, job *Job) error
+
+var jobHandlers = map[string]JobHandler{}
+
+// permanentJobError fails a job without further retries
+type permanentJobError struct{ err error }
+
+func (e *permanentJobError) Error() string { return e.err.Error() }
+func (e *permanentJobError) Unwrap() error { return e.err }
+
+// rescheduleJob puts a recurring job back in the queue instead of completing it
+type rescheduleJob struct{ after time.Duration }
+
+func (e *rescheduleJob) Error() string { return fmt.Sprintf("rescheduled in %s", e.after) }
+
+// enqueueJob adds a job to the queue using tx, so callers can enqueue
+// atomically with the state change that requires the job
+func enqueueJob(tx *gorm.DB, jobType, envID string, payload map[string]interface{}) (*Job, error) {
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	job := Job{
+		Type:          jobType,
+		EnvironmentID: envID,
+		Payload:       payload,
+		Checkpoint:    []string{},
+		Status:        JobStatusQueued,
+		MaxAttempts:   jobDefaultAttempts,
+		RunAt:         time.Now(),
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if err := tx.Create(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// enqueueUniqueJob enqueues a job unless the environment already has an
+// active job of the same type. With an empty envID at most one job of the
+// type that is not tied to an environment is active.
+func enqueueUniqueJob(tx *gorm.DB, jobType, envID string, payload map[string]interface{}) error {
+	query := tx.Model(&Job{}).Where("type = ? AND status IN ?", jobType, []string{JobStatusQueued, JobStatusRunning})
+	if envID == "" {
+		query = query.Where("environment_id IS NULL")
+	} else {
+		query = query.Where("environment_id = ?", envID)
+	}
+	var active int64
+	if err := query.Count(&active).Error; err != nil {
+		return err
+	}
+	if active > 0 {
+		return nil
+	}
+	_, err := enqueueJob(tx, jobType, envID, payload)
+	return err
+}
+
+// startJobWorkers starts the worker pool. Abandoned jobs from a previous
+// process are picked up by the same claim query once their heartbeat is stale.
+func startJobWorkers(ctx context.Context) {
+	host, _ := os.Hostname()
+
+	var abandoned int64
+	db.Model(&Job{}).Where("status = ?", JobStatusRunning).Count(&abandoned)
+	if abandoned > 0 {
+		log.Printf("Resuming %d job(s) left running by a previous process once their heartbeat expires", abandoned)
+	}
+
+	for i := 0; i < jobWorkers; i++ {
+		workerID := fmt.Sprintf("%s-%d-%d", host, os.Getpid(), i)
+		go runJobWorker(ctx, workerID)
+	}
+}
+
+func runJobWorker(ctx context.Context, workerID string) {
+	for {
+		job, err := claimJob(workerID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Job worker %s failed to claim a job: %v", workerID, err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(jobPollInterval):
+			}
+			continue
+		}
+		runJob(ctx, workerID, job)
+	}
+}
+
+// claimJob locks the next runnable job, skipping rows other workers hold
+func claimJob(workerID string) (*Job, error) {
+	var job Job
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND heartbeat_at < ?)",
+				JobStatusQueued, now, JobStatusRunning, now.Add(-jobStaleAfter)).
+			Order("run_at, id").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+		if job.Status == JobStatusRunning {
+			log.Printf("Reclaiming job %d abandoned by %s", job.ID, job.LockedBy)
+		}
+
+		job.Status = JobStatusRunning
+		job.Attempts++
+		job.LockedBy = workerID
+		job.HeartbeatAt = &now
+		return tx.Model(&Job{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+			"status":       job.Status,
+			"attempts":     job.Attempts,
+			"locked_by":    workerID,
+			"heartbeat_at": now,
+			"updated_at":   now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+var (
+	// errJobCancelled is the context cause when a user cancels a running job
+	errJobCancelled = errors.New("job cancelled")
+	// errJobClaimLost is the context cause when another worker reclaimed the job
+	errJobClaimLost = errors.New("job claim lost")
+)
+
+// runJob executes a claimed job while heartbeating, then records the outcome.
+// Handlers can tell a user cancellation from a lost claim via context.Cause.
+func runJob(parent context.Context, workerID string, job *Job) {
+	handler, ok := jobHandlers[job.Type]
+	if !ok {
+		finishJob(workerID, job, &permanentJobError{fmt.Errorf("unknown job type %q", job.Type)})
+		return
+	}
+
+	ctx, cancel := context.WithCancelCause(parent)
+	defer cancel(nil)
+
+	go func() {
+		ticker := time.NewTicker(jobHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := heartbeatJob(workerID, job.ID); err != nil {
+					cancel(err)
+					return
+				}
+			}
+		}
+	}()
+
+	err := handler(ctx, job)
+	if cause := context.Cause(ctx); errors.Is(cause, errJobCancelled) || errors.Is(cause, errJobClaimLost) {
+		err = cause
+	}
+	finishJob(workerID, job, err)
+}
+
+// heartbeatJob extends the worker's claim on a job. It returns errJobClaimLost
+// if another worker owns the job and errJobCancelled if cancellation was requested.
+func heartbeatJob(workerID string, jobID uint) error {
+	result := db.Model(&Job{}).
+		Where("id = ? AND locked_by = ? AND status = ?", jobID, workerID, JobStatusRunning).
+		Update("heartbeat_at", time.Now())
+	if result.Error != nil {
+		return nil // Transient; the claim is kept until the heartbeat goes stale
+	}
+	if result.RowsAffected == 0 {
+		return errJobClaimLost
+	}
+	var job Job
+	if err := db.Select("cancel_requested").First(&job, jobID).Error; err == nil && job.CancelRequested {
+		return errJobCancelled
+	}
+	return nil
+}
+
+// finishJob records the outcome of a job run. Failed jobs are retried with a
+// growing delay until they run out of attempts.
+func finishJob(workerID string, job *Job, err error) {
+	if errors.Is(err, errJobClaimLost) {
+		return // The new owner records the outcome
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"updated_at": now, "locked_by": ""}
+
+	var permanent *permanentJobError
+	var reschedule *rescheduleJob
+	switch {
+	case errors.Is(err, errJobCancelled):
+		updates["status"] = JobStatusCancelled
+		updates["completed_at"] = now
+	case errors.As(err, &reschedule):
+		updates["status"] = JobStatusQueued
+		updates["attempts"] = 0
+		updates["run_at"] = now.Add(reschedule.after)
+	case err == nil:
+		updates["status"] = JobStatusSucceeded
+		updates["completed_at"] = now
+	case !errors.As(err, &permanent) && job.Attempts < job.MaxAttempts:
+		updates["status"] = JobStatusQueued
+		updates["last_error"] = err.Error()
+		updates["run_at"] = now.Add(time.Duration(job.Attempts*job.Attempts) * 5 * time.Second)
+	default:
+		updates["status"] = JobStatusFailed
+		updates["last_error"] = err.Error()
+		updates["completed_at"] = now
+	}
+
+	// Only the worker holding the claim may record the outcome
+	db.Model(&Job{}).Where("id = ? AND locked_by = ?", job.ID, workerID).Updates(updates)
+	if err != nil && reschedule == nil {
+		log.Printf("Job %d (%s) for environment %s: %v", job.ID, job.Type, job.EnvironmentID, err)
+	}
+}
+
+// checkpointJob records a completed step so a resumed job can skip it
+func checkpointJob(job *Job, stepID string) error {
+	job.Checkpoint = append(job.Checkpoint, stepID)
+	encoded, err := json.Marshal(job.Checkpoint)
+	if err != nil {
+		return err
+	}
+	return db.Model(&Job{}).Where("id = ?", job.ID).Update("checkpoint", string(encoded)).Error
+}
+
+// ErrJobFinished is returned when cancelling a job that is no longer queued or running
+var ErrJobFinished = errors.New("job has already finished")
+
+// requestJobCancellation cancels a queued job immediately, or asks the worker
+// running it to stop at its next heartbeat
+func requestJobCancellation(id uint) (*Job, error) {
+	var job Job
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&job, id).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		switch job.Status {
+		case JobStatusQueued:
+			job.Status = JobStatusCancelled
+			job.CompletedAt = &now
+			return tx.Model(&Job{}).Where("id = ?", id).Updates(map[string]interface{}{
+				"status":       JobStatusCancelled,
+				"completed_at": now,
+				"updated_at":   now,
+			}).Error
+		case JobStatusRunning:
+			job.CancelRequested = true
+			return tx.Model(&Job{}).Where("id = ?", id).Updates(map[string]interface{}{
+				"cancel_requested": true,
+				"updated_at":       now,
+			}).Error
+		default:
+			return fmt.Errorf("job %d is %s: %w", id, job.Status, ErrJobFinished)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func init() {
+	jobHandlers[JobTypeProvision] = runProvisionJob
+	jobHandlers[JobTypeTrackUptime] = runTrackUptimeJob
+}
+
+// runProvisionJob executes the environment's plan, resuming after any steps
+// checkpointed by an earlier attempt
+func runProvisionJob(ctx context.Context, job *Job) error {
+	planID, _ := job.Payload["plan_id"].(float64)
+	var plan ExecutionPlan
+	if err := db.First(&plan, uint(planID)).Error; err != nil {
+		return &permanentJobError{fmt.Errorf("plan %v not found: %v", job.Payload["plan_id"], err)}
+	}
+	var env Environment
+	if err := db.First(&env, "id = ?", job.EnvironmentID).Error; err != nil {
+		return &permanentJobError{fmt.Errorf("environment not found: %v", err)}
+	}
+	if env.Status != StateProvisioning {
+		return &permanentJobError{fmt.Errorf("environment is %s, not provisioning", env.Status)}
+	}
+	return executePlan(ctx, job, env, &plan)
+}
+
+// runTrackUptimeJob updates uptime and cost of a running environment once a
+// minute until it stops running
+func runTrackUptimeJob(ctx context.Context, job *Job) error {
+	startTime := job.CreatedAt
+	if started, ok := job.Payload["started_at"].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339, started); err == nil {
+			startTime = parsed
+		}
+	}
+	if !updateUptime(job.EnvironmentID, startTime) {
+		return nil
+	}
+	return &rescheduleJob{after: time.Minute}
+}
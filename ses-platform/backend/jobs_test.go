@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRunConn is a connection pool that is never used: in dry run mode GORM
+// builds statements without executing them, so queries find no rows and
+// updates affect none
+type dryRunConn struct{}
+
+func (*dryRunConn) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConn) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConn) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunConn) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func (c *dryRunConn) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) { return c, nil }
+func (*dryRunConn) Commit() error                                                    { return nil }
+func (*dryRunConn) Rollback() error                                                  { return nil }
+
+// useDryRunDB points db at a dry run database for the test and returns the
+// statements run against it
+func useDryRunDB(t *testing.T) *[]string {
+	t.Helper()
+	dryRun, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunConn{}}), &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	var statements []string
+	record := func(tx *gorm.DB) {
+		statements = append(statements, tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...))
+	}
+	callbacks := dryRun.Callback()
+	callbacks.Create().After("gorm:create").Register("test:record", record)
+	callbacks.Query().After("gorm:query").Register("test:record", record)
+	callbacks.Update().After("gorm:update").Register("test:record", record)
+	callbacks.Delete().After("gorm:delete").Register("test:record", record)
+
+	previous := db
+	db = dryRun
+	t.Cleanup(func() { db = previous })
+	return &statements
+}
+
+// containsAll reports whether s contains every substring
+func containsAll(s string, substrings ...string) bool {
+	for _, substring := range substrings {
+		if !strings.Contains(s, substring) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestClaimJob(t *testing.T) {
+	statements := useDryRunDB(t)
+	job, err := claimJob("worker-1")
+	if err != nil {
+		t.Fatalf("claimJob: %v", err)
+	}
+	if job.Status != JobStatusRunning || job.Attempts != 1 || job.LockedBy != "worker-1" || job.HeartbeatAt == nil {
+		t.Errorf("claimJob = %+v, want a running job locked by worker-1 on its first attempt", job)
+	}
+
+	if len(*statements) != 2 {
+		t.Fatalf("claimJob ran %q, want a select and an update", *statements)
+	}
+	if claim := (*statements)[0]; !containsAll(claim,
+		"(status = 'queued' AND run_at <= ", "OR (status = 'running' AND heartbeat_at < ",
+		"ORDER BY run_at, id", "FOR UPDATE SKIP LOCKED") {
+		t.Errorf("claim query = %s, want due queued or stale running jobs, oldest first, skipping locked rows", claim)
+	}
+	if update := (*statements)[1]; !containsAll(update, `"status"='running'`, `"attempts"=1`, `"locked_by"='worker-1'`) {
+		t.Errorf("claim update = %s, want the job running, locked by worker-1", update)
+	}
+}
+
+func TestHeartbeatJob(t *testing.T) {
+	statements := useDryRunDB(t)
+	// No row is updated in a dry run, as when another worker holds the job
+	if err := heartbeatJob("worker-1", 7); !errors.Is(err, errJobClaimLost) {
+		t.Errorf("heartbeatJob = %v, want %v", err, errJobClaimLost)
+	}
+	if len(*statements) != 1 || !containsAll((*statements)[0], "heartbeat_at", "id = 7 AND locked_by = 'worker-1' AND status = 'running'") {
+		t.Errorf("heartbeatJob ran %q, want an update of the worker's running job", *statements)
+	}
+}
+
+func TestRunJobStopsWhenHeartbeatFails(t *testing.T) {
+	useDryRunDB(t)
+	interval := jobHeartbeatInterval
+	jobHeartbeatInterval = 10 * time.Millisecond
+	t.Cleanup(func() { jobHeartbeatInterval = interval })
+
+	var cause error
+	jobHandlers["test"] = func(ctx context.Context, job *Job) error {
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { delete(jobHandlers, "test") })
+
+	done := make(chan struct{})
+	go func() {
+		runJob(context.Background(), "worker-1", &Job{ID: 7, Type: "test"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runJob kept running after losing its claim")
+	}
+	if !errors.Is(cause, errJobClaimLost) {
+		t.Errorf("handler context cause = %v, want %v", cause, errJobClaimLost)
+	}
+}
+
+func TestFinishJob(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		want     []string // Substrings of the update
+		none     bool     // Whether no update is made
+	}{
+		{
+			name:     "succeeded",
+			attempts: 1,
+			want:     []string{`"status"='succeeded'`, `"completed_at"=`},
+		},
+		{
+			name:     "retried while attempts remain",
+			attempts: 1,
+			err:      errors.New("timeout"),
+			want:     []string{`"status"='queued'`, `"last_error"='timeout'`, `"run_at"=`},
+		},
+		{
+			name:     "failed after the last attempt",
+			attempts: 3,
+			err:      errors.New("timeout"),
+			want:     []string{`"status"='failed'`, `"last_error"='timeout'`},
+		},
+		{
+			name:     "permanent errors are not retried",
+			attempts: 1,
+			err:      &permanentJobError{errors.New("plan not found")},
+			want:     []string{`"status"='failed'`, `"last_error"='plan not found'`},
+		},
+		{
+			name:     "cancelled",
+			attempts: 1,
+			err:      errJobCancelled,
+			want:     []string{`"status"='cancelled'`},
+		},
+		{
+			name:     "rescheduled without using up attempts",
+			attempts: 3,
+			err:      &rescheduleJob{after: time.Minute},
+			want:     []string{`"status"='queued'`, `"attempts"=0`},
+		},
+		{
+			name:     "claim lost",
+			attempts: 1,
+			err:      errJobClaimLost,
+			none:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements := useDryRunDB(t)
+			finishJob("worker-1", &Job{ID: 7, Type: "test", Attempts: tt.attempts, MaxAttempts: 3}, tt.err)
+			if tt.none {
+				if len(*statements) != 0 {
+					t.Errorf("finishJob ran %q, want nothing", *statements)
+				}
+				return
+			}
+			if len(*statements) != 1 {
+				t.Fatalf("finishJob ran %q, want one update", *statements)
+			}
+			update := (*statements)[0]
+			if !containsAll(update, tt.want...) || !strings.Contains(update, "id = 7 AND locked_by = 'worker-1'") {
+				t.Errorf("finishJob update = %s, want %q for the worker's job", update, tt.want)
+			}
+		})
+	}
+}
@@ -1,25 +1,34 @@
 package main
 
 import (
+	"context"
+	cryptorand "crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+
+	"ses-platform/graph"
+	"ses-platform/spec"
 )
 
 // Database Models
 type Capability struct {
-	ID           string   `gorm:"primaryKey" json:"id"`
-	Name         string   `json:"name"`
-	Description  string   `json:"description"`
-	Enablers     string   `json:"enablers"` // JSON array stored as string
-	Dependencies string   `json:"dependencies"` // JSON array stored as string
+	ID           string    `gorm:"primaryKey" json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	Enablers     []string  `gorm:"type:jsonb;serializer:json" json:"enablers"`
+	Dependencies []string  `gorm:"type:jsonb;serializer:json" json:"dependencies"`
 	CreatedAt    time.Time `json:"created_at"`
 }
 
@@ -31,46 +40,50 @@ type Enabler struct {
 }
 
 type Environment struct {
-	ID                 string    `gorm:"primaryKey" json:"id"`
-	Name               string    `json:"name"`
-	Description        string    `json:"description"`
-	Owner              string    `json:"owner"`
-	Tags               string    `json:"tags"`
-	Status             string    `json:"status"` // provisioning, running, stopped, error
-	Capabilities       string    `json:"capabilities"` // JSON array
-	EnablersConfig     string    `json:"enablers_config"` // JSON object
-	ComputeConfig      string    `json:"compute_config"` // JSON object
-	Storage            int       `json:"storage"`
-	Network            string    `json:"network"`
-	Priority           string    `json:"priority"`
-	Duration           int       `json:"duration"`
-	EstimatedCost      float64   `json:"estimated_cost"`
-	ActualCost         float64   `json:"actual_cost"`
-	Health             int       `json:"health"`
-	Uptime             string    `json:"uptime"`
-	FleetWiseConfig    string    `json:"fleetwise_config"` // JSON object for AWS FleetWise configuration
-	UseRealAWSBackend  bool      `json:"use_real_aws_backend"` // Flag to use real AWS instead of simulation
-	CreatedAt          time.Time `json:"created_at"`
-	UpdatedAt          time.Time `json:"updated_at"`
+	ID                string                 `gorm:"primaryKey" json:"id"`
+	Name              string                 `json:"name"`
+	Description       string                 `json:"description"`
+	Owner             string                 `json:"owner"`
+	Tags              string                 `json:"tags"`
+	Status            string                 `json:"status"` // See state_machine.go for the lifecycle states
+	Capabilities      []string               `gorm:"type:jsonb;serializer:json" json:"capabilities"`
+	EnablersConfig    map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"enablers_config"`
+	ComputeConfig     ComputeConfig          `gorm:"type:jsonb;serializer:json" json:"compute_config"`
+	Storage           int                    `json:"storage"`
+	Network           string                 `json:"network"`
+	Priority          string                 `json:"priority"`
+	Duration          int                    `json:"duration"`
+	EstimatedCost     float64                `json:"estimated_cost"`
+	ActualCost        float64                `json:"actual_cost"`
+	Health            int                    `json:"health"`
+	Uptime            string                 `json:"uptime"`
+	FleetWiseConfig   *FleetWiseConfig       `gorm:"type:jsonb;serializer:json" json:"fleetwise_config,omitempty"`
+	Provider          string                 `json:"provider"`                                         // Selects the ProviderAdapter, see provider.go
+	UseRealAWSBackend bool                   `json:"use_real_aws_backend"`                             // Deprecated: use Provider "aws"
+	Spec              *spec.Document         `gorm:"type:jsonb;serializer:json" json:"spec,omitempty"` // SES document the environment was created from
+	SpecHash          string                 `json:"spec_hash,omitempty"`                              // SHA-256 of the canonical SES document
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt         `gorm:"index" json:"deleted_at,omitempty"` // Set once a deleted environment is cleaned
 }
 
 type StateTransition struct {
-	ID            uint      `gorm:"primaryKey" json:"id"`
-	EnvironmentID string    `json:"environment_id"`
-	FromState     string    `json:"from_state"`
-	ToState       string    `json:"to_state"`
-	Reason        string    `json:"reason"`
-	Metadata      string    `json:"metadata"` // JSON object
-	CreatedAt     time.Time `json:"created_at"`
+	ID            uint                   `gorm:"primaryKey" json:"id"`
+	EnvironmentID string                 `json:"environment_id"`
+	FromState     string                 `json:"from_state"`
+	ToState       string                 `json:"to_state"`
+	Reason        string                 `json:"reason"`
+	Metadata      map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"metadata"`
+	CreatedAt     time.Time              `json:"created_at"`
 }
 
 type AuditLog struct {
-	ID            uint      `gorm:"primaryKey" json:"id"`
-	EnvironmentID string    `json:"environment_id"`
-	Action        string    `json:"action"`
-	UserID        string    `json:"user_id"`
-	Details       string    `json:"details"` // JSON object
-	CreatedAt     time.Time `json:"created_at"`
+	ID            uint                   `gorm:"primaryKey" json:"id"`
+	EnvironmentID string                 `json:"environment_id"`
+	Action        string                 `json:"action"`
+	UserID        string                 `json:"user_id"`
+	Details       map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"details"`
+	CreatedAt     time.Time              `json:"created_at"`
 }
 
 type Upload struct {
@@ -108,6 +121,7 @@ type CreateEnvironmentRequest struct {
 	Priority          string                 `json:"priority"`
 	Duration          int                    `json:"duration"`
 	FleetWiseConfig   *FleetWiseConfig       `json:"fleetwise_config,omitempty"`
+	Provider          string                 `json:"provider"`
 	UseRealAWSBackend bool                   `json:"use_real_aws_backend"`
 }
 
@@ -117,10 +131,23 @@ type ComputeConfig struct {
 	Instances int `json:"instances"`
 }
 
+type ValidateSpecRequest struct {
+	CreateEnvironmentRequest
+	Spec *spec.Document `json:"spec,omitempty"` // Optional SES document for component-level checks
+}
+
 type ValidationResponse struct {
-	Valid    bool     `json:"valid"`
-	Errors   []string `json:"errors,omitempty"`
-	Warnings []string `json:"warnings,omitempty"`
+	Valid       bool                   `json:"valid"`
+	Errors      []string               `json:"errors,omitempty"`
+	Warnings    []string               `json:"warnings,omitempty"`
+	Suggestions []ValidationSuggestion `json:"suggestions,omitempty"`
+}
+
+// ValidationSuggestion is an automatic fix a client can apply to the request
+type ValidationSuggestion struct {
+	Action       string   `json:"action"` // add_capabilities
+	Capabilities []string `json:"capabilities,omitempty"`
+	Message      string   `json:"message"`
 }
 
 type CostEstimationResponse struct {
@@ -140,6 +167,20 @@ func main() {
 	// Seed initial data
 	seedData()
 
+	// Serve FleetWise from memory instead of AWS, e.g. in CI
+	if os.Getenv("FLEETWISE_EMULATOR") == "true" {
+		if err := startFleetWiseEmulator(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fleetWiseClients = NewFleetWiseClients(newAWSFleetWiseAPI)
+
+	// Process queued jobs, including those left behind by a previous process
+	startJobWorkers(context.Background())
+	if err := enqueueDriftReconciliation(); err != nil {
+		log.Printf("Failed to schedule drift reconciliation: %v", err)
+	}
+
 	// Initialize Gin router
 	router := gin.Default()
 
@@ -171,13 +212,24 @@ func main() {
 
 		// Environment Operations
 		v1.POST("/environments/:id/provision", provisionEnvironment)
+		v1.GET("/environments/:id/plan", getEnvironmentPlan)
+		v1.POST("/environments/:id/plan", createEnvironmentPlan)
+		v1.POST("/environments/:id/plan/approve", approveEnvironmentPlan)
 		v1.POST("/environments/:id/start", startEnvironment)
 		v1.POST("/environments/:id/stop", stopEnvironment)
+		v1.POST("/environments/:id/complete", completeEnvironment)
+		v1.POST("/environments/:id/campaigns/approve", approveEnvironmentCampaigns)
+		v1.POST("/environments/:id/rollback", rollbackEnvironment)
+		v1.GET("/environments/:id/rollbacks", getEnvironmentRollbacks)
+		v1.GET("/environments/:id/resources", getEnvironmentResources)
 		v1.POST("/environments/:id/upload", uploadArtifact)
 		v1.GET("/environments/:id/status", getEnvironmentStatus)
 		v1.GET("/environments/:id/metrics", getEnvironmentMetrics)
 		v1.GET("/environments/:id/logs", getEnvironmentLogs)
 
+		// SES Documents
+		v1.POST("/specs", createSpec)
+
 		// Validation and Cost
 		v1.POST("/validate", validateSpec)
 		v1.POST("/cost/estimate", estimateCost)
@@ -189,6 +241,18 @@ func main() {
 		v1.GET("/audit", getAuditLogs)
 		v1.GET("/environments/:id/history", getEnvironmentHistory)
 
+		// Errors
+		v1.GET("/errors", listErrors)
+
+		// Drift
+		v1.GET("/drift", getDriftReport)
+		v1.POST("/drift/reconcile", reconcileDriftNow)
+
+		// Background Jobs
+		v1.GET("/jobs", listJobs)
+		v1.GET("/jobs/:id", getJob)
+		v1.POST("/jobs/:id/cancel", cancelJob)
+
 		// AWS FleetWise Operations
 		v1.POST("/fleetwise/vehicles", createFleetWiseVehicle)
 		v1.POST("/fleetwise/vehicles/batch", batchCreateFleetWiseVehicles)
@@ -196,6 +260,7 @@ func main() {
 		v1.PUT("/fleetwise/vehicles/:name", updateFleetWiseVehicle)
 		v1.DELETE("/fleetwise/vehicles/:name", deleteFleetWiseVehicle)
 		v1.GET("/fleetwise/vehicles/:name/status", getFleetWiseVehicleStatus)
+		v1.GET("/fleetwise/vehicles/:name/fleets", listVehicleFleets)
 		v1.GET("/fleetwise/vehicles", listFleetWiseVehicles)
 
 		v1.POST("/fleetwise/campaigns", createFleetWiseCampaign)
@@ -203,9 +268,38 @@ func main() {
 		v1.PUT("/fleetwise/campaigns/:name", updateFleetWiseCampaign)
 		v1.DELETE("/fleetwise/campaigns/:name", deleteFleetWiseCampaign)
 		v1.GET("/fleetwise/campaigns", listFleetWiseCampaigns)
+		v1.POST("/fleetwise/campaigns/validate-expression", validateCampaignExpression)
+		v1.POST("/fleetwise/campaigns/dry-run", dryRunCampaignExpression)
 
 		v1.POST("/fleetwise/fleets", createFleetWiseFleet)
+		v1.GET("/fleetwise/fleets", listFleetWiseFleets)
+		v1.GET("/fleetwise/fleets/:id", getFleetWiseFleet)
+		v1.DELETE("/fleetwise/fleets/:id", deleteFleetWiseFleet)
+		v1.GET("/fleetwise/fleets/:id/vehicles", listFleetVehicles)
 		v1.POST("/fleetwise/fleets/:id/vehicles", associateVehicleToFleet)
+		v1.POST("/fleetwise/fleets/:id/vehicles/batch", batchAssociateVehiclesToFleet)
+		v1.DELETE("/fleetwise/fleets/:id/vehicles/:name", disassociateVehicleFromFleet)
+
+		v1.POST("/fleetwise/signal-catalogs", createSignalCatalog)
+		v1.GET("/fleetwise/signal-catalogs", listSignalCatalogs)
+		v1.GET("/fleetwise/signal-catalogs/:name", getSignalCatalog)
+		v1.PUT("/fleetwise/signal-catalogs/:name", updateSignalCatalog)
+		v1.DELETE("/fleetwise/signal-catalogs/:name", deleteSignalCatalog)
+		v1.POST("/fleetwise/signal-catalogs/:name/diff", diffSignalCatalog)
+
+		v1.POST("/fleetwise/model-manifests", createModelManifest)
+		v1.GET("/fleetwise/model-manifests", listModelManifests)
+		v1.GET("/fleetwise/model-manifests/:name", getModelManifest)
+		v1.PUT("/fleetwise/model-manifests/:name", updateModelManifest)
+		v1.DELETE("/fleetwise/model-manifests/:name", deleteModelManifest)
+		v1.POST("/fleetwise/model-manifests/:name/activate", activateModelManifest)
+
+		v1.POST("/fleetwise/decoder-manifests", createDecoderManifest)
+		v1.GET("/fleetwise/decoder-manifests", listDecoderManifests)
+		v1.GET("/fleetwise/decoder-manifests/:name", getDecoderManifest)
+		v1.PUT("/fleetwise/decoder-manifests/:name", updateDecoderManifest)
+		v1.DELETE("/fleetwise/decoder-manifests/:name", deleteDecoderManifest)
+		v1.POST("/fleetwise/decoder-manifests/:name/activate", activateDecoderManifest)
 	}
 
 	// Start server
@@ -230,21 +324,27 @@ func initDB() {
 		&AuditLog{},
 		&Upload{},
 		&Reservation{},
+		&ExecutionPlan{},
+		&Job{},
+		&RollbackOperation{},
+		&ErrorRecord{},
+		&ResourceAllocation{},
+		&DriftRecord{},
 	)
 }
 
 func seedData() {
 	// Seed Capabilities
 	capabilities := []Capability{
-		{ID: "C01", Name: "Spec Authoring & Validation", Description: "Web editor and services to author SES", Enablers: `["E02","E17","E11","E20"]`, Dependencies: `[]`},
-		{ID: "C02", Name: "Parsing & Internal Modeling", Description: "Convert validated SES into normalized models", Enablers: `["E03","E04","E17","E01"]`, Dependencies: `["C01"]`},
-		{ID: "C03", Name: "Planning Engine", Description: "Generates execution plan", Enablers: `["E03","E04","E08","E16"]`, Dependencies: `["C01","C02"]`},
-		{ID: "C04", Name: "Provisioning Automation", Description: "Executes plan to create infrastructure", Enablers: `["E05","E04","E01","E18","E20"]`, Dependencies: `["C03","C09"]`},
-		{ID: "C06", Name: "Monitoring & Metrics", Description: "Collects infrastructure metrics", Enablers: `["E06","E07","E11","E19"]`, Dependencies: `["C04"]`},
-		{ID: "C08", Name: "Cost Management", Description: "Real-time cost tracking", Enablers: `["E08","E16","E06","E11"]`, Dependencies: `["C06"]`},
-		{ID: "C09", Name: "Security & Compliance", Description: "Credential vault, RBAC", Enablers: `["E09","E10","E19","E01"]`, Dependencies: `[]`},
-		{ID: "C12", Name: "Simulation Execution", Description: "Runs scenarios", Enablers: `["E12","E13","E04","E06","E20"]`, Dependencies: `["C04"]`},
-		{ID: "C19", Name: "AWS Automotive Integration", Description: "Real AWS IoT FleetWise integration for vehicle simulation", Enablers: `["E05","E21","E01","E18","E20"]`, Dependencies: `["C04","C09","C14"]`},
+		{ID: "C01", Name: "Spec Authoring & Validation", Description: "Web editor and services to author SES", Enablers: []string{"E02", "E17", "E11", "E20"}, Dependencies: []string{}},
+		{ID: "C02", Name: "Parsing & Internal Modeling", Description: "Convert validated SES into normalized models", Enablers: []string{"E03", "E04", "E17", "E01"}, Dependencies: []string{"C01"}},
+		{ID: "C03", Name: "Planning Engine", Description: "Generates execution plan", Enablers: []string{"E03", "E04", "E08", "E16"}, Dependencies: []string{"C01", "C02"}},
+		{ID: "C04", Name: "Provisioning Automation", Description: "Executes plan to create infrastructure", Enablers: []string{"E05", "E04", "E01", "E18", "E20"}, Dependencies: []string{"C03", "C09"}},
+		{ID: "C06", Name: "Monitoring & Metrics", Description: "Collects infrastructure metrics", Enablers: []string{"E06", "E07", "E11", "E19"}, Dependencies: []string{"C04"}},
+		{ID: "C08", Name: "Cost Management", Description: "Real-time cost tracking", Enablers: []string{"E08", "E16", "E06", "E11"}, Dependencies: []string{"C06"}},
+		{ID: "C09", Name: "Security & Compliance", Description: "Credential vault, RBAC", Enablers: []string{"E09", "E10", "E19", "E01"}, Dependencies: []string{}},
+		{ID: "C12", Name: "Simulation Execution", Description: "Runs scenarios", Enablers: []string{"E12", "E13", "E04", "E06", "E20"}, Dependencies: []string{"C04"}},
+		{ID: "C19", Name: "AWS Automotive Integration", Description: "Real AWS IoT FleetWise integration for vehicle simulation", Enablers: []string{"E05", "E21", "E01", "E18", "E20"}, Dependencies: []string{"C04", "C09", "C14"}},
 	}
 	for _, cap := range capabilities {
 		db.FirstOrCreate(&cap, Capability{ID: cap.ID})
@@ -285,6 +385,16 @@ func listEnvironments(c *gin.Context) {
 	c.JSON(http.StatusOK, environments)
 }
 
+// newEnvironmentID returns env-<unix time>_<random hex>. The random part keeps
+// environments created in the same second apart.
+func newEnvironmentID() string {
+	suffix := make([]byte, 4)
+	if _, err := cryptorand.Read(suffix); err != nil {
+		return fmt.Sprintf("env-%d_%x", time.Now().Unix(), time.Now().UnixNano())
+	}
+	return fmt.Sprintf("env-%d_%x", time.Now().Unix(), suffix)
+}
+
 func createEnvironment(c *gin.Context) {
 	var req CreateEnvironmentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -295,28 +405,31 @@ func createEnvironment(c *gin.Context) {
 	// Calculate estimated cost
 	estimatedCost := calculateCost(req.Compute, req.Storage, len(req.Capabilities))
 
-	// Serialize data
-	capabilitiesJSON, _ := json.Marshal(req.Capabilities)
-	enablersJSON, _ := json.Marshal(req.EnablersConfig)
-	computeJSON, _ := json.Marshal(req.Compute)
+	// JSONB columns are NOT NULL, so store empty values rather than null
+	capabilities := req.Capabilities
+	if capabilities == nil {
+		capabilities = []string{}
+	}
+	enablersConfig := req.EnablersConfig
+	if enablersConfig == nil {
+		enablersConfig = map[string]interface{}{}
+	}
 
-	// Serialize FleetWise config if provided
-	fleetwiseConfigJSON := ""
-	if req.FleetWiseConfig != nil {
-		fwJSON, _ := json.Marshal(req.FleetWiseConfig)
-		fleetwiseConfigJSON = string(fwJSON)
+	provider := req.Provider
+	if provider == "" {
+		provider = providerName(Environment{UseRealAWSBackend: req.UseRealAWSBackend})
 	}
 
 	env := Environment{
-		ID:                fmt.Sprintf("env-%d", time.Now().Unix()),
+		ID:                newEnvironmentID(),
 		Name:              req.Name,
 		Description:       req.Description,
 		Owner:             req.Owner,
 		Tags:              req.Tags,
-		Status:            "pending",
-		Capabilities:      string(capabilitiesJSON),
-		EnablersConfig:    string(enablersJSON),
-		ComputeConfig:     string(computeJSON),
+		Status:            StatePending,
+		Capabilities:      capabilities,
+		EnablersConfig:    enablersConfig,
+		ComputeConfig:     req.Compute,
 		Storage:           req.Storage,
 		Network:           req.Network,
 		Priority:          req.Priority,
@@ -325,8 +438,9 @@ func createEnvironment(c *gin.Context) {
 		ActualCost:        0,
 		Health:            100,
 		Uptime:            "0h",
-		FleetWiseConfig:   fleetwiseConfigJSON,
-		UseRealAWSBackend: req.UseRealAWSBackend,
+		FleetWiseConfig:   req.FleetWiseConfig,
+		Provider:          provider,
+		UseRealAWSBackend: provider == "aws",
 		CreatedAt:         time.Now(),
 		UpdatedAt:         time.Now(),
 	}
@@ -341,14 +455,18 @@ func createEnvironment(c *gin.Context) {
 		EnvironmentID: env.ID,
 		Action:        "created",
 		UserID:        req.Owner,
-		Details:       `{"message":"Environment created"}`,
+		Details:       map[string]interface{}{"message": "Environment created"},
 		CreatedAt:     time.Now(),
 	}
 	db.Create(&auditLog)
 
-	// Simulate provisioning in background
-	go simulateProvisioning(env.ID)
+	// The environment is planned here and provisioned through the job queue
+	// once a reviewer approves its plan
+	if _, err := startProvisioning(env); err != nil && !errors.Is(err, ErrPlanAwaitingApproval) {
+		log.Printf("Failed to start provisioning for environment %s: %v", env.ID, err)
+	}
 
+	db.First(&env, "id = ?", env.ID)
 	c.JSON(http.StatusCreated, env)
 }
 
@@ -362,6 +480,16 @@ func getEnvironment(c *gin.Context) {
 	c.JSON(http.StatusOK, env)
 }
 
+// updatableColumns are the environment fields PUT /environments/:id accepts
+var updatableColumns = []string{
+	"name", "description", "owner", "tags", "capabilities", "enablers_config", "compute_config",
+	"storage", "network", "priority", "duration", "fleetwise_config", "spec",
+}
+
+// plannedColumns change what provisioning creates, so they can only be updated
+// while the environment can still be planned
+var plannedColumns = []string{"compute_config", "fleetwise_config", "spec"}
+
 func updateEnvironment(c *gin.Context) {
 	id := c.Param("id")
 	var env Environment
@@ -376,32 +504,127 @@ func updateEnvironment(c *gin.Context) {
 		return
 	}
 
+	// Only descriptive and configuration fields can be updated. Status only
+	// changes through the lifecycle endpoints and the state machine.
+	replanned := false
+	for key := range updates {
+		if key == "status" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status cannot be updated directly; use the lifecycle endpoints"})
+			return
+		}
+		if !slices.Contains(updatableColumns, key) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s cannot be updated; updatable fields are %s", key, strings.Join(updatableColumns, ", "))})
+			return
+		}
+		if slices.Contains(plannedColumns, key) {
+			if !slices.Contains(plannableStates, env.Status) {
+				c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%s cannot be updated while the environment is %s", key, env.Status), "status": env.Status})
+				return
+			}
+			replanned = true
+		}
+	}
+
+	// A new spec goes through the same parsing, validation and hashing as
+	// POST /specs, so a changed spec needs a new plan
+	if value, ok := updates["spec"]; ok {
+		doc, hash, err := parseSpecUpdate(value)
+		if err != nil {
+			var validationErr *spec.ValidationError
+			if errors.As(err, &validationErr) {
+				c.JSON(http.StatusBadRequest, ValidationResponse{Valid: false, Errors: validationErr.Errors})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["spec"] = doc
+		updates["spec_hash"] = hash
+	}
+
+	// Map updates bypass GORM serializers, so encode JSONB fields explicitly
+	for _, column := range []string{"capabilities", "enablers_config", "compute_config", "fleetwise_config", "spec"} {
+		if value, ok := updates[column]; ok {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			updates[column] = string(encoded)
+		}
+	}
+
 	updates["updated_at"] = time.Now()
-	db.Model(&env).Updates(updates)
+	query := db.Model(&env)
+	if replanned {
+		// Provisioning may have started since the environment was read
+		query = query.Where("status IN ?", plannableStates)
+	}
+	result := query.Updates(updates)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if replanned && result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": ErrConcurrentTransition.Error()})
+		return
+	}
 
+	db.First(&env, "id = ?", id)
 	c.JSON(http.StatusOK, env)
 }
 
+// parseSpecUpdate parses the spec field of an environment update and returns
+// the document and its hash
+func parseSpecUpdate(value interface{}) (*spec.Document, string, error) {
+	if value == nil {
+		return nil, "", errors.New("spec cannot be removed")
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, "", err
+	}
+	doc, err := spec.Parse(data)
+	if err != nil {
+		return nil, "", err
+	}
+	hash, err := spec.Hash(doc)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to hash specification: %v", err)
+	}
+	return doc, hash, nil
+}
+
+// deleteEnvironment starts an asynchronous teardown: deleting -> releasing ->
+// cleaned. With force=true any environment can be deleted, and it is cleaned
+// even if some of its resources cannot be released.
 func deleteEnvironment(c *gin.Context) {
 	id := c.Param("id")
+	force := c.Query("force") == "true"
+
 	var env Environment
 	if err := db.First(&env, "id = ?", id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Environment not found"})
 		return
 	}
 
-	// Create state transition
-	transition := StateTransition{
-		EnvironmentID: id,
-		FromState:     env.Status,
-		ToState:       "deleted",
-		Reason:        "User requested deletion",
-		CreatedAt:     time.Now(),
+	var job *Job
+	var err error
+	if env.Status == StateDeleting || env.Status == StateReleasing {
+		job, err = retryDeletion(id, force)
+	} else {
+		job, err = startDeletion(id, force)
+	}
+	if errors.Is(err, ErrRollbackInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error() + "; wait for it to finish or delete with force=true"})
+		return
+	}
+	if err != nil {
+		respondTransitionError(c, err)
+		return
 	}
-	db.Create(&transition)
 
-	db.Delete(&env)
-	c.JSON(http.StatusOK, gin.H{"message": "Environment deleted"})
+	c.JSON(http.StatusAccepted, gin.H{"message": "Environment deletion started", "job_id": job.ID, "force": force})
 }
 
 func provisionEnvironment(c *gin.Context) {
@@ -412,71 +635,46 @@ func provisionEnvironment(c *gin.Context) {
 		return
 	}
 
-	if env.Status != "pending" && env.Status != "stopped" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Environment cannot be provisioned in current state"})
+	// Provisioning always executes a reviewed plan
+	plan, err := startProvisioning(env)
+	if err != nil {
+		respondPlanningError(c, err)
 		return
 	}
 
-	// Update status
-	db.Model(&env).Updates(map[string]interface{}{
-		"status":     "provisioning",
-		"updated_at": time.Now(),
-	})
-
-	// Create state transition
-	transition := StateTransition{
-		EnvironmentID: id,
-		FromState:     env.Status,
-		ToState:       "provisioning",
-		Reason:        "Provisioning initiated",
-		CreatedAt:     time.Now(),
-	}
-	db.Create(&transition)
-
-	// Use real AWS or simulate provisioning based on flag
-	if env.UseRealAWSBackend && env.FleetWiseConfig != "" {
-		go provisionAWSFleetWise(id, env.FleetWiseConfig)
-	} else {
-		go simulateProvisioning(id)
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Provisioning started"})
+	c.JSON(http.StatusOK, gin.H{"message": "Provisioning started", "plan_id": plan.ID})
 }
 
 func startEnvironment(c *gin.Context) {
 	id := c.Param("id")
-	updateEnvironmentStatus(id, "running", c)
+	updateEnvironmentStatus(id, StateRunning, inTxAll(enqueueUptimeTracking(id), enqueueCampaignLifecycle(id)), c)
 }
 
 func stopEnvironment(c *gin.Context) {
 	id := c.Param("id")
-	updateEnvironmentStatus(id, "stopped", c)
+	updateEnvironmentStatus(id, StateReady, enqueueCampaignLifecycle(id), c)
 }
 
-func updateEnvironmentStatus(id, newStatus string, c *gin.Context) {
-	var env Environment
-	if err := db.First(&env, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Environment not found"})
-		return
-	}
+// completeEnvironment ends the execution of a running environment. Its
+// campaigns are suspended and it keeps its resources until it is deleted.
+func completeEnvironment(c *gin.Context) {
+	id := c.Param("id")
+	updateEnvironmentStatus(id, StateCompleted, enqueueCampaignLifecycle(id), c)
+}
 
-	oldStatus := env.Status
-	db.Model(&env).Updates(map[string]interface{}{
-		"status":     newStatus,
-		"updated_at": time.Now(),
+func updateEnvironmentStatus(id, newStatus string, inTx func(tx *gorm.DB) error, c *gin.Context) bool {
+	_, err := transitionEnvironment(id, Transition{
+		To:     newStatus,
+		Reason: fmt.Sprintf("Status changed to %s", newStatus),
+		InTx:   inTx,
 	})
-
-	// State transition
-	transition := StateTransition{
-		EnvironmentID: id,
-		FromState:     oldStatus,
-		ToState:       newStatus,
-		Reason:        fmt.Sprintf("Status changed to %s", newStatus),
-		CreatedAt:     time.Now(),
+	if err != nil {
+		respondTransitionError(c, err)
+		return false
 	}
-	db.Create(&transition)
 
 	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Environment %s", newStatus)})
+	return true
 }
 
 func uploadArtifact(c *gin.Context) {
@@ -514,27 +712,54 @@ func getEnvironmentStatus(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"id":      env.ID,
-		"status":  env.Status,
-		"health":  env.Health,
-		"uptime":  env.Uptime,
-		"cost":    env.ActualCost,
-	})
+	response := gin.H{
+		"id":       env.ID,
+		"status":   env.Status,
+		"terminal": IsTerminalState(env.Status),
+		"health":   env.Health,
+		"uptime":   env.Uptime,
+		"cost":     env.ActualCost,
+	}
+
+	// Campaign statuses as last polled by the campaign lifecycle job
+	campaigns, err := campaignStatuses(env.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(campaigns) > 0 {
+		response["campaigns"] = campaigns
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 func getEnvironmentMetrics(c *gin.Context) {
 	id := c.Param("id")
+	var env Environment
+	if err := db.First(&env, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Environment not found"})
+		return
+	}
+
+	adapter, err := providerFor(env)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	providerMetrics, err := adapter.GetMetrics(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Simulate metrics data
 	metrics := gin.H{
 		"environment_id": id,
+		"provider":       providerName(env),
 		"timestamp":      time.Now(),
-		"cpu_usage":      rand.Float64() * 100,
-		"memory_usage":   rand.Float64() * 100,
-		"disk_usage":     rand.Float64() * 100,
-		"network_in":     rand.Float64() * 1000,
-		"network_out":    rand.Float64() * 1000,
+	}
+	for name, value := range providerMetrics {
+		metrics[name] = value
 	}
 
 	c.JSON(http.StatusOK, metrics)
@@ -564,7 +789,7 @@ func getEnvironmentLogs(c *gin.Context) {
 }
 
 func validateSpec(c *gin.Context) {
-	var req CreateEnvironmentRequest
+	var req ValidateSpecRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusOK, ValidationResponse{
 			Valid:  false,
@@ -575,29 +800,65 @@ func validateSpec(c *gin.Context) {
 
 	errors := []string{}
 	warnings := []string{}
+	var suggestions []ValidationSuggestion
+
+	// Validate capability dependencies, including transitive ones and cycles
+	var allCapabilities []Capability
+	db.Find(&allCapabilities)
+	capGraph := capabilityGraph(allCapabilities)
 
-	// Validate capabilities dependencies
+	selected := make(map[string]bool)
 	for _, capID := range req.Capabilities {
-		var cap Capability
-		if err := db.First(&cap, "id = ?", capID).Error; err != nil {
+		selected[capID] = true
+		if _, known := capabilityByID(allCapabilities, capID); !known {
 			errors = append(errors, fmt.Sprintf("Capability %s not found", capID))
-			continue
 		}
+	}
 
-		var deps []string
-		json.Unmarshal([]byte(cap.Dependencies), &deps)
-		for _, depID := range deps {
-			found := false
-			for _, selectedCap := range req.Capabilities {
-				if selectedCap == depID {
-					found = true
-					break
-				}
+	for _, cycle := range capabilityCycles(capGraph, req.Capabilities) {
+		errors = append(errors, fmt.Sprintf("Capability dependency cycle: %s", graph.FormatPath(cycle)))
+	}
+
+	var missing []string
+	for _, depID := range capGraph.Closure(req.Capabilities...) {
+		if selected[depID] {
+			continue
+		}
+		for _, capID := range req.Capabilities {
+			path := capGraph.Path(capID, depID)
+			if path == nil {
+				continue
 			}
-			if !found {
+			if len(path) == 2 {
 				errors = append(errors, fmt.Sprintf("Capability %s requires %s", capID, depID))
+			} else {
+				errors = append(errors, fmt.Sprintf("Capability %s requires %s (via %s)", capID, depID, graph.FormatPath(path)))
 			}
+			break
+		}
+		if _, known := capabilityByID(allCapabilities, depID); !known {
+			errors = append(errors, fmt.Sprintf("Capability %s not found", depID))
+			continue
 		}
+		missing = append(missing, depID)
+	}
+	if len(missing) > 0 {
+		suggestions = append(suggestions, ValidationSuggestion{
+			Action:       "add_capabilities",
+			Capabilities: missing,
+			Message:      fmt.Sprintf("Add %s to satisfy transitive dependencies", strings.Join(missing, ", ")),
+		})
+	}
+
+	// Validate component dependencies and network topology when an SES document is supplied
+	if req.Spec != nil {
+		errors = append(errors, spec.Validate(req.Spec)...)
+		componentErrors, componentWarnings := spec.ValidateDependencies(req.Spec)
+		errors = append(errors, componentErrors...)
+		warnings = append(warnings, componentWarnings...)
+		networkErrors, networkWarnings := spec.ValidateNetworks(req.Spec)
+		errors = append(errors, networkErrors...)
+		warnings = append(warnings, networkWarnings...)
 	}
 
 	if req.Compute.CPU < 1 {
@@ -611,9 +872,10 @@ func validateSpec(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, ValidationResponse{
-		Valid:    len(errors) == 0,
-		Errors:   errors,
-		Warnings: warnings,
+		Valid:       len(errors) == 0,
+		Errors:      errors,
+		Warnings:    warnings,
+		Suggestions: suggestions,
 	})
 }
 
@@ -625,7 +887,7 @@ func estimateCost(c *gin.Context) {
 	}
 
 	dailyCost := calculateCost(req.Compute, req.Storage, len(req.Capabilities))
-	
+
 	breakdown := map[string]float64{
 		"compute":      float64(req.Compute.CPU*req.Compute.Memory*req.Compute.Instances) * 0.05,
 		"storage":      float64(req.Storage) * 0.1,
@@ -696,169 +958,240 @@ func calculateCost(compute ComputeConfig, storage, capabilityCount int) float64
 	return computeCost + storageCost + capabilityCost
 }
 
-func simulateProvisioning(envID string) {
-	// Simulate provisioning stages
-	stages := []string{"validating", "allocating", "configuring", "starting"}
-	
-	for i, stage := range stages {
-		time.Sleep(2 * time.Second)
-		
-		// Update environment status
-		var env Environment
-		db.First(&env, "id = ?", envID)
-		
-		progress := int((float64(i+1) / float64(len(stages))) * 100)
-		status := "provisioning"
-		if progress == 100 {
-			status = "running"
-		}
-		
-		db.Model(&env).Updates(map[string]interface{}{
-			"status":     status,
-			"health":     90 + rand.Intn(10),
-			"updated_at": time.Now(),
-		})
-		
-		// Create state transition
-		transition := StateTransition{
-			EnvironmentID: envID,
-			FromState:     env.Status,
-			ToState:       status,
-			Reason:        fmt.Sprintf("Stage: %s (%d%%)", stage, progress),
-			Metadata:      fmt.Sprintf(`{"stage":"%s","progress":%d}`, stage, progress),
-			CreatedAt:     time.Now(),
+// capabilityGraph builds the capability dependency DAG
+func capabilityGraph(capabilities []Capability) *graph.Graph {
+	g := graph.New()
+	for _, cap := range capabilities {
+		g.AddNode(cap.ID)
+		for _, depID := range cap.Dependencies {
+			g.AddEdge(cap.ID, depID)
 		}
-		db.Create(&transition)
 	}
-	
-	// Update uptime
-	go updateUptime(envID)
+	return g
 }
 
-func updateUptime(envID string) {
-	startTime := time.Now()
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		var env Environment
-		if err := db.First(&env, "id = ?", envID).Error; err != nil {
-			return
+// capabilityCycles returns the dependency cycles reachable from the selected capabilities
+func capabilityCycles(g *graph.Graph, selected []string) [][]string {
+	reachable := graph.New()
+	for _, capID := range g.Closure(selected...) {
+		reachable.AddNode(capID)
+		for _, depID := range g.Dependencies(capID) {
+			reachable.AddEdge(capID, depID)
 		}
+	}
+	return reachable.Cycles()
+}
 
-		if env.Status != "running" {
-			return
+func capabilityByID(capabilities []Capability, id string) (Capability, bool) {
+	for _, cap := range capabilities {
+		if cap.ID == id {
+			return cap, true
 		}
-
-		duration := time.Since(startTime)
-		uptime := fmt.Sprintf("%dd %dh", int(duration.Hours()/24), int(duration.Hours())%24)
-
-		db.Model(&env).Updates(map[string]interface{}{
-			"uptime":      uptime,
-			"actual_cost": env.EstimatedCost * (duration.Hours() / 24),
-		})
 	}
+	return Capability{}, false
 }
 
-// provisionAWSFleetWise provisions an environment using real AWS IoT FleetWise
-func provisionAWSFleetWise(envID string, configJSON string) {
-	log.Printf("Starting real AWS FleetWise provisioning for environment: %s", envID)
+// startProvisioning moves the environment through validated and planned to
+// provisioning and executes its approved plan in the background. Without an
+// approved plan for the current spec, the environment is planned and
+// ErrPlanAwaitingApproval returned.
+func startProvisioning(env Environment) (*ExecutionPlan, error) {
+	if err := validateForPlanning(env); err != nil {
+		return nil, err
+	}
 
-	// Parse FleetWise configuration
-	var config FleetWiseConfig
-	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
-		log.Printf("Error parsing FleetWise config: %v", err)
-		updateEnvironmentStatusWithError(envID, "error", fmt.Sprintf("Failed to parse FleetWise config: %v", err))
-		return
+	plan, err := planForProvisioning(env)
+	if err != nil {
+		return nil, err
+	}
+	if env.Status != StatePlanned {
+		if err := recordPlanned(env, plan); err != nil {
+			return nil, err
+		}
 	}
 
-	// Create FleetWise client
-	client, err := NewAWSFleetWiseClient(config.Region)
+	// The provision job is enqueued with the transition, so a provisioning
+	// environment always has a job that will execute its plan
+	_, err = transitionEnvironment(env.ID, Transition{
+		To:       StateProvisioning,
+		Reason:   "Provisioning initiated",
+		Metadata: map[string]interface{}{"plan_id": plan.ID, "strategy": plan.Strategy},
+		InTx: func(tx *gorm.DB) error {
+			if err := setPlanStatus(plan, PlanStatusExecuting)(tx); err != nil {
+				return err
+			}
+			_, err := enqueueJob(tx, JobTypeProvision, env.ID, map[string]interface{}{"plan_id": plan.ID})
+			return err
+		},
+	})
 	if err != nil {
-		log.Printf("Error creating FleetWise client: %v", err)
-		updateEnvironmentStatusWithError(envID, "error", fmt.Sprintf("Failed to create AWS client: %v", err))
-		return
+		return nil, err
 	}
 
-	// Update status to validating
-	updateEnvironmentStatusWithTransition(envID, "provisioning", "Validating FleetWise configuration")
-	time.Sleep(1 * time.Second)
+	return plan, nil
+}
 
-	// Provision the environment
-	err = client.ProvisionFleetWiseEnvironment(envID, config)
+// executePlan runs a plan with its provisioning strategy and moves the
+// environment to ready and then running, or to failed if any step fails, in
+// which case the completed steps are rolled back. Steps checkpointed by an
+// earlier attempt of the job are skipped.
+func executePlan(ctx context.Context, job *Job, env Environment, plan *ExecutionPlan) error {
+	metadata := map[string]interface{}{"plan_id": plan.ID, "strategy": plan.Strategy, "job_id": job.ID}
+
+	adapter, err := connectProvider(ctx, env)
+	if err == nil {
+		err = adapter.ValidateQuota(ctx, plan)
+	}
 	if err != nil {
-		log.Printf("Error provisioning FleetWise environment: %v", err)
-		updateEnvironmentStatusWithError(envID, "error", fmt.Sprintf("Provisioning failed: %v", err))
-		return
+		if ClassifyError(err).Recoverable() && job.Attempts < job.MaxAttempts {
+			return err
+		}
+		log.Printf("Error preparing provider %s: %v", providerName(env), err)
+		failEnvironment(env.ID, fmt.Sprintf("Provider %s is not available: %v", providerName(env), err), metadata, failAndRollBack(plan, job.Checkpoint, RollbackTriggerError))
+		return &permanentJobError{err}
 	}
+	runner := newStepRunner(env.ID, adapter)
 
-	// Update status to running
-	updateEnvironmentStatusWithTransition(envID, "running", "AWS FleetWise environment provisioned successfully")
+	remaining := withoutCompletedSteps(plan, job.Checkpoint)
+	done := len(plan.Steps) - len(remaining.Steps)
+	if done > 0 {
+		log.Printf("Resuming plan %d for environment %s after %d completed step(s)", plan.ID, env.ID, done)
+	}
 
-	// Update health to 95-100 (real AWS environment)
-	var env Environment
-	db.First(&env, "id = ?", envID)
-	db.Model(&env).Updates(map[string]interface{}{
-		"health":     95 + rand.Intn(5),
-		"updated_at": time.Now(),
-	})
+	executor := &Executor{
+		Strategy: plan.Strategy,
+		Runner:   runner,
+		OnStep: func(step PlanStep, completed, total int, stepErr error) {
+			if stepErr == nil {
+				if err := checkpointJob(job, step.ID); err != nil {
+					log.Printf("Error checkpointing step %s of job %d: %v", step.ID, job.ID, err)
+				}
+			}
 
-	log.Printf("Successfully provisioned AWS FleetWise environment: %s", envID)
+			progress := (done + completed) * 100 / len(plan.Steps)
+			stepMetadata := map[string]interface{}{
+				"plan_id":  plan.ID,
+				"strategy": plan.Strategy,
+				"step":     step.ID,
+				"progress": progress,
+			}
+			reason := fmt.Sprintf("Step: %s (%d%%)", step.Name, progress)
+			if stepErr != nil {
+				stepMetadata["error"] = stepErr.Error()
+				reason = fmt.Sprintf("Step failed: %s", step.Name)
+			}
 
-	// Start uptime tracking
-	go updateUptime(envID)
-}
+			_, err := transitionEnvironment(env.ID, Transition{
+				To:       StateProvisioning,
+				Reason:   reason,
+				Metadata: stepMetadata,
+				Updates:  map[string]interface{}{"health": 90 + rand.Intn(10)},
+			})
+			if err != nil {
+				log.Printf("Error recording progress of environment %s: %v", env.ID, err)
+			}
+		},
+	}
 
-// Helper function to update environment status with transition
-func updateEnvironmentStatusWithTransition(envID, status, reason string) {
-	var env Environment
-	db.First(&env, "id = ?", envID)
+	log.Printf("Executing plan %d for environment %s with %s strategy", plan.ID, env.ID, plan.Strategy)
+	if err := executor.Execute(ctx, remaining); err != nil {
+		cause := context.Cause(ctx)
+		if errors.Is(cause, errJobClaimLost) {
+			return cause // Another worker resumes the plan
+		}
+		if errors.Is(cause, errJobCancelled) {
+			failEnvironment(env.ID, "Provisioning cancelled", metadata, failAndRollBack(plan, job.Checkpoint, RollbackTriggerManual))
+			return cause
+		}
+		log.Printf("Error provisioning environment %s: %v", env.ID, err)
+		failEnvironment(env.ID, fmt.Sprintf("Provisioning failed: %v", err), metadata, failAndRollBack(plan, job.Checkpoint, RollbackTriggerError))
+		return &permanentJobError{err}
+	}
 
-	oldStatus := env.Status
-	db.Model(&env).Updates(map[string]interface{}{
-		"status":     status,
-		"updated_at": time.Now(),
-	})
+	if _, err := transitionEnvironment(env.ID, Transition{
+		To:       StateReady,
+		Reason:   fmt.Sprintf("Provisioned with %s strategy", plan.Strategy),
+		Metadata: metadata,
+		InTx:     setPlanStatus(plan, PlanStatusCompleted),
+	}); err != nil {
+		return fmt.Errorf("completing provisioning: %w", err)
+	}
+	if _, err := transitionEnvironment(env.ID, Transition{
+		To:       StateRunning,
+		Reason:   "Execution started",
+		Metadata: metadata,
+		InTx:     inTxAll(enqueueUptimeTracking(env.ID), enqueueCampaignLifecycle(env.ID)),
+	}); err != nil {
+		return &permanentJobError{fmt.Errorf("starting environment: %w", err)}
+	}
+	return nil
+}
 
-	transition := StateTransition{
-		EnvironmentID: envID,
-		FromState:     oldStatus,
-		ToState:       status,
-		Reason:        reason,
-		CreatedAt:     time.Now(),
+// enqueueUptimeTracking returns a write that starts uptime tracking within a transition
+func enqueueUptimeTracking(envID string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return enqueueUniqueJob(tx, JobTypeTrackUptime, envID, map[string]interface{}{
+			"started_at": time.Now().Format(time.RFC3339),
+		})
 	}
-	db.Create(&transition)
 }
 
-// Helper function to update environment with error status
-func updateEnvironmentStatusWithError(envID, status, errorMsg string) {
+// updateUptime records uptime and cost since startTime and reports whether
+// the environment is still running
+func updateUptime(envID string, startTime time.Time) bool {
 	var env Environment
-	db.First(&env, "id = ?", envID)
+	if err := db.First(&env, "id = ?", envID).Error; err != nil {
+		return false
+	}
 
-	oldStatus := env.Status
-	db.Model(&env).Updates(map[string]interface{}{
-		"status":     status,
-		"health":     0,
-		"updated_at": time.Now(),
-	})
+	if env.Status != StateRunning {
+		return false
+	}
 
-	transition := StateTransition{
-		EnvironmentID: envID,
-		FromState:     oldStatus,
-		ToState:       status,
-		Reason:        errorMsg,
-		Metadata:      fmt.Sprintf(`{"error": "%s"}`, errorMsg),
-		CreatedAt:     time.Now(),
+	duration := time.Since(startTime)
+	uptime := fmt.Sprintf("%dd %dh", int(duration.Hours()/24), int(duration.Hours())%24)
+
+	// Only write while still running, so a concurrent stop is never overwritten
+	result := db.Model(&Environment{}).
+		Where("id = ? AND status = ?", envID, StateRunning).
+		Updates(map[string]interface{}{
+			"uptime":      uptime,
+			"actual_cost": env.EstimatedCost * (duration.Hours() / 24),
+		})
+	return result.Error == nil && result.RowsAffected > 0
+}
+
+// failEnvironment moves an environment to failed and records the error.
+// inTx, if set, is committed together with the transition.
+func failEnvironment(envID, errorMsg string, metadata map[string]interface{}, inTx func(tx *gorm.DB) error) {
+	errorMetadata := map[string]interface{}{"error": errorMsg}
+	for k, v := range metadata {
+		errorMetadata[k] = v
 	}
-	db.Create(&transition)
 
-	// Create audit log for error
-	auditLog := AuditLog{
-		EnvironmentID: envID,
-		Action:        "provisioning_failed",
-		UserID:        "system",
-		Details:       fmt.Sprintf(`{"error": "%s"}`, errorMsg),
-		CreatedAt:     time.Now(),
+	_, err := transitionEnvironment(envID, Transition{
+		To:       StateFailed,
+		Reason:   errorMsg,
+		Metadata: errorMetadata,
+		Updates:  map[string]interface{}{"health": 0},
+		InTx: func(tx *gorm.DB) error {
+			if inTx != nil {
+				if err := inTx(tx); err != nil {
+					return err
+				}
+			}
+			// Create audit log for error
+			auditLog := AuditLog{
+				EnvironmentID: envID,
+				Action:        "provisioning_failed",
+				UserID:        "system",
+				Details:       map[string]interface{}{"error": errorMsg},
+				CreatedAt:     time.Now(),
+			}
+			return tx.Create(&auditLog).Error
+		},
+	})
+	if err != nil {
+		log.Printf("Error failing environment %s: %v", envID, err)
 	}
-	db.Create(&auditLog)
 }
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"ses-platform/spec"
+)
+
+// Execution Plan API Handlers
+
+// ErrPlanAwaitingApproval is returned when provisioning is requested while the
+// current plan still has to be reviewed
+var ErrPlanAwaitingApproval = errors.New("execution plan is awaiting approval")
+
+type CreatePlanRequest struct {
+	DryRun bool   `json:"dry_run"`
+	Owner  string `json:"owner"`
+}
+
+type ApprovePlanRequest struct {
+	ApprovedBy string `json:"approved_by" binding:"required"`
+}
+
+// getEnvironmentPlan returns the current execution plan of an environment
+func getEnvironmentPlan(c *gin.Context) {
+	id := c.Param("id")
+	plan, err := currentPlan(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Execution plan not found"})
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// createEnvironmentPlan generates a plan for review. With dry_run the plan is
+// returned without being stored; otherwise it replaces the current plan and
+// must be approved before provisioning.
+func createEnvironmentPlan(c *gin.Context) {
+	id := c.Param("id")
+	var env Environment
+	if err := db.First(&env, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Environment not found"})
+		return
+	}
+
+	var req CreatePlanRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if c.Query("dry_run") == "true" {
+		req.DryRun = true
+	}
+
+	if !req.DryRun {
+		if err := validateForPlanning(env); err != nil {
+			respondPlanningError(c, err)
+			return
+		}
+	}
+
+	plan, err := BuildExecutionPlan(env)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.DryRun {
+		c.JSON(http.StatusOK, plan)
+		return
+	}
+
+	if err := savePlan(plan); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := recordPlanned(env, plan); err != nil {
+		respondPlanningError(c, err)
+		return
+	}
+
+	auditLog := AuditLog{
+		EnvironmentID: id,
+		Action:        "plan_created",
+		UserID:        req.Owner,
+		Details: map[string]interface{}{
+			"message": fmt.Sprintf("Execution plan %d created with %d steps", plan.ID, len(plan.Steps)),
+			"plan_id": plan.ID,
+		},
+		CreatedAt: time.Now(),
+	}
+	db.Create(&auditLog)
+
+	c.JSON(http.StatusCreated, plan)
+}
+
+// approveEnvironmentPlan approves the pending plan so it can be provisioned
+func approveEnvironmentPlan(c *gin.Context) {
+	id := c.Param("id")
+	var req ApprovePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := currentPlan(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Execution plan not found"})
+		return
+	}
+	if plan.Status != PlanStatusPendingApproval {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Execution plan is %s", plan.Status)})
+		return
+	}
+
+	// Only a plan that is still pending is approved, so concurrent approvals
+	// and a plan superseded since it was read are refused
+	now := time.Now()
+	result := db.Model(&ExecutionPlan{}).
+		Where("id = ? AND status = ?", plan.ID, PlanStatusPendingApproval).
+		Updates(map[string]interface{}{
+			"status":      PlanStatusApproved,
+			"approved_by": req.ApprovedBy,
+			"approved_at": now,
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Execution plan is no longer pending approval"})
+		return
+	}
+
+	auditLog := AuditLog{
+		EnvironmentID: id,
+		Action:        "plan_approved",
+		UserID:        req.ApprovedBy,
+		Details: map[string]interface{}{
+			"message": fmt.Sprintf("Execution plan %d approved", plan.ID),
+			"plan_id": plan.ID,
+		},
+		CreatedAt: now,
+	}
+	db.Create(&auditLog)
+
+	db.First(plan, plan.ID)
+	c.JSON(http.StatusOK, plan)
+}
+
+// currentPlan returns the most recent plan that has not been superseded
+func currentPlan(envID string) (*ExecutionPlan, error) {
+	var plan ExecutionPlan
+	err := db.Where("environment_id = ? AND status <> ?", envID, PlanStatusSuperseded).
+		Order("created_at desc, id desc").
+		First(&plan).Error
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// savePlan stores a plan and supersedes any earlier plan of the environment
+func savePlan(plan *ExecutionPlan) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&ExecutionPlan{}).
+			Where("environment_id = ? AND status IN ?", plan.EnvironmentID, []string{PlanStatusPendingApproval, PlanStatusApproved}).
+			Update("status", PlanStatusSuperseded).Error; err != nil {
+			return err
+		}
+		return tx.Create(plan).Error
+	})
+}
+
+// planForProvisioning returns the approved plan to execute. If there is no
+// plan, or the spec, compute or FleetWise configuration or the provider has
+// changed since it was made, a new plan is generated and the environment
+// planned; like a plan still pending approval, it blocks provisioning until a
+// reviewer approves it.
+func planForProvisioning(env Environment) (*ExecutionPlan, error) {
+	plan, err := currentPlan(env.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if plan != nil && plan.InputHash == planInputHash(env) {
+		switch plan.Status {
+		case PlanStatusPendingApproval:
+			return nil, ErrPlanAwaitingApproval
+		case PlanStatusApproved:
+			return plan, nil
+		}
+	}
+
+	plan, err = BuildExecutionPlan(env)
+	if err != nil {
+		return nil, err
+	}
+	if err := savePlan(plan); err != nil {
+		return nil, err
+	}
+	if err := recordPlanned(env, plan); err != nil {
+		return nil, err
+	}
+
+	auditLog := AuditLog{
+		EnvironmentID: env.ID,
+		Action:        "plan_created",
+		UserID:        "system",
+		Details: map[string]interface{}{
+			"message": fmt.Sprintf("Execution plan %d created with %d steps for provisioning", plan.ID, len(plan.Steps)),
+			"plan_id": plan.ID,
+		},
+		CreatedAt: time.Now(),
+	}
+	db.Create(&auditLog)
+
+	return nil, ErrPlanAwaitingApproval
+}
+
+// plannableStates are the states an environment can be (re)planned from. Its
+// spec and configuration can only change in these states.
+var plannableStates = []string{StatePending, StateValidated, StatePlanned}
+
+// validateForPlanning checks that the environment is in a state that can be
+// (re)planned and that its configuration is valid
+func validateForPlanning(env Environment) error {
+	if !slices.Contains(plannableStates, env.Status) {
+		return &TransitionError{From: env.Status, To: StatePlanned}
+	}
+	if errs := validateEnvironment(env); len(errs) > 0 {
+		return &spec.ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// validateEnvironment re-checks the spec, or the flat compute configuration of
+// environments created without one, and that an adapter serves its provider
+func validateEnvironment(env Environment) []string {
+	var errs []string
+	if _, err := providerFor(env); err != nil {
+		errs = append(errs, fmt.Sprintf("%v (available: %s)", err, strings.Join(RegisteredProviders(), ", ")))
+	}
+	if env.FleetWiseConfig != nil {
+		errs = append(errs, validateFleetWiseConfig(*env.FleetWiseConfig)...)
+	}
+
+	if env.Spec != nil {
+		errs = append(errs, spec.Validate(env.Spec)...)
+		dependencyErrs, _ := spec.ValidateDependencies(env.Spec)
+		networkErrs, _ := spec.ValidateNetworks(env.Spec)
+		return append(append(errs, dependencyErrs...), networkErrs...)
+	}
+
+	if env.ComputeConfig.CPU < 1 {
+		errs = append(errs, "CPU must be at least 1")
+	}
+	if env.ComputeConfig.Memory < 1 {
+		errs = append(errs, "Memory must be at least 1 GB")
+	}
+	return errs
+}
+
+// recordPlanned moves a validated environment to planned for the given plan
+func recordPlanned(env Environment, plan *ExecutionPlan) error {
+	if env.Status == StatePending {
+		if _, err := transitionEnvironment(env.ID, Transition{To: StateValidated, Reason: "Configuration validated"}); err != nil {
+			return err
+		}
+	}
+	_, err := transitionEnvironment(env.ID, Transition{
+		To:       StatePlanned,
+		Reason:   fmt.Sprintf("Execution plan %d created", plan.ID),
+		Metadata: map[string]interface{}{"plan_id": plan.ID, "strategy": plan.Strategy},
+	})
+	return err
+}
+
+// respondPlanningError maps planning and provisioning errors to HTTP responses
+func respondPlanningError(c *gin.Context, err error) {
+	var validationErr *spec.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusUnprocessableEntity, ValidationResponse{
+			Valid:  false,
+			Errors: validationErr.Errors,
+		})
+	case errors.Is(err, ErrPlanAwaitingApproval):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		var transitionErr *TransitionError
+		if errors.As(err, &transitionErr) || errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrConcurrentTransition) {
+			respondTransitionError(c, err)
+			return
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	}
+}
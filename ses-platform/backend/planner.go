@@ -0,0 +1,479 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"ses-platform/graph"
+	"ses-platform/spec"
+)
+
+// ExecutionPlan is the ordered set of steps that provisioning will execute.
+// Plans are generated from the environment's spec and persisted so they can be
+// reviewed and approved before any resource is created. Steps carry the
+// configuration they were planned with, so an approved plan runs as reviewed.
+type ExecutionPlan struct {
+	ID                       uint       `gorm:"primaryKey" json:"id"`
+	EnvironmentID            string     `gorm:"index" json:"environment_id"`
+	SpecHash                 string     `json:"spec_hash,omitempty"`
+	InputHash                string     `json:"input_hash"` // See planInputHash
+	Status                   string     `json:"status"`     // pending_approval, approved, executing, completed, failed, superseded
+	Provider                 string     `json:"provider"`
+	Strategy                 string     `json:"strategy"` // parallel, sequential, optimized
+	Steps                    []PlanStep `gorm:"type:jsonb;serializer:json" json:"steps"`
+	EstimatedDurationSeconds int        `json:"estimated_duration_seconds"` // Critical path duration
+	ApprovedBy               string     `json:"approved_by,omitempty"`
+	ApprovedAt               *time.Time `json:"approved_at,omitempty"`
+	CreatedAt                time.Time  `json:"created_at"`
+}
+
+// PlanStep is a single provisioning action and how to undo it
+type PlanStep struct {
+	ID                       string                 `json:"id"`
+	Name                     string                 `json:"name"`
+	Action                   string                 `json:"action"`
+	Component                string                 `json:"component,omitempty"`
+	Provider                 string                 `json:"provider"`
+	ProviderResource         string                 `json:"provider_resource"`
+	DependsOn                []string               `json:"depends_on,omitempty"`
+	EstimatedDurationSeconds int                    `json:"estimated_duration_seconds"`
+	RollbackAction           string                 `json:"rollback_action,omitempty"`
+	Config                   map[string]interface{} `json:"config,omitempty"`
+}
+
+const (
+	PlanStatusPendingApproval = "pending_approval"
+	PlanStatusApproved        = "approved"
+	PlanStatusExecuting       = "executing"
+	PlanStatusCompleted       = "completed"
+	PlanStatusFailed          = "failed"
+	PlanStatusSuperseded      = "superseded"
+)
+
+// Step actions and their estimated duration in seconds
+var stepDurations = map[string]int{
+	"validate":           5,
+	"provision_network":  30,
+	"configure_security": 15,
+	"provision_compute":  120,
+	"provision_storage":  60,
+	"provision_database": 300,
+	"provision_cache":    180,
+	"deploy_component":   45,
+	"health_check":       30,
+	"create_fleet":       10,
+	"create_vehicles":    5, // per vehicle
+	"associate_vehicles": 2, // per vehicle
+	"create_campaign":    20,
+	"reuse_campaign":     2,
+	"start":              10,
+}
+
+// Inverse action executed when a step has to be rolled back
+var stepRollbackActions = map[string]string{
+	"provision_network":  "delete_network",
+	"configure_security": "revoke_security",
+	"provision_compute":  "deallocate_compute",
+	"provision_storage":  "deallocate_storage",
+	"provision_database": "deallocate_database",
+	"provision_cache":    "deallocate_cache",
+	"deploy_component":   "undeploy_component",
+	"create_fleet":       "delete_fleet",
+	"create_vehicles":    "delete_vehicles",
+	"associate_vehicles": "disassociate_vehicles",
+	"create_campaign":    "delete_campaign",
+	"reuse_campaign":     "release_campaign",
+	"start":              "stop",
+}
+
+// Provider resource types per provider (spec §7.2)
+var providerResources = map[string]map[string]string{
+	"aws": {
+		"network": "vpc", "security": "security_group", "compute": "ec2", "storage": "ebs",
+		"database": "rds", "cache": "elasticache", "component": "ecs_service",
+	},
+	"azure": {
+		"network": "vnet", "security": "network_security_group", "compute": "virtual_machine", "storage": "managed_disk",
+		"database": "azure_sql", "cache": "azure_cache", "component": "container_instance",
+	},
+	"gcp": {
+		"network": "vpc_network", "security": "firewall", "compute": "compute_instance", "storage": "persistent_disk",
+		"database": "cloud_sql", "cache": "memorystore", "component": "cloud_run_service",
+	},
+	"on-prem": {
+		"network": "docker_network", "security": "firewall_rule", "compute": "host", "storage": "docker_volume",
+		"database": "container", "cache": "container", "component": "container",
+	},
+	"simulated": {
+		"network": "simulated_network", "security": "simulated_firewall", "compute": "simulated_instance", "storage": "simulated_volume",
+		"database": "simulated_database", "cache": "simulated_cache", "component": "simulated_workload",
+	},
+}
+
+func providerResource(provider, kind string) string {
+	if resources, ok := providerResources[provider]; ok {
+		if r, ok := resources[kind]; ok {
+			return r
+		}
+	}
+	return kind
+}
+
+// planBuilder accumulates steps while a plan is generated
+type planBuilder struct {
+	provider string
+	steps    []PlanStep
+}
+
+func (b *planBuilder) add(step PlanStep) string {
+	if step.Provider == "" {
+		step.Provider = b.provider
+	}
+	if step.EstimatedDurationSeconds == 0 {
+		step.EstimatedDurationSeconds = stepDurations[step.Action]
+	}
+	if step.RollbackAction == "" {
+		step.RollbackAction = stepRollbackActions[step.Action]
+	}
+	b.steps = append(b.steps, step)
+	return step.ID
+}
+
+// BuildExecutionPlan turns an environment and its validated spec into an ordered plan
+func BuildExecutionPlan(env Environment) (*ExecutionPlan, error) {
+	var b *planBuilder
+	strategy := "optimized"
+
+	switch {
+	case providerName(env) == "aws" && env.FleetWiseConfig != nil:
+		b = planFleetWise(env)
+	case env.Spec != nil:
+		if env.Spec.Spec.Orchestration != nil && env.Spec.Spec.Orchestration.ProvisioningStrategy != "" {
+			strategy = env.Spec.Spec.Orchestration.ProvisioningStrategy
+		}
+		var err error
+		if b, err = planFromSpec(env); err != nil {
+			return nil, err
+		}
+	default:
+		b = planFromCompute(env)
+	}
+
+	ordered, critical, err := orderSteps(b.steps)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExecutionPlan{
+		EnvironmentID:            env.ID,
+		SpecHash:                 env.SpecHash,
+		InputHash:                planInputHash(env),
+		Status:                   PlanStatusPendingApproval,
+		Provider:                 b.provider,
+		Strategy:                 strategy,
+		Steps:                    ordered,
+		EstimatedDurationSeconds: critical,
+		CreatedAt:                time.Now(),
+	}, nil
+}
+
+// planInputHash identifies everything a plan is built from: the spec, the flat
+// compute configuration, the FleetWise configuration and the provider
+func planInputHash(env Environment) string {
+	input, _ := json.Marshal(map[string]interface{}{
+		"spec_hash":        env.SpecHash,
+		"provider":         providerName(env),
+		"compute_config":   env.ComputeConfig,
+		"network":          env.Network,
+		"storage":          env.Storage,
+		"fleetwise_config": env.FleetWiseConfig,
+	})
+	sum := sha256.Sum256(input)
+	return hex.EncodeToString(sum[:])
+}
+
+// planFromSpec plans networks, security, infrastructure, components and health
+// checks. They are planned for the environment's provider, which may have been
+// chosen over the spec's when the environment was created.
+func planFromSpec(env Environment) (*planBuilder, error) {
+	doc := env.Spec
+	provider := providerName(env)
+	b := &planBuilder{provider: provider}
+
+	networkSteps := map[string][]string{}
+	var infraSteps []string
+	if arch := doc.Spec.Architecture; arch != nil {
+		for _, n := range arch.Networks {
+			netStep := b.add(PlanStep{
+				ID:               "network:" + n.ID,
+				Name:             fmt.Sprintf("Provision network %s", n.ID),
+				Action:           "provision_network",
+				ProviderResource: providerResource(provider, "network"),
+				Config:           map[string]interface{}{"cidr": n.CIDR, "type": n.Type, "subnets": len(n.Subnets)},
+			})
+			networkSteps[n.ID] = append(networkSteps[n.ID], netStep)
+			if len(n.SecurityGroups) > 0 {
+				sgStep := b.add(PlanStep{
+					ID:               "security:" + n.ID,
+					Name:             fmt.Sprintf("Configure security groups for %s", n.ID),
+					Action:           "configure_security",
+					ProviderResource: providerResource(provider, "security"),
+					DependsOn:        []string{netStep},
+					Config:           map[string]interface{}{"security_groups": len(n.SecurityGroups)},
+				})
+				networkSteps[n.ID] = append(networkSteps[n.ID], sgStep)
+			}
+		}
+	}
+
+	if environment := doc.Spec.Environment; environment != nil {
+		for i, infra := range environment.Infrastructure {
+			resource := infra.ProviderResource
+			if resource == "" {
+				resource = providerResource(provider, infra.Type)
+			}
+			infraSteps = append(infraSteps, b.add(PlanStep{
+				ID:               fmt.Sprintf("infrastructure:%d:%s", i, infra.Type),
+				Name:             fmt.Sprintf("Provision %s (%s)", infra.Type, resource),
+				Action:           "provision_" + infra.Type,
+				ProviderResource: resource,
+			}))
+		}
+	}
+
+	if arch := doc.Spec.Architecture; arch != nil {
+		order := spec.ComponentGraph(doc, false)
+		if _, err := order.Levels(); err != nil {
+			// Soft cycles are allowed in a valid spec; order by hard edges only
+			order = spec.ComponentGraph(doc, true)
+		}
+		for _, comp := range arch.Components {
+			deps := append([]string(nil), infraSteps...)
+			for _, ref := range comp.Networks {
+				netID, _, _ := strings.Cut(ref, "/")
+				deps = append(deps, networkSteps[netID]...)
+			}
+			for _, dep := range order.Dependencies(comp.ID) {
+				deps = append(deps, "component:"+dep)
+			}
+			quantity := comp.Quantity
+			if quantity == 0 {
+				quantity = 1
+			}
+			b.add(PlanStep{
+				ID:               "component:" + comp.ID,
+				Name:             fmt.Sprintf("Deploy %s %s", comp.Type, comp.ID),
+				Action:           "deploy_component",
+				Component:        comp.ID,
+				ProviderResource: providerResource(provider, "component"),
+				DependsOn:        deps,
+				Config: map[string]interface{}{
+					"type":     comp.Type,
+					"image":    comp.Specification.Image,
+					"version":  comp.Specification.Version,
+					"quantity": quantity,
+				},
+			})
+			if hc := comp.HealthCheck; hc != nil {
+				duration := stepDurations["health_check"]
+				if !hc.Interval.IsZero() && hc.Retries > 0 {
+					duration = int(hc.Interval.Seconds()) * hc.Retries
+				}
+				b.add(PlanStep{
+					ID:                       "health:" + comp.ID,
+					Name:                     fmt.Sprintf("Health check %s", comp.ID),
+					Action:                   "health_check",
+					Component:                comp.ID,
+					ProviderResource:         "health_check",
+					DependsOn:                []string{"component:" + comp.ID},
+					EstimatedDurationSeconds: duration,
+					Config:                   map[string]interface{}{"endpoint": hc.Endpoint},
+				})
+			}
+		}
+	}
+
+	return b, nil
+}
+
+// planFleetWise plans the AWS IoT FleetWise resources of an environment
+func planFleetWise(env Environment) *planBuilder {
+	config := env.FleetWiseConfig
+	b := &planBuilder{provider: "aws"}
+	vehicles := len(config.VehicleNames)
+
+	var vehicleDeps []string
+	if config.FleetID != "" {
+		vehicleDeps = append(vehicleDeps, b.add(PlanStep{
+			ID:               "fleet:" + config.FleetID,
+			Name:             fmt.Sprintf("Create fleet %s", config.FleetID),
+			Action:           "create_fleet",
+			ProviderResource: "iotfleetwise:fleet",
+			Config: map[string]interface{}{
+				"region":             config.Region,
+				"fleet_id":           config.FleetID,
+				"signal_catalog_arn": config.SignalCatalogARN,
+			},
+		}))
+	}
+
+	vehicleStep := b.add(PlanStep{
+		ID:                       "vehicles",
+		Name:                     fmt.Sprintf("Create %d vehicles", vehicles),
+		Action:                   "create_vehicles",
+		ProviderResource:         "iotfleetwise:vehicle",
+		EstimatedDurationSeconds: stepDurations["create_vehicles"] * max(vehicles, 1),
+		Config: map[string]interface{}{
+			"region":               config.Region,
+			"vehicle_names":        config.VehicleNames,
+			"model_manifest_arn":   config.ModelManifestARN,
+			"decoder_manifest_arn": config.DecoderManifestARN,
+		},
+	})
+
+	campaignDeps := []string{vehicleStep}
+	if config.FleetID != "" {
+		campaignDeps = append(campaignDeps, b.add(PlanStep{
+			ID:                       "fleet-association",
+			Name:                     fmt.Sprintf("Associate vehicles with fleet %s", config.FleetID),
+			Action:                   "associate_vehicles",
+			ProviderResource:         "iotfleetwise:fleet_association",
+			DependsOn:                append(vehicleDeps, vehicleStep),
+			EstimatedDurationSeconds: stepDurations["associate_vehicles"] * max(vehicles, 1),
+			Config:                   map[string]interface{}{"region": config.Region, "fleet_id": config.FleetID, "vehicle_names": config.VehicleNames},
+		}))
+	}
+
+	// Campaigns are planned with their defaults applied; only a missing target
+	// is resolved when the fleet or vehicles exist
+	for _, campaign := range config.Campaigns {
+		b.add(PlanStep{
+			ID:               "campaign:" + campaign.Name,
+			Name:             fmt.Sprintf("Create and approve campaign %s", environmentCampaignName(env.ID, campaign.Name)),
+			Action:           "create_campaign",
+			ProviderResource: "iotfleetwise:campaign",
+			DependsOn:        campaignDeps,
+			Config: map[string]interface{}{
+				"region":        config.Region,
+				"campaign":      campaign.Name,
+				"definition":    EnvironmentCampaign(env.ID, *config, campaign, ""),
+				"fleet_id":      config.FleetID,
+				"vehicle_names": config.VehicleNames,
+			},
+		})
+	}
+	if config.CampaignARN != "" {
+		b.add(PlanStep{
+			ID:               "campaign-reuse",
+			Name:             fmt.Sprintf("Reuse campaign %s", campaignNameFromARN(config.CampaignARN)),
+			Action:           "reuse_campaign",
+			ProviderResource: "iotfleetwise:campaign",
+			Config:           map[string]interface{}{"region": config.Region, "campaign_arn": config.CampaignARN},
+		})
+	}
+
+	return b
+}
+
+// planFromCompute plans environments created from the flat CreateEnvironmentRequest
+func planFromCompute(env Environment) *planBuilder {
+	b := &planBuilder{provider: providerName(env)}
+	validate := b.add(PlanStep{ID: "validate", Name: "Validate configuration", Action: "validate", ProviderResource: "none"})
+	network := b.add(PlanStep{
+		ID:               "network",
+		Name:             fmt.Sprintf("Configure %s network", env.Network),
+		Action:           "provision_network",
+		ProviderResource: providerResource(b.provider, "network"),
+		DependsOn:        []string{validate},
+	})
+	compute := b.add(PlanStep{
+		ID:               "compute",
+		Name:             fmt.Sprintf("Allocate %d instances", env.ComputeConfig.Instances),
+		Action:           "provision_compute",
+		ProviderResource: providerResource(b.provider, "compute"),
+		DependsOn:        []string{validate},
+		Config: map[string]interface{}{
+			"cpu":       env.ComputeConfig.CPU,
+			"memory":    env.ComputeConfig.Memory,
+			"instances": env.ComputeConfig.Instances,
+		},
+	})
+	deps := []string{network, compute}
+	if env.Storage > 0 {
+		deps = append(deps, b.add(PlanStep{
+			ID:               "storage",
+			Name:             fmt.Sprintf("Allocate %d GB storage", env.Storage),
+			Action:           "provision_storage",
+			ProviderResource: providerResource(b.provider, "storage"),
+			DependsOn:        []string{validate},
+			Config:           map[string]interface{}{"size_gb": env.Storage},
+		}))
+	}
+	b.add(PlanStep{
+		ID:               "start",
+		Name:             "Start environment",
+		Action:           "start",
+		ProviderResource: providerResource(b.provider, "component"),
+		DependsOn:        deps,
+	})
+	return b
+}
+
+// orderSteps sorts steps topologically and returns the critical path duration
+func orderSteps(steps []PlanStep) ([]PlanStep, int, error) {
+	g := graph.New()
+	byID := make(map[string]PlanStep, len(steps))
+	for _, step := range steps {
+		if _, dup := byID[step.ID]; dup {
+			return nil, 0, fmt.Errorf("step %s is planned more than once", step.ID)
+		}
+		byID[step.ID] = step
+		g.AddNode(step.ID)
+	}
+	for _, step := range steps {
+		for _, dep := range step.DependsOn {
+			if _, ok := byID[dep]; !ok {
+				return nil, 0, fmt.Errorf("step %s depends on unknown step %s", step.ID, dep)
+			}
+			g.AddEdge(step.ID, dep)
+		}
+	}
+
+	order, err := g.TopologicalSort()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	finish := make(map[string]int, len(order))
+	critical := 0
+	ordered := make([]PlanStep, 0, len(order))
+	for _, id := range order {
+		step := byID[id]
+		start := 0
+		for _, dep := range step.DependsOn {
+			if finish[dep] > start {
+				start = finish[dep]
+			}
+		}
+		finish[id] = start + step.EstimatedDurationSeconds
+		if finish[id] > critical {
+			critical = finish[id]
+		}
+		ordered = append(ordered, step)
+	}
+	return ordered, critical, nil
+}
+
+// setPlanStatus returns a write that updates the plan status within a transition
+func setPlanStatus(plan *ExecutionPlan, status string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		plan.Status = status
+		return tx.Model(&ExecutionPlan{}).Where("id = ?", plan.ID).Update("status", status).Error
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"ses-platform/spec"
+)
+
+func TestBuildExecutionPlan(t *testing.T) {
+	doc := &spec.Document{}
+	doc.Spec.Environment = &spec.Environment{
+		Provider:       "aws",
+		Infrastructure: []spec.Infrastructure{{Type: "storage"}},
+	}
+	doc.Spec.Architecture = &spec.Architecture{
+		Networks: []spec.Network{{ID: "lan", CIDR: "10.0.0.0/24"}},
+		Components: []spec.Component{
+			{ID: "db", Type: "container", Networks: []string{"lan"}},
+			{ID: "app", Type: "container", Quantity: 2, Dependencies: []string{"db"}, HealthCheck: &spec.HealthCheck{Endpoint: "/health"}},
+		},
+	}
+	fleetWise := &FleetWiseConfig{
+		Region:           "us-east-1",
+		SignalCatalogARN: "arn:aws:iotfleetwise:us-east-1:123456789012:signal-catalog/default",
+		FleetID:          "fleet-1",
+		VehicleNames:     []string{"car-1", "car-2"},
+		Campaigns:        []CampaignConfig{{Name: "speed"}},
+	}
+
+	tests := []struct {
+		name      string
+		env       Environment
+		provider  string
+		steps     []string
+		dependsOn map[string][]string
+		resources map[string]string
+		duration  int
+	}{
+		{
+			name:     "flat compute configuration",
+			env:      Environment{ID: "env-1", ComputeConfig: ComputeConfig{CPU: 2, Memory: 4, Instances: 2}, Storage: 10, Network: "isolated"},
+			provider: "simulated",
+			steps:    []string{"validate", "network", "compute", "storage", "start"},
+			dependsOn: map[string][]string{
+				"start": {"network", "compute", "storage"},
+			},
+			resources: map[string]string{"network": "simulated_network", "start": "simulated_workload"},
+			duration:  5 + 120 + 10,
+		},
+		{
+			name:     "spec planned for the environment's provider",
+			env:      Environment{ID: "env-1", Provider: "on-prem", Spec: doc},
+			provider: "on-prem",
+			steps:    []string{"network:lan", "infrastructure:0:storage", "component:db", "component:app", "health:app"},
+			dependsOn: map[string][]string{
+				"component:db":  {"infrastructure:0:storage", "network:lan"},
+				"component:app": {"infrastructure:0:storage", "component:db"},
+				"health:app":    {"component:app"},
+			},
+			resources: map[string]string{"network:lan": "docker_network", "infrastructure:0:storage": "docker_volume", "component:app": "container"},
+			duration:  60 + 45 + 45 + 30,
+		},
+		{
+			name:     "FleetWise",
+			env:      Environment{ID: "env-1", Provider: "aws", FleetWiseConfig: fleetWise},
+			provider: "aws",
+			steps:    []string{"fleet:fleet-1", "vehicles", "fleet-association", "campaign:speed"},
+			dependsOn: map[string][]string{
+				"fleet-association": {"fleet:fleet-1", "vehicles"},
+				"campaign:speed":    {"vehicles", "fleet-association"},
+			},
+			resources: map[string]string{"campaign:speed": "iotfleetwise:campaign"},
+			duration:  10 + 2*2 + 20,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := BuildExecutionPlan(tt.env)
+			if err != nil {
+				t.Fatalf("BuildExecutionPlan: %v", err)
+			}
+			if plan.Provider != tt.provider {
+				t.Errorf("Provider = %s, want %s", plan.Provider, tt.provider)
+			}
+			var ids []string
+			for _, step := range plan.Steps {
+				ids = append(ids, step.ID)
+				if step.Provider != tt.provider {
+					t.Errorf("step %s Provider = %s, want %s", step.ID, step.Provider, tt.provider)
+				}
+				if want, ok := tt.dependsOn[step.ID]; ok && !reflect.DeepEqual(step.DependsOn, want) {
+					t.Errorf("step %s DependsOn = %v, want %v", step.ID, step.DependsOn, want)
+				}
+				if want, ok := tt.resources[step.ID]; ok && step.ProviderResource != want {
+					t.Errorf("step %s ProviderResource = %s, want %s", step.ID, step.ProviderResource, want)
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.steps) {
+				t.Errorf("steps = %v, want %v", ids, tt.steps)
+			}
+			if plan.EstimatedDurationSeconds != tt.duration {
+				t.Errorf("EstimatedDurationSeconds = %d, want %d", plan.EstimatedDurationSeconds, tt.duration)
+			}
+			if plan.InputHash != planInputHash(tt.env) {
+				t.Errorf("InputHash = %s, want planInputHash", plan.InputHash)
+			}
+		})
+	}
+}
+
+func TestPlanFleetWiseCampaignDefinition(t *testing.T) {
+	env := Environment{ID: "env-1", Provider: "aws", FleetWiseConfig: &FleetWiseConfig{
+		Region:            "us-east-1",
+		SignalCatalogARN:  "arn:aws:iotfleetwise:us-east-1:123456789012:signal-catalog/default",
+		VehicleNames:      []string{"car-1"},
+		Campaigns:         []CampaignConfig{{Name: "speed"}},
+		EnableCompression: true,
+	}}
+	plan, err := BuildExecutionPlan(env)
+	if err != nil {
+		t.Fatalf("BuildExecutionPlan: %v", err)
+	}
+	for _, step := range plan.Steps {
+		if step.Action != "create_campaign" {
+			continue
+		}
+		config, err := decodeFleetWiseStep(step)
+		if err != nil {
+			t.Fatalf("decodeFleetWiseStep: %v", err)
+		}
+		want := EnvironmentCampaign(env.ID, *env.FleetWiseConfig, CampaignConfig{Name: "speed"}, "")
+		if config.Definition == nil || !reflect.DeepEqual(*config.Definition, want) {
+			t.Errorf("campaign definition = %+v, want %+v", config.Definition, want)
+		}
+		if config.Region != "us-east-1" || !reflect.DeepEqual(config.VehicleNames, []string{"car-1"}) {
+			t.Errorf("campaign step config = %+v, want the environment's region and vehicles", config)
+		}
+		return
+	}
+	t.Fatal("plan has no create_campaign step")
+}
+
+func TestPlanInputHash(t *testing.T) {
+	env := Environment{
+		ID:              "env-1",
+		ComputeConfig:   ComputeConfig{CPU: 2, Memory: 4, Instances: 1},
+		SpecHash:        "abc",
+		FleetWiseConfig: &FleetWiseConfig{Region: "us-east-1"},
+	}
+	tests := []struct {
+		name   string
+		change func(env *Environment)
+		same   bool
+	}{
+		{name: "unchanged", change: func(env *Environment) {}, same: true},
+		{name: "descriptive fields", change: func(env *Environment) { env.Name, env.Status = "renamed", StatePlanned }, same: true},
+		{name: "spec", change: func(env *Environment) { env.SpecHash = "def" }},
+		{name: "compute", change: func(env *Environment) { env.ComputeConfig.Memory = 8 }},
+		{name: "storage", change: func(env *Environment) { env.Storage = 10 }},
+		{name: "FleetWise", change: func(env *Environment) { env.FleetWiseConfig = &FleetWiseConfig{Region: "eu-central-1"} }},
+		{name: "provider", change: func(env *Environment) { env.Provider = "on-prem" }},
+		{name: "deprecated AWS flag", change: func(env *Environment) { env.UseRealAWSBackend = true }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := env
+			tt.change(&changed)
+			if same := planInputHash(changed) == planInputHash(env); same != tt.same {
+				t.Errorf("hash unchanged = %t, want %t", same, tt.same)
+			}
+		})
+	}
+}
+
+func TestOrderStepsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []PlanStep
+		err   string
+	}{
+		{
+			name:  "duplicate step",
+			steps: []PlanStep{{ID: "network:lan"}, {ID: "network:lan"}},
+			err:   "step network:lan is planned more than once",
+		},
+		{
+			name:  "unknown dependency",
+			steps: []PlanStep{{ID: "a", DependsOn: []string{"b"}}},
+			err:   "step a depends on unknown step b",
+		},
+		{
+			name:  "cycle",
+			steps: []PlanStep{{ID: "a", DependsOn: []string{"b"}}, {ID: "b", DependsOn: []string{"a"}}},
+			err:   "cycle",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := orderSteps(tt.steps)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("orderSteps error = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ProviderAdapter is the unified interface to an infrastructure provider (spec §7.1).
+// Adapters are registered by provider name and selected by the environment's
+// provider field.
+type ProviderAdapter interface {
+	// Authenticate verifies the adapter's credentials before any step runs
+	Authenticate(ctx context.Context) error
+	// ValidateQuota rejects a plan the provider cannot fulfil before any step runs
+	ValidateQuota(ctx context.Context, plan *ExecutionPlan) error
+	ProvisionCompute(ctx context.Context, step PlanStep) error
+	ProvisionStorage(ctx context.Context, step PlanStep) error
+	ProvisionNetwork(ctx context.Context, step PlanStep) error
+	ConfigureSecurity(ctx context.Context, step PlanStep) error
+	// ProvisionResource performs every other plan action, such as deploying a
+	// component or creating FleetWise vehicles
+	ProvisionResource(ctx context.Context, step PlanStep) error
+	HealthCheck(ctx context.Context, step PlanStep) error
+	// Deallocate performs the step's RollbackAction
+	Deallocate(ctx context.Context, step PlanStep) error
+	GetMetrics(ctx context.Context) (map[string]float64, error)
+}
+
+// ProviderFactory creates the adapter for an environment
+type ProviderFactory func(env Environment) (ProviderAdapter, error)
+
+const defaultProvider = "simulated"
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{}
+)
+
+// RegisterProvider makes an adapter available under a provider name
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if _, exists := providers[name]; exists {
+		panic(fmt.Sprintf("provider %q registered twice", name))
+	}
+	providers[name] = factory
+}
+
+// RegisteredProviders returns the names of all registered providers
+func RegisteredProviders() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// providerName returns the provider that serves an environment. Environments
+// created before the provider field existed fall back to use_real_aws_backend.
+func providerName(env Environment) string {
+	switch {
+	case env.Provider != "":
+		return env.Provider
+	case env.UseRealAWSBackend:
+		return "aws"
+	default:
+		return defaultProvider
+	}
+}
+
+// providerFor creates the adapter registered for the environment's provider
+func providerFor(env Environment) (ProviderAdapter, error) {
+	name := providerName(env)
+	providersMu.RLock()
+	factory, ok := providers[name]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, &ClassifiedError{
+			Severity: SeverityFatal,
+			Type:     ErrorTypeValidation,
+			Err:      fmt.Errorf("no adapter registered for provider %q", name),
+		}
+	}
+	return factory(env)
+}
+
+// connectProvider creates and authenticates the environment's adapter
+func connectProvider(ctx context.Context, env Environment) (ProviderAdapter, error) {
+	adapter, err := providerFor(env)
+	if err != nil {
+		return nil, err
+	}
+	if err := adapter.Authenticate(ctx); err != nil {
+		return nil, fmt.Errorf("authentication with provider %s failed: %w", providerName(env), err)
+	}
+	return adapter, nil
+}
+
+// newStepRunner runs plan steps through an adapter. Recoverable step errors
+// are retried and every failure is recorded.
+func newStepRunner(envID string, adapter ProviderAdapter) StepRunner {
+	return &retryingRunner{StepRunner: adapterRunner{adapter}, envID: envID, policy: defaultRetryPolicy}
+}
+
+// adapterRunner dispatches plan steps to the matching adapter operation
+type adapterRunner struct {
+	adapter ProviderAdapter
+}
+
+func (r adapterRunner) RunStep(ctx context.Context, step PlanStep) error {
+	switch step.Action {
+	case "provision_compute":
+		return r.adapter.ProvisionCompute(ctx, step)
+	case "provision_storage":
+		return r.adapter.ProvisionStorage(ctx, step)
+	case "provision_network":
+		return r.adapter.ProvisionNetwork(ctx, step)
+	case "configure_security":
+		return r.adapter.ConfigureSecurity(ctx, step)
+	case "health_check":
+		return r.adapter.HealthCheck(ctx, step)
+	default:
+		return r.adapter.ProvisionResource(ctx, step)
+	}
+}
+
+func (r adapterRunner) CheckHealth(ctx context.Context, step PlanStep) error {
+	return r.adapter.HealthCheck(ctx, step)
+}
+
+func (r adapterRunner) RollbackStep(ctx context.Context, step PlanStep) error {
+	return r.adapter.Deallocate(ctx, step)
+}
+
+// unsupportedStep is returned by adapters for actions they cannot perform
+func unsupportedStep(provider string, step PlanStep) error {
+	return &ClassifiedError{
+		Severity: SeverityFatal,
+		Type:     ErrorTypeValidation,
+		Err:      fmt.Errorf("provider %s does not support action %q", provider, step.Action),
+	}
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise/types"
+	"github.com/aws/smithy-go"
+)
+
+func init() {
+	RegisterProvider("aws", newFleetWiseAdapter)
+}
+
+// fleetWiseAdapter provisions AWS IoT FleetWise fleets, vehicles and campaigns.
+// Generic AWS infrastructure (VPCs, EC2, ...) is not supported yet.
+type fleetWiseAdapter struct {
+	envID  string
+	config FleetWiseConfig
+	client FleetWiseAPI
+}
+
+func newFleetWiseAdapter(env Environment) (ProviderAdapter, error) {
+	if env.FleetWiseConfig == nil {
+		return nil, &ClassifiedError{
+			Severity: SeverityFatal,
+			Type:     ErrorTypeValidation,
+			Err:      errors.New("the aws provider requires a fleetwise_config"),
+		}
+	}
+	client, err := fleetWiseClients.Client(env.FleetWiseConfig.Region)
+	if errors.Is(err, ErrUnsupportedRegion) {
+		return nil, &ClassifiedError{Severity: SeverityFatal, Type: ErrorTypeValidation, Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &fleetWiseAdapter{envID: env.ID, config: *env.FleetWiseConfig, client: client}, nil
+}
+
+// Authenticate makes a cheap read so invalid credentials fail before any step runs
+func (a *fleetWiseAdapter) Authenticate(ctx context.Context) error {
+	_, _, err := a.client.ListCampaignsPage(ctx, "", "", 1)
+	return err
+}
+
+// fleetWiseStep is the configuration a FleetWise step was planned with, see
+// planFleetWise. Steps are executed from it rather than from the environment's
+// current configuration, so an approved plan creates what was reviewed.
+type fleetWiseStep struct {
+	Region             string          `json:"region"`
+	FleetID            string          `json:"fleet_id"`
+	SignalCatalogARN   string          `json:"signal_catalog_arn"`
+	ModelManifestARN   string          `json:"model_manifest_arn"`
+	DecoderManifestARN string          `json:"decoder_manifest_arn"`
+	VehicleNames       []string        `json:"vehicle_names"`
+	Campaign           string          `json:"campaign"`
+	Definition         *CampaignConfig `json:"definition"` // With the environment's defaults applied
+	CampaignARN        string          `json:"campaign_arn"`
+}
+
+// decodeFleetWiseStep reads a step's config, which is a map once the plan has
+// been stored
+func decodeFleetWiseStep(step PlanStep) (fleetWiseStep, error) {
+	var config fleetWiseStep
+	data, err := json.Marshal(step.Config)
+	if err == nil {
+		err = json.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return config, fmt.Errorf("step %s has an invalid config: %w", step.ID, err)
+	}
+	return config, nil
+}
+
+// ValidateQuota checks that every step is a FleetWise action, that it was
+// planned for the adapter's region and that its config has what it needs
+func (a *fleetWiseAdapter) ValidateQuota(ctx context.Context, plan *ExecutionPlan) error {
+	var errs []error
+	for _, step := range plan.Steps {
+		config, err := decodeFleetWiseStep(step)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if config.Region != a.config.Region {
+			errs = append(errs, fmt.Errorf("step %s was planned for region %q, not %q", step.ID, config.Region, a.config.Region))
+			continue
+		}
+		switch step.Action {
+		case "create_fleet":
+			if config.SignalCatalogARN == "" {
+				errs = append(errs, errors.New("creating a fleet requires signal_catalog_arn"))
+			}
+		case "create_vehicles":
+			if config.ModelManifestARN == "" || config.DecoderManifestARN == "" {
+				errs = append(errs, errors.New("creating vehicles requires model_manifest_arn and decoder_manifest_arn"))
+			}
+		case "create_campaign":
+			if config.Definition == nil {
+				errs = append(errs, fmt.Errorf("step %s: campaign %s has no definition", step.ID, config.Campaign))
+				continue
+			}
+			if config.Definition.SignalCatalogARN == "" {
+				errs = append(errs, errors.New("creating a campaign requires signal_catalog_arn"))
+				continue
+			}
+			// Check the condition against the catalog now rather than have
+			// CreateCampaign reject it after the fleet and vehicles exist
+			if err := CheckCampaignExpression(ctx, a.client, *config.Definition); err != nil {
+				errs = append(errs, fmt.Errorf("campaign %s: %w", config.Definition.Name, err))
+			}
+		case "associate_vehicles", "reuse_campaign":
+		default:
+			errs = append(errs, unsupportedStep("aws", step))
+		}
+	}
+	if len(errs) > 0 {
+		return &ClassifiedError{Severity: SeverityFatal, Type: ErrorTypeValidation, Err: errors.Join(errs...)}
+	}
+	return nil
+}
+
+func (a *fleetWiseAdapter) ProvisionCompute(ctx context.Context, step PlanStep) error {
+	return unsupportedStep("aws", step)
+}
+
+func (a *fleetWiseAdapter) ProvisionStorage(ctx context.Context, step PlanStep) error {
+	return unsupportedStep("aws", step)
+}
+
+func (a *fleetWiseAdapter) ProvisionNetwork(ctx context.Context, step PlanStep) error {
+	return unsupportedStep("aws", step)
+}
+
+func (a *fleetWiseAdapter) ConfigureSecurity(ctx context.Context, step PlanStep) error {
+	return unsupportedStep("aws", step)
+}
+
+// ProvisionResource creates FleetWise resources and records each one. Resources
+// recorded by an earlier attempt of the step are not created again.
+func (a *fleetWiseAdapter) ProvisionResource(ctx context.Context, step PlanStep) error {
+	recorded, err := activeAllocations(a.envID, step.ID)
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, allocation := range recorded {
+		existing[allocation.ResourceID] = true
+	}
+	config, err := decodeFleetWiseStep(step)
+	if err != nil {
+		return err
+	}
+
+	switch step.Action {
+	case "create_fleet":
+		if len(recorded) > 0 {
+			return nil
+		}
+		arn, err := CreateEnvironmentFleet(ctx, a.client, a.envID, FleetWiseConfig{
+			FleetID:          config.FleetID,
+			SignalCatalogARN: config.SignalCatalogARN,
+		})
+		if err != nil || arn == "" {
+			return err
+		}
+		return a.record(step, "fleet", arn, map[string]interface{}{"fleet_id": config.FleetID})
+	case "create_vehicles":
+		names := map[string]bool{}
+		for _, allocation := range recorded {
+			names[fmt.Sprint(allocation.Config["name"])] = true
+		}
+		var missing []string
+		for _, name := range config.VehicleNames {
+			if !names[name] {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		created, errs := CreateEnvironmentVehicles(ctx, a.client, a.envID, FleetWiseConfig{
+			ModelManifestARN:   config.ModelManifestARN,
+			DecoderManifestARN: config.DecoderManifestARN,
+		}, missing)
+		for _, vehicle := range created {
+			errs = append(errs, a.record(step, "vehicle", *vehicle.Arn, map[string]interface{}{"name": *vehicle.VehicleName}))
+		}
+		return errors.Join(errs...)
+	case "associate_vehicles":
+		var errs []error
+		for _, name := range config.VehicleNames {
+			id := config.FleetID + "/" + name
+			if existing[id] {
+				continue
+			}
+			if err := a.client.AssociateVehicleToFleet(ctx, name, config.FleetID); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			errs = append(errs, a.record(step, "fleet_association", id, map[string]interface{}{
+				"fleet_id":     config.FleetID,
+				"vehicle_name": name,
+			}))
+		}
+		return errors.Join(errs...)
+	case "create_campaign":
+		if config.Definition == nil {
+			return fmt.Errorf("campaign %s has no definition", config.Campaign)
+		}
+		campaign := *config.Definition
+		if len(recorded) == 0 {
+			if campaign.TargetARN == "" {
+				if campaign.TargetARN, err = a.campaignTarget(ctx, config); err != nil {
+					return err
+				}
+			}
+			result, err := a.client.CreateCampaign(ctx, campaign)
+			if err != nil {
+				return err
+			}
+			if err := a.record(step, "campaign", *result.Arn, map[string]interface{}{"name": campaign.Name}); err != nil {
+				return err
+			}
+		}
+		// Approved when the environment starts, see campaign_lifecycle.go
+		_, err := AwaitCampaign(ctx, a.client, campaign.Name)
+		return err
+	case "reuse_campaign":
+		if len(recorded) > 0 {
+			return nil
+		}
+		name := campaignNameFromARN(config.CampaignARN)
+		campaign, err := a.client.GetCampaign(ctx, name)
+		if err != nil {
+			return fmt.Errorf("campaign %s cannot be reused: %w", config.CampaignARN, err)
+		}
+		// Recorded so that the campaign is tracked, but marked reused so that
+		// it is left in place when the environment is torn down
+		return a.record(step, "campaign", *campaign.Arn, map[string]interface{}{"name": name, "reused": true})
+	default:
+		return unsupportedStep("aws", step)
+	}
+}
+
+// campaignTarget is the fleet, or without a fleet the first vehicle, that
+// campaigns without a target_arn are deployed to
+func (a *fleetWiseAdapter) campaignTarget(ctx context.Context, config fleetWiseStep) (string, error) {
+	if config.FleetID != "" {
+		fleets, err := activeAllocations(a.envID, "fleet:"+config.FleetID)
+		if err != nil {
+			return "", err
+		}
+		if len(fleets) == 0 {
+			return "", fmt.Errorf("fleet %s has not been created", config.FleetID)
+		}
+		return fleets[0].ResourceID, nil
+	}
+	if len(config.VehicleNames) == 0 {
+		return "", nil
+	}
+	vehicle, err := a.client.GetVehicle(ctx, config.VehicleNames[0])
+	if err != nil {
+		return "", err
+	}
+	return *vehicle.Arn, nil
+}
+
+func (a *fleetWiseAdapter) record(step PlanStep, resourceType, arn string, config map[string]interface{}) error {
+	return recordAllocation(ResourceAllocation{
+		EnvironmentID: a.envID,
+		StepID:        step.ID,
+		ResourceType:  resourceType,
+		ResourceID:    arn,
+		Provider:      "aws",
+		Region:        a.config.Region,
+		Config:        config,
+	})
+}
+
+// HealthCheck verifies that the vehicles created by a step are known to
+// FleetWise and that the campaigns created by a step have been created
+func (a *fleetWiseAdapter) HealthCheck(ctx context.Context, step PlanStep) error {
+	config, err := decodeFleetWiseStep(step)
+	if err != nil {
+		return err
+	}
+	switch step.Action {
+	case "create_vehicles":
+		for _, name := range config.VehicleNames {
+			if _, err := a.client.GetVehicle(ctx, name); err != nil {
+				return fmt.Errorf("vehicle %s is not available: %w", name, err)
+			}
+		}
+	case "create_campaign":
+		if config.Definition == nil {
+			return nil
+		}
+		name := config.Definition.Name
+		result, err := a.client.GetCampaign(ctx, name)
+		if err != nil {
+			return fmt.Errorf("campaign %s is not available: %w", name, err)
+		}
+		if result.Status == types.CampaignStatusCreating {
+			return fmt.Errorf("campaign %s is still %s", name, result.Status)
+		}
+	}
+	return nil
+}
+
+// Deallocate deletes or detaches the FleetWise resources recorded for a step
+func (a *fleetWiseAdapter) Deallocate(ctx context.Context, step PlanStep) error {
+	return releaseAllocations(ctx, a.envID, step.ID, func(ctx context.Context, allocation ResourceAllocation) error {
+		var err error
+		switch allocation.ResourceType {
+		case "fleet":
+			err = a.client.DeleteFleet(ctx, fmt.Sprint(allocation.Config["fleet_id"]))
+		case "vehicle":
+			err = a.client.DeleteVehicle(ctx, fmt.Sprint(allocation.Config["name"]))
+		case "fleet_association":
+			err = a.client.DisassociateVehicleFromFleet(ctx, fmt.Sprint(allocation.Config["vehicle_name"]), fmt.Sprint(allocation.Config["fleet_id"]))
+		case "campaign":
+			if allocation.Config["reused"] == true {
+				return nil // Not the environment's to delete
+			}
+			err = a.client.DeleteCampaign(ctx, fmt.Sprint(allocation.Config["name"]))
+		default:
+			return fmt.Errorf("unsupported FleetWise resource type %q", allocation.ResourceType)
+		}
+		if isAWSNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// ReleaseUnrecorded deletes the vehicles and campaigns of an environment that
+// was provisioned before resources were recorded. Only vehicles tagged with
+// the environment's ID are deleted.
+func (a *fleetWiseAdapter) ReleaseUnrecorded(ctx context.Context) error {
+	config := a.config
+	config.VehicleNames = nil
+	for _, name := range a.config.VehicleNames {
+		vehicle, err := a.client.GetVehicle(ctx, name)
+		if isAWSNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if vehicle.Attributes["EnvironmentID"] == a.envID {
+			config.VehicleNames = append(config.VehicleNames, name)
+		}
+	}
+	return DeProvisionFleetWiseEnvironment(ctx, a.client, a.envID, config)
+}
+
+// isAWSNotFound reports whether err says the resource does not exist
+func isAWSNotFound(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "ResourceNotFoundException"
+}
+
+// GetMetrics reports how many of the environment's vehicles FleetWise knows about
+func (a *fleetWiseAdapter) GetMetrics(ctx context.Context) (map[string]float64, error) {
+	available := 0
+	for _, name := range a.config.VehicleNames {
+		if _, err := a.client.GetVehicle(ctx, name); err == nil {
+			available++
+		}
+	}
+	return map[string]float64{
+		"vehicles":           float64(len(a.config.VehicleNames)),
+		"vehicles_available": float64(available),
+	}, nil
+}
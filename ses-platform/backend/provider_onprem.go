@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"ses-platform/spec"
+)
+
+// defaultContainerHealthTimeout bounds the wait for a container without a health_check
+const defaultContainerHealthTimeout = 30 * time.Second
+
+func init() {
+	RegisterProvider("on-prem", newOnPremAdapter)
+}
+
+// onPremAdapter runs container components on the local Docker engine. Networks
+// become bridge networks, storage becomes named volumes mounted into every
+// container, and created resources are recorded in resource_allocations.
+// Security group rules are not enforced.
+type onPremAdapter struct {
+	env    Environment
+	docker *dockerClient
+}
+
+func newOnPremAdapter(env Environment) (ProviderAdapter, error) {
+	docker, err := newDockerClient()
+	if err != nil {
+		return nil, err
+	}
+	return &onPremAdapter{env: env, docker: docker}, nil
+}
+
+func (a *onPremAdapter) Authenticate(ctx context.Context) error {
+	return a.docker.Ping(ctx)
+}
+
+// ValidateQuota checks that every component is a container and that the
+// host has the memory the environment asks for: each container of each
+// component gets the environment's per-instance memory. Containers run without
+// CPU limits and share the host's CPUs, so asking for more CPUs than the host
+// has only slows the environment down and is logged rather than rejected.
+func (a *onPremAdapter) ValidateQuota(ctx context.Context, plan *ExecutionPlan) error {
+	var errs []error
+	containers := 0
+	for _, step := range plan.Steps {
+		switch step.Action {
+		case "deploy_component":
+			comp := a.component(step.Component)
+			switch {
+			case comp == nil:
+				errs = append(errs, fmt.Errorf("component %s is not in the spec", step.Component))
+			case comp.Type != "container":
+				errs = append(errs, fmt.Errorf("component %s has type %s; only container components can run on-prem", comp.ID, comp.Type))
+			case comp.Specification.Image == "":
+				errs = append(errs, fmt.Errorf("component %s has no specification.image", comp.ID))
+			default:
+				containers += max(comp.Quantity, 1)
+			}
+		case "validate", "provision_network", "configure_security", "provision_compute", "provision_storage", "health_check", "start":
+		default:
+			errs = append(errs, unsupportedStep("on-prem", step))
+		}
+	}
+
+	info, err := a.docker.Info(ctx)
+	if err != nil {
+		return err
+	}
+	if cpu := a.env.ComputeConfig.CPU * containers; cpu > info.NCPU {
+		log.Printf("Warning: environment %s asks for %d CPUs but the host has %d; containers will share them", a.env.ID, cpu, info.NCPU)
+	}
+	if memory := int64(a.env.ComputeConfig.Memory*containers) << 30; memory > info.MemTotal {
+		errs = append(errs, fmt.Errorf("environment needs %d GB of memory but the host has %d GB", memory>>30, info.MemTotal>>30))
+	}
+
+	if len(errs) > 0 {
+		return &ClassifiedError{Severity: SeverityFatal, Type: ErrorTypeValidation, Err: errors.Join(errs...)}
+	}
+	return nil
+}
+
+// ProvisionCompute is a no-op: containers run directly on the host
+func (a *onPremAdapter) ProvisionCompute(ctx context.Context, step PlanStep) error {
+	return ctx.Err()
+}
+
+// ProvisionStorage creates a named volume for a storage request
+func (a *onPremAdapter) ProvisionStorage(ctx context.Context, step PlanStep) error {
+	if err := a.releaseStep(ctx, step.ID); err != nil {
+		return err
+	}
+	name, err := a.docker.CreateVolume(ctx, a.resourceName(step.ID), a.labels(step.ID))
+	if err != nil {
+		return err
+	}
+	return a.record(step, "storage", name, map[string]interface{}{"mount_path": storageMountPath(step.ID)})
+}
+
+// ProvisionNetwork creates a bridge network using the spec network's CIDR as subnet
+func (a *onPremAdapter) ProvisionNetwork(ctx context.Context, step PlanStep) error {
+	if err := a.releaseStep(ctx, step.ID); err != nil {
+		return err
+	}
+	req := dockerNetworkCreate{Name: a.resourceName(step.ID), Driver: "bridge", Labels: a.labels(step.ID)}
+	if cidr, _ := step.Config["cidr"].(string); cidr != "" {
+		req.IPAM.Config = []dockerIPAMConfig{{Subnet: cidr}}
+	}
+	id, err := a.docker.CreateNetwork(ctx, req)
+	if err != nil {
+		return err
+	}
+	return a.record(step, "network", id, map[string]interface{}{"name": req.Name})
+}
+
+// ConfigureSecurity is a no-op: security group rules are not enforced on-prem
+func (a *onPremAdapter) ConfigureSecurity(ctx context.Context, step PlanStep) error {
+	return ctx.Err()
+}
+
+func (a *onPremAdapter) ProvisionResource(ctx context.Context, step PlanStep) error {
+	switch step.Action {
+	case "deploy_component":
+		return a.deployComponent(ctx, step)
+	case "validate", "start":
+		return ctx.Err()
+	default:
+		return unsupportedStep("on-prem", step)
+	}
+}
+
+// deployComponent pulls the component's image and starts one container per
+// unit of quantity, attached to its networks and the environment's volumes
+func (a *onPremAdapter) deployComponent(ctx context.Context, step PlanStep) error {
+	comp := a.component(step.Component)
+	if comp == nil {
+		return fmt.Errorf("component %s is not in the spec", step.Component)
+	}
+	if err := a.releaseStep(ctx, step.ID); err != nil {
+		return err
+	}
+
+	allocations, err := activeAllocations(a.env.ID, "")
+	if err != nil {
+		return err
+	}
+	var binds []string
+	storage := map[string]bool{}
+	networkIDs := map[string]string{}
+	for _, allocation := range allocations {
+		switch allocation.ResourceType {
+		case "storage":
+			binds = append(binds, fmt.Sprintf("%s:%v", allocation.ResourceID, allocation.Config["mount_path"]))
+			storage[allocation.StepID] = true
+		case "network":
+			networkIDs[allocation.StepID] = allocation.ResourceID
+		}
+	}
+	// The component must not start without the storage and networks it was
+	// planned with, even if their steps were skipped or rolled back
+	var errs []error
+	for _, dep := range step.DependsOn {
+		if strings.HasPrefix(dep, "infrastructure:") && strings.HasSuffix(dep, ":storage") && !storage[dep] {
+			errs = append(errs, fmt.Errorf("storage %s has not been provisioned", dep))
+		}
+	}
+	var networks []string
+	for _, ref := range comp.Networks {
+		netID, _, _ := strings.Cut(ref, "/")
+		id, ok := networkIDs["network:"+netID]
+		if !ok {
+			errs = append(errs, fmt.Errorf("network %s has not been provisioned", netID))
+			continue
+		}
+		networks = append(networks, id)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	image := comp.Specification.Image
+	if version := comp.Specification.Version; version != "" && !strings.Contains(image[strings.LastIndex(image, "/")+1:], ":") {
+		image += ":" + version
+	}
+	if err := a.docker.PullImage(ctx, image); err != nil {
+		return err
+	}
+
+	req := dockerContainerCreate{
+		Image:       image,
+		Env:         containerEnv(comp.Specification.Configuration),
+		Labels:      a.labels(step.ID),
+		Healthcheck: containerHealthcheck(comp.HealthCheck),
+	}
+	req.Labels["io.simplan.component"] = comp.ID
+	req.HostConfig.Binds = binds
+	if len(networks) > 0 {
+		req.HostConfig.NetworkMode = networks[0]
+	}
+
+	quantity := max(comp.Quantity, 1)
+	for i := 1; i <= quantity; i++ {
+		name := a.resourceName(fmt.Sprintf("%s-%d", comp.ID, i))
+		id, err := a.docker.CreateContainer(ctx, name, req)
+		if err != nil {
+			return err
+		}
+		if err := a.record(step, "container", id, map[string]interface{}{"name": name, "image": image}); err != nil {
+			return err
+		}
+		for _, network := range networks[min(1, len(networks)):] {
+			if err := a.docker.ConnectNetwork(ctx, network, id); err != nil {
+				return err
+			}
+		}
+		if err := a.docker.StartContainer(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HealthCheck waits for a component's containers to run. For health_check
+// steps it waits for Docker to report them healthy within interval*retries.
+func (a *onPremAdapter) HealthCheck(ctx context.Context, step PlanStep) error {
+	if step.Component == "" {
+		return ctx.Err()
+	}
+	containers, err := activeAllocations(a.env.ID, "component:"+step.Component)
+	if err != nil {
+		return err
+	}
+
+	timeout := defaultContainerHealthTimeout
+	waitHealthy := false
+	if comp := a.component(step.Component); step.Action == "health_check" && comp != nil && comp.HealthCheck != nil {
+		waitHealthy = true
+		if hc := comp.HealthCheck; hc.Retries > 0 && !hc.Interval.IsZero() {
+			timeout = hc.Interval.Duration*time.Duration(hc.Retries) + hc.Timeout.Duration
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for _, container := range containers {
+		if err := a.waitForContainer(ctx, container, waitHealthy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *onPremAdapter) waitForContainer(ctx context.Context, container ResourceAllocation, waitHealthy bool) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		state, err := a.docker.InspectContainer(ctx, container.ResourceID)
+		if err != nil {
+			return err
+		}
+		switch {
+		case !state.Running && state.Status == "exited":
+			return fmt.Errorf("container %v exited with code %d", container.Config["name"], state.ExitCode)
+		case state.Health != nil && state.Health.Status == "unhealthy":
+			return fmt.Errorf("container %v is unhealthy", container.Config["name"])
+		case state.Running && (!waitHealthy || state.Health == nil || state.Health.Status == "healthy"):
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("container %v did not become healthy: %w", container.Config["name"], ctx.Err())
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// Deallocate removes every resource recorded for the step
+func (a *onPremAdapter) Deallocate(ctx context.Context, step PlanStep) error {
+	return a.releaseStep(ctx, step.ID)
+}
+
+// releaseStep removes the resources a step created, newest first
+func (a *onPremAdapter) releaseStep(ctx context.Context, stepID string) error {
+	return releaseAllocations(ctx, a.env.ID, stepID, func(ctx context.Context, allocation ResourceAllocation) error {
+		var err error
+		switch allocation.ResourceType {
+		case "container":
+			err = a.docker.RemoveContainer(ctx, allocation.ResourceID)
+		case "network":
+			err = a.docker.RemoveNetwork(ctx, allocation.ResourceID)
+		case "storage":
+			err = a.docker.RemoveVolume(ctx, allocation.ResourceID)
+		}
+		if isDockerNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// GetMetrics reports how many of the environment's containers are running
+func (a *onPremAdapter) GetMetrics(ctx context.Context) (map[string]float64, error) {
+	allocations, err := activeAllocations(a.env.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	containers, running := 0, 0
+	for _, allocation := range allocations {
+		if allocation.ResourceType != "container" {
+			continue
+		}
+		containers++
+		if state, err := a.docker.InspectContainer(ctx, allocation.ResourceID); err == nil && state.Running {
+			running++
+		}
+	}
+	return map[string]float64{
+		"containers":         float64(containers),
+		"containers_running": float64(running),
+	}, nil
+}
+
+func (a *onPremAdapter) component(id string) *spec.Component {
+	if a.env.Spec == nil || a.env.Spec.Spec.Architecture == nil {
+		return nil
+	}
+	for i, comp := range a.env.Spec.Spec.Architecture.Components {
+		if comp.ID == id {
+			return &a.env.Spec.Spec.Architecture.Components[i]
+		}
+	}
+	return nil
+}
+
+// resourceName derives a Docker object name that is unique to the environment
+func (a *onPremAdapter) resourceName(suffix string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '-'
+		}
+	}, suffix)
+	return fmt.Sprintf("ses-%s-%s", a.env.ID, name)
+}
+
+func (a *onPremAdapter) record(step PlanStep, resourceType, id string, config map[string]interface{}) error {
+	return recordAllocation(ResourceAllocation{
+		EnvironmentID: a.env.ID,
+		StepID:        step.ID,
+		ResourceType:  resourceType,
+		ResourceID:    id,
+		Provider:      "on-prem",
+		Config:        config,
+	})
+}
+
+func (a *onPremAdapter) labels(stepID string) map[string]string {
+	return map[string]string{
+		"io.simplan.environment": a.env.ID,
+		"io.simplan.step":        stepID,
+	}
+}
+
+// storageMountPath is where a storage step's volume is mounted in containers:
+// /mnt/storage-<i> for spec infrastructure, /mnt/storage otherwise
+func storageMountPath(stepID string) string {
+	if parts := strings.Split(stepID, ":"); len(parts) == 3 {
+		return "/mnt/storage-" + parts[1]
+	}
+	return "/mnt/storage"
+}
+
+// containerEnv passes a component's configuration as environment variables
+func containerEnv(config map[string]interface{}) []string {
+	env := make([]string, 0, len(config))
+	for key, value := range config {
+		env = append(env, fmt.Sprintf("%s=%v", key, value))
+	}
+	sort.Strings(env)
+	return env
+}
+
+// containerHealthcheck probes an HTTP health_check endpoint from inside the
+// container. Relative endpoints are resolved against localhost.
+func containerHealthcheck(hc *spec.HealthCheck) *dockerHealthConfig {
+	if hc == nil || hc.Endpoint == "" {
+		return nil
+	}
+	endpoint := hc.Endpoint
+	if strings.HasPrefix(endpoint, "/") {
+		endpoint = "http://localhost" + endpoint
+	}
+	probe := fmt.Sprintf("wget -q -O /dev/null '%[1]s' || curl -fsS -o /dev/null '%[1]s' || exit 1", endpoint)
+	return &dockerHealthConfig{
+		Test:     []string{"CMD-SHELL", probe},
+		Interval: int64(hc.Interval.Duration),
+		Timeout:  int64(hc.Timeout.Duration),
+		Retries:  hc.Retries,
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ses-platform/spec"
+)
+
+// serveDocker serves a Docker engine that only answers /info on a unix
+// socket and points DOCKER_HOST at it
+func serveDocker(t *testing.T, info dockerInfo) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+dockerAPIVersion+"/info" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(info)
+	})}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	t.Setenv("DOCKER_HOST", "unix://"+socket)
+}
+
+func TestOnPremValidateQuota(t *testing.T) {
+	serveDocker(t, dockerInfo{NCPU: 4, MemTotal: 16 << 30})
+
+	components := []spec.Component{
+		{ID: "app", Type: "container", Specification: spec.ComponentSpecification{Image: "nginx"}},
+		{ID: "workers", Type: "container", Quantity: 2, Specification: spec.ComponentSpecification{Image: "worker"}},
+		{ID: "ecu", Type: "hardware"},
+		{ID: "empty", Type: "container"},
+	}
+	tests := []struct {
+		name    string
+		compute ComputeConfig
+		steps   []PlanStep
+		errs    []string // Substrings of the expected error
+	}{
+		{
+			name:    "fits the host",
+			compute: ComputeConfig{CPU: 2, Memory: 4, Instances: 2},
+			steps:   []PlanStep{{ID: "component:app", Action: "deploy_component", Component: "app"}},
+		},
+		{
+			name:    "more CPUs than the host is allowed",
+			compute: ComputeConfig{CPU: 8, Memory: 2, Instances: 4},
+			steps:   []PlanStep{{ID: "component:app", Action: "deploy_component", Component: "app"}},
+		},
+		{
+			name:    "more memory than the host",
+			compute: ComputeConfig{CPU: 1, Memory: 8, Instances: 3},
+			steps: []PlanStep{
+				{ID: "component:app", Action: "deploy_component", Component: "app"},
+				{ID: "component:workers", Action: "deploy_component", Component: "workers"},
+			},
+			errs: []string{"needs 24 GB of memory but the host has 16 GB"},
+		},
+		{
+			name:    "memory is counted per deployed container",
+			compute: ComputeConfig{CPU: 1, Memory: 8, Instances: 3},
+			steps:   []PlanStep{{ID: "component:app", Action: "deploy_component", Component: "app"}},
+		},
+		{
+			name:    "components that cannot run on-prem",
+			compute: ComputeConfig{CPU: 1, Memory: 1},
+			steps: []PlanStep{
+				{ID: "component:ecu", Action: "deploy_component", Component: "ecu"},
+				{ID: "component:empty", Action: "deploy_component", Component: "empty"},
+				{ID: "component:gone", Action: "deploy_component", Component: "gone"},
+			},
+			errs: []string{
+				"only container components can run on-prem",
+				"component empty has no specification.image",
+				"component gone is not in the spec",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := Environment{ID: "env-1", ComputeConfig: tt.compute, Spec: &spec.Document{}}
+			env.Spec.Spec.Architecture = &spec.Architecture{Components: components}
+			adapter, err := newOnPremAdapter(env)
+			if err != nil {
+				t.Fatalf("newOnPremAdapter: %v", err)
+			}
+
+			err = adapter.ValidateQuota(context.Background(), &ExecutionPlan{Steps: tt.steps})
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Fatalf("ValidateQuota: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("ValidateQuota succeeded, want %q", tt.errs)
+			}
+			for _, want := range tt.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("ValidateQuota error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// simulatedStepDelay is how long a simulated step takes to complete
+var simulatedStepDelay = 2 * time.Second
+
+func init() {
+	RegisterProvider("simulated", func(env Environment) (ProviderAdapter, error) {
+		return simulatedAdapter{envID: env.ID}, nil
+	})
+}
+
+// simulatedAdapter completes every operation after a short delay. Nothing is
+// created, but simulated resources are recorded like real ones.
+type simulatedAdapter struct {
+	envID string
+}
+
+func (simulatedAdapter) Authenticate(ctx context.Context) error {
+	return nil
+}
+
+func (simulatedAdapter) ValidateQuota(ctx context.Context, plan *ExecutionPlan) error {
+	return nil
+}
+
+func (a simulatedAdapter) ProvisionCompute(ctx context.Context, step PlanStep) error {
+	return a.allocate(ctx, step, "compute")
+}
+
+func (a simulatedAdapter) ProvisionStorage(ctx context.Context, step PlanStep) error {
+	return a.allocate(ctx, step, "storage")
+}
+
+func (a simulatedAdapter) ProvisionNetwork(ctx context.Context, step PlanStep) error {
+	return a.allocate(ctx, step, "network")
+}
+
+func (a simulatedAdapter) ConfigureSecurity(ctx context.Context, step PlanStep) error {
+	return a.allocate(ctx, step, "security")
+}
+
+func (a simulatedAdapter) ProvisionResource(ctx context.Context, step PlanStep) error {
+	switch step.Action {
+	case "provision_database":
+		return a.allocate(ctx, step, "database")
+	case "provision_cache":
+		return a.allocate(ctx, step, "cache")
+	case "deploy_component":
+		return a.allocate(ctx, step, "container")
+	default:
+		return a.wait(ctx)
+	}
+}
+
+func (simulatedAdapter) HealthCheck(ctx context.Context, step PlanStep) error {
+	return ctx.Err()
+}
+
+func (a simulatedAdapter) Deallocate(ctx context.Context, step PlanStep) error {
+	return releaseAllocations(ctx, a.envID, step.ID, func(ctx context.Context, _ ResourceAllocation) error {
+		return a.wait(ctx)
+	})
+}
+
+func (simulatedAdapter) GetMetrics(ctx context.Context) (map[string]float64, error) {
+	return map[string]float64{
+		"cpu_usage":    rand.Float64() * 100,
+		"memory_usage": rand.Float64() * 100,
+		"disk_usage":   rand.Float64() * 100,
+		"network_in":   rand.Float64() * 1000,
+		"network_out":  rand.Float64() * 1000,
+	}, nil
+}
+
+// allocate waits and records one simulated resource for the step
+func (a simulatedAdapter) allocate(ctx context.Context, step PlanStep, resourceType string) error {
+	if recorded, err := activeAllocations(a.envID, step.ID); err != nil || len(recorded) > 0 {
+		return err
+	}
+	if err := a.wait(ctx); err != nil {
+		return err
+	}
+	return recordAllocation(ResourceAllocation{
+		EnvironmentID: a.envID,
+		StepID:        step.ID,
+		ResourceType:  resourceType,
+		ResourceID:    fmt.Sprintf("%s-%s-%s", step.ProviderResource, a.envID, step.ID),
+		Provider:      "simulated",
+		Config:        step.Config,
+	})
+}
+
+func (simulatedAdapter) wait(ctx context.Context) error {
+	select {
+	case <-time.After(simulatedStepDelay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getEnvironmentResources returns the provider resources recorded for an
+// environment, optionally filtered by status or resource_type
+func getEnvironmentResources(c *gin.Context) {
+	query := db.Where("environment_id = ?", c.Param("id"))
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if resourceType := c.Query("resource_type"); resourceType != "" {
+		query = query.Where("resource_type = ?", resourceType)
+	}
+
+	var resources []ResourceAllocation
+	if err := query.Order("allocated_at, id").Find(&resources).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resources)
+}
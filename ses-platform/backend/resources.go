@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ResourceAllocation is a provider resource created for an environment
+type ResourceAllocation struct {
+	ID            uint                   `gorm:"primaryKey" json:"id"`
+	EnvironmentID string                 `gorm:"index" json:"environment_id"`
+	StepID        string                 `json:"step_id"`       // Plan step that created the resource
+	ResourceType  string                 `json:"resource_type"` // compute, storage, network, container, fleet, vehicle, campaign, ...
+	ResourceID    string                 `json:"resource_id"`   // Provider-specific resource identifier, such as an ARN
+	Provider      string                 `json:"provider"`
+	Region        string                 `json:"region,omitempty"`
+	Status        string                 `gorm:"index" json:"status"` // allocating, active, releasing, released, failed
+	Config        map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"config"`
+	LastError     string                 `json:"last_error,omitempty"` // Why the last release attempt failed
+	AllocatedAt   time.Time              `json:"allocated_at"`
+	ReleasedAt    *time.Time             `json:"released_at,omitempty"`
+}
+
+const (
+	AllocationStatusAllocating = "allocating"
+	AllocationStatusActive     = "active"
+	AllocationStatusReleasing  = "releasing"
+	AllocationStatusReleased   = "released"
+	AllocationStatusFailed     = "failed"
+)
+
+// recordAllocation stores an active resource created by a plan step
+func recordAllocation(allocation ResourceAllocation) error {
+	if allocation.Config == nil {
+		allocation.Config = map[string]interface{}{}
+	}
+	allocation.Status = AllocationStatusActive
+	allocation.AllocatedAt = time.Now()
+	return db.Create(&allocation).Error
+}
+
+// activeAllocations returns the resources of an environment that have not
+// been released, newest first. With a stepID only that step's resources are returned.
+func activeAllocations(envID, stepID string) ([]ResourceAllocation, error) {
+	query := db.Where("environment_id = ? AND status IN ?", envID,
+		[]string{AllocationStatusAllocating, AllocationStatusActive, AllocationStatusReleasing, AllocationStatusFailed})
+	if stepID != "" {
+		query = query.Where("step_id = ?", stepID)
+	}
+	var allocations []ResourceAllocation
+	err := query.Order("allocated_at desc, id desc").Find(&allocations).Error
+	return allocations, err
+}
+
+// releaseAllocations releases the resources recorded for a step, newest first.
+// release must treat a resource that no longer exists as released.
+func releaseAllocations(ctx context.Context, envID, stepID string, release func(context.Context, ResourceAllocation) error) error {
+	allocations, err := activeAllocations(envID, stepID)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range allocations {
+		allocation := &allocations[i]
+		if err := setAllocationStatus(allocation, AllocationStatusReleasing, nil); err != nil {
+			return err
+		}
+		status := AllocationStatusReleased
+		releaseErr := release(ctx, *allocation)
+		if releaseErr != nil {
+			status = AllocationStatusFailed
+			errs = append(errs, fmt.Errorf("releasing %s %s: %w", allocation.ResourceType, allocation.ResourceID, releaseErr))
+		}
+		if err := setAllocationStatus(allocation, status, releaseErr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setAllocationStatus records the lifecycle status of a resource and the
+// error that caused a failed release
+func setAllocationStatus(allocation *ResourceAllocation, status string, cause error) error {
+	allocation.LastError = ""
+	if cause != nil {
+		allocation.LastError = cause.Error()
+	}
+	updates := map[string]interface{}{"status": status, "last_error": allocation.LastError}
+	if status == AllocationStatusReleased {
+		now := time.Now()
+		allocation.ReleasedAt = &now
+		updates["released_at"] = now
+	}
+	allocation.Status = status
+	return db.Model(&ResourceAllocation{}).Where("id = ?", allocation.ID).Updates(updates).Error
+}
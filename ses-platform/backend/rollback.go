@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RollbackOperation unwinds the completed steps of a provisioning plan in
+// reverse order (spec §10.1). Step results are persisted as they finish, so an
+// interrupted rollback resumes where it stopped.
+type RollbackOperation struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	EnvironmentID  string         `gorm:"index" json:"environment_id"`
+	PlanID         uint           `json:"plan_id"`
+	TriggerType    string         `json:"trigger_type"` // manual, error, timeout, policy_violation
+	RollbackTarget string         `json:"rollback_target"`
+	Steps          []RollbackStep `gorm:"type:jsonb;serializer:json" json:"steps"`
+	Status         string         `json:"status"` // pending, in_progress, completed, failed
+	StartedAt      time.Time      `json:"started_at"`
+	CompletedAt    *time.Time     `json:"completed_at,omitempty"`
+	ErrorMessage   string         `json:"error_message,omitempty"`
+}
+
+// RollbackStep is the inverse action of a completed plan step and its result
+type RollbackStep struct {
+	StepID           string     `json:"step_id"`
+	Name             string     `json:"name"`
+	Action           string     `json:"action"`
+	ProviderResource string     `json:"provider_resource"`
+	Status           string     `json:"status"` // pending, completed, failed
+	Error            string     `json:"error,omitempty"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+}
+
+const (
+	RollbackStatusPending    = "pending"
+	RollbackStatusInProgress = "in_progress"
+	RollbackStatusCompleted  = "completed"
+	RollbackStatusFailed     = "failed"
+
+	RollbackTriggerManual = "manual"
+	RollbackTriggerError  = "error"
+
+	JobTypeRollback = "rollback"
+)
+
+// ErrRollbackInProgress is returned when a rollback is requested while another
+// rollback of the environment has not finished
+var ErrRollbackInProgress = errors.New("a rollback is already in progress")
+
+func init() {
+	jobHandlers[JobTypeRollback] = runRollbackJob
+}
+
+// failAndRollBack returns a write that fails the plan and schedules the
+// rollback of its completed steps, for use with failEnvironment. The
+// environment still fails when a rollback is already in progress.
+func failAndRollBack(plan *ExecutionPlan, completed []string, trigger string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		if err := setPlanStatus(plan, PlanStatusFailed)(tx); err != nil {
+			return err
+		}
+		_, err := enqueueRollback(tx, plan.EnvironmentID, plan.ID, completed, trigger)
+		if errors.Is(err, ErrRollbackInProgress) {
+			log.Printf("Not rolling back plan %d of environment %s: %v", plan.ID, plan.EnvironmentID, err)
+			return nil
+		}
+		return err
+	}
+}
+
+// checkNoActiveRollback returns ErrRollbackInProgress while a rollback of the
+// environment is pending or in progress
+func checkNoActiveRollback(tx *gorm.DB, envID string) error {
+	var active int64
+	if err := tx.Model(&RollbackOperation{}).
+		Where("environment_id = ? AND status IN ?", envID, []string{RollbackStatusPending, RollbackStatusInProgress}).
+		Count(&active).Error; err != nil {
+		return err
+	}
+	if active > 0 {
+		return ErrRollbackInProgress
+	}
+	return nil
+}
+
+// enqueueRollback records a rollback of the completed plan steps and enqueues
+// the job that performs it. Steps that did not complete but recorded
+// resources, such as a step that failed halfway, are undone first. Steps
+// already undone by an earlier rollback of the same plan, and steps without
+// an inverse action, are left out.
+func enqueueRollback(tx *gorm.DB, envID string, planID uint, completed []string, trigger string) (*RollbackOperation, error) {
+	if err := checkNoActiveRollback(tx, envID); err != nil {
+		return nil, err
+	}
+
+	var plan ExecutionPlan
+	if planID != 0 {
+		if err := tx.First(&plan, planID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var earlier []RollbackOperation
+	if err := tx.Where("environment_id = ? AND plan_id = ?", envID, planID).Find(&earlier).Error; err != nil {
+		return nil, err
+	}
+	undone := map[string]bool{}
+	for _, op := range earlier {
+		for _, step := range op.Steps {
+			if step.Status == RollbackStatusCompleted {
+				undone[step.StepID] = true
+			}
+		}
+	}
+
+	var allocated []string
+	if err := tx.Model(&ResourceAllocation{}).
+		Where("environment_id = ? AND status <> ?", envID, AllocationStatusReleased).
+		Distinct().Pluck("step_id", &allocated).Error; err != nil {
+		return nil, err
+	}
+	steps := rollbackSteps(plan, completed, allocated, undone)
+
+	op := RollbackOperation{
+		EnvironmentID:  envID,
+		PlanID:         planID,
+		TriggerType:    trigger,
+		RollbackTarget: StateRolledBack,
+		Steps:          steps,
+		Status:         RollbackStatusPending,
+		StartedAt:      time.Now(),
+	}
+	if err := tx.Create(&op).Error; err != nil {
+		return nil, err
+	}
+	if _, err := enqueueJob(tx, JobTypeRollback, envID, map[string]interface{}{"rollback_id": op.ID}); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// rollbackSteps orders the inverse actions of a plan's steps LIFO: the last
+// step to complete is the first to be rolled back, after the steps that did
+// not complete but have allocated resources
+func rollbackSteps(plan ExecutionPlan, completed, allocated []string, undone map[string]bool) []RollbackStep {
+	byID := make(map[string]PlanStep, len(plan.Steps))
+	for _, step := range plan.Steps {
+		byID[step.ID] = step
+	}
+	var partial []string
+	for _, step := range plan.Steps {
+		if !slices.Contains(completed, step.ID) && slices.Contains(allocated, step.ID) {
+			partial = append(partial, step.ID)
+		}
+	}
+
+	order := append(append([]string{}, completed...), partial...)
+	steps := []RollbackStep{}
+	for i := len(order) - 1; i >= 0; i-- {
+		step, ok := byID[order[i]]
+		if !ok || step.RollbackAction == "" || undone[step.ID] {
+			continue
+		}
+		steps = append(steps, RollbackStep{
+			StepID:           step.ID,
+			Name:             step.Name,
+			Action:           step.RollbackAction,
+			ProviderResource: step.ProviderResource,
+			Status:           RollbackStatusPending,
+		})
+	}
+	return steps
+}
+
+// completedPlanSteps returns the plan last executed for an environment and the
+// steps its provision job completed, in completion order
+func completedPlanSteps(envID string) (uint, []string, error) {
+	var job Job
+	err := db.Where("environment_id = ? AND type = ?", envID, JobTypeProvision).
+		Order("id desc").
+		First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	planID, _ := job.Payload["plan_id"].(float64)
+	return uint(planID), job.Checkpoint, nil
+}
+
+// runRollbackJob performs the pending steps of a rollback operation and moves
+// the environment from failed to rolled_back once every step is undone
+func runRollbackJob(ctx context.Context, job *Job) error {
+	rollbackID, _ := job.Payload["rollback_id"].(float64)
+	var op RollbackOperation
+	if err := db.First(&op, uint(rollbackID)).Error; err != nil {
+		return &permanentJobError{fmt.Errorf("rollback %v not found: %v", job.Payload["rollback_id"], err)}
+	}
+	var env Environment
+	if err := db.First(&env, "id = ?", op.EnvironmentID).Error; err != nil {
+		return &permanentJobError{fmt.Errorf("environment not found: %v", err)}
+	}
+	if env.Status != StateFailed {
+		finishRollback(&op, fmt.Errorf("environment is %s, not failed", env.Status))
+		return &permanentJobError{fmt.Errorf("environment is %s, not failed", env.Status)}
+	}
+
+	var plan ExecutionPlan
+	if op.PlanID != 0 {
+		if err := db.First(&plan, op.PlanID).Error; err != nil {
+			return &permanentJobError{fmt.Errorf("plan %d not found: %v", op.PlanID, err)}
+		}
+	}
+	byID := make(map[string]PlanStep, len(plan.Steps))
+	for _, step := range plan.Steps {
+		byID[step.ID] = step
+	}
+
+	if op.Status == RollbackStatusPending {
+		op.Status = RollbackStatusInProgress
+		db.Model(&op).Update("status", op.Status)
+	}
+
+	adapter, err := connectProvider(ctx, env)
+	if err != nil {
+		return rollbackAttemptFailed(job, &op, fmt.Errorf("provider %s is not available: %w", providerName(env), err))
+	}
+	runner := newStepRunner(env.ID, adapter)
+
+	log.Printf("Rolling back %d step(s) of environment %s", len(op.Steps), env.ID)
+	for i := range op.Steps {
+		step := &op.Steps[i]
+		if step.Status == RollbackStatusCompleted {
+			continue
+		}
+		if ctx.Err() != nil {
+			return rollbackInterrupted(ctx, &op)
+		}
+
+		err := runner.RollbackStep(ctx, byID[step.StepID])
+		now := time.Now()
+		step.CompletedAt = &now
+		if err != nil {
+			step.Status = RollbackStatusFailed
+			step.Error = err.Error()
+		} else {
+			step.Status = RollbackStatusCompleted
+			step.Error = ""
+		}
+		if saveErr := saveRollbackSteps(&op); saveErr != nil {
+			log.Printf("Error saving rollback %d: %v", op.ID, saveErr)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return rollbackInterrupted(ctx, &op)
+			}
+			return rollbackAttemptFailed(job, &op, &StepError{StepID: step.StepID, Err: err})
+		}
+	}
+
+	now := time.Now()
+	_, err = transitionEnvironment(env.ID, Transition{
+		To:       StateRolledBack,
+		Reason:   fmt.Sprintf("Rolled back %d step(s)", len(op.Steps)),
+		Metadata: map[string]interface{}{"rollback_id": op.ID, "plan_id": op.PlanID, "trigger": op.TriggerType},
+		InTx: func(tx *gorm.DB) error {
+			op.Status = RollbackStatusCompleted
+			op.CompletedAt = &now
+			return tx.Model(&RollbackOperation{}).Where("id = ?", op.ID).Updates(map[string]interface{}{
+				"status":       RollbackStatusCompleted,
+				"completed_at": now,
+			}).Error
+		},
+	})
+	if err != nil {
+		return rollbackAttemptFailed(job, &op, err)
+	}
+	return nil
+}
+
+// rollbackAttemptFailed lets the job retry a failed rollback; completed steps
+// are skipped on the next attempt. After the last attempt the operation fails
+// and the environment stays failed until a manual rollback.
+func rollbackAttemptFailed(job *Job, op *RollbackOperation, err error) error {
+	if job.Attempts < job.MaxAttempts {
+		return err
+	}
+	finishRollback(op, err)
+
+	auditLog := AuditLog{
+		EnvironmentID: op.EnvironmentID,
+		Action:        "rollback_failed",
+		UserID:        "system",
+		Details:       map[string]interface{}{"error": err.Error(), "rollback_id": op.ID},
+		CreatedAt:     time.Now(),
+	}
+	db.Create(&auditLog)
+	return &permanentJobError{err}
+}
+
+// rollbackInterrupted fails a cancelled rollback. When the claim was lost the
+// operation is left in progress for the worker that took over.
+func rollbackInterrupted(ctx context.Context, op *RollbackOperation) error {
+	cause := context.Cause(ctx)
+	if errors.Is(cause, errJobCancelled) {
+		finishRollback(op, cause)
+	}
+	return cause
+}
+
+// finishRollback marks an operation failed with the error that stopped it
+func finishRollback(op *RollbackOperation, err error) {
+	now := time.Now()
+	op.Status = RollbackStatusFailed
+	op.ErrorMessage = err.Error()
+	op.CompletedAt = &now
+	db.Model(&RollbackOperation{}).Where("id = ?", op.ID).Updates(map[string]interface{}{
+		"status":        op.Status,
+		"error_message": op.ErrorMessage,
+		"completed_at":  now,
+	})
+}
+
+// saveRollbackSteps persists the step results of an operation
+func saveRollbackSteps(op *RollbackOperation) error {
+	// Map updates bypass GORM serializers, so encode the JSONB column explicitly
+	encoded, err := json.Marshal(op.Steps)
+	if err != nil {
+		return err
+	}
+	return db.Model(&RollbackOperation{}).Where("id = ?", op.ID).Update("steps", string(encoded)).Error
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Rollback API Handlers
+
+type RollbackRequest struct {
+	RequestedBy string `json:"requested_by"`
+}
+
+// rollbackEnvironment starts a rollback of a failed environment, for example
+// after an automatic rollback ran out of attempts. Steps an earlier rollback
+// already undid are not repeated.
+func rollbackEnvironment(c *gin.Context) {
+	id := c.Param("id")
+	var env Environment
+	if err := db.First(&env, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Environment not found"})
+		return
+	}
+	if !CanTransition(env.Status, StateRolledBack) {
+		respondTransitionError(c, &TransitionError{From: env.Status, To: StateRolledBack})
+		return
+	}
+
+	var req RollbackRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	planID, completed, err := completedPlanSteps(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var op *RollbackOperation
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		op, err = enqueueRollback(tx, id, planID, completed, RollbackTriggerManual)
+		if err != nil {
+			return err
+		}
+		auditLog := AuditLog{
+			EnvironmentID: id,
+			Action:        "rollback_requested",
+			UserID:        req.RequestedBy,
+			Details: map[string]interface{}{
+				"message":     fmt.Sprintf("Rollback %d requested with %d step(s)", op.ID, len(op.Steps)),
+				"rollback_id": op.ID,
+			},
+			CreatedAt: time.Now(),
+		}
+		return tx.Create(&auditLog).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrRollbackInProgress) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, op)
+}
+
+// getEnvironmentRollbacks returns the rollback operations of an environment
+func getEnvironmentRollbacks(c *gin.Context) {
+	id := c.Param("id")
+	var operations []RollbackOperation
+	db.Where("environment_id = ?", id).Order("started_at desc, id desc").Find(&operations)
+	c.JSON(http.StatusOK, operations)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRollbackSteps(t *testing.T) {
+	plan := ExecutionPlan{Steps: []PlanStep{
+		{ID: "validate", Action: "validate"},
+		{ID: "network", Action: "provision_network", RollbackAction: "delete_network"},
+		{ID: "storage", Action: "provision_storage", RollbackAction: "deallocate_storage"},
+		{ID: "component:app", Action: "deploy_component", RollbackAction: "undeploy_component"},
+		{ID: "component:db", Action: "deploy_component", RollbackAction: "undeploy_component"},
+	}}
+
+	tests := []struct {
+		name      string
+		completed []string
+		allocated []string
+		undone    map[string]bool
+		want      []string
+	}{
+		{
+			name:      "last completed is undone first",
+			completed: []string{"validate", "storage", "network", "component:app"},
+			want:      []string{"component:app", "network", "storage"},
+		},
+		{
+			name:      "partially provisioned steps are undone before completed ones",
+			completed: []string{"network", "storage"},
+			allocated: []string{"network", "component:db", "component:app"},
+			want:      []string{"component:db", "component:app", "storage", "network"},
+		},
+		{
+			name:      "steps undone by an earlier rollback are skipped",
+			completed: []string{"network", "storage", "component:app"},
+			undone:    map[string]bool{"component:app": true, "storage": true},
+			want:      []string{"network"},
+		},
+		{
+			name:      "steps of another plan are skipped",
+			completed: []string{"network", "compute"},
+			want:      []string{"network"},
+		},
+		{
+			name: "nothing to undo",
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := rollbackSteps(plan, tt.completed, tt.allocated, tt.undone)
+			ids := []string{}
+			for _, step := range steps {
+				ids = append(ids, step.StepID)
+				if step.Status != RollbackStatusPending {
+					t.Errorf("step %s Status = %s, want %s", step.StepID, step.Status, RollbackStatusPending)
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("rollback order = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	steps := rollbackSteps(plan, []string{"network"}, nil, nil)
+	if want := (RollbackStep{StepID: "network", Action: "delete_network", Status: RollbackStatusPending}); !reflect.DeepEqual(steps, []RollbackStep{want}) {
+		t.Errorf("rollbackSteps = %+v, want %+v", steps, want)
+	}
+}
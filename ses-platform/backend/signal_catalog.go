@@ -0,0 +1,410 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise/types"
+)
+
+// SignalNode is a node of a FleetWise signal catalog. Branches group the
+// sensor, attribute and actuator signals under them.
+type SignalNode struct {
+	FullyQualifiedName string   `json:"fully_qualified_name"` // e.g. Vehicle.Powertrain.Speed
+	Type               string   `json:"type"`                 // branch, sensor, attribute, actuator
+	DataType           string   `json:"data_type,omitempty"`  // FleetWise data type, e.g. DOUBLE; not set for branches
+	Description        string   `json:"description,omitempty"`
+	Unit               string   `json:"unit,omitempty"`
+	Min                *float64 `json:"min,omitempty"`
+	Max                *float64 `json:"max,omitempty"`
+	AllowedValues      []string `json:"allowed_values,omitempty"`
+	DefaultValue       string   `json:"default_value,omitempty"` // Attributes only
+	Comment            string   `json:"comment,omitempty"`
+	DeprecationMessage string   `json:"deprecation_message,omitempty"`
+}
+
+const (
+	SignalNodeBranch    = "branch"
+	SignalNodeSensor    = "sensor"
+	SignalNodeAttribute = "attribute"
+	SignalNodeActuator  = "actuator"
+)
+
+// SignalCatalogChanges are the node changes that turn one catalog into another,
+// in the form UpdateSignalCatalog takes them
+type SignalCatalogChanges struct {
+	NodesToAdd    []SignalNode `json:"nodes_to_add"`
+	NodesToUpdate []SignalNode `json:"nodes_to_update"`
+	NodesToRemove []string     `json:"nodes_to_remove"`
+	// NodesToReplace changed type. FleetWise cannot change a node's type, so
+	// they are removed in one update and added again in the next.
+	NodesToReplace []SignalNode `json:"nodes_to_replace,omitempty"`
+}
+
+// Empty reports whether there are no changes
+func (c SignalCatalogChanges) Empty() bool {
+	return len(c.NodesToAdd) == 0 && len(c.NodesToUpdate) == 0 && len(c.NodesToRemove) == 0 && len(c.NodesToReplace) == 0
+}
+
+// validateSignalNodes checks node names, types and data types
+func validateSignalNodes(nodes []SignalNode) error {
+	var errs []error
+	seen := map[string]bool{}
+	for _, node := range nodes {
+		name := node.FullyQualifiedName
+		if name == "" {
+			errs = append(errs, errors.New("signal node without fully_qualified_name"))
+			continue
+		}
+		if seen[name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate node", name))
+		}
+		seen[name] = true
+
+		switch node.Type {
+		case SignalNodeBranch:
+		case SignalNodeSensor, SignalNodeAttribute, SignalNodeActuator:
+			if !validNodeDataType(node.DataType) {
+				errs = append(errs, fmt.Errorf("%s: unsupported data_type %q", name, node.DataType))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("%s: unsupported node type %q", name, node.Type))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateSignalCatalog checks the nodes of a complete catalog, including that
+// every node's parent branch is defined
+func validateSignalCatalog(nodes []SignalNode) error {
+	errs := []error{validateSignalNodes(nodes)}
+	branches := map[string]bool{}
+	for _, node := range nodes {
+		if node.Type == SignalNodeBranch {
+			branches[node.FullyQualifiedName] = true
+		}
+	}
+	for _, node := range nodes {
+		if parent, _, ok := cutLast(node.FullyQualifiedName, "."); ok && !branches[parent] {
+			errs = append(errs, fmt.Errorf("%s: parent branch %s is not defined", node.FullyQualifiedName, parent))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validNodeDataType(dataType string) bool {
+	for _, known := range types.NodeDataType("").Values() {
+		if string(known) == dataType && known != types.NodeDataTypeStruct && known != types.NodeDataTypeStructArray {
+			return true
+		}
+	}
+	return false
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// vssDataTypes maps COVESA VSS datatypes to FleetWise data types
+var vssDataTypes = map[string]string{
+	"int8": "INT8", "uint8": "UINT8", "int16": "INT16", "uint16": "UINT16",
+	"int32": "INT32", "uint32": "UINT32", "int64": "INT64", "uint64": "UINT64",
+	"boolean": "BOOLEAN", "float": "FLOAT", "double": "DOUBLE", "string": "STRING",
+}
+
+// vssNode is a node of a COVESA VSS tree as exported to JSON by vss-tools
+type vssNode struct {
+	Type        string             `json:"type"`
+	Datatype    string             `json:"datatype"`
+	Description string             `json:"description"`
+	Comment     string             `json:"comment"`
+	Deprecation string             `json:"deprecation"`
+	Unit        string             `json:"unit"`
+	Min         *float64           `json:"min"`
+	Max         *float64           `json:"max"`
+	Allowed     []interface{}      `json:"allowed"`
+	Default     interface{}        `json:"default"`
+	Children    map[string]vssNode `json:"children"`
+}
+
+// ImportVSS converts a COVESA VSS JSON tree, keyed by its root branch (e.g.
+// {"Vehicle": {"type": "branch", "children": {...}}}), into signal catalog
+// nodes. Parents precede their children and siblings are sorted by name.
+func ImportVSS(data []byte) ([]SignalNode, error) {
+	var roots map[string]vssNode
+	if err := json.Unmarshal(data, &roots); err != nil {
+		return nil, fmt.Errorf("invalid VSS JSON: %w", err)
+	}
+	if len(roots) == 0 {
+		return nil, errors.New("VSS tree is empty")
+	}
+
+	var nodes []SignalNode
+	var errs []error
+	var walk func(name string, vss vssNode)
+	walk = func(name string, vss vssNode) {
+		node, err := vss.signalNode(name)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		nodes = append(nodes, node)
+		for _, child := range sortedMapKeys(vss.Children) {
+			walk(name+"."+child, vss.Children[child])
+		}
+	}
+	for _, root := range sortedMapKeys(roots) {
+		walk(root, roots[root])
+	}
+	return nodes, errors.Join(errs...)
+}
+
+func (v vssNode) signalNode(name string) (SignalNode, error) {
+	node := SignalNode{
+		FullyQualifiedName: name,
+		Type:               v.Type,
+		Description:        v.Description,
+		Comment:            v.Comment,
+		DeprecationMessage: v.Deprecation,
+	}
+	switch v.Type {
+	case SignalNodeBranch:
+		return node, nil
+	case SignalNodeSensor, SignalNodeAttribute, SignalNodeActuator:
+	default:
+		return node, fmt.Errorf("%s: VSS type %q is not supported", name, v.Type)
+	}
+
+	base, isArray := strings.CutSuffix(v.Datatype, "[]")
+	dataType, ok := vssDataTypes[base]
+	if !ok {
+		return node, fmt.Errorf("%s: VSS datatype %q is not supported", name, v.Datatype)
+	}
+	if isArray {
+		dataType += "_ARRAY"
+	}
+	node.DataType = dataType
+	node.Unit = v.Unit
+	node.Min = v.Min
+	node.Max = v.Max
+	for _, allowed := range v.Allowed {
+		node.AllowedValues = append(node.AllowedValues, fmt.Sprint(allowed))
+	}
+	if v.Default != nil && v.Type == SignalNodeAttribute {
+		node.DefaultValue = fmt.Sprint(v.Default)
+	}
+	return node, nil
+}
+
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DiffSignalCatalog returns the changes that turn the deployed nodes into the
+// local ones. A node whose type changed is replaced rather than updated. Nodes
+// are added and replaced parents first and removed children first.
+func DiffSignalCatalog(local, deployed []SignalNode) SignalCatalogChanges {
+	changes := SignalCatalogChanges{NodesToAdd: []SignalNode{}, NodesToUpdate: []SignalNode{}, NodesToRemove: []string{}}
+	deployedByName := make(map[string]SignalNode, len(deployed))
+	for _, node := range deployed {
+		deployedByName[node.FullyQualifiedName] = node
+	}
+	localNames := make(map[string]bool, len(local))
+
+	for _, node := range local {
+		localNames[node.FullyQualifiedName] = true
+		existing, ok := deployedByName[node.FullyQualifiedName]
+		switch {
+		case !ok:
+			changes.NodesToAdd = append(changes.NodesToAdd, node)
+		case existing.Type != node.Type:
+			changes.NodesToReplace = append(changes.NodesToReplace, node)
+		case !sameSignalNode(existing, node):
+			changes.NodesToUpdate = append(changes.NodesToUpdate, node)
+		}
+	}
+	for _, node := range deployed {
+		if !localNames[node.FullyQualifiedName] {
+			changes.NodesToRemove = append(changes.NodesToRemove, node.FullyQualifiedName)
+		}
+	}
+
+	for _, nodes := range [][]SignalNode{changes.NodesToAdd, changes.NodesToReplace} {
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return nodeDepth(nodes[i].FullyQualifiedName) < nodeDepth(nodes[j].FullyQualifiedName)
+		})
+	}
+	sort.SliceStable(changes.NodesToRemove, func(i, j int) bool {
+		return nodeDepth(changes.NodesToRemove[i]) > nodeDepth(changes.NodesToRemove[j])
+	})
+	return changes
+}
+
+func nodeDepth(name string) int {
+	return strings.Count(name, ".")
+}
+
+func sameSignalNode(a, b SignalNode) bool {
+	if a.Type != b.Type || a.DataType != b.DataType || a.Description != b.Description || a.Unit != b.Unit ||
+		a.DefaultValue != b.DefaultValue || a.Comment != b.Comment || a.DeprecationMessage != b.DeprecationMessage {
+		return false
+	}
+	if !sameBound(a.Min, b.Min) || !sameBound(a.Max, b.Max) || len(a.AllowedValues) != len(b.AllowedValues) {
+		return false
+	}
+	for i := range a.AllowedValues {
+		if a.AllowedValues[i] != b.AllowedValues[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameBound(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// optionalString returns nil for an empty string, since FleetWise rejects empty values
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
+// toFleetWiseNode converts a node to its SDK representation
+func toFleetWiseNode(node SignalNode) (types.Node, error) {
+	name := aws.String(node.FullyQualifiedName)
+	dataType := types.NodeDataType(node.DataType)
+	switch node.Type {
+	case SignalNodeBranch:
+		return &types.NodeMemberBranch{Value: types.Branch{
+			FullyQualifiedName: name,
+			Description:        optionalString(node.Description),
+			Comment:            optionalString(node.Comment),
+			DeprecationMessage: optionalString(node.DeprecationMessage),
+		}}, nil
+	case SignalNodeSensor:
+		return &types.NodeMemberSensor{Value: types.Sensor{
+			FullyQualifiedName: name,
+			DataType:           dataType,
+			Description:        optionalString(node.Description),
+			Unit:               optionalString(node.Unit),
+			Min:                node.Min,
+			Max:                node.Max,
+			AllowedValues:      node.AllowedValues,
+			Comment:            optionalString(node.Comment),
+			DeprecationMessage: optionalString(node.DeprecationMessage),
+		}}, nil
+	case SignalNodeAttribute:
+		return &types.NodeMemberAttribute{Value: types.Attribute{
+			FullyQualifiedName: name,
+			DataType:           dataType,
+			Description:        optionalString(node.Description),
+			Unit:               optionalString(node.Unit),
+			Min:                node.Min,
+			Max:                node.Max,
+			AllowedValues:      node.AllowedValues,
+			DefaultValue:       optionalString(node.DefaultValue),
+			Comment:            optionalString(node.Comment),
+			DeprecationMessage: optionalString(node.DeprecationMessage),
+		}}, nil
+	case SignalNodeActuator:
+		return &types.NodeMemberActuator{Value: types.Actuator{
+			FullyQualifiedName: name,
+			DataType:           dataType,
+			Description:        optionalString(node.Description),
+			Unit:               optionalString(node.Unit),
+			Min:                node.Min,
+			Max:                node.Max,
+			AllowedValues:      node.AllowedValues,
+			Comment:            optionalString(node.Comment),
+			DeprecationMessage: optionalString(node.DeprecationMessage),
+		}}, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported node type %q", node.FullyQualifiedName, node.Type)
+}
+
+func toFleetWiseNodes(nodes []SignalNode) ([]types.Node, error) {
+	converted := make([]types.Node, 0, len(nodes))
+	for _, node := range nodes {
+		n, err := toFleetWiseNode(node)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, n)
+	}
+	return converted, nil
+}
+
+// fromFleetWiseNode converts an SDK node. Custom structs and properties are
+// not supported and are reported as not ok.
+func fromFleetWiseNode(node types.Node) (SignalNode, bool) {
+	switch n := node.(type) {
+	case *types.NodeMemberBranch:
+		return SignalNode{
+			FullyQualifiedName: aws.ToString(n.Value.FullyQualifiedName),
+			Type:               SignalNodeBranch,
+			Description:        aws.ToString(n.Value.Description),
+			Comment:            aws.ToString(n.Value.Comment),
+			DeprecationMessage: aws.ToString(n.Value.DeprecationMessage),
+		}, true
+	case *types.NodeMemberSensor:
+		return SignalNode{
+			FullyQualifiedName: aws.ToString(n.Value.FullyQualifiedName),
+			Type:               SignalNodeSensor,
+			DataType:           string(n.Value.DataType),
+			Description:        aws.ToString(n.Value.Description),
+			Unit:               aws.ToString(n.Value.Unit),
+			Min:                n.Value.Min,
+			Max:                n.Value.Max,
+			AllowedValues:      n.Value.AllowedValues,
+			Comment:            aws.ToString(n.Value.Comment),
+			DeprecationMessage: aws.ToString(n.Value.DeprecationMessage),
+		}, true
+	case *types.NodeMemberAttribute:
+		return SignalNode{
+			FullyQualifiedName: aws.ToString(n.Value.FullyQualifiedName),
+			Type:               SignalNodeAttribute,
+			DataType:           string(n.Value.DataType),
+			Description:        aws.ToString(n.Value.Description),
+			Unit:               aws.ToString(n.Value.Unit),
+			Min:                n.Value.Min,
+			Max:                n.Value.Max,
+			AllowedValues:      n.Value.AllowedValues,
+			DefaultValue:       aws.ToString(n.Value.DefaultValue),
+			Comment:            aws.ToString(n.Value.Comment),
+			DeprecationMessage: aws.ToString(n.Value.DeprecationMessage),
+		}, true
+	case *types.NodeMemberActuator:
+		return SignalNode{
+			FullyQualifiedName: aws.ToString(n.Value.FullyQualifiedName),
+			Type:               SignalNodeActuator,
+			DataType:           string(n.Value.DataType),
+			Description:        aws.ToString(n.Value.Description),
+			Unit:               aws.ToString(n.Value.Unit),
+			Min:                n.Value.Min,
+			Max:                n.Value.Max,
+			AllowedValues:      n.Value.AllowedValues,
+			Comment:            aws.ToString(n.Value.Comment),
+			DeprecationMessage: aws.ToString(n.Value.DeprecationMessage),
+		}, true
+	}
+	return SignalNode{}, false
+}
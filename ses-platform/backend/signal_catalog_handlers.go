@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FleetWise Signal Catalog API Handlers
+
+// signalCatalogNodes describes a catalog's nodes either as a node list or as
+// a COVESA VSS JSON tree
+type signalCatalogNodes struct {
+	Nodes []SignalNode    `json:"nodes"`
+	VSS   json.RawMessage `json:"vss"`
+}
+
+// resolve returns the described nodes, validated as a complete catalog
+func (s signalCatalogNodes) resolve() ([]SignalNode, error) {
+	nodes := s.Nodes
+	if len(s.VSS) > 0 {
+		if len(nodes) > 0 {
+			return nil, errors.New("give either nodes or vss, not both")
+		}
+		var err error
+		if nodes, err = ImportVSS(s.VSS); err != nil {
+			return nil, err
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("nodes or vss is required")
+	}
+	return nodes, validateSignalCatalog(nodes)
+}
+
+func createSignalCatalog(c *gin.Context) {
+	var req struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+		Region      string `json:"region"`
+		signalCatalogNodes
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	nodes, err := req.resolve()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := fleetWiseClients.Client(req.Region)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	result, err := client.CreateSignalCatalog(c.Request.Context(), req.Name, req.Description, nodes)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Signal catalog created successfully",
+		"arn":        result.Arn,
+		"name":       result.Name,
+		"node_count": len(nodes),
+	})
+}
+
+func listSignalCatalogs(c *gin.Context) {
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	catalogs, err := client.ListSignalCatalogs(c.Request.Context(), 50)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"signal_catalogs": catalogs,
+		"count":           len(catalogs),
+	})
+}
+
+// getSignalCatalog returns a catalog's summary; nodes=true includes its nodes
+func getSignalCatalog(c *gin.Context) {
+	name := c.Param("name")
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	result, err := client.GetSignalCatalog(c.Request.Context(), name)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+	response := gin.H{
+		"name":                   result.Name,
+		"arn":                    result.Arn,
+		"description":            result.Description,
+		"node_counts":            result.NodeCounts,
+		"creation_time":          result.CreationTime,
+		"last_modification_time": result.LastModificationTime,
+	}
+
+	if c.Query("nodes") == "true" {
+		nodes, err := client.ListSignalCatalogNodes(c.Request.Context(), name)
+		if err != nil {
+			respondFleetWiseError(c, err)
+			return
+		}
+		response["nodes"] = nodes
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// updateSignalCatalog applies explicit node changes, or, given nodes or vss,
+// replaces the catalog's nodes with them
+func updateSignalCatalog(c *gin.Context) {
+	name := c.Param("name")
+	var req struct {
+		Description string `json:"description"`
+		SignalCatalogChanges
+		signalCatalogNodes
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	changes := req.SignalCatalogChanges
+	if len(req.Nodes) > 0 || len(req.VSS) > 0 {
+		if !changes.Empty() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "give either nodes or vss, or node changes, not both"})
+			return
+		}
+		changes, err = diffDeployedSignalCatalog(c, client, name, req.signalCatalogNodes)
+		if err != nil {
+			return
+		}
+	} else if err := validateSignalNodes(slices.Concat(changes.NodesToAdd, changes.NodesToUpdate, changes.NodesToReplace)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if changes.Empty() && req.Description == "" {
+		c.JSON(http.StatusOK, gin.H{"message": "Signal catalog is already up to date", "changes": changes})
+		return
+	}
+	if err := client.UpdateSignalCatalog(c.Request.Context(), name, req.Description, changes); err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Signal catalog updated successfully", "changes": changes})
+}
+
+func deleteSignalCatalog(c *gin.Context) {
+	name := c.Param("name")
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	if err := client.DeleteSignalCatalog(c.Request.Context(), name); err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Signal catalog deleted successfully"})
+}
+
+// diffSignalCatalog compares a local catalog, given as nodes or vss, with the
+// deployed one. The changes can be sent unmodified to PUT /fleetwise/signal-catalogs/:name.
+func diffSignalCatalog(c *gin.Context) {
+	name := c.Param("name")
+	var req signalCatalogNodes
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	changes, err := diffDeployedSignalCatalog(c, client, name, req)
+	if err != nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":             name,
+		"in_sync":          changes.Empty(),
+		"nodes_to_add":     changes.NodesToAdd,
+		"nodes_to_update":  changes.NodesToUpdate,
+		"nodes_to_remove":  changes.NodesToRemove,
+		"nodes_to_replace": changes.NodesToReplace,
+	})
+}
+
+// diffDeployedSignalCatalog diffs local nodes against the deployed catalog.
+// On error the response has already been written.
+func diffDeployedSignalCatalog(c *gin.Context, client FleetWiseAPI, name string, local signalCatalogNodes) (SignalCatalogChanges, error) {
+	nodes, err := local.resolve()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return SignalCatalogChanges{}, err
+	}
+	deployed, err := client.ListSignalCatalogNodes(c.Request.Context(), name)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return SignalCatalogChanges{}, err
+	}
+	return DiffSignalCatalog(nodes, deployed), nil
+}
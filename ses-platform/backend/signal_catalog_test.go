@@ -0,0 +1,185 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImportVSS(t *testing.T) {
+	speedMax := 250.0
+	tests := []struct {
+		name  string
+		vss   string
+		nodes []SignalNode
+		err   string
+	}{
+		{
+			name: "tree",
+			vss: `{"Vehicle": {"type": "branch", "description": "High-level vehicle data.", "children": {
+				"Speed": {"type": "sensor", "datatype": "float", "unit": "km/h", "max": 250},
+				"Cabin": {"type": "branch", "children": {
+					"DoorCount": {"type": "attribute", "datatype": "uint8", "default": 4, "allowed": [2, 4]}
+				}},
+				"TirePressures": {"type": "sensor", "datatype": "uint16[]", "deprecation": "Use Wheel.Tire"}
+			}}}`,
+			nodes: []SignalNode{
+				{FullyQualifiedName: "Vehicle", Type: SignalNodeBranch, Description: "High-level vehicle data."},
+				{FullyQualifiedName: "Vehicle.Cabin", Type: SignalNodeBranch},
+				{FullyQualifiedName: "Vehicle.Cabin.DoorCount", Type: SignalNodeAttribute, DataType: "UINT8", AllowedValues: []string{"2", "4"}, DefaultValue: "4"},
+				{FullyQualifiedName: "Vehicle.Speed", Type: SignalNodeSensor, DataType: "FLOAT", Unit: "km/h", Max: &speedMax},
+				{FullyQualifiedName: "Vehicle.TirePressures", Type: SignalNodeSensor, DataType: "UINT16_ARRAY", DeprecationMessage: "Use Wheel.Tire"},
+			},
+		},
+		{
+			name: "defaults of sensors are ignored",
+			vss:  `{"Vehicle": {"type": "branch", "children": {"Speed": {"type": "sensor", "datatype": "double", "default": 0}}}}`,
+			nodes: []SignalNode{
+				{FullyQualifiedName: "Vehicle", Type: SignalNodeBranch},
+				{FullyQualifiedName: "Vehicle.Speed", Type: SignalNodeSensor, DataType: "DOUBLE"},
+			},
+		},
+		{
+			name: "invalid JSON",
+			vss:  `{"Vehicle":`,
+			err:  "invalid VSS JSON",
+		},
+		{
+			name: "empty",
+			vss:  `{}`,
+			err:  "VSS tree is empty",
+		},
+		{
+			name: "unsupported type",
+			vss:  `{"Vehicle": {"type": "branch", "children": {"Trailer": {"type": "struct"}}}}`,
+			err:  `Vehicle.Trailer: VSS type "struct" is not supported`,
+		},
+		{
+			name: "unsupported datatype",
+			vss:  `{"Vehicle": {"type": "branch", "children": {"Position": {"type": "sensor", "datatype": "Types.Position"}}}}`,
+			err:  `Vehicle.Position: VSS datatype "Types.Position" is not supported`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, err := ImportVSS([]byte(tt.vss))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("ImportVSS error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ImportVSS: %v", err)
+			}
+			if !reflect.DeepEqual(nodes, tt.nodes) {
+				t.Errorf("ImportVSS = %+v, want %+v", nodes, tt.nodes)
+			}
+			if err := validateSignalCatalog(nodes); err != nil {
+				t.Errorf("imported catalog is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestDiffSignalCatalog(t *testing.T) {
+	branch := func(name string) SignalNode {
+		return SignalNode{FullyQualifiedName: name, Type: SignalNodeBranch}
+	}
+	sensor := func(name, unit string) SignalNode {
+		return SignalNode{FullyQualifiedName: name, Type: SignalNodeSensor, DataType: "DOUBLE", Unit: unit}
+	}
+	deployed := []SignalNode{
+		branch("Vehicle"),
+		branch("Vehicle.Cabin"),
+		sensor("Vehicle.Cabin.Temperature", "celsius"),
+		sensor("Vehicle.Speed", "km/h"),
+	}
+
+	tests := []struct {
+		name    string
+		local   []SignalNode
+		changes SignalCatalogChanges
+	}{
+		{
+			name:    "unchanged",
+			local:   deployed,
+			changes: SignalCatalogChanges{NodesToAdd: []SignalNode{}, NodesToUpdate: []SignalNode{}, NodesToRemove: []string{}},
+		},
+		{
+			name: "added parents first",
+			local: append([]SignalNode{
+				sensor("Vehicle.Powertrain.Battery.Voltage", "V"),
+				branch("Vehicle.Powertrain.Battery"),
+				branch("Vehicle.Powertrain"),
+			}, deployed...),
+			changes: SignalCatalogChanges{
+				NodesToAdd: []SignalNode{
+					branch("Vehicle.Powertrain"),
+					branch("Vehicle.Powertrain.Battery"),
+					sensor("Vehicle.Powertrain.Battery.Voltage", "V"),
+				},
+				NodesToUpdate: []SignalNode{},
+				NodesToRemove: []string{},
+			},
+		},
+		{
+			name:  "updated",
+			local: []SignalNode{branch("Vehicle"), branch("Vehicle.Cabin"), sensor("Vehicle.Cabin.Temperature", "fahrenheit"), sensor("Vehicle.Speed", "km/h")},
+			changes: SignalCatalogChanges{
+				NodesToAdd:    []SignalNode{},
+				NodesToUpdate: []SignalNode{sensor("Vehicle.Cabin.Temperature", "fahrenheit")},
+				NodesToRemove: []string{},
+			},
+		},
+		{
+			name:  "removed children first",
+			local: []SignalNode{branch("Vehicle"), sensor("Vehicle.Speed", "km/h")},
+			changes: SignalCatalogChanges{
+				NodesToAdd:    []SignalNode{},
+				NodesToUpdate: []SignalNode{},
+				NodesToRemove: []string{"Vehicle.Cabin.Temperature", "Vehicle.Cabin"},
+			},
+		},
+		{
+			name: "type changed",
+			local: []SignalNode{
+				branch("Vehicle"),
+				branch("Vehicle.Cabin"),
+				{FullyQualifiedName: "Vehicle.Cabin.Temperature", Type: SignalNodeActuator, DataType: "DOUBLE", Unit: "celsius"},
+				sensor("Vehicle.Speed", "km/h"),
+			},
+			changes: SignalCatalogChanges{
+				NodesToAdd:     []SignalNode{},
+				NodesToUpdate:  []SignalNode{},
+				NodesToRemove:  []string{},
+				NodesToReplace: []SignalNode{{FullyQualifiedName: "Vehicle.Cabin.Temperature", Type: SignalNodeActuator, DataType: "DOUBLE", Unit: "celsius"}},
+			},
+		},
+		{
+			name: "branch turned into a sensor",
+			local: []SignalNode{
+				branch("Vehicle"),
+				sensor("Vehicle.Cabin", "celsius"),
+				sensor("Vehicle.Speed", "km/h"),
+			},
+			changes: SignalCatalogChanges{
+				NodesToAdd:     []SignalNode{},
+				NodesToUpdate:  []SignalNode{},
+				NodesToRemove:  []string{"Vehicle.Cabin.Temperature"},
+				NodesToReplace: []SignalNode{sensor("Vehicle.Cabin", "celsius")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := DiffSignalCatalog(tt.local, deployed)
+			if !reflect.DeepEqual(changes, tt.changes) {
+				t.Errorf("DiffSignalCatalog = %+v, want %+v", changes, tt.changes)
+			}
+			if changes.Empty() != tt.changes.Empty() {
+				t.Errorf("Empty = %t, want %t", changes.Empty(), tt.changes.Empty())
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package spec
+
+import (
+	"fmt"
+	"strings"
+
+	"ses-platform/graph"
+)
+
+// ComponentGraph builds the component dependency DAG from both
+// components[].dependencies and architecture.dependencies. When hardOnly is
+// set, soft constraints are left out so only unbreakable edges remain.
+// connects_to edges describe connectivity rather than ordering and are skipped.
+func ComponentGraph(doc *Document, hardOnly bool) *graph.Graph {
+	g := graph.New()
+	if doc.Spec.Architecture == nil {
+		return g
+	}
+
+	arch := doc.Spec.Architecture
+	for _, comp := range arch.Components {
+		g.AddNode(comp.ID)
+		for _, dep := range comp.Dependencies {
+			g.AddEdge(comp.ID, dep)
+		}
+	}
+	for _, d := range arch.Dependencies {
+		if d.Type == "connects_to" {
+			continue
+		}
+		if hardOnly && d.Constraint == "soft" {
+			continue
+		}
+		g.AddEdge(d.Source, d.Target)
+	}
+	return g
+}
+
+// ValidateDependencies applies the "Dependency Cycles" rule (§4.2). Cycles made
+// only of hard dependencies are unresolvable and returned as errors; cycles that
+// can be broken by dropping a soft dependency are returned as warnings. The
+// parallel strategy provisions every component at once, so it is an error for
+// components to depend on each other (§5.3).
+func ValidateDependencies(doc *Document) (errs []string, warnings []string) {
+	for _, cycle := range ComponentGraph(doc, true).Cycles() {
+		errs = append(errs, fmt.Sprintf("Component dependency cycle: %s", graph.FormatPath(cycle)))
+	}
+
+	if o := doc.Spec.Orchestration; o != nil && o.ProvisioningStrategy == "parallel" {
+		g := ComponentGraph(doc, false)
+		for _, id := range g.Nodes() {
+			if deps := g.Dependencies(id); len(deps) > 0 {
+				errs = append(errs, fmt.Sprintf("Parallel provisioning requires components without dependencies: %s depends on %s", id, strings.Join(deps, ", ")))
+			}
+		}
+	}
+
+	soft := map[[2]string]bool{}
+	if doc.Spec.Architecture != nil {
+		for _, d := range doc.Spec.Architecture.Dependencies {
+			if d.Constraint == "soft" && d.Type != "connects_to" {
+				soft[[2]string{d.Source, d.Target}] = true
+			}
+		}
+		for _, comp := range doc.Spec.Architecture.Components {
+			for _, dep := range comp.Dependencies {
+				delete(soft, [2]string{comp.ID, dep})
+			}
+		}
+	}
+	for _, cycle := range ComponentGraph(doc, false).Cycles() {
+		for i := 0; i+1 < len(cycle); i++ {
+			if soft[[2]string{cycle[i], cycle[i+1]}] {
+				warnings = append(warnings, fmt.Sprintf("Component dependency cycle through soft constraint: %s", graph.FormatPath(cycle)))
+				break
+			}
+		}
+	}
+
+	return errs, warnings
+}
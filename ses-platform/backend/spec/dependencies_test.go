@@ -0,0 +1,98 @@
+package spec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateDependencies(t *testing.T) {
+	components := func(deps map[string][]string) []Component {
+		var comps []Component
+		for _, id := range []string{"a", "b", "c"} {
+			comps = append(comps, Component{ID: id, Dependencies: deps[id]})
+		}
+		return comps
+	}
+
+	tests := []struct {
+		name         string
+		components   []Component
+		dependencies []Dependency
+		strategy     string
+		errs         []string
+		warnings     []string
+	}{
+		{
+			name:       "acyclic",
+			components: components(map[string][]string{"a": {"b"}, "b": {"c"}}),
+		},
+		{
+			name:       "component dependencies cycle",
+			components: components(map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}}),
+			errs:       []string{"Component dependency cycle: a -> b -> c -> a"},
+		},
+		{
+			name:         "hard architecture dependency closes a cycle",
+			components:   components(map[string][]string{"a": {"b"}}),
+			dependencies: []Dependency{{Source: "b", Target: "a", Type: "requires", Constraint: "hard"}},
+			errs:         []string{"Component dependency cycle: a -> b -> a"},
+		},
+		{
+			name:         "soft dependency closes a cycle",
+			components:   components(map[string][]string{"a": {"b"}}),
+			dependencies: []Dependency{{Source: "b", Target: "a", Type: "depends_on", Constraint: "soft"}},
+			warnings:     []string{"Component dependency cycle through soft constraint: a -> b -> a"},
+		},
+		{
+			name:         "soft constraint repeated as a component dependency is hard",
+			components:   components(map[string][]string{"a": {"b"}, "b": {"a"}}),
+			dependencies: []Dependency{{Source: "b", Target: "a", Constraint: "soft"}},
+			errs:         []string{"Component dependency cycle: a -> b -> a"},
+		},
+		{
+			name:         "connects_to is not an ordering",
+			components:   components(map[string][]string{"a": {"b"}}),
+			dependencies: []Dependency{{Source: "b", Target: "a", Type: "connects_to"}},
+		},
+		{
+			name:       "parallel without dependencies",
+			components: components(nil),
+			strategy:   "parallel",
+		},
+		{
+			name:         "parallel with dependencies",
+			components:   components(map[string][]string{"a": {"b", "c"}}),
+			dependencies: []Dependency{{Source: "b", Target: "c", Type: "depends_on", Constraint: "soft"}},
+			strategy:     "parallel",
+			errs: []string{
+				"Parallel provisioning requires components without dependencies: a depends on b, c",
+				"Parallel provisioning requires components without dependencies: b depends on c",
+			},
+		},
+		{
+			name:         "parallel ignores connects_to",
+			components:   components(nil),
+			dependencies: []Dependency{{Source: "b", Target: "a", Type: "connects_to"}},
+			strategy:     "parallel",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &Document{Spec: Spec{Architecture: &Architecture{Components: tt.components, Dependencies: tt.dependencies}}}
+			if tt.strategy != "" {
+				doc.Spec.Orchestration = &Orchestration{ProvisioningStrategy: tt.strategy}
+			}
+			errs, warnings := ValidateDependencies(doc)
+			if !reflect.DeepEqual(errs, tt.errs) {
+				t.Errorf("errors = %q, want %q", errs, tt.errs)
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+
+	if errs, warnings := ValidateDependencies(&Document{}); errs != nil || warnings != nil {
+		t.Errorf("ValidateDependencies without an architecture = %q, %q, want nothing", errs, warnings)
+	}
+}
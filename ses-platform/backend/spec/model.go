@@ -0,0 +1,295 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is the typed internal model of a Simulation Environment Specification (SES)
+type Document struct {
+	APIVersion string   `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string   `yaml:"kind" json:"kind"`
+	Metadata   Metadata `yaml:"metadata" json:"metadata"`
+	Spec       Spec     `yaml:"spec" json:"spec"`
+}
+
+// Metadata identifies the specification and its owner
+type Metadata struct {
+	Name        string     `yaml:"name" json:"name"`
+	Version     string     `yaml:"version" json:"version"`
+	Description string     `yaml:"description,omitempty" json:"description,omitempty"`
+	Tags        []string   `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Owner       string     `yaml:"owner" json:"owner"`
+	Created     *time.Time `yaml:"created,omitempty" json:"created,omitempty"`
+	Modified    *time.Time `yaml:"modified,omitempty" json:"modified,omitempty"`
+}
+
+// Spec holds the body of an SES document
+type Spec struct {
+	Objectives    []Objective    `yaml:"objectives" json:"objectives"`
+	Constraints   *Constraints   `yaml:"constraints" json:"constraints"`
+	Architecture  *Architecture  `yaml:"architecture" json:"architecture"`
+	Environment   *Environment   `yaml:"environment" json:"environment"`
+	Simulation    *Simulation    `yaml:"simulation" json:"simulation"`
+	Orchestration *Orchestration `yaml:"orchestration" json:"orchestration"`
+}
+
+// Objective describes a simulation goal
+type Objective struct {
+	Goal            string `yaml:"goal" json:"goal"`
+	SuccessCriteria string `yaml:"success_criteria,omitempty" json:"success_criteria,omitempty"`
+	Priority        string `yaml:"priority,omitempty" json:"priority,omitempty"` // critical, high, medium, low
+}
+
+// Constraints are the hard limits of the environment
+type Constraints struct {
+	Budget    *BudgetConstraint   `yaml:"budget,omitempty" json:"budget,omitempty"`
+	Time      *TimeConstraint     `yaml:"time,omitempty" json:"time,omitempty"`
+	Resources *ResourceConstraint `yaml:"resources,omitempty" json:"resources,omitempty"`
+}
+
+type BudgetConstraint struct {
+	MaxCost  float64 `yaml:"max_cost" json:"max_cost"`
+	Currency string  `yaml:"currency,omitempty" json:"currency,omitempty"`
+}
+
+type TimeConstraint struct {
+	MaxDuration Duration   `yaml:"max_duration,omitempty" json:"max_duration,omitempty"`
+	Deadline    *time.Time `yaml:"deadline,omitempty" json:"deadline,omitempty"`
+}
+
+type ResourceConstraint struct {
+	MaxCPU     int    `yaml:"max_cpu,omitempty" json:"max_cpu,omitempty"`
+	MaxMemory  string `yaml:"max_memory,omitempty" json:"max_memory,omitempty"`
+	MaxStorage string `yaml:"max_storage,omitempty" json:"max_storage,omitempty"`
+}
+
+// Architecture describes the system design (§3.2 - §3.4)
+type Architecture struct {
+	Topology     string       `yaml:"topology" json:"topology"`
+	Components   []Component  `yaml:"components" json:"components"`
+	Networks     []Network    `yaml:"networks,omitempty" json:"networks,omitempty"`
+	Dependencies []Dependency `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
+}
+
+// Component is a single hardware, virtual, container or service element
+type Component struct {
+	ID             string                 `yaml:"id" json:"id"`
+	Type           string                 `yaml:"type" json:"type"` // hardware, virtual, container, service
+	Name           string                 `yaml:"name,omitempty" json:"name,omitempty"`
+	Specification  ComponentSpecification `yaml:"specification,omitempty" json:"specification,omitempty"`
+	Quantity       int                    `yaml:"quantity,omitempty" json:"quantity,omitempty"`
+	Location       string                 `yaml:"location,omitempty" json:"location,omitempty"`
+	Dependencies   []string               `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
+	HealthCheck    *HealthCheck           `yaml:"health_check,omitempty" json:"health_check,omitempty"`
+	Networks       []string               `yaml:"networks,omitempty" json:"networks,omitempty"`               // "network-id" or "network-id/subnet-name"
+	SecurityGroups []string               `yaml:"security_groups,omitempty" json:"security_groups,omitempty"` // Security group names
+}
+
+type ComponentSpecification struct {
+	Model         string                 `yaml:"model,omitempty" json:"model,omitempty"`
+	Image         string                 `yaml:"image,omitempty" json:"image,omitempty"`
+	Version       string                 `yaml:"version,omitempty" json:"version,omitempty"`
+	Configuration map[string]interface{} `yaml:"configuration,omitempty" json:"configuration,omitempty"`
+}
+
+type HealthCheck struct {
+	Endpoint string   `yaml:"endpoint" json:"endpoint"`
+	Interval Duration `yaml:"interval,omitempty" json:"interval,omitempty"`
+	Timeout  Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Retries  int      `yaml:"retries,omitempty" json:"retries,omitempty"`
+}
+
+// Network describes a VPC, VLAN, overlay or physical network (§3.3)
+type Network struct {
+	ID             string                 `yaml:"id" json:"id"`
+	Name           string                 `yaml:"name,omitempty" json:"name,omitempty"`
+	Type           string                 `yaml:"type" json:"type"` // vpc, vlan, overlay, physical
+	CIDR           string                 `yaml:"cidr" json:"cidr"`
+	Subnets        []Subnet               `yaml:"subnets,omitempty" json:"subnets,omitempty"`
+	SecurityGroups []SecurityGroup        `yaml:"security_groups,omitempty" json:"security_groups,omitempty"`
+	Routing        map[string]interface{} `yaml:"routing,omitempty" json:"routing,omitempty"`
+	DNS            map[string]interface{} `yaml:"dns,omitempty" json:"dns,omitempty"`
+}
+
+type Subnet struct {
+	Name             string `yaml:"name" json:"name"`
+	CIDR             string `yaml:"cidr" json:"cidr"`
+	AvailabilityZone string `yaml:"availability_zone,omitempty" json:"availability_zone,omitempty"`
+	RouteTable       string `yaml:"route_table,omitempty" json:"route_table,omitempty"`
+}
+
+type SecurityGroup struct {
+	Name  string              `yaml:"name" json:"name"`
+	Rules []SecurityGroupRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// SecurityGroupRule allows traffic to (ingress) or from (egress) the group's members.
+// Source and Destination accept a CIDR, a security group name or a component ID.
+type SecurityGroupRule struct {
+	Direction   string `yaml:"direction,omitempty" json:"direction,omitempty"` // ingress (default), egress
+	Protocol    string `yaml:"protocol,omitempty" json:"protocol,omitempty"`   // tcp, udp, icmp, all (default)
+	Ports       string `yaml:"ports,omitempty" json:"ports,omitempty"`         // "5432", "8000-8080" or empty for all
+	Source      string `yaml:"source,omitempty" json:"source,omitempty"`
+	Destination string `yaml:"destination,omitempty" json:"destination,omitempty"`
+}
+
+// Dependency is an edge in the component dependency graph (§3.4)
+type Dependency struct {
+	Source              string                 `yaml:"source" json:"source"`
+	Target              string                 `yaml:"target" json:"target"`
+	Type                string                 `yaml:"type,omitempty" json:"type,omitempty"`             // requires, connects_to, depends_on
+	Constraint          string                 `yaml:"constraint,omitempty" json:"constraint,omitempty"` // hard, soft
+	InitializationOrder int                    `yaml:"initialization_order,omitempty" json:"initialization_order,omitempty"`
+	Validation          map[string]interface{} `yaml:"validation,omitempty" json:"validation,omitempty"`
+}
+
+// Environment describes the target infrastructure
+type Environment struct {
+	Provider          string           `yaml:"provider" json:"provider"` // aws, azure, gcp, on-prem, hybrid
+	Region            string           `yaml:"region,omitempty" json:"region,omitempty"`
+	AvailabilityZones []string         `yaml:"availability_zones,omitempty" json:"availability_zones,omitempty"`
+	Infrastructure    []Infrastructure `yaml:"infrastructure,omitempty" json:"infrastructure,omitempty"`
+}
+
+// Infrastructure is a provider resource request (§3.5)
+type Infrastructure struct {
+	Type             string                      `yaml:"type" json:"type"` // compute, storage, network, database, cache
+	ProviderResource string                      `yaml:"provider_resource,omitempty" json:"provider_resource,omitempty"`
+	Specification    InfrastructureSpecification `yaml:"specification,omitempty" json:"specification,omitempty"`
+	Scaling          *Scaling                    `yaml:"scaling,omitempty" json:"scaling,omitempty"`
+	Tags             map[string]string           `yaml:"tags,omitempty" json:"tags,omitempty"`
+}
+
+type InfrastructureSpecification struct {
+	Compute *ComputeSpec `yaml:"compute,omitempty" json:"compute,omitempty"`
+	Storage *StorageSpec `yaml:"storage,omitempty" json:"storage,omitempty"`
+	Network *NetworkSpec `yaml:"network,omitempty" json:"network,omitempty"`
+}
+
+type ComputeSpec struct {
+	InstanceType string `yaml:"instance_type,omitempty" json:"instance_type,omitempty"`
+	CPU          int    `yaml:"cpu,omitempty" json:"cpu,omitempty"`
+	Memory       string `yaml:"memory,omitempty" json:"memory,omitempty"`
+	GPU          int    `yaml:"gpu,omitempty" json:"gpu,omitempty"`
+}
+
+type StorageSpec struct {
+	Type       string `yaml:"type,omitempty" json:"type,omitempty"` // block, object, file
+	Size       string `yaml:"size,omitempty" json:"size,omitempty"`
+	IOPS       int    `yaml:"iops,omitempty" json:"iops,omitempty"`
+	Throughput string `yaml:"throughput,omitempty" json:"throughput,omitempty"`
+}
+
+type NetworkSpec struct {
+	Bandwidth string `yaml:"bandwidth,omitempty" json:"bandwidth,omitempty"`
+	Latency   string `yaml:"latency,omitempty" json:"latency,omitempty"`
+}
+
+type Scaling struct {
+	Min    int                    `yaml:"min,omitempty" json:"min,omitempty"`
+	Max    int                    `yaml:"max,omitempty" json:"max,omitempty"`
+	Policy map[string]interface{} `yaml:"policy,omitempty" json:"policy,omitempty"`
+}
+
+// Simulation describes the scenarios to execute (§3.6)
+type Simulation struct {
+	Scenarios   []Scenario               `yaml:"scenarios" json:"scenarios"`
+	DataSources []map[string]interface{} `yaml:"data_sources,omitempty" json:"data_sources,omitempty"`
+	Monitoring  map[string]interface{}   `yaml:"monitoring,omitempty" json:"monitoring,omitempty"`
+	Reporting   map[string]interface{}   `yaml:"reporting,omitempty" json:"reporting,omitempty"`
+}
+
+type Scenario struct {
+	ID          string                 `yaml:"id" json:"id"`
+	Name        string                 `yaml:"name,omitempty" json:"name,omitempty"`
+	Description string                 `yaml:"description,omitempty" json:"description,omitempty"`
+	Duration    Duration               `yaml:"duration,omitempty" json:"duration,omitempty"`
+	Workload    *Workload              `yaml:"workload,omitempty" json:"workload,omitempty"`
+	Data        map[string]interface{} `yaml:"data,omitempty" json:"data,omitempty"`
+	Validation  map[string]interface{} `yaml:"validation,omitempty" json:"validation,omitempty"`
+}
+
+type Workload struct {
+	Type       string                 `yaml:"type" json:"type"` // load_test, stress_test, chaos, functional
+	Parameters map[string]interface{} `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	Profile    string                 `yaml:"profile,omitempty" json:"profile,omitempty"`
+}
+
+// Orchestration controls how the environment is provisioned (§5.3)
+type Orchestration struct {
+	ProvisioningStrategy string                 `yaml:"provisioning_strategy" json:"provisioning_strategy"` // parallel, sequential, optimized
+	HealthChecks         []HealthCheck          `yaml:"health_checks,omitempty" json:"health_checks,omitempty"`
+	RollbackPolicy       map[string]interface{} `yaml:"rollback_policy,omitempty" json:"rollback_policy,omitempty"`
+	Reservation          *Reservation           `yaml:"reservation,omitempty" json:"reservation,omitempty"`
+}
+
+// Reservation describes resource locking for the environment (§3.7)
+type Reservation struct {
+	Mode               string               `yaml:"mode,omitempty" json:"mode,omitempty"` // exclusive, shared, preemptible
+	StartTime          *time.Time           `yaml:"start_time,omitempty" json:"start_time,omitempty"`
+	EndTime            *time.Time           `yaml:"end_time,omitempty" json:"end_time,omitempty"`
+	Priority           int                  `yaml:"priority,omitempty" json:"priority,omitempty"`
+	ConflictResolution string               `yaml:"conflict_resolution,omitempty" json:"conflict_resolution,omitempty"` // queue, preempt, fail
+	Resources          ReservationResources `yaml:"resources,omitempty" json:"resources,omitempty"`
+}
+
+type ReservationResources struct {
+	Locked []string `yaml:"locked,omitempty" json:"locked,omitempty"`
+	Shared []string `yaml:"shared,omitempty" json:"shared,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "30s" or "4h"
+type Duration struct {
+	time.Duration
+}
+
+func parseDuration(s string) (Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return Duration{}, fmt.Errorf("invalid duration %q: %v", s, err)
+	}
+	return Duration{d}, nil
+}
+
+// UnmarshalYAML parses a duration string
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := parseDuration(value.Value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %v", err)
+	}
+	parsed, err := parseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// MarshalJSON writes the duration in its string form
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// MarshalYAML writes the duration in its string form
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// IsZero reports whether the duration was left unset
+func (d Duration) IsZero() bool {
+	return d.Duration == 0
+}
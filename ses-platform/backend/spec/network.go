@@ -0,0 +1,373 @@
+package spec
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// ValidateNetworks applies the "Network Topology" rule (§4.2): CIDRs must parse,
+// sibling networks must not overlap, subnets must fit inside their parent network,
+// security group rules must reference known groups or components, and every
+// component must be able to reach its dependencies.
+func ValidateNetworks(doc *Document) (errs []string, warnings []string) {
+	arch := doc.Spec.Architecture
+	if arch == nil || len(arch.Networks) == 0 {
+		return nil, nil
+	}
+
+	t := newTopology(arch)
+	errs = append(errs, t.errs...)
+	errs = append(errs, t.validateOverlaps()...)
+	sgErrs, sgWarnings := t.validateSecurityGroups()
+	errs = append(errs, sgErrs...)
+	warnings = append(warnings, sgWarnings...)
+	errs = append(errs, t.validateReachability()...)
+	return errs, warnings
+}
+
+// topology is the parsed view of architecture.networks used by the checks
+type topology struct {
+	arch       *Architecture
+	networks   map[string]netip.Prefix
+	subnets    map[string]netip.Prefix // keyed by "network-id/subnet-name"
+	groups     map[string]SecurityGroup
+	components map[string]Component
+	errs       []string
+}
+
+func newTopology(arch *Architecture) *topology {
+	t := &topology{
+		arch:       arch,
+		networks:   make(map[string]netip.Prefix),
+		subnets:    make(map[string]netip.Prefix),
+		groups:     make(map[string]SecurityGroup),
+		components: make(map[string]Component),
+	}
+
+	for _, n := range arch.Networks {
+		// Physical and VLAN networks may omit a CIDR; they still count for routing
+		if n.CIDR != "" {
+			prefix, err := netip.ParsePrefix(n.CIDR)
+			if err != nil {
+				t.errs = append(t.errs, fmt.Sprintf("Network %s has invalid CIDR %q", n.ID, n.CIDR))
+			} else {
+				t.networks[n.ID] = prefix.Masked()
+			}
+		}
+		for _, sub := range n.Subnets {
+			subPrefix, err := netip.ParsePrefix(sub.CIDR)
+			if err != nil {
+				t.errs = append(t.errs, fmt.Sprintf("Subnet %s/%s has invalid CIDR %q", n.ID, sub.Name, sub.CIDR))
+				continue
+			}
+			t.subnets[n.ID+"/"+sub.Name] = subPrefix.Masked()
+		}
+		for _, sg := range n.SecurityGroups {
+			if _, dup := t.groups[sg.Name]; dup {
+				t.errs = append(t.errs, fmt.Sprintf("Security group %s is declared more than once", sg.Name))
+			}
+			t.groups[sg.Name] = sg
+		}
+	}
+	for _, comp := range arch.Components {
+		t.components[comp.ID] = comp
+	}
+	return t
+}
+
+func overlaps(a, b netip.Prefix) bool {
+	return a.Contains(b.Addr()) || b.Contains(a.Addr())
+}
+
+// validateOverlaps checks sibling networks, subnet containment and sibling subnets
+func (t *topology) validateOverlaps() []string {
+	var errs []string
+	nets := t.arch.Networks
+	for i := 0; i < len(nets); i++ {
+		a, ok := t.networks[nets[i].ID]
+		if !ok {
+			continue
+		}
+		for j := i + 1; j < len(nets); j++ {
+			b, ok := t.networks[nets[j].ID]
+			if ok && overlaps(a, b) {
+				errs = append(errs, fmt.Sprintf("Network %s (%s) overlaps network %s (%s)", nets[i].ID, a, nets[j].ID, b))
+			}
+		}
+
+		subnets := nets[i].Subnets
+		for si, sub := range subnets {
+			sp, ok := t.subnets[nets[i].ID+"/"+sub.Name]
+			if !ok {
+				continue
+			}
+			if !a.Contains(sp.Addr()) || sp.Bits() < a.Bits() {
+				errs = append(errs, fmt.Sprintf("Subnet %s (%s) is not contained in network %s (%s)", sub.Name, sp, nets[i].ID, a))
+			}
+			for _, other := range subnets[si+1:] {
+				op, ok := t.subnets[nets[i].ID+"/"+other.Name]
+				if ok && overlaps(sp, op) {
+					errs = append(errs, fmt.Sprintf("Subnet %s (%s) overlaps subnet %s (%s) in network %s", sub.Name, sp, other.Name, op, nets[i].ID))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// validateSecurityGroups checks that rules are well formed and reference known
+// peers, and warns about ingress open to the world (least-privilege, §9.1)
+func (t *topology) validateSecurityGroups() (errs []string, warnings []string) {
+	for _, n := range t.arch.Networks {
+		for _, sg := range n.SecurityGroups {
+			for i, rule := range sg.Rules {
+				field := fmt.Sprintf("Security group %s rule %d", sg.Name, i)
+				switch rule.Direction {
+				case "", "ingress":
+					if rule.Source == "" {
+						errs = append(errs, field+" (ingress) has no source")
+					}
+					if rule.Source == "0.0.0.0/0" || rule.Source == "::/0" {
+						warnings = append(warnings, field+" allows ingress from anywhere")
+					}
+				case "egress":
+					if rule.Destination == "" {
+						errs = append(errs, field+" (egress) has no destination")
+					}
+				default:
+					errs = append(errs, fmt.Sprintf("%s has invalid direction %q", field, rule.Direction))
+				}
+				switch strings.ToLower(rule.Protocol) {
+				case "", "all", "tcp", "udp", "icmp":
+				default:
+					errs = append(errs, fmt.Sprintf("%s has invalid protocol %q", field, rule.Protocol))
+				}
+				if _, _, err := parsePorts(rule.Ports); err != nil {
+					errs = append(errs, fmt.Sprintf("%s: %v", field, err))
+				}
+				for _, peer := range []string{rule.Source, rule.Destination} {
+					if peer != "" && !t.isKnownPeer(peer) {
+						errs = append(errs, fmt.Sprintf("%s references unknown peer %q", field, peer))
+					}
+				}
+			}
+		}
+	}
+	for _, comp := range t.arch.Components {
+		for _, name := range comp.SecurityGroups {
+			if _, ok := t.groups[name]; !ok {
+				errs = append(errs, fmt.Sprintf("Component %s references unknown security group %s", comp.ID, name))
+			}
+		}
+	}
+	return errs, warnings
+}
+
+func (t *topology) isKnownPeer(peer string) bool {
+	if _, err := netip.ParsePrefix(peer); err == nil {
+		return true
+	}
+	if _, ok := t.groups[peer]; ok {
+		return true
+	}
+	_, ok := t.components[peer]
+	return ok
+}
+
+// parsePorts parses "", "443" or "8000-8080" into an inclusive range
+func parsePorts(ports string) (int, int, error) {
+	if ports == "" {
+		return 0, 65535, nil
+	}
+	lo, hi, isRange := strings.Cut(ports, "-")
+	from, err := strconv.Atoi(strings.TrimSpace(lo))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid ports %q", ports)
+	}
+	to := from
+	if isRange {
+		if to, err = strconv.Atoi(strings.TrimSpace(hi)); err != nil {
+			return 0, 0, fmt.Errorf("invalid ports %q", ports)
+		}
+	}
+	if from < 0 || to > 65535 || from > to {
+		return 0, 0, fmt.Errorf("invalid ports %q", ports)
+	}
+	return from, to, nil
+}
+
+// attachments resolves a component's network references to network IDs and prefixes
+func (t *topology) attachments(comp Component) (netIDs []string, prefixes []netip.Prefix, errs []string) {
+	for _, ref := range comp.Networks {
+		netID, subnet, hasSubnet := strings.Cut(ref, "/")
+		if !t.declaresNetwork(netID) {
+			errs = append(errs, fmt.Sprintf("Component %s references unknown network %s", comp.ID, netID))
+			continue
+		}
+		prefix := t.networks[netID]
+		if hasSubnet {
+			sp, ok := t.subnets[netID+"/"+subnet]
+			if !ok {
+				errs = append(errs, fmt.Sprintf("Component %s references unknown subnet %s", comp.ID, ref))
+				continue
+			}
+			prefix = sp
+		}
+		netIDs = append(netIDs, netID)
+		prefixes = append(prefixes, prefix)
+	}
+	return netIDs, prefixes, errs
+}
+
+func (t *topology) declaresNetwork(id string) bool {
+	for _, n := range t.arch.Networks {
+		if n.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// peered reports whether two networks can route to each other, either because
+// they are the same network or because one lists the other in routing.peers
+func (t *topology) peered(a, b string) bool {
+	if a == b {
+		return true
+	}
+	for _, n := range t.arch.Networks {
+		if n.ID != a && n.ID != b {
+			continue
+		}
+		other := b
+		if n.ID == b {
+			other = a
+		}
+		if peers, ok := n.Routing["peers"].([]interface{}); ok {
+			for _, p := range peers {
+				if p == other {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// validateReachability checks that each component can reach every component it
+// depends on, both at the routing level and through security group rules
+func (t *topology) validateReachability() []string {
+	var errs []string
+	type endpoint struct {
+		netIDs   []string
+		prefixes []netip.Prefix
+	}
+	endpoints := make(map[string]endpoint)
+	for _, comp := range t.arch.Components {
+		netIDs, prefixes, attachErrs := t.attachments(comp)
+		errs = append(errs, attachErrs...)
+		if len(comp.Networks) == 0 {
+			errs = append(errs, fmt.Sprintf("Component %s is not attached to any declared network", comp.ID))
+		}
+		endpoints[comp.ID] = endpoint{netIDs: netIDs, prefixes: prefixes}
+	}
+
+	type edge struct{ from, to string }
+	var edges []edge
+	seen := make(map[edge]bool)
+	add := func(from, to string) {
+		e := edge{from, to}
+		if !seen[e] {
+			seen[e] = true
+			edges = append(edges, e)
+		}
+	}
+	for _, comp := range t.arch.Components {
+		for _, dep := range comp.Dependencies {
+			add(comp.ID, dep)
+		}
+	}
+	for _, d := range t.arch.Dependencies {
+		add(d.Source, d.Target)
+	}
+
+	for _, e := range edges {
+		from, okFrom := endpoints[e.from]
+		to, okTo := endpoints[e.to]
+		if !okFrom || !okTo || len(from.netIDs) == 0 || len(to.netIDs) == 0 {
+			continue
+		}
+
+		routed := false
+		for _, a := range from.netIDs {
+			for _, b := range to.netIDs {
+				if t.peered(a, b) {
+					routed = true
+				}
+			}
+		}
+		if !routed {
+			errs = append(errs, fmt.Sprintf("Component %s cannot reach %s: no shared or peered network", e.from, e.to))
+			continue
+		}
+
+		if !t.allows(t.components[e.to], "ingress", t.components[e.from], from.prefixes) {
+			errs = append(errs, fmt.Sprintf("Component %s cannot reach %s: no security group ingress rule allows it", e.from, e.to))
+		}
+		if !t.allows(t.components[e.from], "egress", t.components[e.to], to.prefixes) {
+			errs = append(errs, fmt.Sprintf("Component %s cannot reach %s: no security group egress rule allows it", e.from, e.to))
+		}
+	}
+	return errs
+}
+
+// allows reports whether comp's security groups permit traffic in the given
+// direction with peer. Components without security groups, and groups that
+// declare no rules for a direction, are unrestricted in that direction.
+func (t *topology) allows(comp Component, direction string, peer Component, peerPrefixes []netip.Prefix) bool {
+	restricted := false
+	for _, name := range comp.SecurityGroups {
+		sg, ok := t.groups[name]
+		if !ok {
+			continue
+		}
+		for _, rule := range sg.Rules {
+			ruleDirection := rule.Direction
+			if ruleDirection == "" {
+				ruleDirection = "ingress"
+			}
+			if ruleDirection != direction {
+				continue
+			}
+			restricted = true
+			target := rule.Source
+			if direction == "egress" {
+				target = rule.Destination
+			}
+			if t.matchesPeer(target, peer, peerPrefixes) {
+				return true
+			}
+		}
+	}
+	return !restricted
+}
+
+func (t *topology) matchesPeer(target string, peer Component, peerPrefixes []netip.Prefix) bool {
+	if target == peer.ID {
+		return true
+	}
+	for _, name := range peer.SecurityGroups {
+		if target == name {
+			return true
+		}
+	}
+	if prefix, err := netip.ParsePrefix(target); err == nil {
+		for _, p := range peerPrefixes {
+			if prefix.Contains(p.Addr()) && prefix.Bits() <= p.Bits() {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package spec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateNetworksCIDRs(t *testing.T) {
+	tests := []struct {
+		name     string
+		networks []Network
+		errs     []string
+	}{
+		{
+			name: "separate networks",
+			networks: []Network{
+				{ID: "vpc-a", CIDR: "10.0.0.0/16"},
+				{ID: "vpc-b", CIDR: "10.1.0.0/16"},
+			},
+		},
+		{
+			name:     "networks without a CIDR",
+			networks: []Network{{ID: "can", Type: "physical"}, {ID: "lan", Type: "vlan"}},
+		},
+		{
+			name:     "invalid CIDRs",
+			networks: []Network{{ID: "vpc-a", CIDR: "10.0.0.0/33", Subnets: []Subnet{{Name: "s1", CIDR: "10.0.0"}}}},
+			errs: []string{
+				`Network vpc-a has invalid CIDR "10.0.0.0/33"`,
+				`Subnet vpc-a/s1 has invalid CIDR "10.0.0"`,
+			},
+		},
+		{
+			name: "nested networks overlap",
+			networks: []Network{
+				{ID: "vpc-a", CIDR: "10.0.0.0/8"},
+				{ID: "vpc-b", CIDR: "10.20.0.0/16"},
+			},
+			errs: []string{"Network vpc-a (10.0.0.0/8) overlaps network vpc-b (10.20.0.0/16)"},
+		},
+		{
+			name: "host bits are masked",
+			networks: []Network{
+				{ID: "vpc-a", CIDR: "10.0.3.7/16"},
+				{ID: "vpc-b", CIDR: "10.0.200.0/24"},
+			},
+			errs: []string{"Network vpc-a (10.0.0.0/16) overlaps network vpc-b (10.0.200.0/24)"},
+		},
+		{
+			name: "IPv6 networks",
+			networks: []Network{
+				{ID: "v6-a", CIDR: "2001:db8::/48"},
+				{ID: "v6-b", CIDR: "2001:db8:0:1::/64"},
+				{ID: "v4", CIDR: "10.0.0.0/16"},
+			},
+			errs: []string{"Network v6-a (2001:db8::/48) overlaps network v6-b (2001:db8:0:1::/64)"},
+		},
+		{
+			name: "subnets inside their network",
+			networks: []Network{{ID: "vpc-a", CIDR: "10.0.0.0/16", Subnets: []Subnet{
+				{Name: "s1", CIDR: "10.0.1.0/24"},
+				{Name: "s2", CIDR: "10.0.2.0/24"},
+			}}},
+		},
+		{
+			name: "subnet outside its network",
+			networks: []Network{{ID: "vpc-a", CIDR: "10.0.0.0/16", Subnets: []Subnet{
+				{Name: "s1", CIDR: "10.1.0.0/24"},
+			}}},
+			errs: []string{"Subnet s1 (10.1.0.0/24) is not contained in network vpc-a (10.0.0.0/16)"},
+		},
+		{
+			name: "subnet larger than its network",
+			networks: []Network{{ID: "vpc-a", CIDR: "10.0.0.0/16", Subnets: []Subnet{
+				{Name: "s1", CIDR: "10.0.0.0/8"},
+			}}},
+			errs: []string{"Subnet s1 (10.0.0.0/8) is not contained in network vpc-a (10.0.0.0/16)"},
+		},
+		{
+			name: "sibling subnets overlap",
+			networks: []Network{{ID: "vpc-a", CIDR: "10.0.0.0/16", Subnets: []Subnet{
+				{Name: "s1", CIDR: "10.0.0.0/23"},
+				{Name: "s2", CIDR: "10.0.1.0/24"},
+				{Name: "s3", CIDR: "10.0.2.0/24"},
+			}}},
+			errs: []string{"Subnet s1 (10.0.0.0/23) overlaps subnet s2 (10.0.1.0/24) in network vpc-a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &Document{Spec: Spec{Architecture: &Architecture{Networks: tt.networks}}}
+			errs, _ := ValidateNetworks(doc)
+			if !reflect.DeepEqual(errs, tt.errs) {
+				t.Errorf("errors = %q, want %q", errs, tt.errs)
+			}
+		})
+	}
+
+	if errs, warnings := ValidateNetworks(&Document{}); errs != nil || warnings != nil {
+		t.Errorf("ValidateNetworks without an architecture = %q, %q, want nothing", errs, warnings)
+	}
+}
@@ -0,0 +1,231 @@
+package spec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// APIVersion is the only SES apiVersion this parser understands
+	APIVersion = "simplan.io/v1"
+	// KindSimulationEnvironment is the only supported SES kind
+	KindSimulationEnvironment = "SimulationEnvironment"
+)
+
+var (
+	ErrEmptyDocument         = errors.New("specification document is empty")
+	ErrUnsupportedAPIVersion = errors.New("unsupported apiVersion")
+	ErrUnsupportedKind       = errors.New("unsupported kind")
+)
+
+// ValidationError collects every schema violation found in a document
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("specification is invalid: %s", strings.Join(e.Errors, "; "))
+}
+
+// Parse decodes a YAML or JSON SES document and validates it against the schema.
+// Unknown fields are rejected so typos surface instead of being silently dropped.
+func Parse(data []byte) (*Document, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, ErrEmptyDocument
+	}
+
+	var doc Document
+	if trimmed[0] == '{' {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON specification: %v", err)
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(trimmed))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML specification: %v", err)
+		}
+	}
+
+	if doc.APIVersion != APIVersion {
+		return nil, fmt.Errorf("%w %q (expected %s)", ErrUnsupportedAPIVersion, doc.APIVersion, APIVersion)
+	}
+	if doc.Kind != KindSimulationEnvironment {
+		return nil, fmt.Errorf("%w %q (expected %s)", ErrUnsupportedKind, doc.Kind, KindSimulationEnvironment)
+	}
+
+	if errs := Validate(&doc); len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+	if errs, _ := ValidateDependencies(&doc); len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+	if errs, _ := ValidateNetworks(&doc); len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+
+	return &doc, nil
+}
+
+// Hash returns the SHA-256 of the document's canonical JSON form, so the same
+// specification always hashes identically regardless of YAML/JSON formatting
+func Hash(doc *Document) (string, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+var (
+	validPriorities     = []string{"critical", "high", "medium", "low"}
+	validComponentTypes = []string{"hardware", "virtual", "container", "service"}
+	validNetworkTypes   = []string{"vpc", "vlan", "overlay", "physical"}
+	validDependencyType = []string{"requires", "connects_to", "depends_on"}
+	validConstraints    = []string{"hard", "soft"}
+	validProviders      = []string{"aws", "azure", "gcp", "on-prem", "hybrid"}
+	validInfraTypes     = []string{"compute", "storage", "network", "database", "cache"}
+	validWorkloadTypes  = []string{"load_test", "stress_test", "chaos", "functional"}
+	validStrategies     = []string{"parallel", "sequential", "optimized"}
+	validReservation    = []string{"exclusive", "shared", "preemptible"}
+	validConflictModes  = []string{"queue", "preempt", "fail"}
+)
+
+// Validate checks required fields and enumerations of a decoded document.
+// It never fills in defaults for required fields (spec §4.1).
+func Validate(doc *Document) []string {
+	var errs []string
+	required := func(value, field string) {
+		if strings.TrimSpace(value) == "" {
+			errs = append(errs, fmt.Sprintf("%s is required", field))
+		}
+	}
+	oneOf := func(value, field string, allowed []string) {
+		if value == "" {
+			return
+		}
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		errs = append(errs, fmt.Sprintf("%s must be one of [%s], got %q", field, strings.Join(allowed, "|"), value))
+	}
+
+	required(doc.Metadata.Name, "metadata.name")
+	required(doc.Metadata.Version, "metadata.version")
+	required(doc.Metadata.Owner, "metadata.owner")
+
+	s := doc.Spec
+	if len(s.Objectives) == 0 {
+		errs = append(errs, "spec.objectives is required")
+	}
+	for i, o := range s.Objectives {
+		required(o.Goal, fmt.Sprintf("spec.objectives[%d].goal", i))
+		oneOf(o.Priority, fmt.Sprintf("spec.objectives[%d].priority", i), validPriorities)
+	}
+
+	if s.Constraints == nil {
+		errs = append(errs, "spec.constraints is required")
+	}
+
+	if s.Architecture == nil {
+		errs = append(errs, "spec.architecture is required")
+	} else {
+		seen := map[string]bool{}
+		for i, comp := range s.Architecture.Components {
+			field := fmt.Sprintf("spec.architecture.components[%d]", i)
+			required(comp.ID, field+".id")
+			if comp.ID != "" && seen[comp.ID] {
+				errs = append(errs, fmt.Sprintf("%s.id %q is duplicated", field, comp.ID))
+			}
+			seen[comp.ID] = true
+			required(comp.Type, field+".type")
+			oneOf(comp.Type, field+".type", validComponentTypes)
+			if comp.Quantity < 0 {
+				errs = append(errs, fmt.Sprintf("%s.quantity must not be negative", field))
+			}
+		}
+		for i, comp := range s.Architecture.Components {
+			for _, dep := range comp.Dependencies {
+				if !seen[dep] {
+					errs = append(errs, fmt.Sprintf("spec.architecture.components[%d].dependencies references unknown component %q", i, dep))
+				}
+			}
+		}
+		networks := map[string]bool{}
+		for i, n := range s.Architecture.Networks {
+			field := fmt.Sprintf("spec.architecture.networks[%d]", i)
+			required(n.ID, field+".id")
+			if n.ID != "" && networks[n.ID] {
+				errs = append(errs, fmt.Sprintf("%s.id %q is duplicated", field, n.ID))
+			}
+			networks[n.ID] = true
+			oneOf(n.Type, field+".type", validNetworkTypes)
+		}
+		for i, d := range s.Architecture.Dependencies {
+			field := fmt.Sprintf("spec.architecture.dependencies[%d]", i)
+			required(d.Source, field+".source")
+			required(d.Target, field+".target")
+			if d.Source != "" && !seen[d.Source] {
+				errs = append(errs, fmt.Sprintf("%s.source references unknown component %q", field, d.Source))
+			}
+			if d.Target != "" && !seen[d.Target] {
+				errs = append(errs, fmt.Sprintf("%s.target references unknown component %q", field, d.Target))
+			}
+			oneOf(d.Type, field+".type", validDependencyType)
+			oneOf(d.Constraint, field+".constraint", validConstraints)
+		}
+	}
+
+	if s.Environment == nil {
+		errs = append(errs, "spec.environment is required")
+	} else {
+		required(s.Environment.Provider, "spec.environment.provider")
+		oneOf(s.Environment.Provider, "spec.environment.provider", validProviders)
+		for i, infra := range s.Environment.Infrastructure {
+			field := fmt.Sprintf("spec.environment.infrastructure[%d].type", i)
+			required(infra.Type, field)
+			oneOf(infra.Type, field, validInfraTypes)
+		}
+	}
+
+	if s.Simulation == nil {
+		errs = append(errs, "spec.simulation is required")
+	} else {
+		for i, sc := range s.Simulation.Scenarios {
+			field := fmt.Sprintf("spec.simulation.scenarios[%d]", i)
+			required(sc.ID, field+".id")
+			if sc.Workload != nil {
+				oneOf(sc.Workload.Type, field+".workload.type", validWorkloadTypes)
+			}
+		}
+	}
+
+	if s.Orchestration == nil {
+		errs = append(errs, "spec.orchestration is required")
+	} else {
+		required(s.Orchestration.ProvisioningStrategy, "spec.orchestration.provisioning_strategy")
+		oneOf(s.Orchestration.ProvisioningStrategy, "spec.orchestration.provisioning_strategy", validStrategies)
+		if r := s.Orchestration.Reservation; r != nil {
+			oneOf(r.Mode, "spec.orchestration.reservation.mode", validReservation)
+			oneOf(r.ConflictResolution, "spec.orchestration.reservation.conflict_resolution", validConflictModes)
+			if r.StartTime != nil && r.EndTime != nil && !r.EndTime.After(*r.StartTime) {
+				errs = append(errs, "spec.orchestration.reservation.end_time must be after start_time")
+			}
+		}
+	}
+
+	return errs
+}
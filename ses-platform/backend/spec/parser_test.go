@@ -0,0 +1,194 @@
+package spec
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// webAppExample is the example of spec §12.1
+const webAppExample = `apiVersion: simplan.io/v1
+kind: SimulationEnvironment
+metadata:
+  name: web-app-load-test
+  version: 1.0.0
+  owner: devops-team@example.com
+
+spec:
+  objectives:
+    - goal: "Validate application performance under peak load"
+      success_criteria: "p95 latency < 200ms at 10k RPS"
+      priority: critical
+
+  constraints:
+    budget:
+      max_cost: 500
+      currency: USD
+    time:
+      max_duration: 4h
+
+  architecture:
+    topology: "three-tier"
+    components:
+      - id: "web-tier"
+        type: virtual
+        specification:
+          image: "nginx:1.21"
+          version: "1.21.0"
+        quantity: 3
+
+      - id: "app-tier"
+        type: container
+        specification:
+          image: "myapp:latest"
+          version: "2.3.1"
+        quantity: 5
+        dependencies: ["web-tier"]
+
+      - id: "db-tier"
+        type: virtual
+        specification:
+          image: "postgres:14"
+          version: "14.5"
+        quantity: 1
+        dependencies: ["app-tier"]
+
+  environment:
+    provider: aws
+    region: us-west-2
+    infrastructure:
+      - type: compute
+        provider_resource: "ec2"
+        specification:
+          compute:
+            instance_type: "t3.medium"
+            cpu: 2
+            memory: "4GB"
+
+  simulation:
+    scenarios:
+      - id: "peak-load"
+        name: "Peak traffic simulation"
+        duration: 1h
+        workload:
+          type: load_test
+          parameters:
+            rps: 10000
+            ramp_up: 5m
+
+  orchestration:
+    provisioning_strategy: optimized
+    health_checks:
+      - endpoint: "http://{{web-tier}}/health"
+        interval: 30s
+        timeout: 10s
+`
+
+// toJSON converts a YAML document to JSON
+func toJSON(t *testing.T, document string) string {
+	t.Helper()
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(document), &value); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	return string(data)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		err      error  // Wrapped by the returned error
+		contains string // Substring of the returned error
+	}{
+		{
+			name:     "example in YAML",
+			document: webAppExample,
+		},
+		{
+			name:     "example in JSON",
+			document: toJSON(t, webAppExample),
+		},
+		{
+			name:     "empty document",
+			document: " \n\t",
+			err:      ErrEmptyDocument,
+		},
+		{
+			name:     "unknown field in YAML",
+			document: strings.Replace(webAppExample, "  owner: devops-team@example.com", "  owner: devops-team@example.com\n  team: devops", 1),
+			contains: "field team not found",
+		},
+		{
+			name:     "unknown field in JSON",
+			document: strings.Replace(toJSON(t, webAppExample), `"owner":`, `"team":"devops","owner":`, 1),
+			contains: `unknown field "team"`,
+		},
+		{
+			name:     "wrong apiVersion",
+			document: strings.Replace(webAppExample, "simplan.io/v1", "simplan.io/v2", 1),
+			err:      ErrUnsupportedAPIVersion,
+		},
+		{
+			name:     "wrong kind",
+			document: strings.Replace(webAppExample, "kind: SimulationEnvironment", "kind: Simulation", 1),
+			err:      ErrUnsupportedKind,
+		},
+		{
+			name:     "invalid document",
+			document: strings.Replace(webAppExample, "priority: critical", "priority: urgent", 1),
+			contains: `spec.objectives[0].priority must be one of [critical|high|medium|low], got "urgent"`,
+		},
+		{
+			name:     "dependency cycle",
+			document: strings.Replace(webAppExample, "quantity: 3", "quantity: 3\n        dependencies: [\"db-tier\"]", 1),
+			contains: "Component dependency cycle: web-tier -> db-tier -> app-tier -> web-tier",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.document))
+			if tt.err == nil && tt.contains == "" {
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				if doc.Metadata.Name != "web-app-load-test" || len(doc.Spec.Architecture.Components) != 3 || doc.Spec.Environment.Provider != "aws" {
+					t.Errorf("Parse = %+v, want the web-app-load-test example", doc)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Parse succeeded, want an error")
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("Parse error = %v, want %v", err, tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("Parse error = %v, want it to contain %q", err, tt.contains)
+			}
+		})
+	}
+}
+
+func TestHashIgnoresFormat(t *testing.T) {
+	fromYAML, err := Parse([]byte(webAppExample))
+	if err != nil {
+		t.Fatalf("Parse YAML: %v", err)
+	}
+	fromJSON, err := Parse([]byte(toJSON(t, webAppExample)))
+	if err != nil {
+		t.Fatalf("Parse JSON: %v", err)
+	}
+	yamlHash, _ := Hash(fromYAML)
+	jsonHash, _ := Hash(fromJSON)
+	if yamlHash != jsonHash {
+		t.Errorf("Hash of the YAML document = %s, of the JSON document = %s, want them equal", yamlHash, jsonHash)
+	}
+}
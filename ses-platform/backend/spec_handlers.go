@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ses-platform/spec"
+)
+
+// SES Document API Handlers
+
+// createSpec accepts a YAML or JSON SES document and creates the environment it describes
+func createSpec(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doc, err := spec.Parse(body)
+	if err != nil {
+		var validationErr *spec.ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, ValidationResponse{
+				Valid:  false,
+				Errors: validationErr.Errors,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	env, err := environmentFromSpec(doc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// environment.provider names a cloud that may have no adapter, and an SES
+	// document cannot carry the fleetwise_config the aws adapter needs, so the
+	// adapter that runs the spec can be chosen with ?provider=
+	if provider := c.Query("provider"); provider != "" {
+		env.Provider = provider
+		env.UseRealAWSBackend = provider == "aws"
+	}
+	if _, err := providerFor(env); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     fmt.Sprintf("%v; choose another provider with ?provider= (available: %s)", err, strings.Join(RegisteredProviders(), ", ")),
+			"providers": RegisteredProviders(),
+		})
+		return
+	}
+
+	if err := db.Create(&env).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditLog := AuditLog{
+		EnvironmentID: env.ID,
+		Action:        "created",
+		UserID:        env.Owner,
+		Details: map[string]interface{}{
+			"message":      "Environment created from specification",
+			"spec_version": doc.Metadata.Version,
+			"spec_hash":    env.SpecHash,
+		},
+		CreatedAt: time.Now(),
+	}
+	db.Create(&auditLog)
+
+	// Unlike createEnvironment, provisioning is not started here: the spec is
+	// the source of truth and is provisioned explicitly via /provision.
+	c.JSON(http.StatusCreated, env)
+}
+
+// environmentFromSpec maps a parsed SES document onto an Environment record
+func environmentFromSpec(doc *spec.Document) (Environment, error) {
+	hash, err := spec.Hash(doc)
+	if err != nil {
+		return Environment{}, fmt.Errorf("failed to hash specification: %v", err)
+	}
+
+	compute := ComputeConfig{}
+	storage := 0
+	if doc.Spec.Environment != nil {
+		for _, infra := range doc.Spec.Environment.Infrastructure {
+			if cs := infra.Specification.Compute; cs != nil {
+				if cs.CPU > compute.CPU {
+					compute.CPU = cs.CPU
+				}
+				if mem := sizeInGB(cs.Memory); mem > compute.Memory {
+					compute.Memory = mem
+				}
+			}
+			if ss := infra.Specification.Storage; ss != nil {
+				storage += sizeInGB(ss.Size)
+			}
+		}
+	}
+	if doc.Spec.Architecture != nil {
+		for _, comp := range doc.Spec.Architecture.Components {
+			if comp.Quantity > 0 {
+				compute.Instances += comp.Quantity
+			} else {
+				compute.Instances++
+			}
+		}
+	}
+
+	priority := "medium"
+	rank := map[string]int{"low": 1, "medium": 2, "high": 3, "critical": 4}
+	for _, o := range doc.Spec.Objectives {
+		if rank[o.Priority] > rank[priority] {
+			priority = o.Priority
+		}
+	}
+
+	provider := defaultProvider
+	if doc.Spec.Environment != nil && doc.Spec.Environment.Provider != "" {
+		provider = doc.Spec.Environment.Provider
+	}
+
+	duration := 24
+	if t := doc.Spec.Constraints.Time; t != nil && !t.MaxDuration.IsZero() {
+		duration = int(math.Ceil(t.MaxDuration.Hours()))
+	}
+
+	return Environment{
+		ID:                newEnvironmentID(),
+		Name:              doc.Metadata.Name,
+		Description:       doc.Metadata.Description,
+		Owner:             doc.Metadata.Owner,
+		Tags:              strings.Join(doc.Metadata.Tags, ","),
+		Status:            StatePending,
+		Capabilities:      []string{},
+		EnablersConfig:    map[string]interface{}{},
+		ComputeConfig:     compute,
+		Storage:           storage,
+		Network:           "private",
+		Priority:          priority,
+		Duration:          duration,
+		EstimatedCost:     calculateCost(compute, storage, 0),
+		ActualCost:        0,
+		Health:            100,
+		Uptime:            "0h",
+		Spec:              doc,
+		SpecHash:          hash,
+		Provider:          provider,
+		UseRealAWSBackend: provider == "aws",
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
+	}, nil
+}
+
+// sizeInGB converts sizes such as "4GB", "512Mi" or "1TB" to whole gigabytes
+func sizeInGB(size string) int {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	if s == "" {
+		return 0
+	}
+
+	units := []struct {
+		suffix string
+		factor float64
+	}{
+		{"TIB", 1024}, {"TI", 1024}, {"TB", 1024}, {"T", 1024},
+		{"GIB", 1}, {"GI", 1}, {"GB", 1}, {"G", 1},
+		{"MIB", 1.0 / 1024}, {"MI", 1.0 / 1024}, {"MB", 1.0 / 1024}, {"M", 1.0 / 1024},
+	}
+	factor := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			factor = u.factor
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int(math.Ceil(value * factor))
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Environment lifecycle states (spec §5.2)
+const (
+	StatePending      = "pending"
+	StateValidated    = "validated"
+	StatePlanned      = "planned"
+	StateProvisioning = "provisioning"
+	StateReady        = "ready"
+	StateRunning      = "running"
+	StateCompleted    = "completed"
+	StateFailed       = "failed"
+	StateCleaned      = "cleaned"
+	StateRolledBack   = "rolled_back"
+	// Deletion: deleting -> releasing (resources are torn down) -> cleaned
+	StateDeleting  = "deleting"
+	StateReleasing = "releasing"
+)
+
+// stateTransitions lists the legal target states of every state. Beyond the
+// §5.2 diagram, a running environment can be stopped back to ready, a
+// provisioning environment records step progress against itself, and
+// environments that hold no running workload can be deleted; a failed
+// environment can be deleted without a rollback, or after its rollback
+// failed. Deleting environments are cleaned once their resources have been released.
+var stateTransitions = map[string][]string{
+	StatePending:      {StateValidated, StateDeleting},
+	StateValidated:    {StatePlanned, StateDeleting},
+	StatePlanned:      {StatePlanned, StateProvisioning, StateDeleting},
+	StateProvisioning: {StateProvisioning, StateReady, StateFailed},
+	StateReady:        {StateRunning, StateDeleting},
+	StateRunning:      {StateReady, StateCompleted, StateFailed},
+	StateCompleted:    {StateDeleting},
+	StateFailed:       {StateRolledBack, StateDeleting},
+	StateRolledBack:   {StateDeleting},
+	StateDeleting:     {StateReleasing},
+	StateReleasing:    {StateCleaned},
+	StateCleaned:      {},
+}
+
+// IsTerminalState reports whether no further lifecycle transition is possible
+// other than cleaning up the environment
+func IsTerminalState(state string) bool {
+	switch state {
+	case StateCompleted, StateCleaned, StateRolledBack:
+		return true
+	}
+	return false
+}
+
+// CanTransition reports whether from -> to is a legal lifecycle transition
+func CanTransition(from, to string) bool {
+	for _, allowed := range stateTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError is returned when a transition is not allowed by the state machine
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("illegal state transition from %s to %s", e.From, e.To)
+}
+
+// ErrConcurrentTransition is returned when the status kept changing underneath
+// a transition on every attempt
+var ErrConcurrentTransition = errors.New("environment status was changed concurrently")
+
+// maxTransitionAttempts bounds retries after losing an optimistic lock
+const maxTransitionAttempts = 3
+
+// Transition describes a status change and the writes committed along with it
+type Transition struct {
+	To       string
+	Reason   string
+	Metadata map[string]interface{}
+	Updates  map[string]interface{}
+	// InTx runs additional writes in the same database transaction
+	InTx func(tx *gorm.DB) error
+	// Force allows the transition from any state but cleaned; it is only used
+	// for forced deletion
+	Force bool
+}
+
+// inTxAll combines writes to commit along with one transition
+func inTxAll(writes ...func(tx *gorm.DB) error) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, write := range writes {
+			if err := write(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// transitionEnvironment is the only place environment status is written. The
+// status update and its StateTransition row are committed in one transaction,
+// and the update only applies while the status is still the one that was
+// checked (WHERE status = ?). If another writer got there first the transition
+// is re-validated against the new status and retried.
+func transitionEnvironment(envID string, t Transition) (*Environment, error) {
+	for attempt := 0; attempt < maxTransitionAttempts; attempt++ {
+		env, err := tryTransition(envID, t)
+		if !errors.Is(err, ErrConcurrentTransition) {
+			return env, err
+		}
+	}
+	return nil, ErrConcurrentTransition
+}
+
+func tryTransition(envID string, t Transition) (*Environment, error) {
+	var env Environment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&env, "id = ?", envID).Error; err != nil {
+			return err
+		}
+		if !CanTransition(env.Status, t.To) && !(t.Force && env.Status != StateCleaned) {
+			return &TransitionError{From: env.Status, To: t.To}
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{}
+		for k, v := range t.Updates {
+			updates[k] = v
+		}
+		updates["status"] = t.To
+		updates["updated_at"] = now
+
+		result := tx.Model(&Environment{}).
+			Where("id = ? AND status = ?", envID, env.Status).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrConcurrentTransition
+		}
+
+		transition := StateTransition{
+			EnvironmentID: envID,
+			FromState:     env.Status,
+			ToState:       t.To,
+			Reason:        t.Reason,
+			Metadata:      t.Metadata,
+			CreatedAt:     now,
+		}
+		if err := tx.Create(&transition).Error; err != nil {
+			return err
+		}
+
+		env.Status = t.To
+		if t.InTx != nil {
+			return t.InTx(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// respondTransitionError writes 404 for unknown environments, 409 for illegal
+// transitions and 500 for anything else
+func respondTransitionError(c *gin.Context, err error) {
+	var transitionErr *TransitionError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Environment not found"})
+	case errors.As(err, &transitionErr):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "status": transitionErr.From})
+	case errors.Is(err, ErrConcurrentTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
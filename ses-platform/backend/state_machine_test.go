@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatePending, StateValidated, true},
+		{StatePending, StateProvisioning, false},
+		{StateValidated, StatePlanned, true},
+		{StatePlanned, StatePlanned, true},
+		{StatePlanned, StateProvisioning, true},
+		{StatePlanned, StateRunning, false},
+		{StateProvisioning, StateProvisioning, true},
+		{StateProvisioning, StateReady, true},
+		{StateProvisioning, StateFailed, true},
+		{StateProvisioning, StateDeleting, false},
+		{StateReady, StateRunning, true},
+		{StateReady, StateDeleting, true},
+		{StateReady, StateCompleted, false},
+		{StateRunning, StateReady, true},
+		{StateRunning, StateCompleted, true},
+		{StateRunning, StateFailed, true},
+		{StateRunning, StateDeleting, false},
+		{StateCompleted, StateDeleting, true},
+		{StateCompleted, StateRunning, false},
+		{StateFailed, StateRolledBack, true},
+		{StateFailed, StateDeleting, true},
+		{StateFailed, StateReady, false},
+		{StateRolledBack, StateDeleting, true},
+		{StateDeleting, StateReleasing, true},
+		{StateDeleting, StateCleaned, false},
+		{StateReleasing, StateCleaned, true},
+		{StateCleaned, StateDeleting, false},
+		{StateCleaned, StatePending, false},
+		{"unknown", StatePending, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %t, want %t", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestStateTransitionsAreKnownStates(t *testing.T) {
+	for from, targets := range stateTransitions {
+		for _, to := range targets {
+			if _, ok := stateTransitions[to]; !ok {
+				t.Errorf("%s -> %s leads to a state without transitions", from, to)
+			}
+		}
+	}
+}
+
+func TestIsTerminalState(t *testing.T) {
+	for state := range stateTransitions {
+		want := state == StateCompleted || state == StateCleaned || state == StateRolledBack
+		if got := IsTerminalState(state); got != want {
+			t.Errorf("IsTerminalState(%s) = %t, want %t", state, got, want)
+		}
+	}
+}
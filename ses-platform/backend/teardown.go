@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// JobTypeTeardown releases a deleted environment's resources
+const JobTypeTeardown = "teardown"
+
+// teardownWaitInterval is how long a teardown waits for the environment's
+// other jobs to stop before it starts releasing resources
+var teardownWaitInterval = 5 * time.Second
+
+func init() {
+	jobHandlers[JobTypeTeardown] = runTeardownJob
+}
+
+// unrecordedReleaser is implemented by adapters that can release resources
+// provisioned before they were recorded in resource_allocations
+type unrecordedReleaser interface {
+	ReleaseUnrecorded(ctx context.Context) error
+}
+
+// startDeletion moves an environment to deleting and queues its teardown.
+// Unless forced, it waits for a running rollback to finish. A forced deletion
+// is allowed from any state, cancels the environment's other jobs and cleans
+// the environment even if some resources cannot be released.
+func startDeletion(envID string, force bool) (*Job, error) {
+	var job *Job
+	_, err := transitionEnvironment(envID, Transition{
+		To:       StateDeleting,
+		Reason:   "User requested deletion",
+		Metadata: map[string]interface{}{"force": force},
+		Force:    force,
+		InTx: func(tx *gorm.DB) error {
+			if !force {
+				if err := checkNoActiveRollback(tx, envID); err != nil {
+					return err
+				}
+			}
+			var err error
+			job, err = enqueueJob(tx, JobTypeTeardown, envID, map[string]interface{}{"force": force})
+			return err
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if force {
+		var active []Job
+		db.Where("environment_id = ? AND type <> ? AND status IN ?", envID, JobTypeTeardown,
+			[]string{JobStatusQueued, JobStatusRunning}).Find(&active)
+		for _, other := range active {
+			if _, err := requestJobCancellation(other.ID); err != nil && !errors.Is(err, ErrJobFinished) {
+				log.Printf("Failed to cancel job %d of deleted environment %s: %v", other.ID, envID, err)
+			}
+		}
+	}
+	return job, nil
+}
+
+// retryDeletion queues a new teardown for an environment whose teardown stopped
+// before it was cleaned
+func retryDeletion(envID string, force bool) (*Job, error) {
+	var existing Job
+	err := db.Where("environment_id = ? AND type = ? AND status IN ?", envID, JobTypeTeardown,
+		[]string{JobStatusQueued, JobStatusRunning}).First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return enqueueJob(db, JobTypeTeardown, envID, map[string]interface{}{"force": force})
+}
+
+// runTeardownJob releases the environment's resources and then cleans it.
+// Release failures are retried; a forced teardown cleans the environment
+// anyway and leaves the failed resources recorded for drift reconciliation.
+func runTeardownJob(ctx context.Context, job *Job) error {
+	force, _ := job.Payload["force"].(bool)
+
+	var env Environment
+	if err := db.First(&env, "id = ?", job.EnvironmentID).Error; err != nil {
+		return &permanentJobError{fmt.Errorf("environment not found: %v", err)}
+	}
+
+	// Let provisioning or rollback observe its cancellation before tearing down
+	var others int64
+	if err := db.Model(&Job{}).Where("environment_id = ? AND type <> ? AND status = ?",
+		env.ID, JobTypeTeardown, JobStatusRunning).Count(&others).Error; err != nil {
+		return err
+	}
+	if others > 0 {
+		return &rescheduleJob{after: teardownWaitInterval}
+	}
+
+	switch env.Status {
+	case StateDeleting:
+		if _, err := transitionEnvironment(env.ID, Transition{To: StateReleasing, Reason: "Releasing resources"}); err != nil {
+			return err
+		}
+	case StateReleasing:
+	default:
+		return &permanentJobError{fmt.Errorf("environment is %s, not deleting", env.Status)}
+	}
+
+	metadata := map[string]interface{}{"force": force}
+	if err := teardownEnvironment(ctx, env); err != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			return cause
+		}
+		if !force {
+			return err
+		}
+		log.Printf("Forced deletion of environment %s leaves resources behind: %v", env.ID, err)
+		metadata["teardown_error"] = err.Error()
+	}
+
+	// The row is kept, soft-deleted, so its history and resources stay queryable
+	_, err := transitionEnvironment(env.ID, Transition{
+		To:       StateCleaned,
+		Reason:   "Resources released",
+		Metadata: metadata,
+		Updates:  map[string]interface{}{"deleted_at": time.Now()},
+	})
+	return err
+}
+
+// teardownEnvironment releases every resource still allocated to an
+// environment, undoing the steps that created them in reverse order. The
+// outcome for each resource is recorded on its allocation.
+func teardownEnvironment(ctx context.Context, env Environment) error {
+	var recorded int64
+	if err := db.Model(&ResourceAllocation{}).Where("environment_id = ?", env.ID).Count(&recorded).Error; err != nil {
+		return err
+	}
+	allocations, err := activeAllocations(env.ID, "")
+	if err != nil {
+		return err
+	}
+	if recorded > 0 && len(allocations) == 0 {
+		return nil
+	}
+
+	adapter, err := providerFor(env)
+	if err != nil {
+		return err
+	}
+	releaser, releasesUnrecorded := adapter.(unrecordedReleaser)
+	if recorded == 0 && !releasesUnrecorded {
+		return nil
+	}
+	if err := adapter.Authenticate(ctx); err != nil {
+		return fmt.Errorf("authentication with provider %s failed: %w", providerName(env), err)
+	}
+	if recorded == 0 {
+		return releaser.ReleaseUnrecorded(ctx)
+	}
+
+	var errs []error
+	for _, step := range teardownSteps(allocations) {
+		if err := adapter.Deallocate(ctx, step); err != nil {
+			errs = append(errs, &StepError{StepID: step.ID, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// teardownSteps returns the steps that created the allocations, newest first,
+// so each step is undone once and after the steps that depend on it
+func teardownSteps(allocations []ResourceAllocation) []PlanStep {
+	var steps []PlanStep
+	seen := map[string]bool{}
+	for _, allocation := range allocations {
+		if seen[allocation.StepID] {
+			continue
+		}
+		seen[allocation.StepID] = true
+		steps = append(steps, PlanStep{ID: allocation.StepID, Provider: allocation.Provider})
+	}
+	return steps
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTeardownSteps(t *testing.T) {
+	tests := []struct {
+		name        string
+		allocations []ResourceAllocation
+		want        []PlanStep
+	}{
+		{
+			name: "nothing allocated",
+		},
+		{
+			name: "each step is undone once, newest first",
+			allocations: []ResourceAllocation{
+				{StepID: "campaign:speed", Provider: "aws"},
+				{StepID: "vehicles", Provider: "aws"},
+				{StepID: "vehicles", Provider: "aws"},
+				{StepID: "fleet:fleet-1", Provider: "aws"},
+			},
+			want: []PlanStep{
+				{ID: "campaign:speed", Provider: "aws"},
+				{ID: "vehicles", Provider: "aws"},
+				{ID: "fleet:fleet-1", Provider: "aws"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if steps := teardownSteps(tt.allocations); !reflect.DeepEqual(steps, tt.want) {
+				t.Errorf("teardownSteps = %+v, want %+v", steps, tt.want)
+			}
+		})
+	}
+}
+
+// teardownAdapter records how a teardown uses it
+type teardownAdapter struct {
+	ProviderAdapter
+	authErr       error
+	authenticated bool
+}
+
+func (a *teardownAdapter) Authenticate(context.Context) error {
+	a.authenticated = true
+	return a.authErr
+}
+
+// unrecordedAdapter also releases resources that were never recorded
+type unrecordedAdapter struct {
+	teardownAdapter
+	releaseErr error
+	released   bool
+}
+
+func (a *unrecordedAdapter) ReleaseUnrecorded(context.Context) error {
+	a.released = true
+	return a.releaseErr
+}
+
+// useProvider serves the provider name with the adapter for the test
+func useProvider(t *testing.T, name string, adapter ProviderAdapter) {
+	t.Helper()
+	providersMu.Lock()
+	providers[name] = func(Environment) (ProviderAdapter, error) { return adapter, nil }
+	providersMu.Unlock()
+	t.Cleanup(func() {
+		providersMu.Lock()
+		delete(providers, name)
+		providersMu.Unlock()
+	})
+}
+
+func TestTeardownEnvironmentWithoutAllocations(t *testing.T) {
+	tests := []struct {
+		name          string
+		adapter       ProviderAdapter
+		authenticated bool
+		released      bool
+		err           string
+	}{
+		{
+			name:    "nothing to release",
+			adapter: &teardownAdapter{},
+		},
+		{
+			name:          "unrecorded resources are released",
+			adapter:       &unrecordedAdapter{},
+			authenticated: true,
+			released:      true,
+		},
+		{
+			name:          "release failure",
+			adapter:       &unrecordedAdapter{releaseErr: errors.New("vehicle still associated")},
+			authenticated: true,
+			released:      true,
+			err:           "vehicle still associated",
+		},
+		{
+			name:          "authentication failure",
+			adapter:       &unrecordedAdapter{teardownAdapter: teardownAdapter{authErr: errors.New("expired token")}},
+			authenticated: true,
+			err:           "authentication with provider teardown-test failed: expired token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No allocations are found in a dry run
+			useDryRunDB(t)
+			useProvider(t, "teardown-test", tt.adapter)
+
+			err := teardownEnvironment(context.Background(), Environment{ID: "env-1", Provider: "teardown-test"})
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+				t.Errorf("teardownEnvironment = %v, want %q", err, tt.err)
+			}
+			var authenticated, released bool
+			switch adapter := tt.adapter.(type) {
+			case *teardownAdapter:
+				authenticated = adapter.authenticated
+			case *unrecordedAdapter:
+				authenticated, released = adapter.authenticated, adapter.released
+			}
+			if authenticated != tt.authenticated {
+				t.Errorf("authenticated = %t, want %t", authenticated, tt.authenticated)
+			}
+			if released != tt.released {
+				t.Errorf("released unrecorded = %t, want %t", released, tt.released)
+			}
+		})
+	}
+}
+
+func TestRunTeardownJobWithoutEnvironment(t *testing.T) {
+	useDryRunDB(t)
+	err := runTeardownJob(context.Background(), &Job{ID: 7, Type: JobTypeTeardown, EnvironmentID: "env-1"})
+	var permanent *permanentJobError
+	if !errors.As(err, &permanent) {
+		t.Errorf("runTeardownJob = %v, want a permanent error", err)
+	}
+}
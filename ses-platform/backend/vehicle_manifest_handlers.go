@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise/types"
+	"github.com/gin-gonic/gin"
+)
+
+// FleetWise Model and Decoder Manifest API Handlers
+
+func createModelManifest(c *gin.Context) {
+	var req struct {
+		Name             string   `json:"name" binding:"required"`
+		Description      string   `json:"description"`
+		Region           string   `json:"region"`
+		SignalCatalogARN string   `json:"signal_catalog_arn" binding:"required"`
+		Nodes            []string `json:"nodes" binding:"required"`
+		Activate         bool     `json:"activate"` // Activate right after creating
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateModelManifestNodes(req.Nodes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := fleetWiseClients.Client(req.Region)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	result, err := client.CreateModelManifest(ctx, req.Name, req.Description, req.SignalCatalogARN, req.Nodes)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+	status := types.ManifestStatusDraft
+	if req.Activate {
+		if err := client.UpdateModelManifest(ctx, req.Name, "", nil, nil, types.ManifestStatusActive); err != nil {
+			respondFleetWiseError(c, err)
+			return
+		}
+		status = types.ManifestStatusActive
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Model manifest created successfully",
+		"arn":        result.Arn,
+		"name":       result.Name,
+		"status":     status,
+		"node_count": len(req.Nodes),
+	})
+}
+
+func listModelManifests(c *gin.Context) {
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	manifests, err := client.ListModelManifests(c.Request.Context(), c.Query("signal_catalog_arn"), 50)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"model_manifests": manifests,
+		"count":           len(manifests),
+	})
+}
+
+// getModelManifest returns a model manifest; nodes=true includes its nodes
+func getModelManifest(c *gin.Context) {
+	name := c.Param("name")
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	result, err := client.GetModelManifest(c.Request.Context(), name)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+	response := gin.H{
+		"name":                   result.Name,
+		"arn":                    result.Arn,
+		"description":            result.Description,
+		"signal_catalog_arn":     result.SignalCatalogArn,
+		"status":                 result.Status,
+		"creation_time":          result.CreationTime,
+		"last_modification_time": result.LastModificationTime,
+	}
+
+	if c.Query("nodes") == "true" {
+		nodes, err := client.ListModelManifestNodes(c.Request.Context(), name)
+		if err != nil {
+			respondFleetWiseError(c, err)
+			return
+		}
+		response["nodes"] = nodes
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// updateModelManifest adds and removes nodes of a DRAFT model manifest
+func updateModelManifest(c *gin.Context) {
+	name := c.Param("name")
+	var req struct {
+		Description   string   `json:"description"`
+		NodesToAdd    []string `json:"nodes_to_add"`
+		NodesToRemove []string `json:"nodes_to_remove"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Description == "" && len(req.NodesToAdd) == 0 && len(req.NodesToRemove) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "description, nodes_to_add or nodes_to_remove is required"})
+		return
+	}
+	if err := validateModelManifestNodes(append(req.NodesToAdd, req.NodesToRemove...)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	if err := client.UpdateModelManifest(c.Request.Context(), name, req.Description, req.NodesToAdd, req.NodesToRemove, ""); err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Model manifest updated successfully"})
+}
+
+// activateModelManifest moves a model manifest from DRAFT to ACTIVE
+func activateModelManifest(c *gin.Context) {
+	name := c.Param("name")
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	manifest, err := client.GetModelManifest(ctx, name)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+	if manifest.Status == types.ManifestStatusActive {
+		c.JSON(http.StatusOK, gin.H{"message": "Model manifest is already active", "arn": manifest.Arn, "status": manifest.Status})
+		return
+	}
+
+	if err := client.UpdateModelManifest(ctx, name, "", nil, nil, types.ManifestStatusActive); err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Model manifest activated successfully", "arn": manifest.Arn, "status": types.ManifestStatusActive})
+}
+
+func deleteModelManifest(c *gin.Context) {
+	name := c.Param("name")
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	if err := client.DeleteModelManifest(c.Request.Context(), name); err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Model manifest deleted successfully"})
+}
+
+func createDecoderManifest(c *gin.Context) {
+	var req struct {
+		Name              string                   `json:"name" binding:"required"`
+		Description       string                   `json:"description"`
+		Region            string                   `json:"region"`
+		ModelManifestARN  string                   `json:"model_manifest_arn" binding:"required"`
+		NetworkInterfaces []NetworkInterfaceConfig `json:"network_interfaces"`
+		SignalDecoders    []SignalDecoderConfig    `json:"signal_decoders"`
+		Activate          bool                     `json:"activate"` // Activate right after creating
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateDecoderManifest(req.NetworkInterfaces, req.SignalDecoders); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := fleetWiseClients.Client(req.Region)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	result, err := client.CreateDecoderManifest(ctx, req.Name, req.Description, req.ModelManifestARN, req.NetworkInterfaces, req.SignalDecoders)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+	status := types.ManifestStatusDraft
+	if req.Activate {
+		if status, err = activateDecoderManifestStatus(ctx, client, req.Name); err != nil {
+			respondFleetWiseError(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":              "Decoder manifest created successfully",
+		"arn":                  result.Arn,
+		"name":                 result.Name,
+		"status":               status,
+		"interface_count":      len(req.NetworkInterfaces),
+		"signal_decoder_count": len(req.SignalDecoders),
+	})
+}
+
+func listDecoderManifests(c *gin.Context) {
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	manifests, err := client.ListDecoderManifests(c.Request.Context(), c.Query("model_manifest_arn"), 50)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"decoder_manifests": manifests,
+		"count":             len(manifests),
+	})
+}
+
+// getDecoderManifest returns a decoder manifest; details=true includes its
+// network interfaces and signal decoders
+func getDecoderManifest(c *gin.Context) {
+	name := c.Param("name")
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	result, err := client.GetDecoderManifest(ctx, name)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+	response := gin.H{
+		"name":                   result.Name,
+		"arn":                    result.Arn,
+		"description":            result.Description,
+		"model_manifest_arn":     result.ModelManifestArn,
+		"status":                 result.Status,
+		"status_message":         result.Message,
+		"creation_time":          result.CreationTime,
+		"last_modification_time": result.LastModificationTime,
+	}
+
+	if c.Query("details") == "true" {
+		interfaces, err := client.ListDecoderManifestNetworkInterfaces(ctx, name)
+		if err != nil {
+			respondFleetWiseError(c, err)
+			return
+		}
+		decoders, err := client.ListDecoderManifestSignalDecoders(ctx, name)
+		if err != nil {
+			respondFleetWiseError(c, err)
+			return
+		}
+		response["network_interfaces"] = interfaces
+		response["signal_decoders"] = decoders
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// updateDecoderManifest edits the interfaces and decoders of a DRAFT decoder manifest
+func updateDecoderManifest(c *gin.Context) {
+	name := c.Param("name")
+	var req struct {
+		Description string `json:"description"`
+		DecoderManifestChanges
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Description == "" && req.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "description or a network interface or signal decoder change is required"})
+		return
+	}
+	// Decoders may read from interfaces that are already in the manifest, so
+	// FleetWise checks the interface references
+	err := errors.Join(
+		validateNetworkInterfaces(append(req.NetworkInterfacesToAdd, req.NetworkInterfacesToUpdate...)),
+		validateSignalDecoders(append(req.SignalDecodersToAdd, req.SignalDecodersToUpdate...), nil),
+	)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	if err := client.UpdateDecoderManifest(c.Request.Context(), name, req.Description, req.DecoderManifestChanges, ""); err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Decoder manifest updated successfully"})
+}
+
+// activateDecoderManifest moves a decoder manifest from DRAFT to ACTIVE. Its
+// model manifest must be ACTIVE already.
+func activateDecoderManifest(c *gin.Context) {
+	name := c.Param("name")
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	manifest, err := client.GetDecoderManifest(ctx, name)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+	if manifest.Status == types.ManifestStatusActive {
+		c.JSON(http.StatusOK, gin.H{"message": "Decoder manifest is already active", "arn": manifest.Arn, "status": manifest.Status})
+		return
+	}
+
+	status, err := activateDecoderManifestStatus(ctx, client, name)
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Decoder manifest activation requested", "arn": manifest.Arn, "status": status})
+}
+
+// activateDecoderManifestStatus activates a decoder manifest and returns its
+// resulting status. FleetWise validates decoder manifests on activation, so
+// the status can also be VALIDATING or INVALID.
+func activateDecoderManifestStatus(ctx context.Context, client FleetWiseAPI, name string) (types.ManifestStatus, error) {
+	if err := client.UpdateDecoderManifest(ctx, name, "", DecoderManifestChanges{}, types.ManifestStatusActive); err != nil {
+		return "", err
+	}
+	manifest, err := client.GetDecoderManifest(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return manifest.Status, nil
+}
+
+func deleteDecoderManifest(c *gin.Context) {
+	name := c.Param("name")
+	client, err := fleetWiseClients.Client(c.Query("region"))
+	if err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	if err := client.DeleteDecoderManifest(c.Request.Context(), name); err != nil {
+		respondFleetWiseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Decoder manifest deleted successfully"})
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iotfleetwise/types"
+)
+
+// A model manifest selects the signal catalog nodes a vehicle model has. A
+// decoder manifest describes, for one model manifest, the vehicle's network
+// interfaces and how each signal is decoded from them. Both are created as
+// DRAFT and must be ACTIVE before vehicles can use them; ACTIVE manifests can
+// no longer be edited.
+
+// NetworkInterfaceConfig is a vehicle network a decoder manifest reads signals from
+type NetworkInterfaceConfig struct {
+	InterfaceID       string                   `json:"interface_id"`
+	Type              string                   `json:"type"` // CAN_INTERFACE, OBD_INTERFACE, VEHICLE_MIDDLEWARE
+	CAN               *CANInterfaceConfig      `json:"can_interface,omitempty"`
+	OBD               *OBDInterfaceConfig      `json:"obd_interface,omitempty"`
+	VehicleMiddleware *VehicleMiddlewareConfig `json:"vehicle_middleware,omitempty"`
+}
+
+type CANInterfaceConfig struct {
+	Name            string `json:"name"` // e.g. can0
+	ProtocolName    string `json:"protocol_name,omitempty"`
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+}
+
+type OBDInterfaceConfig struct {
+	Name                      string `json:"name"`
+	RequestMessageID          int32  `json:"request_message_id"` // e.g. 2015 (0x7DF)
+	OBDStandard               string `json:"obd_standard,omitempty"`
+	PIDRequestIntervalSeconds int32  `json:"pid_request_interval_seconds,omitempty"`
+	DTCRequestIntervalSeconds int32  `json:"dtc_request_interval_seconds,omitempty"`
+	UseExtendedIDs            bool   `json:"use_extended_ids,omitempty"`
+	HasTransmissionECU        bool   `json:"has_transmission_ecu,omitempty"`
+}
+
+type VehicleMiddlewareConfig struct {
+	Name         string `json:"name"`
+	ProtocolName string `json:"protocol_name"` // ROS_2
+}
+
+// SignalDecoderConfig decodes one signal of the model manifest from a network interface
+type SignalDecoderConfig struct {
+	FullyQualifiedName string               `json:"fully_qualified_name"`
+	Type               string               `json:"type"` // CAN_SIGNAL, OBD_SIGNAL, MESSAGE_SIGNAL
+	InterfaceID        string               `json:"interface_id"`
+	CAN                *CANSignalConfig     `json:"can_signal,omitempty"`
+	OBD                *OBDSignalConfig     `json:"obd_signal,omitempty"`
+	Message            *MessageSignalConfig `json:"message_signal,omitempty"`
+}
+
+type CANSignalConfig struct {
+	MessageID   int32   `json:"message_id"`
+	Name        string  `json:"name,omitempty"`
+	IsBigEndian bool    `json:"is_big_endian"`
+	IsSigned    bool    `json:"is_signed"`
+	StartBit    int32   `json:"start_bit"`
+	Length      int32   `json:"length"`
+	Factor      float64 `json:"factor"`
+	Offset      float64 `json:"offset"`
+}
+
+type OBDSignalConfig struct {
+	ServiceMode       int32   `json:"service_mode"`
+	PID               int32   `json:"pid"`
+	PIDResponseLength int32   `json:"pid_response_length"`
+	StartByte         int32   `json:"start_byte"`
+	ByteLength        int32   `json:"byte_length"`
+	BitRightShift     int32   `json:"bit_right_shift,omitempty"`
+	BitMaskLength     int32   `json:"bit_mask_length,omitempty"`
+	Scaling           float64 `json:"scaling"`
+	Offset            float64 `json:"offset"`
+}
+
+// MessageSignalConfig decodes a signal from a vehicle middleware topic. Only
+// ROS 2 primitive messages are supported.
+type MessageSignalConfig struct {
+	TopicName     string   `json:"topic_name"`
+	PrimitiveType string   `json:"primitive_type"` // ROS 2 type, e.g. FLOAT64
+	Offset        *float64 `json:"offset,omitempty"`
+	Scaling       *float64 `json:"scaling,omitempty"`
+	UpperBound    *int64   `json:"upper_bound,omitempty"`
+}
+
+// DecoderManifestChanges are the edits UpdateDecoderManifest takes. Interfaces
+// are removed by interface ID and decoders by fully qualified name.
+type DecoderManifestChanges struct {
+	NetworkInterfacesToAdd    []NetworkInterfaceConfig `json:"network_interfaces_to_add"`
+	NetworkInterfacesToUpdate []NetworkInterfaceConfig `json:"network_interfaces_to_update"`
+	NetworkInterfacesToRemove []string                 `json:"network_interfaces_to_remove"`
+	SignalDecodersToAdd       []SignalDecoderConfig    `json:"signal_decoders_to_add"`
+	SignalDecodersToUpdate    []SignalDecoderConfig    `json:"signal_decoders_to_update"`
+	SignalDecodersToRemove    []string                 `json:"signal_decoders_to_remove"`
+}
+
+// Empty reports whether there are no changes
+func (c DecoderManifestChanges) Empty() bool {
+	return len(c.NetworkInterfacesToAdd) == 0 && len(c.NetworkInterfacesToUpdate) == 0 && len(c.NetworkInterfacesToRemove) == 0 &&
+		len(c.SignalDecodersToAdd) == 0 && len(c.SignalDecodersToUpdate) == 0 && len(c.SignalDecodersToRemove) == 0
+}
+
+// decoderInterfaceTypes is the network interface type each signal decoder type reads from
+var decoderInterfaceTypes = map[string]string{
+	string(types.SignalDecoderTypeCanSignal):     string(types.NetworkInterfaceTypeCanInterface),
+	string(types.SignalDecoderTypeObdSignal):     string(types.NetworkInterfaceTypeObdInterface),
+	string(types.SignalDecoderTypeMessageSignal): string(types.NetworkInterfaceTypeVehicleMiddleware),
+}
+
+// validateModelManifestNodes checks that nodes are named and listed once
+func validateModelManifestNodes(nodes []string) error {
+	var errs []error
+	seen := map[string]bool{}
+	for _, node := range nodes {
+		if node == "" {
+			errs = append(errs, errors.New("empty node name"))
+			continue
+		}
+		if seen[node] {
+			errs = append(errs, fmt.Errorf("%s: duplicate node", node))
+		}
+		seen[node] = true
+	}
+	return errors.Join(errs...)
+}
+
+// validateNetworkInterfaces checks interface IDs and that each interface has
+// the settings of its type
+func validateNetworkInterfaces(interfaces []NetworkInterfaceConfig) error {
+	var errs []error
+	seen := map[string]bool{}
+	for _, ni := range interfaces {
+		id := ni.InterfaceID
+		if id == "" {
+			errs = append(errs, errors.New("network interface without interface_id"))
+			continue
+		}
+		if seen[id] {
+			errs = append(errs, fmt.Errorf("interface %s: duplicate interface_id", id))
+		}
+		seen[id] = true
+
+		var name string
+		switch types.NetworkInterfaceType(ni.Type) {
+		case types.NetworkInterfaceTypeCanInterface:
+			if ni.CAN != nil {
+				name = ni.CAN.Name
+			}
+		case types.NetworkInterfaceTypeObdInterface:
+			if ni.OBD != nil {
+				name = ni.OBD.Name
+			}
+		case types.NetworkInterfaceTypeVehicleMiddleware:
+			if ni.VehicleMiddleware != nil {
+				name = ni.VehicleMiddleware.Name
+				if ni.VehicleMiddleware.ProtocolName != string(types.VehicleMiddlewareProtocolRos2) {
+					errs = append(errs, fmt.Errorf("interface %s: unsupported protocol_name %q", id, ni.VehicleMiddleware.ProtocolName))
+				}
+			}
+		default:
+			errs = append(errs, fmt.Errorf("interface %s: unsupported type %q", id, ni.Type))
+			continue
+		}
+		if name == "" {
+			errs = append(errs, fmt.Errorf("interface %s: %s settings with a name are required", id, ni.Type))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateSignalDecoders checks that each decoder has the settings of its
+// type. When interfaces is not nil, decoders must also read from one of them
+// of a matching type; it maps interface IDs to types.
+func validateSignalDecoders(decoders []SignalDecoderConfig, interfaces map[string]string) error {
+	var errs []error
+	seen := map[string]bool{}
+	for _, d := range decoders {
+		name := d.FullyQualifiedName
+		if name == "" {
+			errs = append(errs, errors.New("signal decoder without fully_qualified_name"))
+			continue
+		}
+		if seen[name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate signal decoder", name))
+		}
+		seen[name] = true
+
+		interfaceType, ok := decoderInterfaceTypes[d.Type]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unsupported decoder type %q", name, d.Type))
+			continue
+		}
+		switch types.SignalDecoderType(d.Type) {
+		case types.SignalDecoderTypeCanSignal:
+			if d.CAN == nil {
+				errs = append(errs, fmt.Errorf("%s: can_signal is required", name))
+			} else if d.CAN.Length <= 0 || d.CAN.Factor == 0 {
+				errs = append(errs, fmt.Errorf("%s: can_signal needs a positive length and a non-zero factor", name))
+			}
+		case types.SignalDecoderTypeObdSignal:
+			if d.OBD == nil {
+				errs = append(errs, fmt.Errorf("%s: obd_signal is required", name))
+			} else if d.OBD.PIDResponseLength <= 0 || d.OBD.ByteLength <= 0 {
+				errs = append(errs, fmt.Errorf("%s: obd_signal needs a positive pid_response_length and byte_length", name))
+			}
+		case types.SignalDecoderTypeMessageSignal:
+			if d.Message == nil || d.Message.TopicName == "" {
+				errs = append(errs, fmt.Errorf("%s: message_signal with a topic_name is required", name))
+			} else if !validROS2PrimitiveType(d.Message.PrimitiveType) {
+				errs = append(errs, fmt.Errorf("%s: unsupported primitive_type %q", name, d.Message.PrimitiveType))
+			}
+		}
+
+		if interfaces == nil {
+			continue
+		}
+		if t, ok := interfaces[d.InterfaceID]; !ok {
+			errs = append(errs, fmt.Errorf("%s: interface %q is not defined", name, d.InterfaceID))
+		} else if t != interfaceType {
+			errs = append(errs, fmt.Errorf("%s: %s decoders need a %s, interface %s is %s", name, d.Type, interfaceType, d.InterfaceID, t))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateDecoderManifest checks a decoder manifest's interfaces and decoders
+// together
+func validateDecoderManifest(interfaces []NetworkInterfaceConfig, decoders []SignalDecoderConfig) error {
+	interfaceTypes := make(map[string]string, len(interfaces))
+	for _, ni := range interfaces {
+		interfaceTypes[ni.InterfaceID] = ni.Type
+	}
+	return errors.Join(validateNetworkInterfaces(interfaces), validateSignalDecoders(decoders, interfaceTypes))
+}
+
+func validROS2PrimitiveType(primitiveType string) bool {
+	for _, known := range types.ROS2PrimitiveType("").Values() {
+		if string(known) == primitiveType {
+			return true
+		}
+	}
+	return false
+}
+
+// toFleetWiseNetworkInterfaces converts interfaces to their SDK representation
+func toFleetWiseNetworkInterfaces(interfaces []NetworkInterfaceConfig) []types.NetworkInterface {
+	converted := make([]types.NetworkInterface, 0, len(interfaces))
+	for _, ni := range interfaces {
+		n := types.NetworkInterface{
+			InterfaceId: aws.String(ni.InterfaceID),
+			Type:        types.NetworkInterfaceType(ni.Type),
+		}
+		if ni.CAN != nil {
+			n.CanInterface = &types.CanInterface{
+				Name:            aws.String(ni.CAN.Name),
+				ProtocolName:    optionalString(ni.CAN.ProtocolName),
+				ProtocolVersion: optionalString(ni.CAN.ProtocolVersion),
+			}
+		}
+		if ni.OBD != nil {
+			n.ObdInterface = &types.ObdInterface{
+				Name:                      aws.String(ni.OBD.Name),
+				RequestMessageId:          ni.OBD.RequestMessageID,
+				ObdStandard:               optionalString(ni.OBD.OBDStandard),
+				PidRequestIntervalSeconds: ni.OBD.PIDRequestIntervalSeconds,
+				DtcRequestIntervalSeconds: ni.OBD.DTCRequestIntervalSeconds,
+				UseExtendedIds:            ni.OBD.UseExtendedIDs,
+				HasTransmissionEcu:        ni.OBD.HasTransmissionECU,
+			}
+		}
+		if ni.VehicleMiddleware != nil {
+			n.VehicleMiddleware = &types.VehicleMiddleware{
+				Name:         aws.String(ni.VehicleMiddleware.Name),
+				ProtocolName: types.VehicleMiddlewareProtocol(ni.VehicleMiddleware.ProtocolName),
+			}
+		}
+		converted = append(converted, n)
+	}
+	return converted
+}
+
+// toFleetWiseSignalDecoders converts decoders to their SDK representation
+func toFleetWiseSignalDecoders(decoders []SignalDecoderConfig) []types.SignalDecoder {
+	converted := make([]types.SignalDecoder, 0, len(decoders))
+	for _, d := range decoders {
+		s := types.SignalDecoder{
+			FullyQualifiedName: aws.String(d.FullyQualifiedName),
+			Type:               types.SignalDecoderType(d.Type),
+			InterfaceId:        aws.String(d.InterfaceID),
+		}
+		if d.CAN != nil {
+			s.CanSignal = &types.CanSignal{
+				MessageId:   d.CAN.MessageID,
+				Name:        optionalString(d.CAN.Name),
+				IsBigEndian: d.CAN.IsBigEndian,
+				IsSigned:    d.CAN.IsSigned,
+				StartBit:    d.CAN.StartBit,
+				Length:      d.CAN.Length,
+				Factor:      aws.Float64(d.CAN.Factor),
+				Offset:      aws.Float64(d.CAN.Offset),
+			}
+		}
+		if d.OBD != nil {
+			s.ObdSignal = &types.ObdSignal{
+				ServiceMode:       d.OBD.ServiceMode,
+				Pid:               d.OBD.PID,
+				PidResponseLength: aws.Int32(d.OBD.PIDResponseLength),
+				StartByte:         d.OBD.StartByte,
+				ByteLength:        aws.Int32(d.OBD.ByteLength),
+				BitRightShift:     d.OBD.BitRightShift,
+				Scaling:           aws.Float64(d.OBD.Scaling),
+				Offset:            aws.Float64(d.OBD.Offset),
+			}
+			if d.OBD.BitMaskLength > 0 {
+				s.ObdSignal.BitMaskLength = aws.Int32(d.OBD.BitMaskLength)
+			}
+		}
+		if d.Message != nil {
+			s.MessageSignal = &types.MessageSignal{
+				TopicName: aws.String(d.Message.TopicName),
+				StructuredMessage: &types.StructuredMessageMemberPrimitiveMessageDefinition{
+					Value: &types.PrimitiveMessageDefinitionMemberRos2PrimitiveMessageDefinition{
+						Value: types.ROS2PrimitiveMessageDefinition{
+							PrimitiveType: types.ROS2PrimitiveType(d.Message.PrimitiveType),
+							Offset:        d.Message.Offset,
+							Scaling:       d.Message.Scaling,
+							UpperBound:    d.Message.UpperBound,
+						},
+					},
+				},
+			}
+		}
+		converted = append(converted, s)
+	}
+	return converted
+}
+
+// fromFleetWiseNetworkInterface converts an SDK network interface
+func fromFleetWiseNetworkInterface(n types.NetworkInterface) NetworkInterfaceConfig {
+	ni := NetworkInterfaceConfig{
+		InterfaceID: aws.ToString(n.InterfaceId),
+		Type:        string(n.Type),
+	}
+	if n.CanInterface != nil {
+		ni.CAN = &CANInterfaceConfig{
+			Name:            aws.ToString(n.CanInterface.Name),
+			ProtocolName:    aws.ToString(n.CanInterface.ProtocolName),
+			ProtocolVersion: aws.ToString(n.CanInterface.ProtocolVersion),
+		}
+	}
+	if n.ObdInterface != nil {
+		ni.OBD = &OBDInterfaceConfig{
+			Name:                      aws.ToString(n.ObdInterface.Name),
+			RequestMessageID:          n.ObdInterface.RequestMessageId,
+			OBDStandard:               aws.ToString(n.ObdInterface.ObdStandard),
+			PIDRequestIntervalSeconds: n.ObdInterface.PidRequestIntervalSeconds,
+			DTCRequestIntervalSeconds: n.ObdInterface.DtcRequestIntervalSeconds,
+			UseExtendedIDs:            n.ObdInterface.UseExtendedIds,
+			HasTransmissionECU:        n.ObdInterface.HasTransmissionEcu,
+		}
+	}
+	if n.VehicleMiddleware != nil {
+		ni.VehicleMiddleware = &VehicleMiddlewareConfig{
+			Name:         aws.ToString(n.VehicleMiddleware.Name),
+			ProtocolName: string(n.VehicleMiddleware.ProtocolName),
+		}
+	}
+	return ni
+}
+
+// fromFleetWiseSignalDecoder converts an SDK signal decoder. Message signals
+// other than ROS 2 primitives are returned without their message_signal.
+func fromFleetWiseSignalDecoder(s types.SignalDecoder) SignalDecoderConfig {
+	d := SignalDecoderConfig{
+		FullyQualifiedName: aws.ToString(s.FullyQualifiedName),
+		Type:               string(s.Type),
+		InterfaceID:        aws.ToString(s.InterfaceId),
+	}
+	if s.CanSignal != nil {
+		d.CAN = &CANSignalConfig{
+			MessageID:   s.CanSignal.MessageId,
+			Name:        aws.ToString(s.CanSignal.Name),
+			IsBigEndian: s.CanSignal.IsBigEndian,
+			IsSigned:    s.CanSignal.IsSigned,
+			StartBit:    s.CanSignal.StartBit,
+			Length:      s.CanSignal.Length,
+			Factor:      aws.ToFloat64(s.CanSignal.Factor),
+			Offset:      aws.ToFloat64(s.CanSignal.Offset),
+		}
+	}
+	if s.ObdSignal != nil {
+		d.OBD = &OBDSignalConfig{
+			ServiceMode:       s.ObdSignal.ServiceMode,
+			PID:               s.ObdSignal.Pid,
+			PIDResponseLength: aws.ToInt32(s.ObdSignal.PidResponseLength),
+			StartByte:         s.ObdSignal.StartByte,
+			ByteLength:        aws.ToInt32(s.ObdSignal.ByteLength),
+			BitRightShift:     s.ObdSignal.BitRightShift,
+			BitMaskLength:     aws.ToInt32(s.ObdSignal.BitMaskLength),
+			Scaling:           aws.ToFloat64(s.ObdSignal.Scaling),
+			Offset:            aws.ToFloat64(s.ObdSignal.Offset),
+		}
+	}
+	if s.MessageSignal != nil {
+		if m, ok := s.MessageSignal.StructuredMessage.(*types.StructuredMessageMemberPrimitiveMessageDefinition); ok {
+			if ros2, ok := m.Value.(*types.PrimitiveMessageDefinitionMemberRos2PrimitiveMessageDefinition); ok {
+				d.Message = &MessageSignalConfig{
+					TopicName:     aws.ToString(s.MessageSignal.TopicName),
+					PrimitiveType: string(ros2.Value.PrimitiveType),
+					Offset:        ros2.Value.Offset,
+					Scaling:       ros2.Value.Scaling,
+					UpperBound:    ros2.Value.UpperBound,
+				}
+			}
+		}
+	}
+	return d
+}
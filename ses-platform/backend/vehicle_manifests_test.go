@@ -0,0 +1,194 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// errorLines splits the errors joined into err
+func errorLines(err error) []string {
+	if err == nil {
+		return nil
+	}
+	return strings.Split(err.Error(), "\n")
+}
+
+func TestValidateModelManifestNodes(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []string
+		errs  []string
+	}{
+		{name: "valid", nodes: []string{"Vehicle.Speed", "Vehicle.Cabin.Temperature"}},
+		{name: "none"},
+		{
+			name:  "empty and duplicate names",
+			nodes: []string{"Vehicle.Speed", "", "Vehicle.Speed"},
+			errs:  []string{"empty node name", "Vehicle.Speed: duplicate node"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := errorLines(validateModelManifestNodes(tt.nodes)); !reflect.DeepEqual(errs, tt.errs) {
+				t.Errorf("errors = %q, want %q", errs, tt.errs)
+			}
+		})
+	}
+}
+
+func TestValidateNetworkInterfaces(t *testing.T) {
+	can := NetworkInterfaceConfig{InterfaceID: "1", Type: "CAN_INTERFACE", CAN: &CANInterfaceConfig{Name: "can0"}}
+	obd := NetworkInterfaceConfig{InterfaceID: "2", Type: "OBD_INTERFACE", OBD: &OBDInterfaceConfig{Name: "obd0", RequestMessageID: 2015}}
+	ros := NetworkInterfaceConfig{InterfaceID: "3", Type: "VEHICLE_MIDDLEWARE", VehicleMiddleware: &VehicleMiddlewareConfig{Name: "ros", ProtocolName: "ROS_2"}}
+
+	tests := []struct {
+		name       string
+		interfaces []NetworkInterfaceConfig
+		errs       []string
+	}{
+		{name: "valid", interfaces: []NetworkInterfaceConfig{can, obd, ros}},
+		{
+			name:       "missing and duplicate IDs",
+			interfaces: []NetworkInterfaceConfig{can, {Type: "CAN_INTERFACE"}, can},
+			errs:       []string{"network interface without interface_id", "interface 1: duplicate interface_id"},
+		},
+		{
+			name:       "unsupported type",
+			interfaces: []NetworkInterfaceConfig{{InterfaceID: "1", Type: "LIN_INTERFACE"}},
+			errs:       []string{`interface 1: unsupported type "LIN_INTERFACE"`},
+		},
+		{
+			name: "settings without a name",
+			interfaces: []NetworkInterfaceConfig{
+				{InterfaceID: "1", Type: "CAN_INTERFACE"},
+				{InterfaceID: "2", Type: "OBD_INTERFACE", OBD: &OBDInterfaceConfig{}},
+			},
+			errs: []string{
+				"interface 1: CAN_INTERFACE settings with a name are required",
+				"interface 2: OBD_INTERFACE settings with a name are required",
+			},
+		},
+		{
+			name:       "unsupported middleware protocol",
+			interfaces: []NetworkInterfaceConfig{{InterfaceID: "3", Type: "VEHICLE_MIDDLEWARE", VehicleMiddleware: &VehicleMiddlewareConfig{Name: "dds", ProtocolName: "DDS"}}},
+			errs:       []string{`interface 3: unsupported protocol_name "DDS"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := errorLines(validateNetworkInterfaces(tt.interfaces)); !reflect.DeepEqual(errs, tt.errs) {
+				t.Errorf("errors = %q, want %q", errs, tt.errs)
+			}
+		})
+	}
+}
+
+func TestValidateSignalDecoders(t *testing.T) {
+	speed := SignalDecoderConfig{
+		FullyQualifiedName: "Vehicle.Speed",
+		Type:               "CAN_SIGNAL",
+		InterfaceID:        "1",
+		CAN:                &CANSignalConfig{MessageID: 100, StartBit: 0, Length: 16, Factor: 0.01},
+	}
+	rpm := SignalDecoderConfig{
+		FullyQualifiedName: "Vehicle.Powertrain.RPM",
+		Type:               "OBD_SIGNAL",
+		InterfaceID:        "2",
+		OBD:                &OBDSignalConfig{ServiceMode: 1, PID: 12, PIDResponseLength: 2, ByteLength: 2, Scaling: 0.25},
+	}
+	heading := SignalDecoderConfig{
+		FullyQualifiedName: "Vehicle.Heading",
+		Type:               "MESSAGE_SIGNAL",
+		InterfaceID:        "3",
+		Message:            &MessageSignalConfig{TopicName: "/heading", PrimitiveType: "FLOAT64"},
+	}
+	interfaces := map[string]string{"1": "CAN_INTERFACE", "2": "OBD_INTERFACE", "3": "VEHICLE_MIDDLEWARE"}
+
+	tests := []struct {
+		name       string
+		decoders   []SignalDecoderConfig
+		interfaces map[string]string
+		errs       []string
+	}{
+		{name: "valid", decoders: []SignalDecoderConfig{speed, rpm, heading}, interfaces: interfaces},
+		{
+			name:     "missing and duplicate names",
+			decoders: []SignalDecoderConfig{speed, {Type: "CAN_SIGNAL"}, speed},
+			errs:     []string{"signal decoder without fully_qualified_name", "Vehicle.Speed: duplicate signal decoder"},
+		},
+		{
+			name:     "unsupported type",
+			decoders: []SignalDecoderConfig{{FullyQualifiedName: "Vehicle.Speed", Type: "CUSTOM_DECODING_SIGNAL"}},
+			errs:     []string{`Vehicle.Speed: unsupported decoder type "CUSTOM_DECODING_SIGNAL"`},
+		},
+		{
+			name: "settings of the type are required",
+			decoders: []SignalDecoderConfig{
+				{FullyQualifiedName: "Vehicle.Speed", Type: "CAN_SIGNAL"},
+				{FullyQualifiedName: "Vehicle.Powertrain.RPM", Type: "OBD_SIGNAL"},
+				{FullyQualifiedName: "Vehicle.Heading", Type: "MESSAGE_SIGNAL", Message: &MessageSignalConfig{PrimitiveType: "FLOAT64"}},
+			},
+			errs: []string{
+				"Vehicle.Speed: can_signal is required",
+				"Vehicle.Powertrain.RPM: obd_signal is required",
+				"Vehicle.Heading: message_signal with a topic_name is required",
+			},
+		},
+		{
+			name: "invalid settings",
+			decoders: []SignalDecoderConfig{
+				{FullyQualifiedName: "Vehicle.Speed", Type: "CAN_SIGNAL", CAN: &CANSignalConfig{Length: 16}},
+				{FullyQualifiedName: "Vehicle.Powertrain.RPM", Type: "OBD_SIGNAL", OBD: &OBDSignalConfig{PIDResponseLength: 2}},
+				{FullyQualifiedName: "Vehicle.Heading", Type: "MESSAGE_SIGNAL", Message: &MessageSignalConfig{TopicName: "/heading", PrimitiveType: "DOUBLE"}},
+			},
+			errs: []string{
+				"Vehicle.Speed: can_signal needs a positive length and a non-zero factor",
+				"Vehicle.Powertrain.RPM: obd_signal needs a positive pid_response_length and byte_length",
+				`Vehicle.Heading: unsupported primitive_type "DOUBLE"`,
+			},
+		},
+		{
+			name:       "interface must be defined and of the decoder's type",
+			decoders:   []SignalDecoderConfig{speed, {FullyQualifiedName: rpm.FullyQualifiedName, Type: rpm.Type, InterfaceID: "1", OBD: rpm.OBD}},
+			interfaces: map[string]string{"1": "CAN_INTERFACE"},
+			errs: []string{
+				"Vehicle.Powertrain.RPM: OBD_SIGNAL decoders need a OBD_INTERFACE, interface 1 is CAN_INTERFACE",
+			},
+		},
+		{
+			name:       "undefined interface",
+			decoders:   []SignalDecoderConfig{speed},
+			interfaces: map[string]string{},
+			errs:       []string{`Vehicle.Speed: interface "1" is not defined`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := errorLines(validateSignalDecoders(tt.decoders, tt.interfaces)); !reflect.DeepEqual(errs, tt.errs) {
+				t.Errorf("errors = %q, want %q", errs, tt.errs)
+			}
+		})
+	}
+}
+
+func TestValidateDecoderManifest(t *testing.T) {
+	interfaces := []NetworkInterfaceConfig{
+		{InterfaceID: "1", Type: "CAN_INTERFACE", CAN: &CANInterfaceConfig{Name: "can0"}},
+		{InterfaceID: "2", Type: "OBD_INTERFACE"},
+	}
+	decoders := []SignalDecoderConfig{
+		{FullyQualifiedName: "Vehicle.Speed", Type: "CAN_SIGNAL", InterfaceID: "1", CAN: &CANSignalConfig{Length: 16, Factor: 1}},
+		{FullyQualifiedName: "Vehicle.Heading", Type: "CAN_SIGNAL", InterfaceID: "2", CAN: &CANSignalConfig{Length: 16, Factor: 1}},
+	}
+	want := []string{
+		"interface 2: OBD_INTERFACE settings with a name are required",
+		"Vehicle.Heading: CAN_SIGNAL decoders need a CAN_INTERFACE, interface 2 is OBD_INTERFACE",
+	}
+	if errs := errorLines(validateDecoderManifest(interfaces, decoders)); !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors = %q, want %q", errs, want)
+	}
+	if err := validateDecoderManifest(interfaces[:1], decoders[:1]); err != nil {
+		t.Errorf("validateDecoderManifest = %v, want nil", err)
+	}
+}
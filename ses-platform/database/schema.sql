@@ -49,7 +49,8 @@ CREATE TABLE environments (
     owner VARCHAR(255),
     tags TEXT[], -- Array of tags
     status VARCHAR(50) NOT NULL DEFAULT 'pending',
-        -- Valid statuses: pending, validating, provisioning, running, stopped, error, deleted
+        -- Valid statuses (spec §5.2): pending, validated, planned, provisioning, ready, running,
+        -- completed, failed, cleaned, rolled_back, deleting, releasing
     capabilities JSONB NOT NULL DEFAULT '[]', -- Selected capability IDs
     enablers_config JSONB NOT NULL DEFAULT '{}', -- Enabler configurations
     
@@ -76,12 +77,23 @@ CREATE TABLE environments (
     uptime VARCHAR(50) DEFAULT '0h',
     last_health_check TIMESTAMP WITH TIME ZONE,
     
+    -- AWS IoT FleetWise (C19)
+    fleetwise_config JSONB, -- FleetWiseConfig, NULL when not AWS-backed
+    use_real_aws_backend BOOLEAN DEFAULT FALSE, -- Deprecated: use provider = 'aws'
+
+    -- Provider adapter (C14): simulated, aws, ...
+    provider VARCHAR(50) NOT NULL DEFAULT 'simulated',
+    
+    -- Source Specification (C02)
+    spec JSONB, -- Parsed SES document (apiVersion: simplan.io/v1)
+    spec_hash VARCHAR(64), -- SHA-256 of the canonical SES document
+    
     -- Timestamps
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     deleted_at TIMESTAMP WITH TIME ZONE,
     
-    CONSTRAINT chk_status CHECK (status IN ('pending', 'validating', 'provisioning', 'running', 'stopped', 'error', 'deleted')),
+    CONSTRAINT chk_status CHECK (status IN ('pending', 'validated', 'planned', 'provisioning', 'ready', 'running', 'completed', 'failed', 'cleaned', 'rolled_back', 'deleting', 'releasing')),
     CONSTRAINT chk_priority CHECK (priority IN ('low', 'medium', 'high', 'critical')),
     CONSTRAINT chk_health CHECK (health >= 0 AND health <= 100)
 );
@@ -110,6 +122,56 @@ CREATE TABLE state_transitions (
 CREATE INDEX idx_state_transitions_env ON state_transitions(environment_id, created_at DESC);
 CREATE INDEX idx_state_transitions_states ON state_transitions(from_state, to_state);
 
+-- Execution plans - Ordered provisioning steps reviewed before execution
+CREATE TABLE execution_plans (
+    id SERIAL PRIMARY KEY,
+    environment_id VARCHAR(50) NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    spec_hash VARCHAR(64),
+    input_hash VARCHAR(64), -- SHA-256 of the spec, compute and FleetWise configuration and provider
+    status VARCHAR(50) NOT NULL DEFAULT 'pending_approval',
+        -- Valid: pending_approval, approved, executing, completed, failed, superseded
+    provider VARCHAR(50) NOT NULL,
+    strategy VARCHAR(20) NOT NULL DEFAULT 'optimized',
+    steps JSONB NOT NULL DEFAULT '[]',
+        -- [{id, name, action, provider_resource, depends_on, estimated_duration_seconds, rollback_action}]
+    estimated_duration_seconds INTEGER DEFAULT 0,
+    approved_by VARCHAR(255),
+    approved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_plan_status CHECK (status IN ('pending_approval', 'approved', 'executing', 'completed', 'failed', 'superseded')),
+    CONSTRAINT chk_plan_strategy CHECK (strategy IN ('parallel', 'sequential', 'optimized'))
+);
+
+CREATE INDEX idx_execution_plans_env ON execution_plans(environment_id, created_at DESC);
+
+-- Jobs - Durable background work claimed by workers with FOR UPDATE SKIP LOCKED
+CREATE TABLE jobs (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(50) NOT NULL,
+        -- Valid: provision, track_uptime, rollback, reconcile_drift, teardown
+    environment_id VARCHAR(50) REFERENCES environments(id) ON DELETE CASCADE, -- NULL for jobs not tied to an environment
+    payload JSONB NOT NULL DEFAULT '{}',
+    checkpoint JSONB NOT NULL DEFAULT '[]', -- Completed plan step IDs
+    status VARCHAR(50) NOT NULL DEFAULT 'queued',
+        -- Valid: queued, running, succeeded, failed, cancelled
+    attempts INTEGER DEFAULT 0,
+    max_attempts INTEGER DEFAULT 3,
+    last_error TEXT,
+    cancel_requested BOOLEAN DEFAULT false,
+    run_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    locked_by VARCHAR(255), -- Worker holding the claim
+    heartbeat_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_job_status CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled'))
+);
+
+CREATE INDEX idx_jobs_claim ON jobs(status, run_at);
+CREATE INDEX idx_jobs_env ON jobs(environment_id, created_at DESC);
+
 -- =============================================
 -- RESOURCE ALLOCATION (C04, C11)
 -- =============================================
@@ -118,14 +180,17 @@ CREATE INDEX idx_state_transitions_states ON state_transitions(from_state, to_st
 CREATE TABLE resource_allocations (
     id SERIAL PRIMARY KEY,
     environment_id VARCHAR(50) NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    step_id VARCHAR(255), -- Plan step that created the resource
     resource_type VARCHAR(50) NOT NULL,
-        -- Valid: compute, storage, network, database, cache
+        -- Valid: compute, storage, network, security, database, cache, container,
+        --        fleet, vehicle, fleet_association, campaign
     resource_id VARCHAR(255) NOT NULL, -- Provider-specific resource identifier
     provider VARCHAR(50) NOT NULL, -- aws, azure, gcp, on-prem
     region VARCHAR(50),
     status VARCHAR(50) DEFAULT 'allocating',
         -- Valid: allocating, active, releasing, released, failed
     config JSONB NOT NULL DEFAULT '{}',
+    last_error TEXT, -- Why the last release attempt failed
     allocated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     released_at TIMESTAMP WITH TIME ZONE,
     
@@ -351,14 +416,43 @@ CREATE INDEX idx_error_records_env ON error_records(environment_id, created_at D
 CREATE INDEX idx_error_records_severity ON error_records(severity);
 CREATE INDEX idx_error_records_unresolved ON error_records(resolved_at) WHERE resolved_at IS NULL;
 
+-- Drift records - Differences between resource_allocations and provider state
+CREATE TABLE drift_records (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL,
+        -- Valid: orphaned (exists at the provider only), unallocated (belongs to a live
+        --        environment but is not recorded), missing (recorded only)
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+        -- Valid: open, resolved, collected
+    provider VARCHAR(50) NOT NULL,
+    region VARCHAR(50),
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL,
+    resource_name VARCHAR(255),
+    environment_id VARCHAR(50), -- No foreign key: orphans may belong to deleted environments
+    allocation_id INTEGER REFERENCES resource_allocations(id) ON DELETE SET NULL,
+    reason TEXT,
+    first_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT chk_drift_kind CHECK (kind IN ('orphaned', 'unallocated', 'missing')),
+    CONSTRAINT chk_drift_status CHECK (status IN ('open', 'resolved', 'collected'))
+);
+
+CREATE INDEX idx_drift_records_open ON drift_records(kind, resource_id) WHERE status = 'open';
+CREATE INDEX idx_drift_records_env ON drift_records(environment_id);
+
 -- Rollback operations - Track rollback history
 CREATE TABLE rollback_operations (
     id SERIAL PRIMARY KEY,
     environment_id VARCHAR(50) NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    plan_id INTEGER REFERENCES execution_plans(id) ON DELETE SET NULL, -- Plan whose steps are undone
     trigger_type VARCHAR(50) NOT NULL,
         -- Valid: manual, error, timeout, policy_violation
     rollback_target VARCHAR(50), -- State or snapshot to rollback to
-    steps JSONB NOT NULL DEFAULT '[]', -- Array of rollback steps
+    steps JSONB NOT NULL DEFAULT '[]', -- Array of rollback steps in LIFO order
+        -- [{step_id, name, action, provider_resource, status, error, completed_at}]
     status VARCHAR(50) DEFAULT 'pending',
         -- Valid: pending, in_progress, completed, failed
     started_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
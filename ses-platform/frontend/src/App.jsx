@@ -136,11 +136,14 @@ function Dashboard({ environments, setView }) {
   const getStatusColor = (status) => {
     const colors = {
       running: 'bg-green-100 text-green-800',
+      ready: 'bg-green-50 text-green-700',
       provisioning: 'bg-blue-100 text-blue-800',
-      stopped: 'bg-gray-100 text-gray-800',
-      error: 'bg-red-100 text-red-800'
+      planned: 'bg-blue-50 text-blue-700',
+      failed: 'bg-red-100 text-red-800',
+      rolled_back: 'bg-yellow-100 text-yellow-800',
+      pending: 'bg-gray-100 text-gray-800'
     };
-    return colors[status] || colors.stopped;
+    return colors[status] || colors.pending;
   };
 
   return (